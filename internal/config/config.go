@@ -6,6 +6,12 @@ type Config struct {
 	Keybindings KeybindingsConfig `yaml:"keybindings"`
 	UI          UIConfig          `yaml:"ui"`
 	Theme       ThemeConfig       `yaml:"theme"`
+	Cache       CacheConfig       `yaml:"cache"`
+	Format      FormatConfig      `yaml:"format"`
+	Lint        LintConfig        `yaml:"lint"`
+	Session     SessionConfig     `yaml:"session"`
+	Editor      EditorConfig      `yaml:"editor"`
+	Results     ResultsConfig     `yaml:"results"`
 }
 
 // KeybindingsConfig contains all keybinding configurations
@@ -48,7 +54,8 @@ type NavigationKeybindings struct {
 type GlobalKeybindings struct {
 	Help         string `yaml:"help"`
 	Quit         string `yaml:"quit"`
-	ExecuteQuery string `yaml:"execute_query"`
+	ExecuteQuery string `yaml:"execute_query"` // Statement under the cursor or the visual selection
+	ExecuteAll   string `yaml:"execute_all"`   // Whole editor buffer
 	SaveQuery    string `yaml:"save_query"`
 	OpenNeovim   string `yaml:"open_neovim"`
 }
@@ -94,3 +101,45 @@ type ThemeConfig struct {
 	SyntaxHighlighting bool   `yaml:"syntax_highlighting"`
 	SQLLinting         bool   `yaml:"sql_linting"`
 }
+
+// CacheConfig contains schema metadata cache settings
+type CacheConfig struct {
+	Persist       bool `yaml:"persist"`         // Keep cache files in ~/.lazydb/cache
+	MaxAgeMinutes int  `yaml:"max_age_minutes"` // Refresh in background after this age (0 = never)
+}
+
+// SessionConfig contains session restore settings
+type SessionConfig struct {
+	Restore         bool `yaml:"restore"`          // Reopen buffers, layout and schema tree on startup
+	AutosaveSeconds int  `yaml:"autosave_seconds"` // Save the session this often (0 = only on quit)
+	MaxResultRows   int  `yaml:"max_result_rows"`  // Rows of each buffer's last result kept in the session
+}
+
+// EditorConfig contains external editor settings
+type EditorConfig struct {
+	Command     string `yaml:"command"`      // External editor, with {file}, {line} and {col}; default $VISUAL, $EDITOR
+	NvimAddress string `yaml:"nvim_address"` // Neovim socket for :nvim when $NVIM is unset
+}
+
+// ResultsConfig contains results panel settings
+type ResultsConfig struct {
+	Expanded       string `yaml:"expanded"`         // Record view as psql's \x: "off", "on" or "auto" (when the table is too wide)
+	MinColumnWidth int    `yaml:"min_column_width"` // Narrowest a table column gets, in terminal columns
+	MaxColumnWidth int    `yaml:"max_column_width"` // Widest a table column gets before values are cut
+	FetchLimit     int    `yaml:"fetch_limit"`      // Rows of a result kept in memory, 0 for all; :export! gets the rest
+}
+
+// FormatConfig contains SQL formatter settings
+type FormatConfig struct {
+	KeywordCase string `yaml:"keyword_case"` // "upper", "lower" or "preserve"
+	IndentWidth int    `yaml:"indent_width"`
+	CommaStyle  string `yaml:"comma_style"` // "trailing" or "leading"
+	LineWidth   int    `yaml:"line_width"`
+}
+
+// LintConfig contains SQL lint rule settings. Linting as a whole is
+// controlled by Theme.SQLLinting.
+type LintConfig struct {
+	Disabled []string          `yaml:"disabled"` // Rule names to turn off
+	Severity map[string]string `yaml:"severity"` // Rule name -> "info", "warning" or "error"
+}
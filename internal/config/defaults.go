@@ -7,6 +7,12 @@ func DefaultConfig() *Config {
 		Keybindings: DefaultKeybindings(),
 		UI:          DefaultUIConfig(),
 		Theme:       DefaultThemeConfig(),
+		Cache:       DefaultCacheConfig(),
+		Format:      DefaultFormatConfig(),
+		Lint:        DefaultLintConfig(),
+		Session:     DefaultSessionConfig(),
+		Editor:      DefaultEditorConfig(),
+		Results:     DefaultResultsConfig(),
 	}
 }
 
@@ -37,6 +43,7 @@ func DefaultKeybindings() KeybindingsConfig {
 			Help:         "?",
 			Quit:         "ctrl+q",
 			ExecuteQuery: "ctrl+r",
+			ExecuteAll:   "alt+r",
 			SaveQuery:    "f2",
 			OpenNeovim:   "ctrl+e",
 		},
@@ -81,3 +88,53 @@ func DefaultThemeConfig() ThemeConfig {
 		SQLLinting:         true,
 	}
 }
+
+// DefaultCacheConfig returns the default schema cache configuration
+func DefaultCacheConfig() CacheConfig {
+	return CacheConfig{
+		Persist:       true,
+		MaxAgeMinutes: 60,
+	}
+}
+
+// DefaultSessionConfig returns the default session restore configuration
+func DefaultSessionConfig() SessionConfig {
+	return SessionConfig{
+		Restore:         true,
+		AutosaveSeconds: 30,
+		MaxResultRows:   200,
+	}
+}
+
+// DefaultEditorConfig returns the default external editor configuration
+func DefaultEditorConfig() EditorConfig {
+	return EditorConfig{}
+}
+
+// DefaultResultsConfig returns the default results panel configuration
+func DefaultResultsConfig() ResultsConfig {
+	return ResultsConfig{
+		Expanded:       "off",
+		MinColumnWidth: 10,
+		MaxColumnWidth: 30,
+		FetchLimit:     100000,
+	}
+}
+
+// DefaultFormatConfig returns the default SQL formatter configuration
+func DefaultFormatConfig() FormatConfig {
+	return FormatConfig{
+		KeywordCase: "upper",
+		IndentWidth: 4,
+		CommaStyle:  "trailing",
+		LineWidth:   80,
+	}
+}
+
+// DefaultLintConfig returns the default lint configuration (all rules enabled)
+func DefaultLintConfig() LintConfig {
+	return LintConfig{
+		Disabled: []string{},
+		Severity: map[string]string{},
+	}
+}
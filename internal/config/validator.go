@@ -31,6 +31,62 @@ func ValidateConfig(cfg *Config) error {
 		return fmt.Errorf("resize_increment must be between 1 and 20, got %d", cfg.UI.ResizeIncrement)
 	}
 
+	// Validate cache settings
+	if cfg.Cache.MaxAgeMinutes < 0 {
+		return fmt.Errorf("cache.max_age_minutes must not be negative, got %d", cfg.Cache.MaxAgeMinutes)
+	}
+
+	// Validate session settings
+	if cfg.Session.AutosaveSeconds < 0 {
+		return fmt.Errorf("session.autosave_seconds must not be negative, got %d", cfg.Session.AutosaveSeconds)
+	}
+	if cfg.Session.MaxResultRows < 0 {
+		return fmt.Errorf("session.max_result_rows must not be negative, got %d", cfg.Session.MaxResultRows)
+	}
+
+	// Validate results settings
+	switch cfg.Results.Expanded {
+	case "off", "on", "auto":
+	default:
+		return fmt.Errorf("results.expanded must be off, on or auto, got %q", cfg.Results.Expanded)
+	}
+	if cfg.Results.MinColumnWidth < 1 {
+		return fmt.Errorf("results.min_column_width must be at least 1, got %d", cfg.Results.MinColumnWidth)
+	}
+	if cfg.Results.MaxColumnWidth < cfg.Results.MinColumnWidth {
+		return fmt.Errorf("results.max_column_width must be at least min_column_width (%d), got %d", cfg.Results.MinColumnWidth, cfg.Results.MaxColumnWidth)
+	}
+	if cfg.Results.FetchLimit < 0 {
+		return fmt.Errorf("results.fetch_limit must be 0 (all rows) or more, got %d", cfg.Results.FetchLimit)
+	}
+
+	// Validate formatter settings
+	switch cfg.Format.KeywordCase {
+	case "upper", "lower", "preserve":
+	default:
+		return fmt.Errorf("format.keyword_case must be upper, lower or preserve, got %q", cfg.Format.KeywordCase)
+	}
+	switch cfg.Format.CommaStyle {
+	case "trailing", "leading":
+	default:
+		return fmt.Errorf("format.comma_style must be trailing or leading, got %q", cfg.Format.CommaStyle)
+	}
+	if cfg.Format.IndentWidth < 1 || cfg.Format.IndentWidth > 8 {
+		return fmt.Errorf("format.indent_width must be between 1 and 8, got %d", cfg.Format.IndentWidth)
+	}
+	if cfg.Format.LineWidth < 20 {
+		return fmt.Errorf("format.line_width must be at least 20, got %d", cfg.Format.LineWidth)
+	}
+
+	// Validate lint settings
+	for rule, severity := range cfg.Lint.Severity {
+		switch severity {
+		case "info", "warning", "error":
+		default:
+			return fmt.Errorf("lint.severity.%s must be info, warning or error, got %q", rule, severity)
+		}
+	}
+
 	// Check for duplicate keybindings
 	if err := checkDuplicateKeys(cfg); err != nil {
 		return err
@@ -87,6 +143,9 @@ func checkDuplicateKeys(cfg *Config) error {
 	if err := addKey(cfg.Keybindings.Global.ExecuteQuery, "global.execute_query"); err != nil {
 		return err
 	}
+	if err := addKey(cfg.Keybindings.Global.ExecuteAll, "global.execute_all"); err != nil {
+		return err
+	}
 	if err := addKey(cfg.Keybindings.Global.SaveQuery, "global.save_query"); err != nil {
 		return err
 	}
@@ -0,0 +1,627 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CompletionKind represents the type of a completion candidate
+type CompletionKind int
+
+const (
+	CompletionJoin CompletionKind = iota
+	CompletionColumn
+	CompletionTable
+	CompletionView
+	CompletionSchema
+	CompletionFunction
+	CompletionKeyword
+)
+
+func (k CompletionKind) String() string {
+	switch k {
+	case CompletionJoin:
+		return "join"
+	case CompletionColumn:
+		return "column"
+	case CompletionTable:
+		return "table"
+	case CompletionView:
+		return "view"
+	case CompletionSchema:
+		return "schema"
+	case CompletionFunction:
+		return "function"
+	case CompletionKeyword:
+		return "keyword"
+	default:
+		return "unknown"
+	}
+}
+
+// CompletionItem is a single completion candidate
+type CompletionItem struct {
+	Label  string // Text shown in the popup
+	Insert string // Text that replaces the typed prefix
+	Detail string // Column type, function signature, ...
+	Kind   CompletionKind
+}
+
+// TableRef is a table referenced in the statement being edited
+type TableRef struct {
+	Schema string
+	Name   string
+	Alias  string
+}
+
+// CompletionResult contains the candidates for a cursor position
+type CompletionResult struct {
+	Items  []CompletionItem
+	Prefix string // Word typed before the cursor
+	Start  int    // Byte offset where Prefix starts
+
+	// MissingColumns lists tables in scope whose columns are not cached yet.
+	// Callers can load them and ask for completions again.
+	MissingColumns []TableRef
+}
+
+// Completer produces context-aware SQL completions from cached schema metadata
+type Completer struct {
+	cache         *MetadataCache
+	connName      string
+	DefaultSchema string // Schema used for unqualified table names
+}
+
+// NewCompleter creates a completer for a connection. The cache may be nil,
+// in which case only keywords and built-in functions are suggested.
+func NewCompleter(cache *MetadataCache, connName string) *Completer {
+	return &Completer{
+		cache:         cache,
+		connName:      connName,
+		DefaultSchema: "public",
+	}
+}
+
+// CacheColumns stores columns loaded for a table reported in MissingColumns
+func (c *Completer) CacheColumns(schema, table string, columns []TableColumn) {
+	if c.cache != nil {
+		c.cache.PutColumns(c.connName, schema, table, columns)
+	}
+}
+
+// scopeRef is a table reference with its position in the statement
+type scopeRef struct {
+	TableRef
+	pos int
+}
+
+// Complete returns completion candidates for the cursor at byte offset in text
+func (c *Completer) Complete(text string, offset int) CompletionResult {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(text) {
+		offset = len(text)
+	}
+
+	// Find the word being typed
+	start := offset
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(text[:start])
+		if !isIdentRune(r) {
+			break
+		}
+		start -= size
+	}
+	result := CompletionResult{Prefix: text[start:offset], Start: start}
+
+	tokens, err := Tokenize(text)
+	if err != nil {
+		// Unterminated quote somewhere after the cursor, use what we have
+		tokens, err = Tokenize(text[:offset])
+		if err != nil {
+			return result
+		}
+	}
+
+	// Never complete inside strings or comments
+	for _, tok := range tokens {
+		if tok.Kind == TokenString && tok.Start < offset && offset < tok.End {
+			return result
+		}
+		if tok.Kind == TokenComment && tok.Start < offset && offset <= tok.End {
+			return result
+		}
+	}
+
+	// Only look at the statement containing the cursor
+	stmtStart, stmtEnd := StatementRange(tokens, len(text), offset)
+	var stmt, before []SQLToken
+	for _, tok := range tokens {
+		if tok.Start < stmtStart || tok.End > stmtEnd || tok.Kind == TokenComment {
+			continue
+		}
+		stmt = append(stmt, tok)
+		if tok.End <= start {
+			before = append(before, tok)
+		}
+	}
+
+	// "alias.col" or "schema.table"
+	qualifier := ""
+	if n := len(before); n >= 2 && before[n-1].Text == "." && before[n-1].End == start && before[n-2].IsIdentLike() {
+		qualifier = unquoteIdent(before[n-2].Text)
+		before = before[:n-2]
+	}
+
+	scope, ctes := tablesInScope(stmt)
+
+	var items []CompletionItem
+	switch {
+	case qualifier != "":
+		items = c.qualifiedItems(qualifier, scope, &result)
+	case len(before) == 0:
+		items = keywordItems(statementKeywords, result.Prefix)
+	case isTableContext(before):
+		items = c.tableItems(ctes)
+	case isJoinContext(before):
+		items = c.joinItems(scope, start)
+		items = append(items, c.columnItems(scope, &result)...)
+		items = append(items, keywordItems(clauseKeywords, result.Prefix)...)
+	default:
+		items = c.columnItems(scope, &result)
+		items = append(items, c.functionItems()...)
+		items = append(items, keywordItems(clauseKeywords, result.Prefix)...)
+	}
+
+	result.Items = filterCompletions(items, result.Prefix)
+	return result
+}
+
+// qualifiedItems returns columns of an alias/table or objects of a schema
+func (c *Completer) qualifiedItems(qualifier string, scope []scopeRef, result *CompletionResult) []CompletionItem {
+	if c.cache == nil {
+		return nil
+	}
+
+	for _, ref := range scope {
+		if !strings.EqualFold(ref.Alias, qualifier) && !strings.EqualFold(ref.Name, qualifier) {
+			continue
+		}
+		// "FROM schema.|" is parsed as a table named after the schema
+		if _, ok := c.resolveTable(ref.TableRef); ok {
+			return c.columnItems([]scopeRef{ref}, result)
+		}
+	}
+
+	objects, ok := c.cache.Objects(c.connName, qualifier)
+	if !ok {
+		return nil
+	}
+
+	var items []CompletionItem
+	for _, table := range objects.Tables {
+		items = append(items, CompletionItem{Label: table.Name, Insert: QuoteIdent(table.Name), Detail: "table", Kind: CompletionTable})
+	}
+	for _, view := range objects.Views {
+		items = append(items, CompletionItem{Label: view.Name, Insert: QuoteIdent(view.Name), Detail: "view", Kind: CompletionView})
+	}
+	for _, fn := range objects.Functions {
+		items = append(items, CompletionItem{Label: fn.Name, Insert: QuoteIdent(fn.Name) + "(", Detail: fn.Signature, Kind: CompletionFunction})
+	}
+	return items
+}
+
+// tableItems returns CTEs, tables, views and schemas
+func (c *Completer) tableItems(ctes []string) []CompletionItem {
+	var items []CompletionItem
+	for _, cte := range ctes {
+		items = append(items, CompletionItem{Label: cte, Insert: QuoteIdent(cte), Detail: "cte", Kind: CompletionTable})
+	}
+
+	if c.cache == nil {
+		return items
+	}
+
+	schemas, _ := c.cache.Schemas(c.connName)
+	for _, schema := range schemas {
+		objects, _ := c.cache.Objects(c.connName, schema)
+		for _, table := range objects.Tables {
+			items = append(items, CompletionItem{Label: table.Name, Insert: c.qualifiedName(table), Detail: schema, Kind: CompletionTable})
+		}
+		for _, view := range objects.Views {
+			items = append(items, CompletionItem{Label: view.Name, Insert: c.qualifiedName(view), Detail: schema + " (view)", Kind: CompletionView})
+		}
+	}
+	for _, schema := range schemas {
+		items = append(items, CompletionItem{Label: schema, Insert: QuoteIdent(schema), Detail: "schema", Kind: CompletionSchema})
+	}
+	return items
+}
+
+// qualifiedName returns the name to insert for a table, adding the schema
+// unless it is the default one
+func (c *Completer) qualifiedName(obj SchemaObject) string {
+	if obj.Schema == "" || obj.Schema == c.DefaultSchema {
+		return QuoteIdent(obj.Name)
+	}
+	return QuoteIdent(obj.Schema) + "." + QuoteIdent(obj.Name)
+}
+
+// columnItems returns the columns of all tables in scope
+func (c *Completer) columnItems(scope []scopeRef, result *CompletionResult) []CompletionItem {
+	if c.cache == nil {
+		return nil
+	}
+
+	var items []CompletionItem
+	for _, ref := range scope {
+		schema, ok := c.resolveTable(ref.TableRef)
+		if !ok {
+			continue
+		}
+
+		columns, ok := c.cache.Columns(c.connName, schema, ref.Name)
+		if !ok {
+			result.MissingColumns = append(result.MissingColumns, TableRef{Schema: schema, Name: ref.Name, Alias: ref.Alias})
+			continue
+		}
+
+		source := ref.Name
+		if ref.Alias != "" {
+			source = ref.Alias
+		}
+		for _, col := range columns {
+			items = append(items, CompletionItem{
+				Label:  col.Name,
+				Insert: QuoteIdent(col.Name),
+				Detail: fmt.Sprintf("%s · %s", col.Type, source),
+				Kind:   CompletionColumn,
+			})
+		}
+	}
+	return items
+}
+
+// joinItems suggests join conditions from foreign keys between the table
+// being joined and the tables joined before it
+func (c *Completer) joinItems(scope []scopeRef, offset int) []CompletionItem {
+	if c.cache == nil {
+		return nil
+	}
+
+	// The joined table is the last one referenced before the cursor
+	target := -1
+	for i, ref := range scope {
+		if ref.pos < offset {
+			target = i
+		}
+	}
+	if target <= 0 {
+		return nil
+	}
+
+	joined := scope[target]
+	joinedSchema, ok := c.resolveTable(joined.TableRef)
+	if !ok {
+		return nil
+	}
+
+	var items []CompletionItem
+	for _, fk := range c.cache.ForeignKeys(c.connName) {
+		for _, other := range scope[:target] {
+			otherSchema, ok := c.resolveTable(other.TableRef)
+			if !ok {
+				continue
+			}
+
+			var condition string
+			switch {
+			case fk.Schema == joinedSchema && fk.Table == joined.Name && fk.RefSchema == otherSchema && fk.RefTable == other.Name:
+				condition = joinCondition(refQualifier(joined.TableRef), fk.Columns, refQualifier(other.TableRef), fk.RefColumns)
+			case fk.Schema == otherSchema && fk.Table == other.Name && fk.RefSchema == joinedSchema && fk.RefTable == joined.Name:
+				condition = joinCondition(refQualifier(joined.TableRef), fk.RefColumns, refQualifier(other.TableRef), fk.Columns)
+			default:
+				continue
+			}
+
+			items = append(items, CompletionItem{Label: condition, Insert: condition, Detail: fk.Name, Kind: CompletionJoin})
+		}
+	}
+	return items
+}
+
+// functionItems returns built-in and user-defined functions
+func (c *Completer) functionItems() []CompletionItem {
+	var items []CompletionItem
+	if c.cache != nil {
+		for _, obj := range c.cache.AllObjects(c.connName) {
+			if obj.Type == "function" {
+				items = append(items, CompletionItem{Label: obj.Name, Insert: c.qualifiedName(obj) + "(", Detail: obj.Signature, Kind: CompletionFunction})
+			}
+		}
+	}
+	for _, fn := range builtinFunctions {
+		items = append(items, CompletionItem{Label: fn.Name, Insert: fn.Name + "(", Detail: fn.Signature, Kind: CompletionFunction})
+	}
+	return items
+}
+
+// resolveTable finds the schema of a table reference in the cache
+func (c *Completer) resolveTable(ref TableRef) (string, bool) {
+	if ref.Schema != "" {
+		return ref.Schema, true
+	}
+
+	schemas, _ := c.cache.Schemas(c.connName)
+	found := ""
+	for _, schema := range schemas {
+		objects, _ := c.cache.Objects(c.connName, schema)
+		if containsObject(objects.Tables, ref.Name) || containsObject(objects.Views, ref.Name) {
+			if schema == c.DefaultSchema {
+				return schema, true
+			}
+			if found == "" {
+				found = schema
+			}
+		}
+	}
+	return found, found != ""
+}
+
+// containsObject reports whether objs contains an object with the given name
+func containsObject(objs []SchemaObject, name string) bool {
+	for _, obj := range objs {
+		if obj.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// tablesInScope finds the tables referenced in FROM, JOIN, UPDATE and INTO
+// clauses of a statement, and the names of its CTEs
+func tablesInScope(tokens []SQLToken) ([]scopeRef, []string) {
+	var refs []scopeRef
+	var ctes []string
+	inFrom := false
+
+	for i, tok := range tokens {
+		// CTE: WITH name AS ( ... ), name AS ( ... )
+		if (tok.Is("WITH") || tok.Text == ",") && i+3 < len(tokens) &&
+			tokens[i+1].IsIdentLike() && tokens[i+2].Is("AS") && tokens[i+3].Text == "(" {
+			ctes = append(ctes, unquoteIdent(tokens[i+1].Text))
+			continue
+		}
+
+		switch {
+		case tok.Is("FROM"):
+			inFrom = true
+		case tok.Is("JOIN"), tok.Is("UPDATE"), tok.Is("INTO"):
+		case tok.Text == "," && inFrom:
+		default:
+			if tok.Kind == TokenKeyword && clauseKeywordSet[tok.Upper()] {
+				inFrom = false
+			}
+			continue
+		}
+
+		j := i + 1
+		if j < len(tokens) && (tokens[j].Is("ONLY") || tokens[j].Is("LATERAL")) {
+			j++
+		}
+		if j >= len(tokens) || !tokens[j].IsIdentLike() {
+			continue
+		}
+
+		ref := scopeRef{pos: tokens[j].Start}
+		ref.Name = unquoteIdent(tokens[j].Text)
+		if j+2 < len(tokens) && tokens[j+1].Text == "." && tokens[j+2].IsIdentLike() {
+			ref.Schema = ref.Name
+			ref.Name = unquoteIdent(tokens[j+2].Text)
+			j += 2
+		}
+
+		k := j + 1
+		if k < len(tokens) && tokens[k].Is("AS") {
+			k++
+		}
+		if k < len(tokens) && tokens[k].IsIdentLike() && !clauseKeywordSet[tokens[k].Upper()] {
+			ref.Alias = unquoteIdent(tokens[k].Text)
+		}
+
+		refs = append(refs, ref)
+	}
+
+	return refs, ctes
+}
+
+// isTableContext reports whether a table name is expected at the cursor
+func isTableContext(before []SQLToken) bool {
+	last := before[len(before)-1]
+	switch last.Upper() {
+	case "FROM", "JOIN", "UPDATE", "INTO", "TABLE", "ONLY":
+		return true
+	case ",":
+		return currentClause(before) == "FROM"
+	}
+	return false
+}
+
+// isJoinContext reports whether a join condition is expected at the cursor
+func isJoinContext(before []SQLToken) bool {
+	last := before[len(before)-1]
+	if last.Is("ON") {
+		return true
+	}
+	return last.Is("AND") && currentClause(before) == "ON"
+}
+
+// currentClause returns the innermost clause keyword before the cursor,
+// ignoring anything inside parentheses
+func currentClause(before []SQLToken) string {
+	depth := 0
+	for i := len(before) - 1; i >= 0; i-- {
+		tok := before[i]
+		switch {
+		case tok.Text == ")":
+			depth++
+		case tok.Text == "(":
+			if depth == 0 {
+				return ""
+			}
+			depth--
+		case depth == 0 && tok.Kind == TokenKeyword && clauseKeywordSet[tok.Upper()]:
+			return tok.Upper()
+		}
+	}
+	return ""
+}
+
+// filterCompletions keeps items matching the prefix and drops duplicates
+func filterCompletions(items []CompletionItem, prefix string) []CompletionItem {
+	lowerPrefix := strings.ToLower(prefix)
+	seen := make(map[string]bool)
+
+	var filtered []CompletionItem
+	for _, item := range items {
+		if !strings.HasPrefix(strings.ToLower(item.Label), lowerPrefix) {
+			continue
+		}
+		// Don't suggest what's already typed
+		if strings.EqualFold(item.Label, prefix) && item.Kind == CompletionKeyword {
+			continue
+		}
+		key := item.Kind.String() + ":" + item.Insert
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		filtered = append(filtered, item)
+	}
+	return filtered
+}
+
+// keywordItems converts keywords to completion items, matching the case of the prefix
+func keywordItems(keywords []string, prefix string) []CompletionItem {
+	lower := prefix != "" && prefix == strings.ToLower(prefix)
+
+	items := make([]CompletionItem, 0, len(keywords))
+	for _, kw := range keywords {
+		insert := kw
+		if lower {
+			insert = strings.ToLower(kw)
+		}
+		items = append(items, CompletionItem{Label: kw, Insert: insert, Kind: CompletionKeyword})
+	}
+	return items
+}
+
+// joinCondition builds "a.x = b.y AND ..." for the given column pairs
+func joinCondition(leftQualifier string, leftColumns []string, rightQualifier string, rightColumns []string) string {
+	parts := make([]string, 0, len(leftColumns))
+	for i := range leftColumns {
+		if i >= len(rightColumns) {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s.%s = %s.%s",
+			leftQualifier, QuoteIdent(leftColumns[i]), rightQualifier, QuoteIdent(rightColumns[i])))
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// refQualifier returns the alias of a table reference, or its name
+func refQualifier(ref TableRef) string {
+	if ref.Alias != "" {
+		return QuoteIdent(ref.Alias)
+	}
+	return QuoteIdent(ref.Name)
+}
+
+// isIdentRune reports whether r can be part of an unquoted identifier
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// unquoteIdent returns the name an identifier token refers to
+func unquoteIdent(text string) string {
+	if len(text) >= 2 && strings.HasPrefix(text, `"`) && strings.HasSuffix(text, `"`) {
+		return strings.ReplaceAll(text[1:len(text)-1], `""`, `"`)
+	}
+	return strings.ToLower(text)
+}
+
+// statementKeywords start a statement
+var statementKeywords = []string{
+	"SELECT", "INSERT INTO", "UPDATE", "DELETE FROM", "WITH", "EXPLAIN", "EXPLAIN ANALYZE",
+	"CREATE TABLE", "CREATE VIEW", "CREATE INDEX", "CREATE FUNCTION", "CREATE SCHEMA",
+	"ALTER TABLE", "DROP TABLE", "DROP VIEW", "TRUNCATE", "BEGIN", "COMMIT", "ROLLBACK",
+	"VACUUM", "ANALYZE", "SHOW", "SET", "GRANT", "REVOKE", "COPY",
+}
+
+// clauseKeywords are suggested inside a statement
+var clauseKeywords = []string{
+	"FROM", "WHERE", "JOIN", "LEFT JOIN", "INNER JOIN", "RIGHT JOIN", "FULL JOIN", "CROSS JOIN",
+	"ON", "USING", "AND", "OR", "NOT", "IN", "EXISTS", "BETWEEN", "LIKE", "ILIKE", "IS NULL",
+	"IS NOT NULL", "GROUP BY", "ORDER BY", "HAVING", "LIMIT", "OFFSET", "AS", "DISTINCT",
+	"CASE", "WHEN", "THEN", "ELSE", "END", "UNION", "UNION ALL", "INTERSECT", "EXCEPT",
+	"ASC", "DESC", "NULLS FIRST", "NULLS LAST", "VALUES", "SET", "RETURNING", "DEFAULT",
+	"NULL", "TRUE", "FALSE", "WINDOW", "OVER", "PARTITION BY", "FILTER", "LATERAL",
+}
+
+// clauseKeywordSet contains keywords that start a new clause
+var clauseKeywordSet = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "ORDER": true, "HAVING": true,
+	"LIMIT": true, "OFFSET": true, "SET": true, "VALUES": true, "RETURNING": true, "ON": true,
+	"USING": true, "JOIN": true, "INTO": true, "UPDATE": true, "WINDOW": true, "UNION": true,
+	"INTERSECT": true, "EXCEPT": true, "LEFT": true, "RIGHT": true, "INNER": true, "FULL": true,
+	"CROSS": true, "NATURAL": true, "FOR": true,
+}
+
+// builtinFunctions are commonly used PostgreSQL functions
+var builtinFunctions = []SchemaObject{
+	{Name: "count", Signature: "(any) → bigint"},
+	{Name: "sum", Signature: "(numeric) → numeric"},
+	{Name: "avg", Signature: "(numeric) → numeric"},
+	{Name: "min", Signature: "(any) → any"},
+	{Name: "max", Signature: "(any) → any"},
+	{Name: "array_agg", Signature: "(anyelement) → anyarray"},
+	{Name: "string_agg", Signature: "(text, delimiter text) → text"},
+	{Name: "json_agg", Signature: "(anyelement) → json"},
+	{Name: "jsonb_agg", Signature: "(anyelement) → jsonb"},
+	{Name: "jsonb_build_object", Signature: "(variadic \"any\") → jsonb"},
+	{Name: "coalesce", Signature: "(value, ...) → any"},
+	{Name: "nullif", Signature: "(value1, value2) → any"},
+	{Name: "greatest", Signature: "(value, ...) → any"},
+	{Name: "least", Signature: "(value, ...) → any"},
+	{Name: "now", Signature: "() → timestamptz"},
+	{Name: "current_date", Signature: "→ date"},
+	{Name: "date_trunc", Signature: "(field text, source timestamp) → timestamp"},
+	{Name: "extract", Signature: "(field FROM source) → numeric"},
+	{Name: "age", Signature: "(timestamp, timestamp) → interval"},
+	{Name: "to_char", Signature: "(value, format text) → text"},
+	{Name: "to_date", Signature: "(text, format text) → date"},
+	{Name: "to_timestamp", Signature: "(text, format text) → timestamptz"},
+	{Name: "lower", Signature: "(text) → text"},
+	{Name: "upper", Signature: "(text) → text"},
+	{Name: "length", Signature: "(text) → integer"},
+	{Name: "substring", Signature: "(text FROM start FOR count) → text"},
+	{Name: "trim", Signature: "(text) → text"},
+	{Name: "concat", Signature: "(value, ...) → text"},
+	{Name: "replace", Signature: "(text, from text, to text) → text"},
+	{Name: "split_part", Signature: "(text, delimiter text, n integer) → text"},
+	{Name: "regexp_replace", Signature: "(text, pattern text, replacement text) → text"},
+	{Name: "round", Signature: "(numeric, digits integer) → numeric"},
+	{Name: "abs", Signature: "(numeric) → numeric"},
+	{Name: "row_number", Signature: "() → bigint"},
+	{Name: "rank", Signature: "() → bigint"},
+	{Name: "dense_rank", Signature: "() → bigint"},
+	{Name: "lag", Signature: "(value, offset integer) → any"},
+	{Name: "lead", Signature: "(value, offset integer) → any"},
+	{Name: "generate_series", Signature: "(start, stop, step) → setof any"},
+	{Name: "unnest", Signature: "(anyarray) → setof anyelement"},
+	{Name: "pg_size_pretty", Signature: "(bigint) → text"},
+	{Name: "pg_total_relation_size", Signature: "(regclass) → bigint"},
+}
@@ -54,9 +54,10 @@ type ConnectionConfig struct {
 
 // SchemaObject represents a database schema object
 type SchemaObject struct {
-	Name   string
-	Type   string // "table", "view", "function", "sequence"
-	Schema string
+	Name      string
+	Type      string // "table", "view", "function", "sequence"
+	Schema    string
+	Signature string // For functions: "(arg types) → return type"
 }
 
 // TableColumn represents a table column
@@ -67,6 +68,17 @@ type TableColumn struct {
 	Default  string
 }
 
+// ForeignKey represents a foreign key constraint between two tables
+type ForeignKey struct {
+	Name       string
+	Schema     string
+	Table      string
+	Columns    []string
+	RefSchema  string
+	RefTable   string
+	RefColumns []string
+}
+
 // Connection represents a database connection
 type Connection interface {
 	Connect(ctx context.Context) error
@@ -81,6 +93,7 @@ type Connection interface {
 	ListViews(ctx context.Context, schema string) ([]SchemaObject, error)
 	ListFunctions(ctx context.Context, schema string) ([]SchemaObject, error)
 	GetTableColumns(ctx context.Context, schema, table string) ([]TableColumn, error)
+	ListForeignKeys(ctx context.Context, schema string) ([]ForeignKey, error)
 }
 
 // ConnectionManager manages multiple database connections
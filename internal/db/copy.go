@@ -0,0 +1,158 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CopyOptions are the CSV settings of a COPY
+type CopyOptions struct {
+	Delimiter string // Field separator, "," when empty
+	Header    bool   // The first line holds the column names
+	Null      string // Text of NULL values
+}
+
+// clause returns the WITH clause of a COPY in CSV format
+func (o CopyOptions) clause() string {
+	delimiter := o.Delimiter
+	if delimiter == "" {
+		delimiter = ","
+	}
+	return fmt.Sprintf("WITH (FORMAT csv, HEADER %t, DELIMITER %s, NULL %s)", o.Header, QuoteLiteral(delimiter), QuoteLiteral(o.Null))
+}
+
+// CopyOutStatement returns the COPY that writes the rows of a query as CSV.
+// The parenthesis closes on a line of its own, so that a line comment
+// ending the query doesn't swallow it.
+func CopyOutStatement(query string, opts CopyOptions) string {
+	if statements := SplitStatements(query); len(statements) == 1 {
+		query = statements[0].Text
+	}
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\n")
+	return fmt.Sprintf("COPY (%s\n) TO STDOUT %s", query, opts.clause())
+}
+
+// CopyInStatement returns the COPY that reads CSV rows into a table. table
+// may name the columns to fill, e.g. users (id, email).
+func CopyInStatement(table string, opts CopyOptions) string {
+	return fmt.Sprintf("COPY %s FROM STDIN %s", strings.TrimSpace(table), opts.clause())
+}
+
+// CopyProgress is how far a COPY got
+type CopyProgress struct {
+	Bytes   int64         // Bytes transferred
+	Total   int64         // Bytes to transfer, 0 when unknown
+	Rows    int64         // Rows transferred; counted from line breaks until the COPY ends
+	Elapsed time.Duration // Time since the COPY started
+}
+
+// Throughput returns the bytes transferred per second
+func (p CopyProgress) Throughput() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Bytes) / p.Elapsed.Seconds()
+}
+
+// copyProgressInterval is how often a COPY reports its progress
+const copyProgressInterval = 100 * time.Millisecond
+
+// copyCounter counts the bytes and lines going through a COPY and reports
+// them at most every copyProgressInterval
+type copyCounter struct {
+	progress CopyProgress
+	started  time.Time
+	reported time.Time
+	report   func(CopyProgress)
+}
+
+func newCopyCounter(total int64, report func(CopyProgress)) *copyCounter {
+	now := time.Now()
+	return &copyCounter{progress: CopyProgress{Total: total}, started: now, reported: now, report: report}
+}
+
+// add counts a chunk of data
+func (c *copyCounter) add(p []byte) {
+	c.progress.Bytes += int64(len(p))
+	c.progress.Rows += int64(bytes.Count(p, []byte{'\n'}))
+	if c.report != nil && time.Since(c.reported) >= copyProgressInterval {
+		c.reported = time.Now()
+		c.report(c.current())
+	}
+}
+
+// current returns the progress so far
+func (c *copyCounter) current() CopyProgress {
+	p := c.progress
+	p.Elapsed = time.Since(c.started)
+	return p
+}
+
+// finish records the row count the server reported and reports the end
+func (c *copyCounter) finish(rows int64) CopyProgress {
+	c.progress.Rows = rows
+	p := c.current()
+	if c.report != nil {
+		c.report(p)
+	}
+	return p
+}
+
+type countingWriter struct {
+	w io.Writer
+	c *copyCounter
+}
+
+func (w countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.c.add(p[:n])
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	c *copyCounter
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.c.add(p[:n])
+	return n, err
+}
+
+// CopyOut runs COPY (query) TO STDOUT and writes the rows to w as CSV,
+// without holding them in memory. progress, if set, is called as data
+// arrives and once at the end. Cancelling ctx stops the COPY.
+func CopyOut(ctx context.Context, conn *pgx.Conn, query string, w io.Writer, opts CopyOptions, progress func(CopyProgress)) (CopyProgress, error) {
+	if strings.TrimSpace(query) == "" {
+		return CopyProgress{}, errors.New("no query to copy")
+	}
+	counter := newCopyCounter(0, progress)
+	tag, err := conn.PgConn().CopyTo(ctx, countingWriter{w: w, c: counter}, CopyOutStatement(query, opts))
+	if err != nil {
+		return counter.current(), err
+	}
+	return counter.finish(tag.RowsAffected()), nil
+}
+
+// CopyIn runs COPY table FROM STDIN, streaming CSV rows from r. total is
+// the size of the input for progress, 0 when unknown. The COPY is a single
+// statement: if it fails or ctx is cancelled, no row is added.
+func CopyIn(ctx context.Context, conn *pgx.Conn, table string, r io.Reader, total int64, opts CopyOptions, progress func(CopyProgress)) (CopyProgress, error) {
+	if strings.TrimSpace(table) == "" {
+		return CopyProgress{}, errors.New("no table to copy into")
+	}
+	counter := newCopyCounter(total, progress)
+	tag, err := conn.PgConn().CopyFrom(ctx, countingReader{r: r, c: counter}, CopyInStatement(table, opts))
+	if err != nil {
+		return counter.current(), err
+	}
+	return counter.finish(tag.RowsAffected()), nil
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+)
+
+// DiffKind is how a row changed between two results
+type DiffKind int
+
+const (
+	DiffSame    DiffKind = iota // Same key, same values
+	DiffAdded                   // Key only in the second result
+	DiffRemoved                 // Key only in the first result
+	DiffChanged                 // Same key, some values differ
+)
+
+// DiffRow is a row of either result, matched by key. Before and After hold
+// the values of the diff's columns, NULL as NullText and flagged in
+// BeforeNulls and AfterNulls; Before is nil for an added row and After for
+// a removed one.
+type DiffRow struct {
+	Kind        DiffKind
+	Key         string
+	KeyNull     bool // The key is NULL rather than the text of Key
+	Before      []string
+	After       []string
+	BeforeNulls []bool
+	AfterNulls  []bool
+	Changed     []bool // Columns whose value differs, for a changed row
+}
+
+// diffValue is a value compared by DiffResults: a NULL differs from any
+// text, NullText included
+type diffValue struct {
+	null bool
+	text string
+}
+
+// ResultDiff compares two results row by row
+type ResultDiff struct {
+	Columns []string // Columns of the second result, then those only in the first
+	Key     string
+	Rows    []DiffRow // In the order of the second result, removed rows after
+	Added   int
+	Removed int
+	Changed int
+	Same    int
+}
+
+// DiffResults matches the rows of two results on a key column, present in
+// both, and compares the other columns by name. Rows with a repeated key
+// are matched in order.
+func DiffResults(before, after QueryResult, key string) (ResultDiff, error) {
+	diff := ResultDiff{Key: key, Columns: slices.Clone(after.Columns)}
+	for _, col := range before.Columns {
+		if !slices.Contains(diff.Columns, col) {
+			diff.Columns = append(diff.Columns, col)
+		}
+	}
+	beforeKey, afterKey := slices.Index(before.Columns, key), slices.Index(after.Columns, key)
+	if beforeKey < 0 || afterKey < 0 {
+		return diff, fmt.Errorf("key column %s is not in both results", key)
+	}
+
+	// Values are compared in the columns of both results
+	shared := make([]bool, len(diff.Columns))
+	for i, col := range diff.Columns {
+		shared[i] = slices.Contains(before.Columns, col) && slices.Contains(after.Columns, col)
+	}
+
+	valueOf := func(r QueryResult, row, col int) diffValue {
+		if r.IsNull(row, col) || col >= len(r.Rows[row]) {
+			return diffValue{null: true, text: NullText}
+		}
+		return diffValue{text: r.Rows[row][col]}
+	}
+	// values returns a row's values in the diff's column order and which
+	// are NULL
+	values := func(r QueryResult, row int) ([]string, []bool) {
+		out, nulls := make([]string, len(diff.Columns)), make([]bool, len(diff.Columns))
+		for i, col := range diff.Columns {
+			if j := slices.Index(r.Columns, col); j >= 0 {
+				v := valueOf(r, row, j)
+				out[i], nulls[i] = v.text, v.null
+			}
+		}
+		return out, nulls
+	}
+
+	pending := map[diffValue][]int{} // Unmatched rows of before, by key
+	for i := range before.Rows {
+		k := valueOf(before, i, beforeKey)
+		pending[k] = append(pending[k], i)
+	}
+	matched := make([]bool, len(before.Rows))
+	for i := range after.Rows {
+		k := valueOf(after, i, afterKey)
+		row := DiffRow{Key: k.text, KeyNull: k.null}
+		row.After, row.AfterNulls = values(after, i)
+		if queue := pending[k]; len(queue) > 0 {
+			pending[k] = queue[1:]
+			matched[queue[0]] = true
+			row.Before, row.BeforeNulls = values(before, queue[0])
+			row.Changed = make([]bool, len(diff.Columns))
+			for c := range diff.Columns {
+				if shared[c] && (row.Before[c] != row.After[c] || row.BeforeNulls[c] != row.AfterNulls[c]) {
+					row.Changed[c] = true
+					row.Kind = DiffChanged
+				}
+			}
+		} else {
+			row.Kind = DiffAdded
+		}
+		diff.Rows = append(diff.Rows, row)
+	}
+	for i := range before.Rows {
+		if !matched[i] {
+			k := valueOf(before, i, beforeKey)
+			row := DiffRow{Kind: DiffRemoved, Key: k.text, KeyNull: k.null}
+			row.Before, row.BeforeNulls = values(before, i)
+			diff.Rows = append(diff.Rows, row)
+		}
+	}
+
+	for _, row := range diff.Rows {
+		switch row.Kind {
+		case DiffAdded:
+			diff.Added++
+		case DiffRemoved:
+			diff.Removed++
+		case DiffChanged:
+			diff.Changed++
+		default:
+			diff.Same++
+		}
+	}
+	return diff, nil
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"bytes"
+	"cmp"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NullText is the text of a NULL value in a result
+const NullText = "NULL"
+
+// FormatValue converts a value read from PostgreSQL to the text shown in a
+// result: json and jsonb as JSON, bytea in hex format (\x...) and arrays as
+// array literals ({1,2,"a b"}), the way psql prints them
+func FormatValue(v any, typeName string) string {
+	if v == nil {
+		return NullText
+	}
+	if typeName == "json" || typeName == "jsonb" {
+		if text, err := formatJSON(v); err == nil {
+			return text
+		}
+	}
+	switch v := v.(type) {
+	case []byte:
+		return `\x` + hex.EncodeToString(v)
+	case []any:
+		return formatArray(v)
+	case [16]byte:
+		if typeName == "uuid" {
+			return fmt.Sprintf("%x-%x-%x-%x-%x", v[0:4], v[4:6], v[6:8], v[8:10], v[10:16])
+		}
+	case driver.Valuer:
+		// pgtype values such as numeric and interval give their text form
+		if value, err := v.Value(); err == nil {
+			return FormatValue(value, typeName)
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// formatJSON encodes v as compact JSON without escaping HTML characters
+func formatJSON(v any) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// formatArray writes an array literal, quoting the elements that need it
+func formatArray(values []any) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		switch v := v.(type) {
+		case nil:
+			b.WriteString(NullText)
+		case []any:
+			b.WriteString(formatArray(v))
+		default:
+			b.WriteString(quoteArrayElement(FormatValue(v, "")))
+		}
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// quoteArrayElement double-quotes an array element that is empty, reads as
+// NULL or holds characters with a meaning in array literals
+func quoteArrayElement(s string) string {
+	if s != "" && !strings.EqualFold(s, "NULL") && !strings.ContainsAny(s, "{},\"\\ \t\n\r") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// Type names compared as numbers or times by CompareValues
+var (
+	numericTypes  = map[string]bool{"int2": true, "int4": true, "int8": true, "float4": true, "float8": true, "numeric": true, "oid": true}
+	temporalTypes = map[string]bool{"date": true, "timestamp": true, "timestamptz": true, "time": true, "timetz": true}
+)
+
+// timeLayouts are the formats of times in results: Go's for time.Time
+// values, then PostgreSQL's
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+	"15:04:05.999999999-07",
+	"15:04:05.999999999",
+}
+
+// CompareValues orders two values of a column of the given type: numbers
+// numerically, dates and times chronologically, anything else (or values
+// that don't parse) as text. It returns -1, 0 or 1.
+func CompareValues(a, b, typeName string) int {
+	switch {
+	case numericTypes[typeName]:
+		x, errA := strconv.ParseFloat(a, 64)
+		y, errB := strconv.ParseFloat(b, 64)
+		if errA == nil && errB == nil {
+			return cmp.Compare(x, y)
+		}
+	case temporalTypes[typeName]:
+		x, okA := parseTime(a)
+		y, okB := parseTime(b)
+		if okA && okB {
+			return x.Compare(y)
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// IsNumericType reports whether values of a type are numbers
+func IsNumericType(typeName string) bool {
+	return numericTypes[typeName]
+}
+
+// parseTime parses a time in one of timeLayouts
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,741 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// Keyword case options
+const (
+	KeywordCaseUpper    = "upper"
+	KeywordCaseLower    = "lower"
+	KeywordCasePreserve = "preserve"
+)
+
+// Comma style options
+const (
+	CommaTrailing = "trailing"
+	CommaLeading  = "leading"
+)
+
+// FormatOptions controls the SQL formatter output
+type FormatOptions struct {
+	KeywordCase string // "upper", "lower" or "preserve"
+	IndentWidth int    // Spaces per indentation level
+	CommaStyle  string // "trailing" or "leading"
+	LineWidth   int    // Lists and conditions longer than this are split over lines
+}
+
+// DefaultFormatOptions returns the default formatter options
+func DefaultFormatOptions() FormatOptions {
+	return FormatOptions{
+		KeywordCase: KeywordCaseUpper,
+		IndentWidth: 4,
+		CommaStyle:  CommaTrailing,
+		LineWidth:   80,
+	}
+}
+
+// Formatter pretty-prints SQL. The query is validated with the PostgreSQL
+// parser first; layout works on scanner tokens so comments are preserved.
+type Formatter struct {
+	opts FormatOptions
+}
+
+// NewFormatter creates a new SQL formatter
+func NewFormatter(opts FormatOptions) *Formatter {
+	if opts.IndentWidth <= 0 {
+		opts.IndentWidth = DefaultFormatOptions().IndentWidth
+	}
+	if opts.LineWidth <= 0 {
+		opts.LineWidth = DefaultFormatOptions().LineWidth
+	}
+	return &Formatter{opts: opts}
+}
+
+// Options returns the formatter options
+func (f *Formatter) Options() FormatOptions {
+	return f.opts
+}
+
+// Format formats all statements in query. Invalid SQL is returned unchanged
+// together with the parse error.
+func (f *Formatter) Format(query string) (string, error) {
+	if strings.TrimSpace(query) == "" {
+		return query, nil
+	}
+
+	if _, err := pg_query.Parse(query); err != nil {
+		return query, err
+	}
+
+	tokens, err := Tokenize(query)
+	if err != nil {
+		return query, err
+	}
+
+	run := &formatRun{opts: f.opts, src: query}
+	var out []string
+	for _, stmt := range splitStatements(tokens, query) {
+		out = append(out, run.formatTopLevel(stmt))
+	}
+
+	formatted := strings.Join(out, "\n\n")
+	if strings.HasSuffix(query, "\n") {
+		formatted += "\n"
+	}
+	return formatted, nil
+}
+
+// FormatRange formats the part of query between start and end (byte offsets),
+// keeping the surrounding text and whitespace as they are
+func (f *Formatter) FormatRange(query string, start, end int) (string, error) {
+	if start < 0 || end > len(query) || start > end {
+		return query, fmt.Errorf("invalid range %d-%d", start, end)
+	}
+
+	part := query[start:end]
+	trimmed := strings.TrimSpace(part)
+	if trimmed == "" {
+		return query, nil
+	}
+	leading := part[:strings.Index(part, trimmed)]
+	trailing := part[len(leading)+len(trimmed):]
+
+	formatted, err := f.Format(trimmed)
+	if err != nil {
+		return query, err
+	}
+	return query[:start] + leading + formatted + trailing + query[end:], nil
+}
+
+// formatStmt is a statement with the comments following it on the same line
+type formatStmt struct {
+	tokens     []SQLToken
+	terminated bool       // Ended with a semicolon
+	trailing   []SQLToken // Comments after the semicolon on the same line
+}
+
+// formatRun holds the state of a single Format call
+type formatRun struct {
+	opts FormatOptions
+	src  string
+}
+
+// splitStatements splits tokens at top-level semicolons
+func splitStatements(tokens []SQLToken, src string) []formatStmt {
+	var stmts []formatStmt
+	var current formatStmt
+	depth := 0
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch tok.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+
+		if tok.Kind == TokenPunct && tok.Text == ";" && depth <= 0 {
+			current.terminated = true
+			// Comments on the same line as the semicolon belong to this statement
+			for i+1 < len(tokens) && tokens[i+1].Kind == TokenComment &&
+				!strings.Contains(src[tok.End:tokens[i+1].Start], "\n") {
+				current.trailing = append(current.trailing, tokens[i+1])
+				tok = tokens[i+1]
+				i++
+			}
+			stmts = append(stmts, current)
+			current = formatStmt{}
+			depth = 0
+			continue
+		}
+		current.tokens = append(current.tokens, tok)
+	}
+
+	if len(current.tokens) > 0 {
+		stmts = append(stmts, current)
+	}
+	return stmts
+}
+
+// formatTopLevel formats one statement including its terminator and comments
+func (r *formatRun) formatTopLevel(stmt formatStmt) string {
+	main, comments := r.splitTrailingComments(stmt.tokens)
+
+	text := r.formatStatement(main, 0)
+	if stmt.terminated {
+		// A line comment on its own line before the semicolon would swallow it
+		if n := len(main); n > 0 && isLineComment(main[n-1]) {
+			text += "\n"
+		}
+		text += ";"
+	}
+	comments = append(comments, stmt.trailing...)
+	if len(comments) > 0 {
+		if text != "" {
+			text += " "
+		}
+		text += r.renderInline(comments, 0)
+	}
+	return text
+}
+
+// formatClause is a clause keyword (e.g. "GROUP BY") and the tokens after it
+type formatClause struct {
+	keyword []SQLToken
+	body    []SQLToken
+}
+
+// formatStatement formats a statement (or subquery) at the given indentation level
+func (r *formatRun) formatStatement(tokens []SQLToken, level int) string {
+	var lines []string
+	for _, c := range r.splitClauses(tokens) {
+		lines = append(lines, r.formatClause(c, level))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitClauses splits a statement at top-level clause keywords
+func (r *formatRun) splitClauses(tokens []SQLToken) []formatClause {
+	var clauses []formatClause
+	current := formatClause{}
+	depth := 0
+	seenUpdate := false
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if depth == 0 && tok.Kind == TokenKeyword {
+			if n := clauseKeywordLength(tokens, i, seenUpdate); n > 0 {
+				if len(current.keyword) > 0 || len(current.body) > 0 {
+					clauses = append(clauses, current)
+				}
+				current = formatClause{keyword: tokens[i : i+n]}
+				if tok.Is("UPDATE") {
+					seenUpdate = true
+				}
+				i += n - 1
+				continue
+			}
+		}
+
+		switch tok.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		current.body = append(current.body, tok)
+	}
+
+	if len(current.keyword) > 0 || len(current.body) > 0 {
+		clauses = append(clauses, current)
+	}
+	return clauses
+}
+
+// clauseKeywordLength returns how many tokens starting at i form a clause
+// keyword, or 0 if tokens[i] doesn't start a clause
+func clauseKeywordLength(tokens []SQLToken, i int, seenUpdate bool) int {
+	at := func(j int, text string) bool {
+		return j < len(tokens) && tokens[j].Is(text)
+	}
+	prevIs := func(text string) bool {
+		for j := i - 1; j >= 0; j-- {
+			if tokens[j].Kind != TokenComment {
+				return tokens[j].Is(text)
+			}
+		}
+		return false
+	}
+	first := true
+	for j := 0; j < i; j++ {
+		if tokens[j].Kind != TokenComment {
+			first = false
+			break
+		}
+	}
+
+	switch tokens[i].Upper() {
+	case "SELECT", "WHERE", "HAVING", "LIMIT", "OFFSET", "RETURNING", "WINDOW", "FETCH":
+		return 1
+	case "VALUES":
+		if prevIs("DEFAULT") {
+			return 0
+		}
+		return 1
+	case "FROM":
+		if prevIs("DELETE") || prevIs("DISTINCT") {
+			return 0
+		}
+		return 1
+	case "GROUP", "ORDER":
+		if at(i+1, "BY") {
+			return 2
+		}
+	case "UNION", "INTERSECT", "EXCEPT":
+		if at(i+1, "ALL") || at(i+1, "DISTINCT") {
+			return 2
+		}
+		return 1
+	case "SET":
+		if first || seenUpdate {
+			return 1
+		}
+	case "INSERT":
+		if at(i+1, "INTO") {
+			return 2
+		}
+		return 1
+	case "DELETE":
+		if at(i+1, "FROM") {
+			return 2
+		}
+		return 1
+	case "UPDATE":
+		if prevIs("FOR") || prevIs("DO") || prevIs("NO") || prevIs("KEY") {
+			return 0
+		}
+		return 1
+	case "WITH":
+		if first {
+			if at(i+1, "RECURSIVE") {
+				return 2
+			}
+			return 1
+		}
+	case "ON":
+		if at(i+1, "CONFLICT") {
+			return 2
+		}
+	case "JOIN", "LEFT", "RIGHT", "FULL", "INNER", "CROSS", "NATURAL":
+		j := i
+		if at(j, "NATURAL") {
+			j++
+		}
+		if at(j, "LEFT") || at(j, "RIGHT") || at(j, "FULL") {
+			j++
+			if at(j, "OUTER") {
+				j++
+			}
+		} else if at(j, "INNER") || at(j, "CROSS") {
+			j++
+		}
+		if at(j, "JOIN") {
+			return j - i + 1
+		}
+	}
+	return 0
+}
+
+// formatClause formats a single clause
+func (r *formatRun) formatClause(c formatClause, level int) string {
+	ind := r.indent(level)
+	if len(c.keyword) == 0 {
+		return ind + r.renderInline(c.body, level)
+	}
+
+	kw := r.renderInline(c.keyword, level)
+	if len(c.body) == 0 {
+		return ind + kw
+	}
+
+	switch strings.ToUpper(c.keyword[0].Text) {
+	case "WITH":
+		return r.formatWith(kw, c.body, level)
+	case "SELECT", "GROUP", "ORDER", "SET", "RETURNING", "VALUES", "FROM":
+		return r.formatList(kw, c.body, level)
+	case "WHERE", "HAVING":
+		return r.formatConditions(ind+kw, c.body, level)
+	case "JOIN", "LEFT", "RIGHT", "FULL", "INNER", "CROSS", "NATURAL":
+		return r.formatJoin(kw, c.body, level)
+	}
+
+	// Render keyword and body together so "INSERT INTO t (a)" keeps its spacing
+	tokens := append(append([]SQLToken{}, c.keyword...), c.body...)
+	return ind + r.renderInline(tokens, level+1)
+}
+
+// formatList formats a comma separated clause, one item per line if it doesn't fit
+func (r *formatRun) formatList(kw string, body []SQLToken, level int) string {
+	ind := r.indent(level)
+	items := r.splitTopLevel(body, func(tok SQLToken) bool { return tok.Text == "," }, false)
+
+	if !hasInnerLineComment(body) {
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = r.renderInline(item, level+1)
+		}
+		line := ind + kw + " " + strings.Join(parts, ", ")
+		if r.fits(line) {
+			return line
+		}
+	}
+
+	lines := []string{ind + kw}
+	itemInd := r.indent(level + 1)
+	for i, item := range items {
+		main, comments := r.splitTrailingComments(item)
+		text := r.renderInline(main, level+1)
+
+		switch {
+		case r.opts.CommaStyle == CommaLeading && i > 0:
+			prefix := itemInd
+			if len(prefix) >= 2 {
+				prefix = prefix[:len(prefix)-2]
+			}
+			text = prefix + ", " + text
+		case r.opts.CommaStyle != CommaLeading && i < len(items)-1:
+			text = itemInd + text + ","
+		default:
+			text = itemInd + text
+		}
+
+		if len(comments) > 0 {
+			text += " " + r.renderInline(comments, level+1)
+		}
+		lines = append(lines, text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatWith formats CTEs, each starting on its own line at the statement level
+func (r *formatRun) formatWith(kw string, body []SQLToken, level int) string {
+	ind := r.indent(level)
+	items := r.splitTopLevel(body, func(tok SQLToken) bool { return tok.Text == "," }, false)
+
+	var parts []string
+	for i, item := range items {
+		main, comments := r.splitTrailingComments(item)
+		text := r.renderInline(main, level)
+		if i < len(items)-1 {
+			text += ","
+		}
+		if len(comments) > 0 {
+			text += " " + r.renderInline(comments, level)
+		}
+		parts = append(parts, text)
+	}
+	return ind + kw + " " + strings.Join(parts, "\n"+ind)
+}
+
+// formatConditions formats a WHERE/HAVING/ON condition, one AND/OR per line
+// if it doesn't fit
+func (r *formatRun) formatConditions(head string, body []SQLToken, level int) string {
+	if !hasInnerLineComment(body) {
+		line := head + " " + r.renderInline(body, level)
+		if r.fits(line) {
+			return line
+		}
+	}
+
+	parts := r.splitTopLevel(body, func(tok SQLToken) bool { return tok.Is("AND") || tok.Is("OR") }, true)
+	lines := make([]string, 0, len(parts))
+	for i, part := range parts {
+		if i == 0 {
+			// A line comment after the keyword ends its line: the first
+			// condition goes under it, like the others
+			if len(part) > 1 && isLineComment(part[0]) {
+				lines = append(lines, head+" "+part[0].Text, r.indent(level+1)+r.renderInline(part[1:], level+1))
+				continue
+			}
+			lines = append(lines, head+" "+r.renderInline(part, level))
+		} else {
+			lines = append(lines, r.indent(level+1)+r.renderInline(part, level+1))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatJoin formats "JOIN table ON condition", moving the condition to its
+// own line if it doesn't fit
+func (r *formatRun) formatJoin(kw string, body []SQLToken, level int) string {
+	ind := r.indent(level)
+	line := ind + kw + " " + r.renderInline(body, level+1)
+	if r.fits(line) && !hasInnerLineComment(body) {
+		return line
+	}
+
+	depth := 0
+	for i, tok := range body {
+		switch tok.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth == 0 && (tok.Is("ON") || tok.Is("USING")) {
+			table := ind + kw + " " + r.renderInline(body[:i], level+1)
+			head := r.indent(level+1) + r.renderInline(body[i:i+1], level+1)
+			return table + "\n" + r.formatConditions(head, body[i+1:], level+1)
+		}
+	}
+	return line
+}
+
+// splitTopLevel splits tokens at separators outside of parentheses. With
+// keepSeparator the separator starts the next part (AND/OR), otherwise it is
+// dropped (commas).
+func (r *formatRun) splitTopLevel(tokens []SQLToken, isSep func(SQLToken) bool, keepSeparator bool) [][]SQLToken {
+	var parts [][]SQLToken
+	var current []SQLToken
+	depth := 0
+	inBetween := false
+	caseDepth := 0
+
+	for _, tok := range tokens {
+		switch {
+		case tok.Text == "(":
+			depth++
+		case tok.Text == ")":
+			depth--
+		case tok.Is("BETWEEN"):
+			inBetween = true
+		case tok.Is("CASE"):
+			caseDepth++
+		case tok.Is("END") && caseDepth > 0:
+			caseDepth--
+		}
+
+		if depth == 0 && caseDepth == 0 && isSep(tok) {
+			// "x BETWEEN a AND b" is a single condition
+			if inBetween && tok.Is("AND") {
+				inBetween = false
+				current = append(current, tok)
+				continue
+			}
+			if len(current) > 0 {
+				parts = append(parts, current)
+			}
+			current = nil
+			if keepSeparator {
+				current = append(current, tok)
+			}
+			continue
+		}
+		current = append(current, tok)
+	}
+
+	if len(current) > 0 {
+		parts = append(parts, current)
+	}
+
+	// A comment right after a comma on the same line belongs to the previous item
+	for i := 1; i < len(parts); i++ {
+		for len(parts[i]) > 0 && parts[i][0].Kind == TokenComment && len(parts[i-1]) > 0 {
+			prev := parts[i-1][len(parts[i-1])-1]
+			if strings.Contains(r.src[prev.End:parts[i][0].Start], "\n") {
+				break
+			}
+			parts[i-1] = append(parts[i-1], parts[i][0])
+			parts[i] = parts[i][1:]
+		}
+	}
+	return parts
+}
+
+// splitTrailingComments separates comments at the end of tokens that are on
+// the same line as the last non-comment token
+func (r *formatRun) splitTrailingComments(tokens []SQLToken) ([]SQLToken, []SQLToken) {
+	end := len(tokens)
+	for end > 0 && tokens[end-1].Kind == TokenComment {
+		end--
+	}
+	if end == 0 || end == len(tokens) {
+		return tokens, nil
+	}
+	if strings.Contains(r.src[tokens[end-1].End:tokens[end].Start], "\n") {
+		return tokens, nil
+	}
+	return tokens[:end], tokens[end:]
+}
+
+// renderInline renders tokens on one line, except for subqueries (which are
+// formatted as nested statements) and line comments (which end the line)
+func (r *formatRun) renderInline(tokens []SQLToken, level int) string {
+	var b strings.Builder
+	var emitted []SQLToken
+	atLineStart := true
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+
+		if tok.Kind == TokenComment && len(emitted) > 0 {
+			// Keep comments that were on their own line on their own line
+			prev := emitted[len(emitted)-1]
+			if !atLineStart && strings.Contains(r.src[prev.End:tok.Start], "\n") {
+				b.WriteString("\n" + r.indent(level))
+				atLineStart = true
+			}
+		}
+
+		if !atLineStart && len(emitted) > 0 && needsSpace(emitted, tok) {
+			b.WriteString(" ")
+		}
+
+		// Subquery: "(SELECT ...)" becomes a nested, indented statement
+		if tok.Text == "(" && i+1 < len(tokens) && startsSubquery(tokens[i+1]) {
+			if end := matchingParen(tokens, i); end > i {
+				b.WriteString("(\n")
+				b.WriteString(r.formatStatement(tokens[i+1:end], level+1))
+				b.WriteString("\n" + r.indent(level) + ")")
+				emitted = append(emitted, tok, tokens[end])
+				atLineStart = false
+				i = end
+				continue
+			}
+		}
+
+		b.WriteString(r.tokenText(tok))
+		emitted = append(emitted, tok)
+		atLineStart = false
+
+		if isLineComment(tok) && i < len(tokens)-1 {
+			b.WriteString("\n" + r.indent(level))
+			atLineStart = true
+		}
+	}
+
+	return strings.TrimRight(b.String(), " \n")
+}
+
+// tokenText returns a token's text with keyword casing applied
+func (r *formatRun) tokenText(tok SQLToken) string {
+	if tok.Kind != TokenKeyword || !formattableKeywords[tok.Upper()] && !reservedKeywords[tok.Upper()] {
+		return tok.Text
+	}
+	switch r.opts.KeywordCase {
+	case KeywordCaseUpper:
+		return strings.ToUpper(tok.Text)
+	case KeywordCaseLower:
+		return strings.ToLower(tok.Text)
+	}
+	return tok.Text
+}
+
+// indent returns the indentation for a level
+func (r *formatRun) indent(level int) string {
+	return strings.Repeat(" ", level*r.opts.IndentWidth)
+}
+
+// fits reports whether a single line fits within the line width
+func (r *formatRun) fits(line string) bool {
+	return !strings.Contains(line, "\n") && utf8.RuneCountInString(line) <= r.opts.LineWidth
+}
+
+// needsSpace reports whether a space goes between the last emitted token and cur
+func needsSpace(emitted []SQLToken, cur SQLToken) bool {
+	prev := emitted[len(emitted)-1]
+
+	switch cur.Text {
+	case ",", ")", ";", ".", "::", "]":
+		return false
+	case "[":
+		return prev.Kind != TokenIdent && prev.Kind != TokenKeyword && prev.Text != ")"
+	case "(":
+		if prev.Kind == TokenKeyword {
+			return spaceBeforeParen[prev.Upper()]
+		}
+		if prev.Kind == TokenIdent {
+			// "INSERT INTO t (a, b)" but "count(x)"
+			return nameFollowsKeyword(emitted, "INTO", "TABLE", "REFERENCES", "EXISTS", "AS")
+		}
+	}
+
+	switch prev.Text {
+	case "(", ".", "::", "[":
+		return false
+	}
+	return true
+}
+
+// nameFollowsKeyword reports whether the (possibly qualified) name at the end
+// of emitted directly follows one of the given keywords
+func nameFollowsKeyword(emitted []SQLToken, keywords ...string) bool {
+	i := len(emitted) - 1
+	for i >= 0 && (emitted[i].Kind == TokenIdent || emitted[i].Text == ".") {
+		i--
+	}
+	if i < 0 {
+		return false
+	}
+	for _, kw := range keywords {
+		if emitted[i].Is(kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// startsSubquery reports whether a token starts a nested statement
+func startsSubquery(tok SQLToken) bool {
+	return tok.Is("SELECT") || tok.Is("WITH") || tok.Is("VALUES") ||
+		tok.Is("INSERT") || tok.Is("UPDATE") || tok.Is("DELETE")
+}
+
+// matchingParen returns the index of the parenthesis closing tokens[open]
+func matchingParen(tokens []SQLToken, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch tokens[i].Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// hasInnerLineComment reports whether a line comment appears before the last token
+func hasInnerLineComment(tokens []SQLToken) bool {
+	for i := 0; i < len(tokens)-1; i++ {
+		if isLineComment(tokens[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLineComment reports whether a token is a -- comment, which ends its line
+func isLineComment(tok SQLToken) bool {
+	return tok.Kind == TokenComment && strings.HasPrefix(tok.Text, "--")
+}
+
+// spaceBeforeParen lists keywords followed by a space before "("
+var spaceBeforeParen = map[string]bool{
+	"AS": true, "IN": true, "ON": true, "AND": true, "OR": true, "NOT": true, "FROM": true,
+	"JOIN": true, "WHERE": true, "SELECT": true, "ANY": true, "ALL": true, "SOME": true,
+	"INTO": true, "VALUES": true, "EXISTS": true, "OVER": true, "FILTER": true, "USING": true,
+	"SET": true, "WITHIN": true, "TABLE": true, "UNION": true, "INTERSECT": true, "EXCEPT": true,
+	"WHEN": true, "THEN": true, "ELSE": true, "LATERAL": true, "CHECK": true, "KEY": true,
+	"REFERENCES": true, "UNIQUE": true, "CONFLICT": true, "RETURNING": true, "HAVING": true,
+	"BY": true, "IS": true, "LIKE": true, "ILIKE": true, "BETWEEN": true, "CASE": true,
+}
+
+// formattableKeywords are non-reserved keywords that get keyword casing.
+// Other non-reserved keywords (name, type, ...) are usually column names.
+var formattableKeywords = map[string]bool{
+	"BY": true, "INSERT": true, "UPDATE": true, "DELETE": true, "SET": true, "VALUES": true,
+	"ASC": true, "DESC": true, "NULLS": true, "FIRST": true, "LAST": true, "OVER": true,
+	"PARTITION": true, "ROWS": true, "RANGE": true, "PRECEDING": true, "FOLLOWING": true,
+	"UNBOUNDED": true, "CURRENT": true, "ROW": true, "BEGIN": true, "COMMIT": true,
+	"ROLLBACK": true, "TRUNCATE": true, "EXPLAIN": true, "ANALYZE": true, "VERBOSE": true,
+	"ALTER": true, "DROP": true, "ADD": true, "RENAME": true, "INDEX": true, "VIEW": true,
+	"SCHEMA": true, "FUNCTION": true, "RETURNS": true, "LANGUAGE": true, "IF": true,
+	"CASCADE": true, "RESTRICT": true, "EXISTS": true, "BETWEEN": true, "LIKE": true,
+	"ILIKE": true, "IS": true, "JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true,
+	"FULL": true, "OUTER": true, "CROSS": true, "NATURAL": true, "INTERVAL": true,
+	"COALESCE": true, "NULLIF": true, "EXTRACT": true, "RECURSIVE": true, "MATERIALIZED": true,
+	"CONFLICT": true, "NOTHING": true, "COPY": true, "TEMP": true, "TEMPORARY": true,
+	"SEQUENCE": true, "REPLACE": true, "FILTER": true, "WITHIN": true, "OVERLAPS": true,
+	"VACUUM": true, "SHOW": true, "GRANT": true, "REVOKE": true, "FOREIGN": true, "KEY": true,
+	"PRIMARY": true, "REFERENCES": true, "NO": true, "ACTION": true, "TRIGGER": true,
+}
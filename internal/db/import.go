@@ -0,0 +1,333 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ImportConflict is what an import does with a row whose key is already in
+// the table
+type ImportConflict int
+
+const (
+	ConflictError  ImportConflict = iota // The row fails
+	ConflictSkip                         // ON CONFLICT DO NOTHING
+	ConflictUpdate                       // ON CONFLICT (key) DO UPDATE
+)
+
+func (c ImportConflict) String() string {
+	switch c {
+	case ConflictSkip:
+		return "skip"
+	case ConflictUpdate:
+		return "update"
+	}
+	return "error"
+}
+
+// ImportColumn maps a column of the imported file onto a table column
+type ImportColumn struct {
+	Source int    // Column of the file
+	Name   string // Column of the table
+	Type   string // Type of the column when the import creates the table
+	Key    bool   // Part of the conflict key, and of the primary key of a created table
+}
+
+// ImportPlan says where and how the rows of a file are loaded
+type ImportPlan struct {
+	Table      string // Target table, optionally schema-qualified
+	Create     bool   // Create the table before loading
+	Columns    []ImportColumn
+	OnConflict ImportConflict
+	SkipErrors bool // Commit the rows that loaded and report the others; otherwise any failed row rolls back the import
+}
+
+// Validate checks that the plan can run
+func (p ImportPlan) Validate() error {
+	if err := p.ValidateColumns(); err != nil {
+		return err
+	}
+	keys := 0
+	for _, col := range p.Columns {
+		if col.Key {
+			keys++
+		}
+	}
+	if p.OnConflict == ConflictUpdate && keys == 0 {
+		return errors.New("updating on conflict needs key columns")
+	}
+	if p.OnConflict == ConflictUpdate && keys == len(p.Columns) {
+		return errors.New("updating on conflict needs a column outside the key")
+	}
+	return nil
+}
+
+// ValidateColumns checks the target table and columns of the plan, leaving
+// out the conflict handling
+func (p ImportPlan) ValidateColumns() error {
+	if strings.TrimSpace(p.Table) == "" {
+		return errors.New("no target table")
+	}
+	if len(p.Columns) == 0 {
+		return errors.New("no column to import")
+	}
+	names := map[string]bool{}
+	for _, col := range p.Columns {
+		if col.Name == "" {
+			return errors.New("a column has no name")
+		}
+		if names[col.Name] {
+			return fmt.Errorf("column %s is imported twice", col.Name)
+		}
+		names[col.Name] = true
+		if p.Create && col.Type == "" {
+			return fmt.Errorf("column %s has no type", col.Name)
+		}
+	}
+	return nil
+}
+
+// CreateStatement returns the CREATE TABLE of a plan, with the key columns
+// as primary key
+func (p ImportPlan) CreateStatement() string {
+	var lines, keys []string
+	for _, col := range p.Columns {
+		lines = append(lines, fmt.Sprintf("  %s %s", QuoteIdent(col.Name), col.Type))
+		if col.Key {
+			keys = append(keys, QuoteIdent(col.Name))
+		}
+	}
+	if len(keys) > 0 {
+		lines = append(lines, fmt.Sprintf("  PRIMARY KEY (%s)", strings.Join(keys, ", ")))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n);", QuoteQualifiedName(p.Table), strings.Join(lines, ",\n"))
+}
+
+// InsertStatement returns the INSERT of one row of the file. Values are
+// quoted literals, which PostgreSQL converts to the column types.
+func (p ImportPlan) InsertStatement(row []string, nulls []bool) string {
+	names := make([]string, len(p.Columns))
+	values := make([]string, len(p.Columns))
+	var keys, updates []string
+	for i, col := range p.Columns {
+		names[i] = QuoteIdent(col.Name)
+		if col.Source < len(row) && !(col.Source < len(nulls) && nulls[col.Source]) {
+			values[i] = QuoteLiteral(row[col.Source])
+		} else {
+			values[i] = "NULL"
+		}
+		if col.Key {
+			keys = append(keys, names[i])
+		} else {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", names[i], names[i]))
+		}
+	}
+	stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", QuoteQualifiedName(p.Table), strings.Join(names, ", "), strings.Join(values, ", "))
+	switch p.OnConflict {
+	case ConflictSkip:
+		stmt += " ON CONFLICT DO NOTHING"
+	case ConflictUpdate:
+		stmt += fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(keys, ", "), strings.Join(updates, ", "))
+	}
+	return stmt
+}
+
+// ImportRowError is a row of the file that failed to load
+type ImportRowError struct {
+	Row int // Data row of the file, from 1
+	Err string
+}
+
+// ImportReport is the outcome of an import
+type ImportReport struct {
+	Inserted  int // Rows inserted or updated
+	Skipped   int // Rows left out by ON CONFLICT DO NOTHING
+	Errors    []ImportRowError
+	Committed bool
+	Elapsed   time.Duration
+}
+
+// maxImportErrors is how many failed rows an import reports before giving
+// up when it rolls back on errors anyway
+const maxImportErrors = 100
+
+// RunImport loads the rows of data into the plan's table in a transaction,
+// creating the table first if asked. Each row runs under a savepoint so a
+// failed row is reported without ending the transaction. The import is
+// committed unless a row failed and the plan doesn't skip errors.
+func RunImport(ctx context.Context, conn *pgx.Conn, plan ImportPlan, data QueryResult, progress func(done int)) (ImportReport, error) {
+	start := time.Now()
+	var report ImportReport
+	if err := plan.Validate(); err != nil {
+		return report, err
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback(ctx)
+
+	if plan.Create {
+		if _, err := tx.Exec(ctx, plan.CreateStatement()); err != nil {
+			return report, fmt.Errorf("creating %s: %w", plan.Table, err)
+		}
+	}
+
+	for i, row := range data.Rows {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if progress != nil {
+			progress(i)
+		}
+		var nulls []bool
+		if i < len(data.Nulls) {
+			nulls = data.Nulls[i]
+		}
+		if _, err := tx.Exec(ctx, "SAVEPOINT import_row"); err != nil {
+			return report, err
+		}
+		tag, err := tx.Exec(ctx, plan.InsertStatement(row, nulls))
+		if err != nil {
+			report.Errors = append(report.Errors, ImportRowError{Row: i + 1, Err: err.Error()})
+			if _, err := tx.Exec(ctx, "ROLLBACK TO SAVEPOINT import_row"); err != nil {
+				return report, err
+			}
+			if !plan.SkipErrors && len(report.Errors) >= maxImportErrors {
+				break
+			}
+			continue
+		}
+		if tag.RowsAffected() == 0 {
+			report.Skipped++
+		} else {
+			report.Inserted++
+		}
+	}
+
+	if len(report.Errors) == 0 || plan.SkipErrors {
+		if err := tx.Commit(ctx); err != nil {
+			return report, err
+		}
+		report.Committed = true
+	}
+	report.Elapsed = time.Since(start)
+	return report, nil
+}
+
+// ImportTypes are the column types an import infers or offers, from the
+// narrowest
+var ImportTypes = []string{"boolean", "integer", "bigint", "numeric", "date", "timestamp", "timestamptz", "uuid", "jsonb", "text"}
+
+var (
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	decimalPattern  = regexp.MustCompile(`^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?$`)
+	datePattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	timestampLayout = []string{"2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999"}
+	timestampTZ     = []string{"2006-01-02 15:04:05.999999999Z07:00", "2006-01-02T15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999Z07", "2006-01-02 15:04:05.999999999 -0700 MST"}
+)
+
+// fitsType reports whether a text value can be read as a type
+func fitsType(value, typeName string) bool {
+	switch typeName {
+	case "boolean":
+		switch strings.ToLower(value) {
+		case "true", "false", "t", "f", "yes", "no":
+			return true
+		}
+		return false
+	case "integer":
+		_, err := strconv.ParseInt(value, 10, 32)
+		return err == nil
+	case "bigint":
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case "numeric":
+		return decimalPattern.MatchString(value)
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		return err == nil && datePattern.MatchString(value)
+	case "timestamp":
+		return parsesAs(value, timestampLayout)
+	case "timestamptz":
+		return parsesAs(value, timestampTZ)
+	case "uuid":
+		return uuidPattern.MatchString(value)
+	case "jsonb":
+		trimmed := strings.TrimSpace(value)
+		return (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed))
+	}
+	return true
+}
+
+// parsesAs reports whether a value parses with one of the layouts
+func parsesAs(value string, layouts []string) bool {
+	for _, layout := range layouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// InferColumnType returns the narrowest of ImportTypes that every non-NULL
+// value of a column fits, text when the column is empty. Only values
+// flagged as NULL are skipped: an empty string that isn't the NULL text is
+// a value, which only text fits.
+func InferColumnType(data QueryResult, col int) string {
+	var values []string
+	for i, row := range data.Rows {
+		if col < len(row) && !data.IsNull(i, col) {
+			values = append(values, row[col])
+		}
+	}
+	if len(values) == 0 {
+		return "text"
+	}
+	for _, typeName := range ImportTypes {
+		fits := true
+		for _, v := range values {
+			if !fitsType(v, typeName) {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return typeName
+		}
+	}
+	return "text"
+}
+
+// InferColumnTypes sets the ColumnTypes of imported data
+func InferColumnTypes(data *QueryResult) {
+	data.ColumnTypes = make([]string, len(data.Columns))
+	for col := range data.Columns {
+		data.ColumnTypes[col] = InferColumnType(*data, col)
+	}
+}
+
+var identifierJunk = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// ImportIdentifier turns a file or header name into a lower case column or
+// table name: "Order Date" becomes order_date
+func ImportIdentifier(name string) string {
+	name = identifierJunk.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return "column"
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
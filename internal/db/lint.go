@@ -0,0 +1,998 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// LintSeverity is the severity of a lint issue
+type LintSeverity int
+
+const (
+	SeverityInfo LintSeverity = iota
+	SeverityWarning
+	SeverityError
+)
+
+// String returns the config name of a severity
+func (s LintSeverity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// ParseLintSeverity parses "info", "warning" or "error"
+func ParseLintSeverity(s string) (LintSeverity, error) {
+	switch strings.ToLower(s) {
+	case "info":
+		return SeverityInfo, nil
+	case "warning":
+		return SeverityWarning, nil
+	case "error":
+		return SeverityError, nil
+	}
+	return SeverityInfo, fmt.Errorf("unknown severity %q", s)
+}
+
+// LintIssue is a problem found by a lint rule
+type LintIssue struct {
+	Rule       string
+	Severity   LintSeverity
+	Message    string
+	Suggestion string
+	Line       int
+	Column     int
+	Offset     int // Byte offset in the query
+	Length     int // Length in bytes of the flagged text
+}
+
+// LintContext is passed to lint rules
+type LintContext struct {
+	Query string
+	Tree  *pg_query.ParseResult
+
+	cache         *MetadataCache
+	connName      string
+	defaultSchema string
+}
+
+// HasSchema reports whether schema metadata is available for rules that
+// check table and column names
+func (c *LintContext) HasSchema() bool {
+	if c.cache == nil {
+		return false
+	}
+	_, ok := c.cache.Schemas(c.connName)
+	return ok
+}
+
+// Issue creates an issue at a byte offset, flagging the token found there
+func (c *LintContext) Issue(offset int, message, suggestion string) LintIssue {
+	if offset < 0 || offset > len(c.Query) {
+		offset = 0
+	}
+	return LintIssue{
+		Message:    message,
+		Suggestion: suggestion,
+		Offset:     offset,
+		Length:     tokenLengthAt(c.Query, offset),
+	}
+}
+
+// LintRule checks a parsed query. Rules only fill in Message, Suggestion,
+// Offset and Length of the issues they return.
+type LintRule interface {
+	Name() string
+	Description() string
+	DefaultSeverity() LintSeverity
+	Check(ctx *LintContext) []LintIssue
+}
+
+// Linter runs lint rules over parsed queries
+type Linter struct {
+	rules         []LintRule
+	disabled      map[string]bool
+	severity      map[string]LintSeverity
+	cache         *MetadataCache
+	connName      string
+	DefaultSchema string
+}
+
+// NewLinter creates a linter with all built-in rules enabled
+func NewLinter() *Linter {
+	l := &Linter{
+		disabled:      make(map[string]bool),
+		severity:      make(map[string]LintSeverity),
+		DefaultSchema: "public",
+	}
+	for _, rule := range builtinLintRules() {
+		l.Register(rule)
+	}
+	return l
+}
+
+// Register adds a rule, replacing any rule with the same name
+func (l *Linter) Register(rule LintRule) {
+	for i, existing := range l.rules {
+		if existing.Name() == rule.Name() {
+			l.rules[i] = rule
+			return
+		}
+	}
+	l.rules = append(l.rules, rule)
+}
+
+// Rules returns all registered rules
+func (l *Linter) Rules() []LintRule {
+	return l.rules
+}
+
+// SetEnabled enables or disables a rule by name
+func (l *Linter) SetEnabled(name string, enabled bool) {
+	l.disabled[name] = !enabled
+}
+
+// IsEnabled reports whether a rule is enabled
+func (l *Linter) IsEnabled(name string) bool {
+	return !l.disabled[name]
+}
+
+// SetSeverity overrides the severity of a rule
+func (l *Linter) SetSeverity(name string, severity LintSeverity) {
+	l.severity[name] = severity
+}
+
+// SetSchemaSource sets the metadata used to check table and column names
+func (l *Linter) SetSchemaSource(cache *MetadataCache, connName string) {
+	l.cache = cache
+	l.connName = connName
+}
+
+// Lint runs all enabled rules over query. Queries that don't parse yield no issues.
+func (l *Linter) Lint(query string) []LintIssue {
+	tree, err := pg_query.Parse(query)
+	if err != nil {
+		return nil
+	}
+
+	ctx := &LintContext{
+		Query:         query,
+		Tree:          tree,
+		cache:         l.cache,
+		connName:      l.connName,
+		defaultSchema: l.DefaultSchema,
+	}
+
+	var issues []LintIssue
+	for _, rule := range l.rules {
+		if l.disabled[rule.Name()] {
+			continue
+		}
+		severity, ok := l.severity[rule.Name()]
+		if !ok {
+			severity = rule.DefaultSeverity()
+		}
+
+		for _, issue := range rule.Check(ctx) {
+			issue.Rule = rule.Name()
+			issue.Severity = severity
+			issue.Line, issue.Column = OffsetToLineColumn(query, issue.Offset)
+			issues = append(issues, issue)
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Offset < issues[j].Offset
+	})
+	return issues
+}
+
+// lintRule is a built-in rule backed by a check function
+type lintRule struct {
+	name        string
+	description string
+	severity    LintSeverity
+	check       func(ctx *LintContext) []LintIssue
+}
+
+func (r lintRule) Name() string                       { return r.name }
+func (r lintRule) Description() string                { return r.description }
+func (r lintRule) DefaultSeverity() LintSeverity      { return r.severity }
+func (r lintRule) Check(ctx *LintContext) []LintIssue { return r.check(ctx) }
+
+// builtinLintRules returns the rules every linter starts with
+func builtinLintRules() []LintRule {
+	return []LintRule{
+		lintRule{"unknown-table", "Table or view not found in the schema metadata", SeverityError, checkUnknownTables},
+		lintRule{"unknown-column", "Column not found in the referenced tables", SeverityError, checkUnknownColumns},
+		lintRule{"missing-where", "UPDATE or DELETE without a WHERE clause", SeverityWarning, checkMissingWhere},
+		lintRule{"select-star-in-view", "SELECT * in a view definition", SeverityWarning, checkSelectStarInView},
+		lintRule{"implicit-cross-join", "Comma-separated FROM without a join condition", SeverityWarning, checkImplicitCrossJoin},
+		lintRule{"not-in-nullable", "NOT IN with a subquery that may return NULL", SeverityWarning, checkNotInNullable},
+		lintRule{"missing-limit", "SELECT from a table without LIMIT", SeverityInfo, checkMissingLimit},
+	}
+}
+
+// walkTree calls fn for every node below msg. Returning false from fn skips
+// the node's children.
+func walkTree(msg proto.Message, fn func(node proto.Message) bool) {
+	if msg == nil {
+		return
+	}
+	walkMessage(msg.ProtoReflect(), fn)
+}
+
+func walkMessage(msg protoreflect.Message, fn func(node proto.Message) bool) {
+	if !msg.IsValid() {
+		return
+	}
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Message() == nil || fd.IsMap() {
+			return true
+		}
+		if fd.IsList() {
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				visitMessage(list.Get(i).Message(), fn)
+			}
+			return true
+		}
+		visitMessage(v.Message(), fn)
+		return true
+	})
+}
+
+func visitMessage(msg protoreflect.Message, fn func(node proto.Message) bool) {
+	if !msg.IsValid() {
+		return
+	}
+	if fn(msg.Interface()) {
+		walkMessage(msg, fn)
+	}
+}
+
+// checkMissingWhere flags UPDATE and DELETE statements that affect every row
+func checkMissingWhere(ctx *LintContext) []LintIssue {
+	var issues []LintIssue
+	walkTree(ctx.Tree, func(node proto.Message) bool {
+		switch n := node.(type) {
+		case *pg_query.UpdateStmt:
+			if n.WhereClause == nil && n.Relation != nil {
+				issues = append(issues, ctx.Issue(int(n.Relation.Location),
+					fmt.Sprintf("UPDATE without WHERE changes every row in %s", n.Relation.Relname),
+					"Add a WHERE clause to limit the affected rows"))
+			}
+		case *pg_query.DeleteStmt:
+			if n.WhereClause == nil && n.Relation != nil {
+				issues = append(issues, ctx.Issue(int(n.Relation.Location),
+					fmt.Sprintf("DELETE without WHERE removes every row from %s", n.Relation.Relname),
+					"Add a WHERE clause, or use TRUNCATE if this is intended"))
+			}
+		}
+		return true
+	})
+	return issues
+}
+
+// checkSelectStarInView flags SELECT * in views, whose column list is fixed
+// at creation time
+func checkSelectStarInView(ctx *LintContext) []LintIssue {
+	var issues []LintIssue
+	checkQuery := func(query *pg_query.Node) {
+		for _, sel := range setOperands(query.GetSelectStmt()) {
+			for _, target := range sel.TargetList {
+				ref := target.GetResTarget().GetVal().GetColumnRef()
+				if ref == nil || !isStarRef(ref) {
+					continue
+				}
+				offset := int(ref.Location)
+				if star := strings.IndexByte(ctx.Query[offset:], '*'); star >= 0 {
+					offset += star
+				}
+				issues = append(issues, ctx.Issue(offset,
+					"SELECT * in a view: columns added to the table later won't appear in the view",
+					"List the columns explicitly"))
+			}
+		}
+	}
+
+	walkTree(ctx.Tree, func(node proto.Message) bool {
+		switch n := node.(type) {
+		case *pg_query.ViewStmt:
+			checkQuery(n.Query)
+		case *pg_query.CreateTableAsStmt:
+			if n.Objtype == pg_query.ObjectType_OBJECT_MATVIEW {
+				checkQuery(n.Query)
+			}
+		}
+		return true
+	})
+	return issues
+}
+
+// checkImplicitCrossJoin flags "FROM a, b" without any WHERE condition
+func checkImplicitCrossJoin(ctx *LintContext) []LintIssue {
+	var issues []LintIssue
+	walkTree(ctx.Tree, func(node proto.Message) bool {
+		sel, ok := node.(*pg_query.SelectStmt)
+		if !ok || len(sel.FromClause) < 2 || sel.WhereClause != nil {
+			return true
+		}
+
+		second := sel.FromClause[1]
+		offset := nodeLocation(second)
+		issues = append(issues, ctx.Issue(offset,
+			fmt.Sprintf("Implicit cross join of %s and %s", fromItemName(sel.FromClause[0]), fromItemName(second)),
+			"Use an explicit JOIN ... ON, or CROSS JOIN if the cartesian product is intended"))
+		return true
+	})
+	return issues
+}
+
+// checkNotInNullable flags "x NOT IN (SELECT col ...)" where col may be NULL,
+// which makes the condition never true
+func checkNotInNullable(ctx *LintContext) []LintIssue {
+	var issues []LintIssue
+	walkTree(ctx.Tree, func(node proto.Message) bool {
+		expr, ok := node.(*pg_query.BoolExpr)
+		if !ok || expr.Boolop != pg_query.BoolExprType_NOT_EXPR || len(expr.Args) != 1 {
+			return true
+		}
+		link := expr.Args[0].GetSubLink()
+		if link == nil || link.SubLinkType != pg_query.SubLinkType_ANY_SUBLINK {
+			return true
+		}
+		sub := link.Subselect.GetSelectStmt()
+		if sub == nil || len(sub.TargetList) != 1 {
+			return true
+		}
+
+		ref := sub.TargetList[0].GetResTarget().GetVal().GetColumnRef()
+		if ref == nil {
+			return true
+		}
+		column := columnRefNames(ref)
+		if len(column) == 0 || hasNotNullTest(sub.WhereClause, column[len(column)-1]) {
+			return true
+		}
+		if ctx.columnNotNull(sub, column) {
+			return true
+		}
+
+		issues = append(issues, ctx.Issue(int(expr.Location),
+			"NOT IN with a subquery returns no rows if the subquery yields a NULL",
+			"Use NOT EXISTS, or filter NULLs in the subquery"))
+		return true
+	})
+	return issues
+}
+
+// checkMissingLimit flags top-level SELECTs reading tables without LIMIT
+func checkMissingLimit(ctx *LintContext) []LintIssue {
+	var issues []LintIssue
+	for _, raw := range ctx.Tree.Stmts {
+		sel := raw.Stmt.GetSelectStmt()
+		if sel == nil || sel.Op != pg_query.SetOperation_SETOP_NONE || sel.IntoClause != nil {
+			continue
+		}
+		if sel.LimitCount != nil || len(sel.GroupClause) > 0 || len(sel.LockingClause) > 0 {
+			continue
+		}
+		if len(sel.FromClause) == 0 || hasAggregate(sel.TargetList) {
+			continue
+		}
+
+		issues = append(issues, ctx.Issue(int(raw.StmtLocation)+leadingSpace(ctx.Query[raw.StmtLocation:]),
+			"SELECT without LIMIT may return a very large result",
+			"Add a LIMIT while exploring data"))
+	}
+	return issues
+}
+
+// checkUnknownTables flags tables and views that don't exist in the cached metadata
+func checkUnknownTables(ctx *LintContext) []LintIssue {
+	if !ctx.HasSchema() {
+		return nil
+	}
+
+	ctes := make(map[string]bool)
+	walkTree(ctx.Tree, func(node proto.Message) bool {
+		if cte, ok := node.(*pg_query.CommonTableExpr); ok {
+			ctes[cte.Ctename] = true
+		}
+		return true
+	})
+
+	var issues []LintIssue
+	var visit func(node proto.Message) bool
+	visit = func(node proto.Message) bool {
+		switch n := node.(type) {
+		case *pg_query.CreateStmt, *pg_query.CreateFunctionStmt, *pg_query.DropStmt, *pg_query.RenameStmt:
+			// The relation doesn't exist yet or is only named
+			return false
+		case *pg_query.ViewStmt:
+			walkTree(n.Query, visit)
+			return false
+		case *pg_query.CreateTableAsStmt:
+			walkTree(n.Query, visit)
+			return false
+		case *pg_query.RangeVar:
+			if n.Schemaname == "" && ctes[n.Relname] {
+				return true
+			}
+			exists, known := ctx.relationExists(n.Schemaname, n.Relname)
+			if known && !exists {
+				name := n.Relname
+				if n.Schemaname != "" {
+					name = n.Schemaname + "." + n.Relname
+				}
+				issue := ctx.Issue(int(n.Location), fmt.Sprintf("relation %q does not exist", name), "")
+				issue.Length = len(name)
+				if match := closestName(n.Relname, ctx.relationNames(n.Schemaname)); match != "" {
+					issue.Suggestion = fmt.Sprintf("Did you mean %s?", match)
+				}
+				issues = append(issues, issue)
+			}
+		}
+		return true
+	}
+	walkTree(ctx.Tree, visit)
+	return issues
+}
+
+// lintScopeTable is a table visible in a query scope
+type lintScopeTable struct {
+	alias   string // Alias or table name used to qualify columns
+	columns []TableColumn
+	known   bool // Columns are known
+}
+
+// lintScope is the set of tables a SELECT (or UPDATE/DELETE) can reference
+type lintScope struct {
+	tables  []lintScopeTable
+	aliases map[string]bool // Output column names usable in ORDER BY/GROUP BY
+	parent  *lintScope
+}
+
+// columnChecker resolves column references against cached table columns
+type columnChecker struct {
+	ctx    *LintContext
+	ctes   map[string]bool
+	issues []LintIssue
+}
+
+// checkUnknownColumns flags column references not found in any table in scope
+func checkUnknownColumns(ctx *LintContext) []LintIssue {
+	if !ctx.HasSchema() {
+		return nil
+	}
+
+	c := &columnChecker{ctx: ctx, ctes: make(map[string]bool)}
+	for _, raw := range ctx.Tree.Stmts {
+		c.checkStatement(raw.Stmt)
+	}
+	return c.issues
+}
+
+// checkStatement checks the top-level statements that reference columns
+func (c *columnChecker) checkStatement(stmt *pg_query.Node) {
+	switch n := stmt.GetNode().(type) {
+	case *pg_query.Node_SelectStmt:
+		c.checkSelect(n.SelectStmt, nil)
+	case *pg_query.Node_ViewStmt:
+		c.checkSelect(n.ViewStmt.Query.GetSelectStmt(), nil)
+	case *pg_query.Node_CreateTableAsStmt:
+		c.checkSelect(n.CreateTableAsStmt.Query.GetSelectStmt(), nil)
+	case *pg_query.Node_ExplainStmt:
+		c.checkStatement(n.ExplainStmt.Query)
+	case *pg_query.Node_UpdateStmt:
+		upd := n.UpdateStmt
+		scope := c.buildScope(append([]*pg_query.Node{{Node: &pg_query.Node_RangeVar{RangeVar: upd.Relation}}}, upd.FromClause...), nil)
+		if target := scope.tables[0]; target.known {
+			for _, set := range upd.TargetList {
+				res := set.GetResTarget()
+				if res != nil && res.Name != "" && !hasColumn(target.columns, res.Name) {
+					c.report(int(res.Location), res.Name, target.columns)
+				}
+			}
+		}
+		for _, set := range upd.TargetList {
+			c.checkExpr(set.GetResTarget().GetVal(), scope)
+		}
+		c.checkExpr(upd.WhereClause, scope)
+		c.checkList(upd.ReturningList, scope)
+	case *pg_query.Node_DeleteStmt:
+		del := n.DeleteStmt
+		scope := c.buildScope(append([]*pg_query.Node{{Node: &pg_query.Node_RangeVar{RangeVar: del.Relation}}}, del.UsingClause...), nil)
+		c.checkExpr(del.WhereClause, scope)
+		c.checkList(del.ReturningList, scope)
+	case *pg_query.Node_InsertStmt:
+		ins := n.InsertStmt
+		scope := c.buildScope([]*pg_query.Node{{Node: &pg_query.Node_RangeVar{RangeVar: ins.Relation}}}, nil)
+		if target := scope.tables[0]; target.known {
+			for _, col := range ins.Cols {
+				res := col.GetResTarget()
+				if res != nil && !hasColumn(target.columns, res.Name) {
+					c.report(int(res.Location), res.Name, target.columns)
+				}
+			}
+		}
+		c.checkSelect(ins.SelectStmt.GetSelectStmt(), nil)
+		c.checkList(ins.ReturningList, scope)
+	}
+}
+
+// checkSelect checks a SELECT with its own scope, nested in parent
+func (c *columnChecker) checkSelect(sel *pg_query.SelectStmt, parent *lintScope) {
+	if sel == nil {
+		return
+	}
+	if sel.Op != pg_query.SetOperation_SETOP_NONE {
+		c.checkSelect(sel.Larg, parent)
+		c.checkSelect(sel.Rarg, parent)
+		return
+	}
+
+	if sel.WithClause != nil {
+		for _, node := range sel.WithClause.Ctes {
+			if cte := node.GetCommonTableExpr(); cte != nil {
+				c.ctes[cte.Ctename] = true
+				c.checkSelect(cte.Ctequery.GetSelectStmt(), parent)
+			}
+		}
+	}
+
+	scope := c.buildScope(sel.FromClause, parent)
+	for _, target := range sel.TargetList {
+		if res := target.GetResTarget(); res != nil && res.Name != "" {
+			scope.aliases[res.Name] = true
+		}
+	}
+
+	c.checkList(sel.TargetList, scope)
+	c.checkExpr(sel.WhereClause, scope)
+	c.checkList(sel.GroupClause, scope)
+	c.checkExpr(sel.HavingClause, scope)
+	c.checkList(sel.SortClause, scope)
+	for _, values := range sel.ValuesLists {
+		c.checkExpr(values, scope)
+	}
+}
+
+// buildScope collects the tables of a FROM clause and checks join conditions
+func (c *columnChecker) buildScope(from []*pg_query.Node, parent *lintScope) *lintScope {
+	scope := &lintScope{aliases: make(map[string]bool), parent: parent}
+
+	var joinQuals []*pg_query.Node
+	var add func(node *pg_query.Node)
+	add = func(node *pg_query.Node) {
+		switch n := node.GetNode().(type) {
+		case *pg_query.Node_RangeVar:
+			rv := n.RangeVar
+			alias := rv.Relname
+			if rv.Alias != nil {
+				alias = rv.Alias.Aliasname
+			}
+			table := lintScopeTable{alias: alias}
+			if !(rv.Schemaname == "" && c.ctes[rv.Relname]) && (rv.Alias == nil || len(rv.Alias.Colnames) == 0) {
+				table.columns, table.known = c.ctx.tableColumns(rv.Schemaname, rv.Relname)
+			}
+			scope.tables = append(scope.tables, table)
+		case *pg_query.Node_JoinExpr:
+			add(n.JoinExpr.Larg)
+			add(n.JoinExpr.Rarg)
+			if n.JoinExpr.Quals != nil {
+				joinQuals = append(joinQuals, n.JoinExpr.Quals)
+			}
+		case *pg_query.Node_RangeSubselect:
+			// Lateral subqueries can see the tables before them
+			c.checkSelect(n.RangeSubselect.Subquery.GetSelectStmt(), scope)
+			scope.tables = append(scope.tables, lintScopeTable{alias: n.RangeSubselect.GetAlias().GetAliasname()})
+		default:
+			// Functions, VALUES, ... with unknown columns
+			scope.tables = append(scope.tables, lintScopeTable{alias: fromItemName(node)})
+		}
+	}
+	for _, node := range from {
+		add(node)
+	}
+
+	for _, quals := range joinQuals {
+		c.checkExpr(quals, scope)
+	}
+	return scope
+}
+
+// checkList checks every expression in a list
+func (c *columnChecker) checkList(nodes []*pg_query.Node, scope *lintScope) {
+	for _, node := range nodes {
+		c.checkExpr(node, scope)
+	}
+}
+
+// checkExpr checks the column references in an expression. Subqueries get
+// their own scope with this one as parent.
+func (c *columnChecker) checkExpr(expr *pg_query.Node, scope *lintScope) {
+	if expr == nil {
+		return
+	}
+
+	visit := func(node proto.Message) bool {
+		switch n := node.(type) {
+		case *pg_query.SelectStmt:
+			c.checkSelect(n, scope)
+			return false
+		case *pg_query.ColumnRef:
+			c.checkColumnRef(n, scope)
+			return false
+		}
+		return true
+	}
+	if visit(expr) {
+		walkTree(expr, visit)
+	}
+}
+
+// checkColumnRef reports a column reference that can't be resolved
+func (c *columnChecker) checkColumnRef(ref *pg_query.ColumnRef, scope *lintScope) {
+	if isStarRef(ref) {
+		return
+	}
+	names := columnRefNames(ref)
+
+	switch len(names) {
+	case 1:
+		name := names[0]
+		if systemColumns[name] {
+			return
+		}
+		var candidates []TableColumn
+		for s := scope; s != nil; s = s.parent {
+			if s.aliases[name] {
+				return
+			}
+			for _, table := range s.tables {
+				// Unknown columns or a whole-row reference: can't tell
+				if !table.known || table.alias == name || hasColumn(table.columns, name) {
+					return
+				}
+				candidates = append(candidates, table.columns...)
+			}
+		}
+		if scope != nil && len(scope.tables) > 0 {
+			c.report(int(ref.Location), name, candidates)
+		}
+	case 2:
+		qualifier, name := names[0], names[1]
+		if systemColumns[name] {
+			return
+		}
+		for s := scope; s != nil; s = s.parent {
+			for _, table := range s.tables {
+				if table.alias != qualifier {
+					continue
+				}
+				if table.known && !hasColumn(table.columns, name) {
+					c.report(int(ref.Location), name, table.columns)
+				}
+				return
+			}
+		}
+	}
+}
+
+// report adds an unknown column issue, underlining the column name
+func (c *columnChecker) report(location int, name string, candidates []TableColumn) {
+	offset := location
+	if idx := strings.Index(strings.ToLower(c.ctx.Query[location:]), strings.ToLower(name)); idx >= 0 {
+		offset += idx
+	}
+	issue := c.ctx.Issue(offset, fmt.Sprintf("column %q does not exist", name), "")
+	issue.Length = len(name)
+
+	names := make([]string, len(candidates))
+	for i, col := range candidates {
+		names[i] = col.Name
+	}
+	if match := closestName(name, names); match != "" {
+		issue.Suggestion = fmt.Sprintf("Did you mean %s?", match)
+	}
+	c.issues = append(c.issues, issue)
+}
+
+// relationExists looks up a table or view in the cached metadata. known is
+// false if the metadata needed to decide is not cached.
+func (c *LintContext) relationExists(schema, name string) (exists, known bool) {
+	schemas, ok := c.cache.Schemas(c.connName)
+	if !ok {
+		return false, false
+	}
+
+	if schema != "" {
+		if isSystemSchema(schema) {
+			return false, false
+		}
+		if !containsString(schemas, schema) {
+			return false, true
+		}
+		objects, ok := c.cache.Objects(c.connName, schema)
+		if !ok {
+			return false, false
+		}
+		return hasRelation(objects, name), true
+	}
+
+	// Unqualified names may resolve to system catalogs through the search path
+	if strings.HasPrefix(name, "pg_") {
+		return false, false
+	}
+	known = true
+	for _, s := range schemas {
+		objects, ok := c.cache.Objects(c.connName, s)
+		if !ok {
+			known = false
+			continue
+		}
+		if hasRelation(objects, name) {
+			return true, true
+		}
+	}
+	return false, known
+}
+
+// relationNames returns the cached table and view names of a schema (or of
+// all schemas if schema is empty)
+func (c *LintContext) relationNames(schema string) []string {
+	schemas := []string{schema}
+	if schema == "" {
+		schemas, _ = c.cache.Schemas(c.connName)
+	}
+
+	var names []string
+	for _, s := range schemas {
+		objects, _ := c.cache.Objects(c.connName, s)
+		for _, obj := range append(append([]SchemaObject{}, objects.Tables...), objects.Views...) {
+			names = append(names, obj.Name)
+		}
+	}
+	return names
+}
+
+// tableColumns returns the cached columns of a table, searching the default
+// schema first for unqualified names
+func (c *LintContext) tableColumns(schema, name string) ([]TableColumn, bool) {
+	if c.cache == nil {
+		return nil, false
+	}
+	if schema != "" {
+		return c.cache.Columns(c.connName, schema, name)
+	}
+
+	if columns, ok := c.cache.Columns(c.connName, c.defaultSchema, name); ok {
+		return columns, true
+	}
+	schemas, _ := c.cache.Schemas(c.connName)
+	for _, s := range schemas {
+		if objects, ok := c.cache.Objects(c.connName, s); ok && hasRelation(objects, name) {
+			return c.cache.Columns(c.connName, s, name)
+		}
+	}
+	return nil, false
+}
+
+// columnNotNull reports whether the column selected by a subquery is known
+// to be NOT NULL
+func (c *LintContext) columnNotNull(sub *pg_query.SelectStmt, column []string) bool {
+	if len(sub.FromClause) != 1 {
+		return false
+	}
+	rv := sub.FromClause[0].GetRangeVar()
+	if rv == nil {
+		return false
+	}
+	columns, ok := c.tableColumns(rv.Schemaname, rv.Relname)
+	if !ok {
+		return false
+	}
+	for _, col := range columns {
+		if col.Name == column[len(column)-1] {
+			return !col.Nullable
+		}
+	}
+	return false
+}
+
+// setOperands returns the SELECTs combined by UNION/INTERSECT/EXCEPT
+func setOperands(sel *pg_query.SelectStmt) []*pg_query.SelectStmt {
+	if sel == nil {
+		return nil
+	}
+	if sel.Op == pg_query.SetOperation_SETOP_NONE {
+		return []*pg_query.SelectStmt{sel}
+	}
+	return append(setOperands(sel.Larg), setOperands(sel.Rarg)...)
+}
+
+// columnRefNames returns the name parts of a column reference
+func columnRefNames(ref *pg_query.ColumnRef) []string {
+	var names []string
+	for _, field := range ref.Fields {
+		if s := field.GetString_(); s != nil {
+			names = append(names, s.Sval)
+		}
+	}
+	return names
+}
+
+// isStarRef reports whether a column reference is "*" or "t.*"
+func isStarRef(ref *pg_query.ColumnRef) bool {
+	return len(ref.Fields) > 0 && ref.Fields[len(ref.Fields)-1].GetAStar() != nil
+}
+
+// hasNotNullTest reports whether an expression contains "column IS NOT NULL"
+func hasNotNullTest(expr *pg_query.Node, column string) bool {
+	found := false
+	walkTree(expr, func(node proto.Message) bool {
+		test, ok := node.(*pg_query.NullTest)
+		if ok && test.Nulltesttype == pg_query.NullTestType_IS_NOT_NULL {
+			if ref := test.Arg.GetColumnRef(); ref != nil {
+				names := columnRefNames(ref)
+				if len(names) > 0 && names[len(names)-1] == column {
+					found = true
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// hasAggregate reports whether a target list calls an aggregate function
+func hasAggregate(targets []*pg_query.Node) bool {
+	found := false
+	for _, target := range targets {
+		walkTree(target, func(node proto.Message) bool {
+			if call, ok := node.(*pg_query.FuncCall); ok && len(call.Funcname) > 0 {
+				name := call.Funcname[len(call.Funcname)-1].GetString_().GetSval()
+				if aggregateFunctions[name] {
+					found = true
+				}
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+// nodeLocation returns the location of a FROM item
+func nodeLocation(node *pg_query.Node) int {
+	switch n := node.GetNode().(type) {
+	case *pg_query.Node_RangeVar:
+		return int(n.RangeVar.Location)
+	case *pg_query.Node_RangeFunction:
+		if len(n.RangeFunction.Functions) > 0 {
+			if list := n.RangeFunction.Functions[0].GetList(); list != nil && len(list.Items) > 0 {
+				if call := list.Items[0].GetFuncCall(); call != nil {
+					return int(call.Location)
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// fromItemName returns the name used to refer to a FROM item
+func fromItemName(node *pg_query.Node) string {
+	switch n := node.GetNode().(type) {
+	case *pg_query.Node_RangeVar:
+		if n.RangeVar.Alias != nil {
+			return n.RangeVar.Alias.Aliasname
+		}
+		return n.RangeVar.Relname
+	case *pg_query.Node_RangeSubselect:
+		return n.RangeSubselect.GetAlias().GetAliasname()
+	case *pg_query.Node_RangeFunction:
+		return n.RangeFunction.GetAlias().GetAliasname()
+	}
+	return "subquery"
+}
+
+// hasRelation reports whether a schema has a table or view with the given name
+func hasRelation(objects SchemaObjects, name string) bool {
+	for _, obj := range objects.Tables {
+		if obj.Name == name {
+			return true
+		}
+	}
+	for _, obj := range objects.Views {
+		if obj.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasColumn reports whether columns contains name
+func hasColumn(columns []TableColumn, name string) bool {
+	for _, col := range columns {
+		if col.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isSystemSchema reports whether schema holds system catalogs that are not cached
+func isSystemSchema(schema string) bool {
+	return schema == "information_schema" || strings.HasPrefix(schema, "pg_")
+}
+
+// leadingSpace returns the number of whitespace bytes at the start of s
+func leadingSpace(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " \t\r\n"))
+}
+
+// closestName returns the candidate closest to name if it is a likely typo
+func closestName(name string, candidates []string) string {
+	best, bestDist := "", 3
+	for _, candidate := range candidates {
+		if d := editDistance(name, candidate); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// systemColumns are implicit columns of every table
+var systemColumns = map[string]bool{
+	"ctid": true, "xmin": true, "xmax": true, "cmin": true, "cmax": true, "tableoid": true, "oid": true,
+}
+
+// aggregateFunctions are built-in aggregates that make a SELECT return few rows
+var aggregateFunctions = map[string]bool{
+	"count": true, "sum": true, "avg": true, "min": true, "max": true, "bool_and": true,
+	"bool_or": true, "every": true, "array_agg": true, "string_agg": true, "json_agg": true,
+	"jsonb_agg": true, "json_object_agg": true, "jsonb_object_agg": true,
+}
@@ -0,0 +1,371 @@
+package db
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// SchemaObjects groups the objects that belong to a single schema
+type SchemaObjects struct {
+	Tables      []SchemaObject `json:"tables"`
+	Views       []SchemaObject `json:"views"`
+	Functions   []SchemaObject `json:"functions"`
+	ForeignKeys []ForeignKey   `json:"foreign_keys"`
+}
+
+// SchemaMetadata holds the cached schema information for one connection
+type SchemaMetadata struct {
+	Schemas  []string                 `json:"schemas"`
+	Objects  map[string]SchemaObjects `json:"objects"` // keyed by schema name
+	Columns  map[string][]TableColumn `json:"columns"` // keyed by "schema.table"
+	LoadedAt time.Time                `json:"loaded_at"`
+}
+
+// newSchemaMetadata creates an empty metadata entry
+func newSchemaMetadata() *SchemaMetadata {
+	return &SchemaMetadata{
+		Objects: make(map[string]SchemaObjects),
+		Columns: make(map[string][]TableColumn),
+	}
+}
+
+// MetadataStore persists schema metadata between sessions
+type MetadataStore interface {
+	Load(connName string) (*SchemaMetadata, error)
+	Save(connName string, md *SchemaMetadata) error
+	Delete(connName string) error
+}
+
+// MetadataCache caches schema metadata per connection name so the schema
+// tree, autocomplete and search don't have to hit the server every time
+type MetadataCache struct {
+	mu       sync.Mutex
+	entries  map[string]*SchemaMetadata
+	maxAge   time.Duration
+	store    MetadataStore
+	versions map[string]int // Changes made to each entry, under mu
+
+	// Writes to the store happen outside mu so that lookups don't wait on
+	// the disk; saveMu orders them and saved skips those overtaken
+	saveMu sync.Mutex
+	saved  map[string]int
+}
+
+// NewMetadataCache creates a new metadata cache. Entries older than maxAge
+// are reported as stale; a zero maxAge means entries never go stale.
+func NewMetadataCache(maxAge time.Duration) *MetadataCache {
+	return &MetadataCache{
+		entries:  make(map[string]*SchemaMetadata),
+		maxAge:   maxAge,
+		versions: make(map[string]int),
+		saved:    make(map[string]int),
+	}
+}
+
+// SetStore enables persistence of cache entries through the given store
+func (c *MetadataCache) SetStore(store MetadataStore) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store = store
+}
+
+// entry returns the entry for a connection, loading it from the store if needed.
+// Callers must hold the write lock.
+func (c *MetadataCache) entry(connName string) *SchemaMetadata {
+	if md, ok := c.entries[connName]; ok {
+		return md
+	}
+
+	if c.store != nil {
+		if md, err := c.store.Load(connName); err == nil && md != nil {
+			if md.Objects == nil {
+				md.Objects = make(map[string]SchemaObjects)
+			}
+			if md.Columns == nil {
+				md.Columns = make(map[string][]TableColumn)
+			}
+			c.entries[connName] = md
+			return md
+		}
+	}
+
+	md := newSchemaMetadata()
+	c.entries[connName] = md
+	return md
+}
+
+// snapshot copies an entry (nil to delete it) and returns the function
+// writing the copy to the store, to call once the lock is released.
+// Callers must hold the lock.
+func (c *MetadataCache) snapshot(connName string, md *SchemaMetadata) func() {
+	if c.store == nil {
+		return func() {}
+	}
+	c.versions[connName]++
+	store, version := c.store, c.versions[connName]
+	if md != nil {
+		md = &SchemaMetadata{
+			Schemas:  md.Schemas,
+			Objects:  maps.Clone(md.Objects),
+			Columns:  maps.Clone(md.Columns),
+			LoadedAt: md.LoadedAt,
+		}
+	}
+	return func() { c.persist(store, connName, version, md) }
+}
+
+// persist saves an entry to the store, or deletes it when md is nil,
+// unless a later change was written already (best effort, cache stays
+// usable on failure)
+func (c *MetadataCache) persist(store MetadataStore, connName string, version int, md *SchemaMetadata) {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+	if c.saved[connName] >= version {
+		return
+	}
+	c.saved[connName] = version
+	if md == nil {
+		_ = store.Delete(connName)
+	} else {
+		_ = store.Save(connName, md)
+	}
+}
+
+// Schemas returns the cached schema list for a connection
+func (c *MetadataCache) Schemas(connName string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	md := c.entry(connName)
+	if md.Schemas == nil {
+		return nil, false
+	}
+	return md.Schemas, true
+}
+
+// Objects returns the cached tables, views and functions of a schema
+func (c *MetadataCache) Objects(connName, schema string) (SchemaObjects, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	objects, ok := c.entry(connName).Objects[schema]
+	return objects, ok
+}
+
+// Columns returns the cached columns of a table
+func (c *MetadataCache) Columns(connName, schema, table string) ([]TableColumn, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	columns, ok := c.entry(connName).Columns[columnsKey(schema, table)]
+	return columns, ok
+}
+
+// AllObjects returns every cached table, view and function across all schemas
+func (c *MetadataCache) AllObjects(connName string) []SchemaObject {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	md := c.entry(connName)
+	var all []SchemaObject
+	for _, schema := range md.Schemas {
+		objects := md.Objects[schema]
+		all = append(all, objects.Tables...)
+		all = append(all, objects.Views...)
+		all = append(all, objects.Functions...)
+	}
+	return all
+}
+
+// PutSchemas stores the schema list for a connection
+func (c *MetadataCache) PutSchemas(connName string, schemas []string) {
+	c.mu.Lock()
+	md := c.entry(connName)
+	if schemas == nil {
+		schemas = []string{}
+	}
+	md.Schemas = schemas
+	if md.LoadedAt.IsZero() {
+		md.LoadedAt = time.Now()
+	}
+	save := c.snapshot(connName, md)
+	c.mu.Unlock()
+	save()
+}
+
+// PutObjects stores the objects of a schema
+func (c *MetadataCache) PutObjects(connName, schema string, objects SchemaObjects) {
+	c.mu.Lock()
+	md := c.entry(connName)
+	md.Objects[schema] = objects
+	save := c.snapshot(connName, md)
+	c.mu.Unlock()
+	save()
+}
+
+// PutColumns stores the columns of a table
+func (c *MetadataCache) PutColumns(connName, schema, table string, columns []TableColumn) {
+	c.mu.Lock()
+	md := c.entry(connName)
+	md.Columns[columnsKey(schema, table)] = columns
+	save := c.snapshot(connName, md)
+	c.mu.Unlock()
+	save()
+}
+
+// Invalidate drops all cached metadata for a connection
+func (c *MetadataCache) Invalidate(connName string) {
+	c.mu.Lock()
+	c.entries[connName] = newSchemaMetadata()
+	remove := c.snapshot(connName, nil)
+	c.mu.Unlock()
+	remove()
+}
+
+// IsStale reports whether the cached metadata is missing or older than maxAge
+func (c *MetadataCache) IsStale(connName string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	md := c.entry(connName)
+	if md.Schemas == nil || md.LoadedAt.IsZero() {
+		return true
+	}
+	if c.maxAge <= 0 {
+		return false
+	}
+	return time.Since(md.LoadedAt) > c.maxAge
+}
+
+// Refresh reloads the schema list from the server, with the objects of the
+// given schemas and of those already cached, and replaces the cached
+// entry. Other schemas and columns are loaded lazily again afterwards.
+// Safe to run in the background while the cache is being read.
+func (c *MetadataCache) Refresh(ctx context.Context, connName string, conn Connection, schemas []string) error {
+	fresh := newSchemaMetadata()
+
+	list, err := conn.ListSchemas(ctx)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		list = []string{}
+	}
+	fresh.Schemas = list
+
+	wanted := make(map[string]bool, len(schemas))
+	for _, schema := range schemas {
+		wanted[schema] = true
+	}
+	c.mu.Lock()
+	for schema := range c.entry(connName).Objects {
+		wanted[schema] = true
+	}
+	c.mu.Unlock()
+
+	for _, schema := range list {
+		if !wanted[schema] {
+			continue
+		}
+		objects, err := LoadSchemaObjects(ctx, conn, schema)
+		if err != nil {
+			return err
+		}
+		fresh.Objects[schema] = objects
+	}
+	fresh.LoadedAt = time.Now()
+
+	c.mu.Lock()
+	c.entries[connName] = fresh
+	save := c.snapshot(connName, fresh)
+	c.mu.Unlock()
+	save()
+	return nil
+}
+
+// ObserveQuery invalidates the cache for a connection if the executed query
+// changes the schema (CREATE, ALTER, DROP, ...)
+func (c *MetadataCache) ObserveQuery(connName, query string) {
+	if IsSchemaChange(query) {
+		c.Invalidate(connName)
+	}
+}
+
+// ForeignKeys returns every cached foreign key across all schemas
+func (c *MetadataCache) ForeignKeys(connName string) []ForeignKey {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	md := c.entry(connName)
+	var keys []ForeignKey
+	for _, schema := range md.Schemas {
+		keys = append(keys, md.Objects[schema].ForeignKeys...)
+	}
+	return keys
+}
+
+// LoadSchemaObjects loads tables, views, functions and foreign keys of a schema from the server
+func LoadSchemaObjects(ctx context.Context, conn Connection, schema string) (SchemaObjects, error) {
+	tables, err := conn.ListTables(ctx, schema)
+	if err != nil {
+		return SchemaObjects{}, err
+	}
+
+	views, err := conn.ListViews(ctx, schema)
+	if err != nil {
+		return SchemaObjects{}, err
+	}
+
+	functions, err := conn.ListFunctions(ctx, schema)
+	if err != nil {
+		return SchemaObjects{}, err
+	}
+
+	// Foreign keys only drive join suggestions, don't fail the schema on them
+	foreignKeys, err := conn.ListForeignKeys(ctx, schema)
+	if err != nil {
+		foreignKeys = nil
+	}
+
+	return SchemaObjects{Tables: tables, Views: views, Functions: functions, ForeignKeys: foreignKeys}, nil
+}
+
+// IsSchemaChange reports whether a query contains DDL that changes the
+// objects shown in the schema tree
+func IsSchemaChange(query string) bool {
+	tree, err := pg_query.Parse(query)
+	if err != nil {
+		return false
+	}
+
+	for _, raw := range tree.Stmts {
+		if raw.Stmt == nil {
+			continue
+		}
+		switch raw.Stmt.Node.(type) {
+		case *pg_query.Node_CreateStmt,
+			*pg_query.Node_CreateTableAsStmt,
+			*pg_query.Node_CreateSchemaStmt,
+			*pg_query.Node_CreateFunctionStmt,
+			*pg_query.Node_CreateForeignTableStmt,
+			*pg_query.Node_CreateExtensionStmt,
+			*pg_query.Node_ViewStmt,
+			*pg_query.Node_AlterTableStmt,
+			*pg_query.Node_AlterFunctionStmt,
+			*pg_query.Node_AlterObjectSchemaStmt,
+			*pg_query.Node_RenameStmt,
+			*pg_query.Node_DropStmt:
+			return true
+		}
+	}
+	return false
+}
+
+// columnsKey builds the map key for a table's columns
+func columnsKey(schema, table string) string {
+	return schema + "." + table
+}
@@ -195,10 +195,14 @@ func (p *PostgresConnection) ListFunctions(ctx context.Context, schema string) (
 	}
 
 	query := `
-		SELECT routine_name
-		FROM information_schema.routines
-		WHERE routine_schema = $1 AND routine_type = 'FUNCTION'
-		ORDER BY routine_name
+		SELECT
+			p.proname,
+			pg_get_function_identity_arguments(p.oid),
+			COALESCE(pg_get_function_result(p.oid), '')
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = $1 AND p.prokind = 'f'
+		ORDER BY p.proname
 	`
 
 	rows, err := p.conn.Query(ctx, query, schema)
@@ -209,14 +213,15 @@ func (p *PostgresConnection) ListFunctions(ctx context.Context, schema string) (
 
 	var functions []SchemaObject
 	for rows.Next() {
-		var name string
-		if err := rows.Scan(&name); err != nil {
+		var name, args, result string
+		if err := rows.Scan(&name, &args, &result); err != nil {
 			return nil, fmt.Errorf("failed to scan function: %w", err)
 		}
 		functions = append(functions, SchemaObject{
-			Name:   name,
-			Type:   "function",
-			Schema: schema,
+			Name:      name,
+			Type:      "function",
+			Schema:    schema,
+			Signature: fmt.Sprintf("(%s) → %s", args, result),
 		})
 	}
 
@@ -259,3 +264,52 @@ func (p *PostgresConnection) GetTableColumns(ctx context.Context, schema, table
 
 	return columns, rows.Err()
 }
+
+// ListForeignKeys returns all foreign keys defined on tables in a schema
+func (p *PostgresConnection) ListForeignKeys(ctx context.Context, schema string) ([]ForeignKey, error) {
+	if p.conn == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	query := `
+		SELECT
+			c.conname,
+			cl.relname,
+			rn.nspname,
+			rcl.relname,
+			ARRAY(
+				SELECT a.attname FROM unnest(c.conkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			),
+			ARRAY(
+				SELECT a.attname FROM unnest(c.confkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = c.confrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			)
+		FROM pg_constraint c
+		JOIN pg_class cl ON cl.oid = c.conrelid
+		JOIN pg_namespace n ON n.oid = cl.relnamespace
+		JOIN pg_class rcl ON rcl.oid = c.confrelid
+		JOIN pg_namespace rn ON rn.oid = rcl.relnamespace
+		WHERE c.contype = 'f' AND n.nspname = $1
+		ORDER BY cl.relname, c.conname
+	`
+
+	rows, err := p.conn.Query(ctx, query, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []ForeignKey
+	for rows.Next() {
+		fk := ForeignKey{Schema: schema}
+		if err := rows.Scan(&fk.Name, &fk.Table, &fk.RefSchema, &fk.RefTable, &fk.Columns, &fk.RefColumns); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		keys = append(keys, fk)
+	}
+
+	return keys, rows.Err()
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -11,18 +12,34 @@ import (
 
 // QueryResult represents the result of a database query
 type QueryResult struct {
+	Query        string // Statement that produced the result
 	Columns      []string
+	ColumnTypes  []string // PostgreSQL type name of each column, e.g. "int4"
 	Rows         [][]string
+	Nulls        [][]bool // Nulls[row][col] is set for NULL values; nil for rows without any
 	RowCount     int
 	ExecutionMs  int64
 	Error        error
 }
 
+// IsNull reports whether the value at a row and column is NULL
+func (r QueryResult) IsNull(row, col int) bool {
+	return row < len(r.Nulls) && col < len(r.Nulls[row]) && r.Nulls[row][col]
+}
+
 // ExecuteQuery executes a SQL query and returns the results
 func ExecuteQuery(ctx context.Context, conn *pgx.Conn, query string) QueryResult {
+	return ExecuteQueryLimit(ctx, conn, query, 0)
+}
+
+// ExecuteQueryLimit executes a SQL query keeping at most maxRows rows of
+// its result (all when maxRows is 0). The rows past the limit are still
+// counted in RowCount.
+func ExecuteQueryLimit(ctx context.Context, conn *pgx.Conn, query string, maxRows int) QueryResult {
 	startTime := time.Now()
 
 	result := QueryResult{
+		Query:   query,
 		Columns: []string{},
 		Rows:    [][]string{},
 	}
@@ -53,6 +70,7 @@ func ExecuteQuery(ctx context.Context, conn *pgx.Conn, query string) QueryResult
 
 		// Return success message
 		result.Columns = []string{"status"}
+		result.ColumnTypes = []string{"text"}
 		result.Rows = [][]string{{fmt.Sprintf("Success: %s", commandTag.String())}}
 		result.RowCount = 1
 		result.ExecutionMs = time.Since(startTime).Milliseconds()
@@ -69,26 +87,23 @@ func ExecuteQuery(ctx context.Context, conn *pgx.Conn, query string) QueryResult
 	defer rows.Close()
 
 	// Get column descriptions
-	fieldDescriptions := rows.FieldDescriptions()
-	for _, fd := range fieldDescriptions {
-		result.Columns = append(result.Columns, string(fd.Name))
-	}
+	result.Columns, result.ColumnTypes = describeColumns(conn, rows)
 
-	// Fetch all rows
+	// Fetch the rows, counting those past the limit
+	count := 0
 	for rows.Next() {
-		values, err := rows.Values()
+		count++
+		if maxRows > 0 && len(result.Rows) >= maxRows {
+			continue
+		}
+		rowStrings, rowNulls, err := formatRow(rows, result.ColumnTypes)
 		if err != nil {
 			result.Error = err
 			result.ExecutionMs = time.Since(startTime).Milliseconds()
 			return result
 		}
-
-		// Convert values to strings
-		rowStrings := make([]string, len(values))
-		for i, v := range values {
-			rowStrings[i] = fmt.Sprintf("%v", v)
-		}
 		result.Rows = append(result.Rows, rowStrings)
+		result.Nulls = append(result.Nulls, rowNulls)
 	}
 
 	// Check for errors during iteration
@@ -98,8 +113,74 @@ func ExecuteQuery(ctx context.Context, conn *pgx.Conn, query string) QueryResult
 		return result
 	}
 
-	result.RowCount = len(result.Rows)
+	result.RowCount = count
 	result.ExecutionMs = time.Since(startTime).Milliseconds()
+	if !slices.ContainsFunc(result.Nulls, func(nulls []bool) bool { return nulls != nil }) {
+		result.Nulls = nil
+	}
 
 	return result
 }
+
+// StreamQuery runs a query returning rows and hands them to row one at a
+// time instead of keeping them, for results too large for memory. columns
+// is called first with the column names and types. It returns the number
+// of rows read.
+func StreamQuery(ctx context.Context, conn *pgx.Conn, query string, columns func(names, types []string) error, row func(values []string, nulls []bool) error) (int, error) {
+	rows, err := conn.Query(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	names, types := describeColumns(conn, rows)
+	if err := columns(names, types); err != nil {
+		return 0, err
+	}
+	count := 0
+	for rows.Next() {
+		values, nulls, err := formatRow(rows, types)
+		if err != nil {
+			return count, err
+		}
+		if err := row(values, nulls); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+// describeColumns returns the names and type names of the columns of rows
+func describeColumns(conn *pgx.Conn, rows pgx.Rows) (names, types []string) {
+	typeMap := conn.TypeMap()
+	for _, fd := range rows.FieldDescriptions() {
+		names = append(names, string(fd.Name))
+		typeName := fmt.Sprintf("oid %d", fd.DataTypeOID)
+		if t, ok := typeMap.TypeForOID(fd.DataTypeOID); ok {
+			typeName = t.Name
+		}
+		types = append(types, typeName)
+	}
+	return names, types
+}
+
+// formatRow converts the current row to text, marking its NULLs. nulls is
+// nil for a row without any.
+func formatRow(rows pgx.Rows, types []string) (values []string, nulls []bool, err error) {
+	raw, err := rows.Values()
+	if err != nil {
+		return nil, nil, err
+	}
+	values = make([]string, len(raw))
+	for i, v := range raw {
+		values[i] = FormatValue(v, types[i])
+		if v == nil {
+			if nulls == nil {
+				nulls = make([]bool, len(raw))
+			}
+			nulls[i] = true
+		}
+	}
+	return values, nulls, nil
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// Statement is one statement of a SQL script
+type Statement struct {
+	Text  string
+	Start int // Byte offset of the first character in the script
+	End   int // Byte offset after the last character, excluding the semicolon
+}
+
+// SplitStatements splits a script into its statements, trimmed of
+// surrounding whitespace. Boundaries come from the PostgreSQL parser; if
+// the script doesn't parse (e.g. one statement has a syntax error) the
+// semicolons found by the scanner are used instead.
+func SplitStatements(query string) []Statement {
+	var ranges [][2]int
+	if tree, err := pg_query.Parse(query); err == nil {
+		for _, raw := range tree.Stmts {
+			start := int(raw.StmtLocation)
+			end := len(query)
+			if raw.StmtLen > 0 {
+				end = start + int(raw.StmtLen)
+			}
+			ranges = append(ranges, [2]int{start, min(end, len(query))})
+		}
+	} else if tokens, err := Tokenize(query); err == nil {
+		start := 0
+		for _, tok := range tokens {
+			if tok.Kind == TokenPunct && tok.Text == ";" {
+				ranges = append(ranges, [2]int{start, tok.Start})
+				start = tok.End
+			}
+		}
+		ranges = append(ranges, [2]int{start, len(query)})
+	} else {
+		// Not even scannable (e.g. an unterminated string): one statement
+		ranges = append(ranges, [2]int{0, len(query)})
+	}
+
+	var statements []Statement
+	for _, r := range ranges {
+		start, end := r[0], r[1]
+		text := query[start:end]
+		trimmed := strings.TrimLeft(text, " \t\r\n")
+		start += len(text) - len(trimmed)
+		trimmed = strings.TrimRight(trimmed, " \t\r\n")
+		end = start + len(trimmed)
+		if trimmed == "" || onlyComments(trimmed) {
+			continue
+		}
+		statements = append(statements, Statement{Text: trimmed, Start: start, End: end})
+	}
+	return statements
+}
+
+// StatementAt returns the statement containing the byte offset. Between
+// statements (e.g. right after a semicolon) the preceding statement is
+// returned, or the first one when offset comes before all of them.
+func StatementAt(query string, offset int) (Statement, bool) {
+	statements := SplitStatements(query)
+	if len(statements) == 0 {
+		return Statement{}, false
+	}
+
+	found := statements[0]
+	for _, stmt := range statements {
+		if stmt.Start > offset {
+			break
+		}
+		found = stmt
+	}
+	return found, true
+}
+
+// onlyComments reports whether text holds nothing but comments
+func onlyComments(text string) bool {
+	tokens, err := Tokenize(text)
+	if err != nil {
+		return false
+	}
+	for _, tok := range tokens {
+		if tok.Kind != TokenComment {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSelectQuery reports whether query is a single SELECT that is safe to run
+// again: no SELECT INTO, no data-modifying WITH and no row locks
+func IsSelectQuery(query string) bool {
+	tree, err := pg_query.Parse(query)
+	if err != nil || len(tree.Stmts) != 1 {
+		return false
+	}
+	return readOnlySelect(tree.Stmts[0].Stmt.GetSelectStmt())
+}
+
+// readOnlySelect reports whether a SELECT and its set operands and common
+// table expressions only read
+func readOnlySelect(sel *pg_query.SelectStmt) bool {
+	if sel == nil || sel.IntoClause != nil || len(sel.LockingClause) > 0 {
+		return false
+	}
+	if sel.WithClause != nil {
+		for _, cte := range sel.WithClause.Ctes {
+			expr := cte.GetCommonTableExpr()
+			if expr == nil || !readOnlySelect(expr.Ctequery.GetSelectStmt()) {
+				return false
+			}
+		}
+	}
+	if sel.Op != pg_query.SetOperation_SETOP_NONE {
+		return readOnlySelect(sel.Larg) && readOnlySelect(sel.Rarg)
+	}
+	return true
+}
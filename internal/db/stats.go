@@ -0,0 +1,120 @@
+package db
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ColumnStats summarizes the values of a numeric column
+type ColumnStats struct {
+	Column    string
+	Count     int // Values that are numbers
+	Nulls     int
+	NonFinite int // NaN and infinities, left out of the statistics
+	Min       float64
+	Max       float64
+	Mean      float64
+	StdDev    float64 // Of the population
+	P25       float64
+	P50       float64
+	P75       float64
+	P95       float64
+}
+
+// ParseNumber reads a numeric value of a result. NaN and infinities don't
+// count as numbers.
+func ParseNumber(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+// NumericValues returns the values of a column that aren't NULL as
+// numbers, and whether they all are
+func NumericValues(result QueryResult, col int) ([]float64, bool) {
+	var values []float64
+	for i, row := range result.Rows {
+		if col >= len(row) || result.IsNull(i, col) {
+			continue
+		}
+		f, ok := ParseNumber(row[col])
+		if !ok {
+			return nil, false
+		}
+		values = append(values, f)
+	}
+	return values, true
+}
+
+// IsNumericColumn reports whether a column holds numbers: by its type, or
+// for untyped results by every value parsing as one
+func IsNumericColumn(result QueryResult, col int) bool {
+	if col < len(result.ColumnTypes) && result.ColumnTypes[col] != "" {
+		return IsNumericType(result.ColumnTypes[col])
+	}
+	values, ok := NumericValues(result, col)
+	return ok && len(values) > 0
+}
+
+// NumericStats returns the statistics of a numeric column, counting the
+// values that aren't finite numbers apart. It reports false when the column
+// isn't numeric.
+func NumericStats(result QueryResult, col int) (ColumnStats, bool) {
+	stats := ColumnStats{Column: result.Columns[col]}
+	if !IsNumericColumn(result, col) {
+		return stats, false
+	}
+	var values []float64
+	for i, row := range result.Rows {
+		if col >= len(row) || result.IsNull(i, col) {
+			stats.Nulls++
+		} else if f, ok := ParseNumber(row[col]); ok {
+			values = append(values, f)
+		} else {
+			stats.NonFinite++
+		}
+	}
+	stats.Count = len(values)
+	if len(values) == 0 {
+		return stats, true
+	}
+
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	stats.Min, stats.Max = sorted[0], sorted[len(sorted)-1]
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	stats.Mean = sum / float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - stats.Mean) * (v - stats.Mean)
+	}
+	stats.StdDev = math.Sqrt(variance / float64(len(values)))
+	stats.P25 = Percentile(sorted, 25)
+	stats.P50 = Percentile(sorted, 50)
+	stats.P75 = Percentile(sorted, 75)
+	stats.P95 = Percentile(sorted, 95)
+	return stats, true
+}
+
+// Percentile returns the p-th percentile of sorted values, interpolated
+// between the closest ranks like PostgreSQL's percentile_cont
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := min(lower+1, len(sorted)-1)
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"regexp"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// TokenKind classifies a SQL token
+type TokenKind int
+
+const (
+	TokenOther TokenKind = iota
+	TokenKeyword
+	TokenIdent
+	TokenString
+	TokenNumber
+	TokenComment
+	TokenPunct
+)
+
+// SQLToken is a single token of a SQL query with its byte offsets
+type SQLToken struct {
+	Kind  TokenKind
+	Text  string
+	Start int // byte offset of the first character
+	End   int // byte offset after the last character
+}
+
+// Upper returns the token text in upper case (for keyword comparison)
+func (t SQLToken) Upper() string {
+	return strings.ToUpper(t.Text)
+}
+
+// Is reports whether the token is the given keyword or punctuation
+func (t SQLToken) Is(text string) bool {
+	return strings.EqualFold(t.Text, text)
+}
+
+// IsIdentLike reports whether the token can be used as a name
+// (identifiers and non-reserved keywords such as "name" or "type")
+func (t SQLToken) IsIdentLike() bool {
+	return t.Kind == TokenIdent || (t.Kind == TokenKeyword && !reservedKeywords[t.Upper()])
+}
+
+// Tokenize splits a SQL query into tokens using the PostgreSQL scanner
+func Tokenize(query string) ([]SQLToken, error) {
+	result, err := pg_query.Scan(query)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]SQLToken, 0, len(result.Tokens))
+	for _, tok := range result.Tokens {
+		start, end := int(tok.Start), int(tok.End)
+		if start < 0 || end > len(query) || start > end {
+			continue
+		}
+
+		kind := TokenOther
+		switch {
+		case tok.Token == pg_query.Token_SQL_COMMENT || tok.Token == pg_query.Token_C_COMMENT:
+			kind = TokenComment
+		case tok.Token == pg_query.Token_IDENT:
+			kind = TokenIdent
+		case tok.Token == pg_query.Token_SCONST || tok.Token == pg_query.Token_USCONST ||
+			tok.Token == pg_query.Token_BCONST || tok.Token == pg_query.Token_XCONST:
+			kind = TokenString
+		case tok.Token == pg_query.Token_ICONST || tok.Token == pg_query.Token_FCONST:
+			kind = TokenNumber
+		case tok.KeywordKind != pg_query.KeywordKind_NO_KEYWORD:
+			kind = TokenKeyword
+		case tok.Token < 256 || tok.Token == pg_query.Token_TYPECAST ||
+			tok.Token == pg_query.Token_Op || tok.Token == pg_query.Token_PARAM:
+			kind = TokenPunct
+		}
+
+		tokens = append(tokens, SQLToken{
+			Kind:  kind,
+			Text:  query[start:end],
+			Start: start,
+			End:   end,
+		})
+	}
+
+	return tokens, nil
+}
+
+// StatementRange returns the byte range [start, end) of the statement that
+// contains offset. Statements are separated by semicolons outside of strings
+// and comments; the range excludes the terminating semicolon.
+func StatementRange(tokens []SQLToken, queryLen, offset int) (start, end int) {
+	start, end = 0, queryLen
+	for _, tok := range tokens {
+		if tok.Kind != TokenPunct || tok.Text != ";" {
+			continue
+		}
+		if tok.End <= offset {
+			start = tok.End
+		} else {
+			end = tok.Start
+			break
+		}
+	}
+	return start, end
+}
+
+// reservedKeywords are keywords that can never be used as bare identifiers
+var reservedKeywords = map[string]bool{
+	"ALL": true, "ANALYSE": true, "ANALYZE": true, "AND": true, "ANY": true, "ARRAY": true,
+	"AS": true, "ASC": true, "ASYMMETRIC": true, "BOTH": true, "CASE": true, "CAST": true,
+	"CHECK": true, "COLLATE": true, "COLUMN": true, "CONSTRAINT": true, "CREATE": true,
+	"CURRENT_CATALOG": true, "CURRENT_DATE": true, "CURRENT_ROLE": true, "CURRENT_TIME": true,
+	"CURRENT_TIMESTAMP": true, "CURRENT_USER": true, "DEFAULT": true, "DEFERRABLE": true,
+	"DESC": true, "DISTINCT": true, "DO": true, "ELSE": true, "END": true, "EXCEPT": true,
+	"FALSE": true, "FETCH": true, "FOR": true, "FOREIGN": true, "FROM": true, "GRANT": true,
+	"GROUP": true, "HAVING": true, "IN": true, "INITIALLY": true, "INTERSECT": true, "INTO": true,
+	"LATERAL": true, "LEADING": true, "LIMIT": true, "LOCALTIME": true, "LOCALTIMESTAMP": true,
+	"NOT": true, "NULL": true, "OFFSET": true, "ON": true, "ONLY": true, "OR": true, "ORDER": true,
+	"PLACING": true, "PRIMARY": true, "REFERENCES": true, "RETURNING": true, "SELECT": true,
+	"SESSION_USER": true, "SOME": true, "SYMMETRIC": true, "SYSTEM_USER": true, "TABLE": true,
+	"THEN": true, "TO": true, "TRAILING": true, "TRUE": true, "UNION": true, "UNIQUE": true,
+	"USER": true, "USING": true, "VARIADIC": true, "WHEN": true, "WHERE": true, "WINDOW": true,
+	"WITH": true,
+	// Not reserved in PostgreSQL, but never table aliases in practice
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true, "OUTER": true,
+	"CROSS": true, "NATURAL": true, "SET": true, "VALUES": true,
+}
+
+var simpleIdentPattern = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// QuoteIdent quotes an identifier if PostgreSQL would not accept it bare
+func QuoteIdent(name string) string {
+	if simpleIdentPattern.MatchString(name) && !reservedKeywords[strings.ToUpper(name)] {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteQualifiedName quotes each part of a name that may be qualified with
+// a schema, e.g. public.users. Parts already in double quotes are kept.
+func QuoteQualifiedName(name string) string {
+	var parts []string
+	start, quoted := 0, false
+	for i, r := range name {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == '.' && !quoted:
+			parts = append(parts, name[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, name[start:])
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if len(part) < 2 || !strings.HasPrefix(part, `"`) || !strings.HasSuffix(part, `"`) {
+			part = QuoteIdent(part)
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, ".")
+}
+
+// QuoteLiteral quotes a string as an SQL literal
+func QuoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
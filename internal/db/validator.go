@@ -1,32 +1,60 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	pg_query "github.com/pganalyze/pg_query_go/v6"
+	"github.com/pganalyze/pg_query_go/v6/parser"
 )
 
 // ValidationError represents a SQL validation error
 type ValidationError struct {
 	Line    int
-	Column  int
+	Column  int // 1-based, 0 if the position is unknown
+	Offset  int // Byte offset of the error in Query, -1 if unknown
+	Length  int // Length in bytes of the offending token
 	Message string
 	Query   string
 }
 
+// HasPosition reports whether the error points at a location in the query
+func (e ValidationError) HasPosition() bool {
+	return e.Offset >= 0 && e.Column > 0
+}
+
 // ValidationResult contains the result of SQL validation
 type ValidationResult struct {
 	Valid  bool
 	Errors []ValidationError
+	Issues []LintIssue // Lint findings, only for valid queries
 }
 
 // SQLValidator validates SQL queries using PostgreSQL parser
-type SQLValidator struct{}
+type SQLValidator struct {
+	formatter *Formatter
+	linter    *Linter
+}
 
 // NewSQLValidator creates a new SQL validator
 func NewSQLValidator() *SQLValidator {
-	return &SQLValidator{}
+	return &SQLValidator{
+		formatter: NewFormatter(DefaultFormatOptions()),
+		linter:    NewLinter(),
+	}
+}
+
+// Linter returns the linter run on valid queries
+func (v *SQLValidator) Linter() *Linter {
+	return v.linter
+}
+
+// SetFormatOptions changes the options used by ValidateAndFormat
+func (v *SQLValidator) SetFormatOptions(opts FormatOptions) {
+	v.formatter = NewFormatter(opts)
 }
 
 // Validate checks if the SQL query is valid
@@ -46,27 +74,119 @@ func (v *SQLValidator) Validate(query string) ValidationResult {
 	_, err := pg_query.Parse(query)
 	if err != nil {
 		result.Valid = false
+		result.Errors = append(result.Errors, parseError(query, err))
+		return result
+	}
 
-		// Extract error details
-		errMsg := err.Error()
-		line, column := extractErrorPosition(errMsg)
+	result.Issues = v.linter.Lint(query)
+	return result
+}
 
-		result.Errors = append(result.Errors, ValidationError{
-			Line:    line,
-			Column:  column,
-			Message: cleanErrorMessage(errMsg),
-			Query:   query,
-		})
+// parseError converts a pg_query parse error into a ValidationError
+func parseError(query string, err error) ValidationError {
+	var parseErr *parser.Error
+	if errors.As(err, &parseErr) && parseErr.Cursorpos > 0 {
+		return NewPositionedError(query, parseErr.Message, parseErr.Cursorpos)
 	}
 
-	return result
+	// Fall back to scraping the message
+	errMsg := err.Error()
+	line, column := extractErrorPosition(errMsg)
+	return ValidationError{
+		Line:    line,
+		Column:  column,
+		Offset:  -1,
+		Message: cleanErrorMessage(errMsg),
+		Query:   query,
+	}
+}
+
+// ServerError converts an error returned by the server for query into a
+// ValidationError, using the error position reported by PostgreSQL.
+// Returns false if err is not a PostgreSQL error.
+func ServerError(query string, err error) (ValidationError, bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return ValidationError{}, false
+	}
+
+	if pgErr.Position > 0 {
+		return NewPositionedError(query, pgErr.Message, int(pgErr.Position)), true
+	}
+	return ValidationError{Line: 1, Offset: -1, Message: pgErr.Message, Query: query}, true
 }
 
-// ValidateAndFormat validates and formats the query (formatting not yet implemented)
+// NewPositionedError creates a ValidationError from a 1-based character
+// position in query, as reported by the PostgreSQL parser and server
+func NewPositionedError(query, message string, position int) ValidationError {
+	offset := CharPositionToOffset(query, position)
+	line, column := OffsetToLineColumn(query, offset)
+	return ValidationError{
+		Line:    line,
+		Column:  column,
+		Offset:  offset,
+		Length:  tokenLengthAt(query, offset),
+		Message: message,
+		Query:   query,
+	}
+}
+
+// CharPositionToOffset converts a 1-based character position to a byte offset
+func CharPositionToOffset(query string, position int) int {
+	offset := 0
+	for i := 1; i < position && offset < len(query); i++ {
+		_, size := utf8.DecodeRuneInString(query[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// OffsetToLineColumn converts a byte offset to a 1-based line and character column
+func OffsetToLineColumn(query string, offset int) (line int, column int) {
+	if offset > len(query) {
+		offset = len(query)
+	}
+	before := query[:offset]
+	line = strings.Count(before, "\n") + 1
+	column = utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:]) + 1
+	return line, column
+}
+
+// tokenLengthAt returns the byte length of the token starting at offset,
+// or of the rest of the word if the query can't be scanned
+func tokenLengthAt(query string, offset int) int {
+	if offset >= len(query) {
+		return 0
+	}
+
+	if tokens, err := Tokenize(query); err == nil {
+		for _, tok := range tokens {
+			if tok.Start <= offset && offset < tok.End {
+				return tok.End - offset
+			}
+		}
+	}
+
+	end := offset
+	for end < len(query) && !strings.ContainsRune(" \t\r\n;,()", rune(query[end])) {
+		end++
+	}
+	return max(end-offset, 1)
+}
+
+// ValidateAndFormat validates the query and formats it if it is valid.
+// Invalid queries are returned unchanged.
 func (v *SQLValidator) ValidateAndFormat(query string) (string, ValidationResult) {
 	result := v.Validate(query)
-	// TODO: Add formatting when available
-	return query, result
+	if !result.Valid {
+		return query, result
+	}
+
+	formatted, err := v.formatter.Format(query)
+	if err != nil {
+		return query, result
+	}
+	return formatted, result
 }
 
 // extractErrorPosition extracts line and column from error message
@@ -0,0 +1,265 @@
+package editor
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Position is a cursor position in a buffer. Col counts runes.
+type Position struct {
+	Row int
+	Col int
+}
+
+// Before reports whether p comes before other
+func (p Position) Before(other Position) bool {
+	return p.Row < other.Row || (p.Row == other.Row && p.Col < other.Col)
+}
+
+// Buffer is the text being edited, as lines of runes, with a cursor
+type Buffer struct {
+	lines   [][]rune
+	cursor  Position
+	changed bool
+}
+
+// NewBuffer creates a buffer holding text with the cursor at the start
+func NewBuffer(text string) *Buffer {
+	b := &Buffer{}
+	b.setText(text)
+	return b
+}
+
+// setText replaces the buffer contents without touching the cursor
+func (b *Buffer) setText(text string) {
+	parts := strings.Split(text, "\n")
+	b.lines = make([][]rune, len(parts))
+	for i, part := range parts {
+		b.lines[i] = []rune(part)
+	}
+}
+
+// Text returns the buffer contents
+func (b *Buffer) Text() string {
+	parts := make([]string, len(b.lines))
+	for i, line := range b.lines {
+		parts[i] = string(line)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Changed reports whether the text was modified since the buffer was created
+func (b *Buffer) Changed() bool {
+	return b.changed
+}
+
+// LineCount returns the number of lines
+func (b *Buffer) LineCount() int {
+	return len(b.lines)
+}
+
+// Line returns a line's text
+func (b *Buffer) Line(row int) string {
+	if row < 0 || row >= len(b.lines) {
+		return ""
+	}
+	return string(b.lines[row])
+}
+
+// LineLen returns the number of runes in a line
+func (b *Buffer) LineLen(row int) int {
+	if row < 0 || row >= len(b.lines) {
+		return 0
+	}
+	return len(b.lines[row])
+}
+
+// Cursor returns the cursor position
+func (b *Buffer) Cursor() Position {
+	return b.cursor
+}
+
+// SetCursor moves the cursor, clamping it to the text. The cursor may sit
+// just past the end of a line (as in insert mode).
+func (b *Buffer) SetCursor(pos Position) {
+	b.cursor = b.Clamp(pos, true)
+}
+
+// Clamp limits pos to the buffer. Without pastEnd the column stays on the
+// last character of the line, like the normal mode cursor.
+func (b *Buffer) Clamp(pos Position, pastEnd bool) Position {
+	pos.Row = max(0, min(pos.Row, len(b.lines)-1))
+	maxCol := len(b.lines[pos.Row])
+	if !pastEnd && maxCol > 0 {
+		maxCol--
+	}
+	pos.Col = max(0, min(pos.Col, maxCol))
+	return pos
+}
+
+// RuneAt returns the rune at pos, '\n' at the end of a line and 0 outside the buffer
+func (b *Buffer) RuneAt(pos Position) rune {
+	if pos.Row < 0 || pos.Row >= len(b.lines) || pos.Col < 0 {
+		return 0
+	}
+	line := b.lines[pos.Row]
+	if pos.Col >= len(line) {
+		if pos.Row == len(b.lines)-1 {
+			return 0
+		}
+		return '\n'
+	}
+	return line[pos.Col]
+}
+
+// Next returns the position after pos, treating line ends as characters.
+// ok is false at the end of the buffer.
+func (b *Buffer) Next(pos Position) (Position, bool) {
+	if pos.Col < len(b.lines[pos.Row]) {
+		return Position{pos.Row, pos.Col + 1}, true
+	}
+	if pos.Row+1 < len(b.lines) {
+		return Position{pos.Row + 1, 0}, true
+	}
+	return pos, false
+}
+
+// Prev returns the position before pos. ok is false at the start of the buffer.
+func (b *Buffer) Prev(pos Position) (Position, bool) {
+	if pos.Col > 0 {
+		return Position{pos.Row, min(pos.Col-1, len(b.lines[pos.Row]))}, true
+	}
+	if pos.Row > 0 {
+		return Position{pos.Row - 1, len(b.lines[pos.Row-1])}, true
+	}
+	return pos, false
+}
+
+// FirstNonBlank returns the column of the first non-blank character of a line
+func (b *Buffer) FirstNonBlank(row int) int {
+	for i, r := range b.lines[row] {
+		if !unicode.IsSpace(r) {
+			return i
+		}
+	}
+	return 0
+}
+
+// Offset converts a position to a byte offset in Text()
+func (b *Buffer) Offset(pos Position) int {
+	offset := 0
+	for i := 0; i < pos.Row && i < len(b.lines); i++ {
+		offset += len(string(b.lines[i])) + 1
+	}
+	if pos.Row < len(b.lines) {
+		line := b.lines[pos.Row]
+		offset += len(string(line[:min(pos.Col, len(line))]))
+	}
+	return offset
+}
+
+// PositionAt converts a byte offset in Text() to a position
+func (b *Buffer) PositionAt(offset int) Position {
+	for row, line := range b.lines {
+		length := len(string(line))
+		if offset <= length || row == len(b.lines)-1 {
+			return Position{row, len([]rune(string(line)[:min(offset, length)]))}
+		}
+		offset -= length + 1
+	}
+	return Position{}
+}
+
+// TextRange returns the text between start (inclusive) and end (exclusive)
+func (b *Buffer) TextRange(start, end Position) string {
+	if end.Before(start) {
+		start, end = end, start
+	}
+	text := b.Text()
+	return text[b.Offset(start):b.Offset(end)]
+}
+
+// DeleteRange removes the text between start (inclusive) and end
+// (exclusive), returning it. The cursor moves to start.
+func (b *Buffer) DeleteRange(start, end Position) string {
+	if end.Before(start) {
+		start, end = end, start
+	}
+	text := b.Text()
+	from, to := b.Offset(start), b.Offset(end)
+	removed := text[from:to]
+	if removed != "" {
+		b.setText(text[:from] + text[to:])
+		b.changed = true
+	}
+	b.SetCursor(start)
+	return removed
+}
+
+// InsertText inserts text at pos and returns the position after it
+func (b *Buffer) InsertText(pos Position, text string) Position {
+	pos = b.Clamp(pos, true)
+	if text == "" {
+		return pos
+	}
+	full := b.Text()
+	offset := b.Offset(pos)
+	b.setText(full[:offset] + text + full[offset:])
+	b.changed = true
+	return b.PositionAt(offset + len(text))
+}
+
+// LinesText returns lines from..to (inclusive) joined with newlines
+func (b *Buffer) LinesText(from, to int) string {
+	parts := make([]string, 0, to-from+1)
+	for i := from; i <= to && i < len(b.lines); i++ {
+		parts = append(parts, string(b.lines[i]))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// DeleteLines removes lines from..to (inclusive) and returns their text.
+// The buffer always keeps at least one (empty) line.
+func (b *Buffer) DeleteLines(from, to int) string {
+	from = max(0, from)
+	to = min(to, len(b.lines)-1)
+	if from > to {
+		return ""
+	}
+	removed := b.LinesText(from, to)
+
+	b.lines = append(b.lines[:from:from], b.lines[to+1:]...)
+	if len(b.lines) == 0 {
+		b.lines = [][]rune{{}}
+	}
+	b.changed = true
+
+	row := min(from, len(b.lines)-1)
+	b.SetCursor(Position{row, b.FirstNonBlank(row)})
+	return removed
+}
+
+// InsertLines inserts text as whole lines before row (row may equal LineCount)
+func (b *Buffer) InsertLines(row int, text string) {
+	row = max(0, min(row, len(b.lines)))
+	var inserted [][]rune
+	for _, part := range strings.Split(text, "\n") {
+		inserted = append(inserted, []rune(part))
+	}
+
+	lines := make([][]rune, 0, len(b.lines)+len(inserted))
+	lines = append(lines, b.lines[:row]...)
+	lines = append(lines, inserted...)
+	lines = append(lines, b.lines[row:]...)
+	b.lines = lines
+	b.changed = true
+}
+
+// ReplaceLine replaces the text of a line
+func (b *Buffer) ReplaceLine(row int, text string) {
+	if row < 0 || row >= len(b.lines) {
+		return
+	}
+	b.lines[row] = []rune(text)
+	b.changed = true
+}
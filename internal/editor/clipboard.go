@@ -0,0 +1,27 @@
+package editor
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+)
+
+// OSC52Clipboard returns a clipboard function for Vim.SetClipboard that
+// copies text to the host terminal's clipboard with an OSC52 escape
+// sequence written to w. This works over SSH, and inside tmux (with
+// allow-passthrough) and screen.
+func OSC52Clipboard(w io.Writer) func(text string) error {
+	return func(text string) error {
+		seq := osc52.New(text)
+		switch {
+		case os.Getenv("TMUX") != "":
+			seq = seq.Tmux()
+		case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+			seq = seq.Screen()
+		}
+		_, err := seq.WriteTo(w)
+		return err
+	}
+}
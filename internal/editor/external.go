@@ -0,0 +1,272 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Placeholders of an editor command template
+const (
+	PlaceholderFile   = "{file}"
+	PlaceholderLine   = "{line}"
+	PlaceholderColumn = "{col}"
+)
+
+// editorTemplates are the arguments opening a file at a position, for
+// editors commands that don't give their own {file} placeholder
+var editorTemplates = map[string]string{
+	"vi":    "+call\\ cursor({line},{col}) {file}",
+	"vim":   "+call\\ cursor({line},{col}) {file}",
+	"nvim":  "+call\\ cursor({line},{col}) {file}",
+	"gvim":  "-f +call\\ cursor({line},{col}) {file}",
+	"nano":  "+{line},{col} {file}",
+	"micro": "+{line}:{col} {file}",
+	"emacs": "+{line}:{col} {file}",
+	"kak":   "+{line}:{col} {file}",
+	"hx":    "{file}:{line}:{col}",
+	"helix": "{file}:{line}:{col}",
+	"code":  "--wait --goto {file}:{line}:{col}",
+	"subl":  "--wait {file}:{line}:{col}",
+}
+
+// EditorErrorMsg reports a failed external edit. TempFile, when set, still
+// holds the query for recovery.
+type EditorErrorMsg struct {
+	Err      error
+	TempFile string
+}
+
+// EditorSuccessMsg carries the text saved in the external editor
+type EditorSuccessMsg struct {
+	Text string
+}
+
+// EditorClosedMsg reports that an editor opened by ViewInEditorCmd exited
+type EditorClosedMsg struct {
+	Err error
+}
+
+// ResolveEditor returns the editor command to use: the configured one,
+// else $VISUAL, else $EDITOR, else nvim or vi when installed
+func ResolveEditor(configured string) (string, error) {
+	for _, command := range []string{configured, os.Getenv("VISUAL"), os.Getenv("EDITOR")} {
+		if strings.TrimSpace(command) != "" {
+			return command, nil
+		}
+	}
+	for _, name := range []string{"nvim", "vi"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return name, nil
+		}
+	}
+	return "", errors.New("no editor found (set $VISUAL or $EDITOR, or editor.command in the config)")
+}
+
+// EditorArgs expands an editor command for a file and a 1-based line and
+// byte column, as Vim's cursor() takes it. A command without {file} gets the arguments its editor takes to
+// open a file at a position, or just the file for unknown editors.
+func EditorArgs(command, file string, line, col int) ([]string, error) {
+	args, err := splitCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty editor command")
+	}
+	if !strings.Contains(command, PlaceholderFile) {
+		template, ok := editorTemplates[filepath.Base(args[0])]
+		if !ok {
+			template = PlaceholderFile
+		}
+		extra, err := splitCommand(template)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, extra...)
+	}
+
+	replacer := strings.NewReplacer(
+		PlaceholderFile, file,
+		PlaceholderLine, strconv.Itoa(line),
+		PlaceholderColumn, strconv.Itoa(col),
+	)
+	for i, arg := range args {
+		args[i] = replacer.Replace(arg)
+	}
+	return args, nil
+}
+
+// splitCommand splits a command line into words. Single and double quotes
+// group words and a backslash escapes the next character, as in a shell.
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote in editor command %q", command)
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
+
+// PrepareEdit writes text to a temp file and returns the command editing it
+// at a 1-based line and byte column, and the file's path
+func PrepareEdit(command, text string, line, col int) (*exec.Cmd, string, error) {
+	command, err := ResolveEditor(command)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tempFile, err := createTempFile("lazydb_query_*.sql", text)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	args, err := EditorArgs(command, tempFile, line, col)
+	if err != nil {
+		os.Remove(tempFile)
+		return nil, "", err
+	}
+	return exec.Command(args[0], args[1:]...), tempFile, nil
+}
+
+// FinishEdit reads the edited temp file back after the editor exited with
+// err. The file is removed on success and kept for recovery otherwise.
+func FinishEdit(tempFile string, err error) tea.Msg {
+	if err != nil {
+		return EditorErrorMsg{
+			Err:      fmt.Errorf("editor failed: %w (query kept in %s)", err, tempFile),
+			TempFile: tempFile,
+		}
+	}
+
+	editedText, err := readFile(tempFile)
+	if err != nil {
+		return EditorErrorMsg{Err: fmt.Errorf("failed to read edited file %s: %w", tempFile, err), TempFile: tempFile}
+	}
+	os.Remove(tempFile)
+	return EditorSuccessMsg{Text: editedText}
+}
+
+// OpenInEditorCmd returns a command that suspends the TUI and edits text
+// in the external editor (see ResolveEditor), at a 1-based line and byte
+// column
+func OpenInEditorCmd(command, text string, line, col int) tea.Cmd {
+	cmd, tempFile, err := PrepareEdit(command, text, line, col)
+	if err != nil {
+		return func() tea.Msg {
+			return EditorErrorMsg{Err: err}
+		}
+	}
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return FinishEdit(tempFile, err)
+	})
+}
+
+// ViewInEditorCmd returns a command that suspends the TUI and opens a copy
+// of text in the external editor. Edits are discarded. pattern names the
+// temp file as for os.CreateTemp, e.g. "lazydb_value_*.json", so the editor
+// picks the right syntax.
+func ViewInEditorCmd(command, text, pattern string) tea.Cmd {
+	command, err := ResolveEditor(command)
+	if err != nil {
+		return func() tea.Msg {
+			return EditorClosedMsg{Err: err}
+		}
+	}
+	tempFile, err := createTempFile(pattern, text)
+	if err != nil {
+		return func() tea.Msg {
+			return EditorClosedMsg{Err: fmt.Errorf("failed to create temp file: %w", err)}
+		}
+	}
+	args, err := EditorArgs(command, tempFile, 1, 1)
+	if err != nil {
+		os.Remove(tempFile)
+		return func() tea.Msg {
+			return EditorClosedMsg{Err: err}
+		}
+	}
+	return tea.ExecProcess(exec.Command(args[0], args[1:]...), func(err error) tea.Msg {
+		os.Remove(tempFile)
+		if err != nil {
+			err = fmt.Errorf("editor failed: %w", err)
+		}
+		return EditorClosedMsg{Err: err}
+	})
+}
+
+// createTempFile creates a temporary file named after pattern with the
+// given content, readable by the user only since queries may hold
+// sensitive data
+func createTempFile(pattern, content string) (string, error) {
+	tempFile, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	defer tempFile.Close()
+
+	// CreateTemp uses 0600 already; make sure of it whatever the platform
+	if err := tempFile.Chmod(0600); err != nil {
+		os.Remove(tempFile.Name())
+		return "", err
+	}
+	if _, err := tempFile.WriteString(content); err != nil {
+		os.Remove(tempFile.Name())
+		return "", err
+	}
+	return tempFile.Name(), nil
+}
+
+// readFile reads the content of the given file
+func readFile(filepath string) (string, error) {
+	data, err := os.ReadFile(filepath)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ByteColumn returns the 1-based byte column of a 0-based rune index in a
+// line, which editors take rather than the index of the character
+func ByteColumn(line string, runeCol int) int {
+	runes := []rune(line)
+	return len(string(runes[:min(runeCol, len(runes))])) + 1
+}
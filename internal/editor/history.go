@@ -0,0 +1,99 @@
+package editor
+
+// DefaultHistoryLimit is the number of undo steps kept by default
+const DefaultHistoryLimit = 1000
+
+// Snapshot is a buffer state recorded in the undo history
+type Snapshot struct {
+	Text   string
+	Cursor Position
+}
+
+// History is an undo/redo history of buffer snapshots. Changes are grouped:
+// everything between Begin and Commit (a normal mode command, or a whole
+// insert session) is undone in one step.
+type History struct {
+	undo  []Snapshot
+	redo  []Snapshot
+	open  *Snapshot // State before the change group being recorded
+	limit int
+}
+
+// NewHistory creates a history keeping at most limit undo steps
+func NewHistory(limit int) *History {
+	return &History{limit: limit}
+}
+
+// Begin starts a change group at the current buffer state. It does nothing
+// if a group is already open.
+func (h *History) Begin(buf *Buffer) {
+	if h.open == nil {
+		snap := buf.Snapshot()
+		h.open = &snap
+	}
+}
+
+// Commit ends the open change group, recording it if the text changed
+func (h *History) Commit(buf *Buffer) {
+	if h.open == nil {
+		return
+	}
+	before := *h.open
+	h.open = nil
+	if before.Text == buf.Text() {
+		return
+	}
+
+	h.undo = append(h.undo, before)
+	if h.limit > 0 && len(h.undo) > h.limit {
+		h.undo = h.undo[len(h.undo)-h.limit:]
+	}
+	h.redo = nil
+}
+
+// DropLast forgets the last change, for one that couldn't be applied
+func (h *History) DropLast() {
+	if len(h.undo) > 0 {
+		h.undo = h.undo[:len(h.undo)-1]
+	}
+}
+
+// Undo restores the state before the last change. It returns false if
+// there is nothing to undo.
+func (h *History) Undo(buf *Buffer) bool {
+	h.Commit(buf)
+	if len(h.undo) == 0 {
+		return false
+	}
+	prev := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = append(h.redo, buf.Snapshot())
+	buf.Restore(prev)
+	return true
+}
+
+// Redo reapplies the last undone change. It returns false if there is
+// nothing to redo.
+func (h *History) Redo(buf *Buffer) bool {
+	h.Commit(buf)
+	if len(h.redo) == 0 {
+		return false
+	}
+	next := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = append(h.undo, buf.Snapshot())
+	buf.Restore(next)
+	return true
+}
+
+// Snapshot returns the buffer text and cursor
+func (b *Buffer) Snapshot() Snapshot {
+	return Snapshot{Text: b.Text(), Cursor: b.cursor}
+}
+
+// Restore replaces the buffer text and cursor with a snapshot
+func (b *Buffer) Restore(s Snapshot) {
+	b.setText(s.Text)
+	b.changed = true
+	b.cursor = b.Clamp(s.Cursor, false)
+}
@@ -0,0 +1,332 @@
+package editor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+)
+
+// MsgpackExt is a msgpack extension value. Neovim sends buffer, window and
+// tabpage handles as extensions; they are passed back unchanged.
+type MsgpackExt struct {
+	Type int8
+	Data []byte
+}
+
+// WriteMsgpack encodes v as msgpack. It supports nil, bool, integers,
+// float64, string, []byte, []string, []any, map[string]any and MsgpackExt.
+func WriteMsgpack(w io.Writer, v any) error {
+	buf, err := appendMsgpack(nil, v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// appendMsgpack appends the encoding of v to buf
+func appendMsgpack(buf []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, 0xc0), nil
+	case bool:
+		if v {
+			return append(buf, 0xc3), nil
+		}
+		return append(buf, 0xc2), nil
+	case int:
+		return appendInt(buf, int64(v)), nil
+	case int8:
+		return appendInt(buf, int64(v)), nil
+	case int16:
+		return appendInt(buf, int64(v)), nil
+	case int32:
+		return appendInt(buf, int64(v)), nil
+	case int64:
+		return appendInt(buf, v), nil
+	case uint:
+		return appendUint(buf, uint64(v)), nil
+	case uint8:
+		return appendUint(buf, uint64(v)), nil
+	case uint16:
+		return appendUint(buf, uint64(v)), nil
+	case uint32:
+		return appendUint(buf, uint64(v)), nil
+	case uint64:
+		return appendUint(buf, v), nil
+	case float64:
+		buf = append(buf, 0xcb)
+		return binary.BigEndian.AppendUint64(buf, math.Float64bits(v)), nil
+	case string:
+		return appendString(buf, v), nil
+	case []byte:
+		buf = appendHeader(buf, len(v), 0xc4, 0xc4, 0xc5, 0xc6, 0)
+		return append(buf, v...), nil
+	case []string:
+		buf = appendHeader(buf, len(v), 0x90, 0, 0xdc, 0xdd, 16)
+		for _, s := range v {
+			buf = appendString(buf, s)
+		}
+		return buf, nil
+	case []any:
+		buf = appendHeader(buf, len(v), 0x90, 0, 0xdc, 0xdd, 16)
+		for _, item := range v {
+			var err error
+			if buf, err = appendMsgpack(buf, item); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case map[string]any:
+		buf = appendHeader(buf, len(v), 0x80, 0, 0xde, 0xdf, 16)
+		for key, item := range v {
+			buf = appendString(buf, key)
+			var err error
+			if buf, err = appendMsgpack(buf, item); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case MsgpackExt:
+		switch n := len(v.Data); n {
+		case 1, 2, 4, 8, 16:
+			buf = append(buf, 0xd4+byte(bits.TrailingZeros(uint(n))))
+		default:
+			buf = appendHeader(buf, len(v.Data), 0xc7, 0xc7, 0xc8, 0xc9, 0)
+		}
+		buf = append(buf, byte(v.Type))
+		return append(buf, v.Data...), nil
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type %T", v)
+	}
+}
+
+// appendInt appends a signed integer in its shortest form
+func appendInt(buf []byte, n int64) []byte {
+	switch {
+	case n >= 0:
+		return appendUint(buf, uint64(n))
+	case n >= -32:
+		return append(buf, byte(n))
+	case n >= math.MinInt8:
+		return append(buf, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xd1), uint16(n))
+	case n >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xd2), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, 0xd3), uint64(n))
+	}
+}
+
+// appendUint appends an unsigned integer in its shortest form
+func appendUint(buf []byte, n uint64) []byte {
+	switch {
+	case n <= 0x7f:
+		return append(buf, byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xcd), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xce), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, 0xcf), n)
+	}
+}
+
+// appendString appends a str value
+func appendString(buf []byte, s string) []byte {
+	buf = appendHeader(buf, len(s), 0xa0, 0xd9, 0xda, 0xdb, 32)
+	return append(buf, s...)
+}
+
+// appendHeader appends the type byte and length of a str, bin, array, map or
+// ext value: the fixed form below fixLimit (0 for none), else the 8 bit
+// (0 for none), 16 or 32 bit length form
+func appendHeader(buf []byte, n int, fix, len8, len16, len32 byte, fixLimit int) []byte {
+	switch {
+	case n < fixLimit:
+		return append(buf, fix|byte(n))
+	case len8 != 0 && n <= math.MaxUint8:
+		return append(buf, len8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, len16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(buf, len32), uint32(n))
+	}
+}
+
+// ReadMsgpack decodes one msgpack value. Integers decode as int64 (uint64
+// when too large), str as string, bin as []byte, arrays as []any, maps as
+// map[string]any and extensions as MsgpackExt.
+func ReadMsgpack(r *bufio.Reader) (any, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xf0 == 0x80:
+		return readMap(r, int(b&0x0f))
+	case b&0xf0 == 0x90:
+		return readArray(r, int(b&0x0f))
+	case b&0xe0 == 0xa0:
+		data, err := readBytes(r, int(b&0x1f))
+		return string(data), err
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := readLength(r, b-0xc4)
+		if err != nil {
+			return nil, err
+		}
+		return readBytes(r, n)
+	case 0xc7, 0xc8, 0xc9:
+		n, err := readLength(r, b-0xc7)
+		if err != nil {
+			return nil, err
+		}
+		return readExt(r, n)
+	case 0xca:
+		data, err := readBytes(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case 0xcb:
+		data, err := readBytes(r, 8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		data, err := readBytes(r, 1<<(b-0xcc))
+		if err != nil {
+			return nil, err
+		}
+		n := readUint(data)
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		data, err := readBytes(r, size)
+		if err != nil {
+			return nil, err
+		}
+		// Sign-extend from the value's width
+		shift := 64 - 8*size
+		return int64(readUint(data)<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return readExt(r, 1<<(b-0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := readLength(r, b-0xd9)
+		if err != nil {
+			return nil, err
+		}
+		data, err := readBytes(r, n)
+		return string(data), err
+	case 0xdc, 0xdd:
+		n, err := readLength(r, b-0xdc+1)
+		if err != nil {
+			return nil, err
+		}
+		return readArray(r, n)
+	case 0xde, 0xdf:
+		n, err := readLength(r, b-0xde+1)
+		if err != nil {
+			return nil, err
+		}
+		return readMap(r, n)
+	}
+	return nil, fmt.Errorf("msgpack: invalid type byte 0x%02x", b)
+}
+
+// readLength reads a 8 (width 0), 16 (1) or 32 (2) bit length
+func readLength(r *bufio.Reader, width byte) (int, error) {
+	data, err := readBytes(r, 1<<width)
+	if err != nil {
+		return 0, err
+	}
+	return int(readUint(data)), nil
+}
+
+// readUint decodes a big endian unsigned integer
+func readUint(data []byte) uint64 {
+	var n uint64
+	for _, b := range data {
+		n = n<<8 | uint64(b)
+	}
+	return n
+}
+
+// readBytes reads exactly n bytes
+func readBytes(r *bufio.Reader, n int) ([]byte, error) {
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// readExt reads the type and n data bytes of an extension
+func readExt(r *bufio.Reader, n int) (MsgpackExt, error) {
+	data, err := readBytes(r, n+1)
+	if err != nil {
+		return MsgpackExt{}, err
+	}
+	return MsgpackExt{Type: int8(data[0]), Data: data[1:]}, nil
+}
+
+// readArray reads n values
+func readArray(r *bufio.Reader, n int) ([]any, error) {
+	items := make([]any, n)
+	for i := range items {
+		var err error
+		if items[i], err = ReadMsgpack(r); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// readMap reads n key/value pairs. Keys that aren't strings are formatted.
+func readMap(r *bufio.Reader, n int) (map[string]any, error) {
+	m := make(map[string]any, n)
+	for range n {
+		key, err := ReadMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		value, err := ReadMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := key.(string); ok {
+			m[s] = value
+		} else {
+			m[fmt.Sprint(key)] = value
+		}
+	}
+	return m, nil
+}
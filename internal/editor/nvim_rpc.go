@@ -0,0 +1,276 @@
+package editor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NvimCallTimeout is how long a call waits for Neovim to answer
+const NvimCallTimeout = 5 * time.Second
+
+// msgpack-RPC message types
+const (
+	rpcRequest      = 0
+	rpcResponse     = 1
+	rpcNotification = 2
+)
+
+// ErrNvimClosed is returned by calls on a closed connection
+var ErrNvimClosed = errors.New("neovim connection closed")
+
+// NvimClient is a msgpack-RPC connection to Neovim
+type NvimClient struct {
+	conn io.ReadWriteCloser
+
+	writeMu sync.Mutex // Serializes messages on conn
+
+	mu       sync.Mutex
+	nextID   uint32
+	pending  map[uint32]chan rpcResult
+	handlers map[string]func(args []any)
+	err      error // Why the connection closed
+
+	done chan struct{}
+}
+
+// rpcResult is the answer to a request
+type rpcResult struct {
+	result any
+	err    error
+}
+
+// NewNvimClient starts a client on an open connection to Neovim
+func NewNvimClient(conn io.ReadWriteCloser) *NvimClient {
+	c := &NvimClient{
+		conn:     conn,
+		pending:  make(map[uint32]chan rpcResult),
+		handlers: make(map[string]func(args []any)),
+		done:     make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// DialNeovim connects to a Neovim started with --listen. The address is a
+// socket path, or host:port for TCP. An empty address uses $NVIM, which
+// Neovim sets for processes started from its terminal.
+func DialNeovim(address string) (*NvimClient, error) {
+	if address == "" {
+		address = os.Getenv("NVIM")
+	}
+	if address == "" {
+		return nil, errors.New("no Neovim address (start nvim with --listen, or set $NVIM)")
+	}
+
+	network := "unix"
+	if !strings.ContainsAny(address, `/\`) && strings.Contains(address, ":") {
+		network = "tcp"
+	}
+	conn, err := net.DialTimeout(network, address, NvimCallTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to neovim at %s: %w", address, err)
+	}
+	return NewNvimClient(conn), nil
+}
+
+// EmbedNeovim starts a headless Neovim talking RPC on its stdin and stdout.
+// Closing the client stops it.
+func EmbedNeovim(args ...string) (*NvimClient, error) {
+	if !IsNvimAvailable() {
+		return nil, fmt.Errorf("neovim is not installed or not in PATH")
+	}
+	cmd := exec.Command("nvim", append([]string{"--embed", "--headless", "--clean"}, args...)...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start neovim: %w", err)
+	}
+	return NewNvimClient(&embeddedNvim{cmd: cmd, stdin: stdin, stdout: stdout}), nil
+}
+
+// embeddedNvim is the connection to an embedded Neovim process
+type embeddedNvim struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+}
+
+func (e *embeddedNvim) Read(p []byte) (int, error)  { return e.stdout.Read(p) }
+func (e *embeddedNvim) Write(p []byte) (int, error) { return e.stdin.Write(p) }
+
+// Close ends the process: Neovim exits when its input closes
+func (e *embeddedNvim) Close() error {
+	e.stdin.Close()
+	done := make(chan error, 1)
+	go func() { done <- e.cmd.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(NvimCallTimeout):
+		e.cmd.Process.Kill()
+		<-done
+	}
+	return nil
+}
+
+// Handle runs fn for each notification of method. Handlers run on the
+// connection's reader goroutine, in the order notifications arrive, so they
+// must not call Neovim themselves.
+func (c *NvimClient) Handle(method string, fn func(args []any)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[method] = fn
+}
+
+// Call calls an API function and waits for its result
+func (c *NvimClient) Call(method string, args ...any) (any, error) {
+	ch := make(chan rpcResult, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	if err := c.send([]any{rpcRequest, id, method, args}); err != nil {
+		c.forget(id)
+		return nil, err
+	}
+
+	select {
+	case res := <-ch:
+		return res.result, res.err
+	case <-time.After(NvimCallTimeout):
+		c.forget(id)
+		return nil, fmt.Errorf("%s: neovim did not answer", method)
+	}
+}
+
+// Notify calls an API function without waiting. Neovim reports errors of
+// notifications with an nvim_error_event notification.
+func (c *NvimClient) Notify(method string, args ...any) error {
+	return c.send([]any{rpcNotification, method, args})
+}
+
+// Done is closed when the connection ends
+func (c *NvimClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection ended, nil while it is open
+func (c *NvimClient) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close ends the connection
+func (c *NvimClient) Close() error {
+	err := c.conn.Close()
+	<-c.done
+	return err
+}
+
+// send writes one message
+func (c *NvimClient) send(msg []any) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return WriteMsgpack(c.conn, msg)
+}
+
+// forget drops a request that is no longer waited for
+func (c *NvimClient) forget(id uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, id)
+}
+
+// readLoop dispatches responses and notifications until the connection ends
+func (c *NvimClient) readLoop() {
+	r := bufio.NewReader(c.conn)
+	var err error
+	for {
+		var v any
+		if v, err = ReadMsgpack(r); err != nil {
+			break
+		}
+		msg, ok := v.([]any)
+		if !ok || len(msg) < 3 {
+			err = fmt.Errorf("invalid msgpack-RPC message: %v", v)
+			break
+		}
+		kind, _ := msg[0].(int64)
+		switch {
+		case kind == rpcResponse && len(msg) == 4:
+			c.resolve(msg[1], msg[2], msg[3])
+		case kind == rpcNotification:
+			method, _ := msg[1].(string)
+			args, _ := msg[2].([]any)
+			c.mu.Lock()
+			fn := c.handlers[method]
+			c.mu.Unlock()
+			if fn != nil {
+				fn(args)
+			}
+		case kind == rpcRequest && len(msg) == 4:
+			// LazyDB serves no requests, only notifications
+			method, _ := msg[2].(string)
+			c.send([]any{rpcResponse, msg[1], fmt.Sprintf("lazydb: unknown request %s", method), nil})
+		}
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, os.ErrClosed) {
+		err = ErrNvimClosed
+	}
+	c.mu.Lock()
+	c.err = err
+	for id, ch := range c.pending {
+		ch <- rpcResult{err: err}
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// resolve delivers a response to the waiting call
+func (c *NvimClient) resolve(msgID, rpcErr, result any) {
+	id, _ := msgID.(int64)
+	c.mu.Lock()
+	ch, ok := c.pending[uint32(id)]
+	delete(c.pending, uint32(id))
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	if rpcErr != nil {
+		ch <- rpcResult{err: nvimError(rpcErr)}
+		return
+	}
+	ch <- rpcResult{result: result}
+}
+
+// nvimError converts an RPC error, [type, message] from Neovim, to an error
+func nvimError(v any) error {
+	if e, ok := v.([]any); ok && len(e) == 2 {
+		if msg, ok := e[1].(string); ok {
+			return errors.New(msg)
+		}
+	}
+	return fmt.Errorf("%v", v)
+}
@@ -0,0 +1,270 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// NvimExecuteCommand is the command defined in Neovim to run SQL in LazyDB.
+// With a range it runs those lines, otherwise the statement under the cursor.
+const NvimExecuteCommand = "LazyDBExecute"
+
+// Notifications LazyDB receives from Neovim
+const (
+	nvimExecuteEvent = "lazydb_execute"
+	nvimLinesEvent   = "nvim_buf_lines_event"
+	nvimDetachEvent  = "nvim_buf_detach_event"
+	nvimErrorEvent   = "nvim_error_event"
+)
+
+// NvimAttachedMsg reports the result of AttachNeovimCmd
+type NvimAttachedMsg struct {
+	Sync *NvimSync
+	Err  error
+}
+
+// NvimTextMsg carries the buffer text after an edit in Neovim
+type NvimTextMsg struct {
+	Text string
+}
+
+// NvimExecuteMsg asks LazyDB to run SQL from the Neovim buffer. Rows and
+// columns are 0-based; Col is a byte column.
+type NvimExecuteMsg struct {
+	Range          bool // Run lines FromRow..ToRow instead of the statement at Row, Col
+	FromRow, ToRow int
+	Row, Col       int
+}
+
+// NvimSyncErrorMsg reports an error Neovim raised for a change sent to it
+type NvimSyncErrorMsg struct {
+	Err error
+}
+
+// NvimDetachedMsg reports the end of a sync
+type NvimDetachedMsg struct {
+	Err error // Why Neovim went away, nil when LazyDB detached
+}
+
+// NvimSync mirrors one LazyDB buffer into a Neovim buffer, both ways.
+// Changes made in Neovim and runs of :LazyDBExecute arrive as messages
+// from Listen.
+type NvimSync struct {
+	client *NvimClient
+	buffer any // Neovim buffer handle
+
+	mu       sync.Mutex
+	lines    []string // Neovim's buffer, as reported by line events
+	text     string   // Text both sides agree on
+	expected []string // Texts of changes sent to Neovim whose events are still to come
+
+	events chan tea.Msg
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// AttachNeovimCmd connects to Neovim at address (see DialNeovim) and
+// mirrors text into a new buffer named name
+func AttachNeovimCmd(address, name, text string) tea.Cmd {
+	return func() tea.Msg {
+		client, err := DialNeovim(address)
+		if err != nil {
+			return NvimAttachedMsg{Err: err}
+		}
+		s, err := AttachNeovim(client, name, text)
+		if err != nil {
+			client.Close()
+			return NvimAttachedMsg{Err: err}
+		}
+		return NvimAttachedMsg{Sync: s}
+	}
+}
+
+// AttachNeovim opens a new SQL buffer holding text in the Neovim of client,
+// shows it in the current window, watches it for changes and defines the
+// :LazyDBExecute command
+func AttachNeovim(client *NvimClient, name, text string) (*NvimSync, error) {
+	s := &NvimSync{
+		client: client,
+		lines:  strings.Split(text, "\n"),
+		text:   text,
+		events: make(chan tea.Msg, 64),
+		stop:   make(chan struct{}),
+	}
+
+	info, err := client.Call("nvim_get_api_info")
+	if err != nil {
+		return nil, fmt.Errorf("neovim: %w", err)
+	}
+	apiInfo, ok := info.([]any)
+	if !ok || len(apiInfo) == 0 {
+		return nil, fmt.Errorf("neovim: unexpected api info %v", info)
+	}
+	channel := apiInfo[0]
+
+	if s.buffer, err = client.Call("nvim_create_buf", true, true); err != nil {
+		return nil, fmt.Errorf("neovim: %w", err)
+	}
+
+	// Buffer names must be unique in Neovim
+	number, err := client.Call("nvim_buf_get_number", s.buffer)
+	if err != nil {
+		return nil, fmt.Errorf("neovim: %w", err)
+	}
+
+	client.Handle(nvimLinesEvent, s.handleLines)
+	client.Handle(nvimExecuteEvent, s.handleExecute)
+	client.Handle(nvimDetachEvent, func([]any) { s.post(NvimDetachedMsg{Err: ErrNvimClosed}) })
+	client.Handle(nvimErrorEvent, func(args []any) {
+		if len(args) == 2 {
+			s.post(NvimSyncErrorMsg{Err: fmt.Errorf("neovim: %v", args[1])})
+		}
+	})
+
+	calls := []struct {
+		method string
+		args   []any
+	}{
+		{"nvim_buf_set_name", []any{s.buffer, fmt.Sprintf("lazydb://%v/%s", number, name)}},
+		{"nvim_buf_set_lines", []any{s.buffer, 0, -1, false, s.lines}},
+		{"nvim_set_option_value", []any{"filetype", "sql", map[string]any{"buf": s.buffer}}},
+		{"nvim_set_current_buf", []any{s.buffer}},
+		{"nvim_command", []any{fmt.Sprintf(
+			"command! -range %s call rpcnotify(%v, '%s', <range>, <line1>, <line2>, line('.'), col('.'))",
+			NvimExecuteCommand, channel, nvimExecuteEvent)}},
+		{"nvim_buf_attach", []any{s.buffer, false, map[string]any{}}},
+	}
+	for _, c := range calls {
+		if _, err := client.Call(c.method, c.args...); err != nil {
+			s.once.Do(func() { close(s.stop) })
+			return nil, fmt.Errorf("neovim: %s: %w", c.method, err)
+		}
+	}
+
+	go func() {
+		select {
+		case <-client.Done():
+			s.post(NvimDetachedMsg{Err: client.Err()})
+		case <-s.stop:
+		}
+	}()
+	return s, nil
+}
+
+// Listen waits for the next change or command from Neovim. Run it again
+// after each message; it returns NvimDetachedMsg once the sync ended.
+func (s *NvimSync) Listen() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case msg := <-s.events:
+			return msg
+		case <-s.stop:
+			return NvimDetachedMsg{}
+		}
+	}
+}
+
+// SetText sends a change made in LazyDB to Neovim, replacing only the
+// lines that differ
+func (s *NvimSync) SetText(text string) error {
+	s.mu.Lock()
+	if text == s.text {
+		s.mu.Unlock()
+		return nil
+	}
+	old := strings.Split(s.text, "\n")
+	lines := strings.Split(text, "\n")
+	s.text = text
+	s.expected = append(s.expected, text)
+	s.mu.Unlock()
+
+	// Keep the lines both versions share at the start and end
+	start := 0
+	for start < len(old) && start < len(lines) && old[start] == lines[start] {
+		start++
+	}
+	end := 0
+	for end < len(old)-start && end < len(lines)-start && old[len(old)-1-end] == lines[len(lines)-1-end] {
+		end++
+	}
+	return s.client.Notify("nvim_buf_set_lines", s.buffer, start, len(old)-end, false, lines[start:len(lines)-end])
+}
+
+// Detach stops mirroring, removes the command from Neovim and closes the
+// connection. The Neovim buffer stays open.
+func (s *NvimSync) Detach() error {
+	s.once.Do(func() { close(s.stop) })
+	s.client.Call("nvim_buf_detach", s.buffer)
+	s.client.Call("nvim_command", "silent! delcommand "+NvimExecuteCommand)
+	return s.client.Close()
+}
+
+// post delivers a message to Listen, dropping it once the sync stopped
+func (s *NvimSync) post(msg tea.Msg) {
+	select {
+	case s.events <- msg:
+	case <-s.stop:
+	}
+}
+
+// handleLines applies a nvim_buf_lines_event, [buf, changedtick, firstline,
+// lastline, linedata, more], and reports edits made in Neovim
+func (s *NvimSync) handleLines(args []any) {
+	if len(args) < 5 {
+		return
+	}
+	first, _ := args[2].(int64)
+	last, _ := args[3].(int64)
+	data, _ := args[4].([]any)
+	replacement := make([]string, len(data))
+	for i, line := range data {
+		replacement[i], _ = line.(string)
+	}
+
+	s.mu.Lock()
+	from := min(int(first), len(s.lines))
+	to := len(s.lines)
+	if last >= 0 {
+		to = max(from, min(int(last), len(s.lines)))
+	}
+	s.lines = append(s.lines[:from], append(replacement, s.lines[to:]...)...)
+	text := strings.Join(s.lines, "\n")
+
+	// Events of changes sent from LazyDB come back in order
+	if len(s.expected) > 0 && text == s.expected[0] {
+		s.expected = s.expected[1:]
+		s.mu.Unlock()
+		return
+	}
+	s.expected = nil
+	changed := text != s.text
+	s.text = text
+	s.mu.Unlock()
+
+	if changed {
+		s.post(NvimTextMsg{Text: text})
+	}
+}
+
+// handleExecute turns a :LazyDBExecute notification, [range, line1, line2,
+// line, col] with 1-based positions, into an NvimExecuteMsg
+func (s *NvimSync) handleExecute(args []any) {
+	if len(args) < 5 {
+		return
+	}
+	n := make([]int, 5)
+	for i := range n {
+		v, _ := args[i].(int64)
+		n[i] = int(v)
+	}
+	s.post(NvimExecuteMsg{
+		Range:   n[0] > 0,
+		FromRow: n[1] - 1,
+		ToRow:   n[2] - 1,
+		Row:     n[3] - 1,
+		Col:     max(0, n[4]-1),
+	})
+}
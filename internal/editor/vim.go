@@ -0,0 +1,995 @@
+package editor
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Mode is the editing mode of the Vim engine
+type Mode int
+
+const (
+	ModeNormal Mode = iota
+	ModeInsert
+	ModeVisual
+	ModeVisualLine
+	ModeVisualBlock
+)
+
+// IsVisual reports whether m is one of the visual modes
+func (m Mode) IsVisual() bool {
+	return m == ModeVisual || m == ModeVisualLine || m == ModeVisualBlock
+}
+
+// Editor commands returned in Result.Command for keys the engine doesn't
+// execute itself
+const (
+	CommandFormatStatement = "format-statement"
+)
+
+// Register holds yanked or deleted text
+type Register struct {
+	Text      string `json:"text"`
+	Linewise  bool   `json:"linewise,omitempty"`
+	Blockwise bool   `json:"blockwise,omitempty"` // One line of Text per row of a Visual-Block selection
+}
+
+// Result describes what the engine did with a key
+type Result struct {
+	Handled          bool   // False if the key is not a Vim command
+	Command          string // Editor command triggered by the key, if any
+	RegistersChanged bool   // Text was yanked or deleted into a register
+}
+
+// motion is the target of a cursor movement
+type motion struct {
+	pos       Position
+	linewise  bool
+	inclusive bool
+}
+
+// blockInsert is an insert started from Visual-Block mode (I, A or c). When
+// insert mode ends the text typed on the top row is repeated on the others.
+type blockInsert struct {
+	top, bottom int
+	col         int
+	before      string // Top row before the insert
+	toEnd       bool   // Append at the end of every row ($A)
+}
+
+// Vim is a Vim normal and visual mode engine operating on a Buffer
+type Vim struct {
+	mode        Mode
+	count       int    // Count typed before the motion or operator
+	opCount     int    // Count typed before the operator
+	operator    string // Pending operator: "d", "c" or "y"
+	pending     string // Key waiting for an argument: "g", "f", "t", "F", "T", "r", "i", "a", "\""
+	register    rune   // Register selected with "x for the current command, 0 for the unnamed one
+	typed       string // Keys of the command being typed, for display
+	wantCol     int    // Column kept when moving vertically
+	lastCursor  Position
+	lastFind    string // Last f/t/F/T command and its character, for ; and ,
+	anchor      Position
+	blockInsert *blockInsert
+	registers   map[rune]Register
+	regsChanged bool
+	history     *History
+	clipboard   func(text string) error
+}
+
+// NewVim creates a Vim engine in normal mode
+func NewVim() *Vim {
+	return &Vim{
+		registers: make(map[rune]Register),
+		history:   NewHistory(DefaultHistoryLimit),
+	}
+}
+
+// Mode returns the current mode
+func (v *Vim) Mode() Mode {
+	return v.mode
+}
+
+// SetMode switches the mode and drops any pending command
+func (v *Vim) SetMode(mode Mode) {
+	v.mode = mode
+	v.reset()
+}
+
+// Pending returns the keys of an incomplete command (e.g. "2d")
+func (v *Vim) Pending() string {
+	return v.typed
+}
+
+// Register returns the contents of a register
+func (v *Vim) Register(name rune) (Register, bool) {
+	reg, ok := v.registers[name]
+	return reg, ok
+}
+
+// SetRegister sets the contents of a register
+func (v *Vim) SetRegister(name rune, reg Register) {
+	v.registers[name] = reg
+}
+
+// Registers returns a copy of all registers
+func (v *Vim) Registers() map[rune]Register {
+	regs := make(map[rune]Register, len(v.registers))
+	for name, reg := range v.registers {
+		regs[name] = reg
+	}
+	return regs
+}
+
+// SetClipboard sets the function that copies the "+ and "* registers to
+// the system clipboard
+func (v *Vim) SetClipboard(copy func(text string) error) {
+	v.clipboard = copy
+}
+
+// History returns the undo history
+func (v *Vim) History() *History {
+	return v.history
+}
+
+// SetHistory replaces the undo history, e.g. when switching to another
+// buffer that keeps its own history
+func (v *Vim) SetHistory(history *History) {
+	v.history = history
+}
+
+// StartInsert enters insert mode from outside the engine. The insert
+// session is undone as one change.
+func (v *Vim) StartInsert(buf *Buffer) {
+	v.mode = ModeInsert
+	v.reset()
+	v.history.Begin(buf)
+}
+
+// EndInsert leaves insert mode like Esc does: a Visual-Block insert is
+// repeated on the other rows, the insert session is closed as one undo
+// step and the cursor steps back onto the last typed character.
+func (v *Vim) EndInsert(buf *Buffer) {
+	v.mode = ModeNormal
+	v.reset()
+
+	if bi := v.blockInsert; bi != nil {
+		v.blockInsert = nil
+		v.finishBlockInsert(buf, bi)
+		v.history.Commit(buf)
+		buf.SetCursor(buf.Clamp(Position{bi.top, bi.col}, false))
+		return
+	}
+
+	v.history.Commit(buf)
+	if cur := buf.Cursor(); cur.Col > 0 {
+		buf.SetCursor(Position{cur.Row, cur.Col - 1})
+	}
+}
+
+// reset drops the pending command
+func (v *Vim) reset() {
+	v.count = 0
+	v.opCount = 0
+	v.operator = ""
+	v.pending = ""
+	v.register = 0
+	v.typed = ""
+}
+
+// totalCount returns the count to apply (at least 1) and whether one was typed
+func (v *Vim) totalCount() (int, bool) {
+	hasCount := v.count > 0 || v.opCount > 0
+	return max(v.opCount, 1) * max(v.count, 1), hasCount
+}
+
+// HandleKey processes a key in normal or visual mode. Keys use Bubble Tea's
+// key names ("a", "esc", "ctrl+r", ...). Each command is one undo step;
+// commands that enter insert mode stay open until EndInsert.
+func (v *Vim) HandleKey(buf *Buffer, key string) Result {
+	if v.mode == ModeInsert {
+		return Result{}
+	}
+
+	if cur := buf.Cursor(); cur != v.lastCursor && v.typed == "" {
+		// The cursor was moved outside of the engine
+		v.wantCol = cur.Col
+	}
+
+	v.history.Begin(buf)
+	result := v.handleKey(buf, key)
+	if v.mode != ModeInsert {
+		v.history.Commit(buf)
+	}
+	v.lastCursor = buf.Cursor()
+
+	result.RegistersChanged = v.regsChanged
+	v.regsChanged = false
+	return result
+}
+
+// handleKey dispatches a key in normal or visual mode
+func (v *Vim) handleKey(buf *Buffer, key string) Result {
+	if key == "esc" {
+		if v.mode.IsVisual() && v.pending == "" && v.operator == "" {
+			v.mode = ModeNormal
+		}
+		v.reset()
+		return Result{Handled: true}
+	}
+
+	v.typed += key
+	handled := Result{Handled: true}
+
+	// Keys that take an argument
+	switch v.pending {
+	case "f", "t", "F", "T":
+		kind := v.pending
+		v.pending = ""
+		if len([]rune(key)) != 1 {
+			v.reset()
+			return handled
+		}
+		v.lastFind = kind + key
+		return v.applyMotion(buf, v.findMotion(buf, kind, []rune(key)[0]))
+	case "r":
+		v.pending = ""
+		if len([]rune(key)) == 1 && v.operator == "" {
+			if v.mode.IsVisual() {
+				v.visualReplace(buf, []rune(key)[0])
+			} else {
+				v.replaceChar(buf, []rune(key)[0])
+			}
+		}
+		v.reset()
+		return handled
+	case "\"":
+		v.pending = ""
+		if name := []rune(key); len(name) == 1 && validRegister(name[0]) {
+			v.register = name[0]
+		} else {
+			v.reset()
+		}
+		return handled
+	case "i", "a":
+		kind := v.pending
+		v.pending = ""
+		m, ok := v.textObject(buf, kind, key)
+		if !ok {
+			v.reset()
+			return handled
+		}
+		if v.mode.IsVisual() {
+			v.selectObject(buf, m)
+			return v.finish(handled)
+		}
+		return v.applyMotion(buf, m)
+	case "g":
+		v.pending = ""
+		switch key {
+		case "g":
+			return v.applyMotion(buf, v.motion(buf, "gg"))
+		case "q":
+			if v.operator == "" {
+				v.reset()
+				return Result{Handled: true, Command: CommandFormatStatement}
+			}
+		}
+		v.reset()
+		return handled
+	}
+
+	// Counts ("0" alone moves to the start of the line)
+	if len(key) == 1 && key[0] >= '0' && key[0] <= '9' && (key != "0" || v.count > 0) {
+		v.count = v.count*10 + int(key[0]-'0')
+		return handled
+	}
+
+	if v.mode.IsVisual() {
+		return v.visualKey(buf, key)
+	}
+
+	// Operators
+	switch key {
+	case "d", "c", "y":
+		if v.operator == key {
+			// dd, cc, yy work on whole lines
+			n, _ := v.totalCount()
+			row := buf.Cursor().Row
+			v.applyOperator(buf, Position{row, 0}, Position{min(row+n-1, buf.LineCount()-1), 0}, true)
+			return v.finish(handled)
+		}
+		if v.operator != "" {
+			v.reset()
+			return handled
+		}
+		v.operator = key
+		v.opCount = v.count
+		v.count = 0
+		return handled
+	}
+
+	if v.operator != "" {
+		switch key {
+		case "i", "a":
+			v.pending = key
+			return handled
+		}
+	}
+
+	switch key {
+	case "g", "f", "t", "F", "T", "r", "\"":
+		v.pending = key
+		return handled
+	}
+
+	if m := v.motion(buf, key); m != nil {
+		return v.applyMotion(buf, m)
+	}
+
+	if v.operator != "" {
+		// Unknown motion cancels the operator
+		v.reset()
+		return handled
+	}
+
+	result := v.command(buf, key)
+	if !result.Handled {
+		v.reset()
+		return result
+	}
+	v.wantCol = buf.Cursor().Col
+	return v.finish(result)
+}
+
+// finish ends a complete command
+func (v *Vim) finish(result Result) Result {
+	v.reset()
+	return result
+}
+
+// command runs a normal mode command that is not a motion
+func (v *Vim) command(buf *Buffer, key string) Result {
+	handled := Result{Handled: true}
+	n, _ := v.totalCount()
+	cur := buf.Cursor()
+
+	switch key {
+	case "i":
+		v.mode = ModeInsert
+	case "a":
+		if buf.LineLen(cur.Row) > 0 {
+			buf.SetCursor(Position{cur.Row, cur.Col + 1})
+		}
+		v.mode = ModeInsert
+	case "I":
+		buf.SetCursor(Position{cur.Row, buf.FirstNonBlank(cur.Row)})
+		v.mode = ModeInsert
+	case "A":
+		buf.SetCursor(Position{cur.Row, buf.LineLen(cur.Row)})
+		v.mode = ModeInsert
+	case "o":
+		buf.InsertLines(cur.Row+1, "")
+		buf.SetCursor(Position{cur.Row + 1, 0})
+		v.mode = ModeInsert
+	case "O":
+		buf.InsertLines(cur.Row, "")
+		buf.SetCursor(Position{cur.Row, 0})
+		v.mode = ModeInsert
+	case "x", "delete":
+		if buf.LineLen(cur.Row) > 0 {
+			end := Position{cur.Row, min(cur.Col+n, buf.LineLen(cur.Row))}
+			v.operator = "d"
+			v.applyOperator(buf, cur, end, false)
+		}
+	case "X":
+		if cur.Col > 0 {
+			v.operator = "d"
+			v.applyOperator(buf, Position{cur.Row, max(cur.Col-n, 0)}, cur, false)
+		}
+	case "D", "C":
+		v.operator = strings.ToLower(key)
+		end := Position{min(cur.Row+n-1, buf.LineCount()-1), 0}
+		end.Col = buf.LineLen(end.Row)
+		v.applyOperator(buf, cur, end, false)
+	case "s":
+		v.operator = "c"
+		v.applyOperator(buf, cur, Position{cur.Row, min(cur.Col+n, buf.LineLen(cur.Row))}, false)
+	case "S":
+		v.operator = "c"
+		v.applyOperator(buf, Position{cur.Row, 0}, Position{min(cur.Row+n-1, buf.LineCount()-1), 0}, true)
+	case "Y":
+		v.operator = "y"
+		v.applyOperator(buf, Position{cur.Row, 0}, Position{min(cur.Row+n-1, buf.LineCount()-1), 0}, true)
+	case "p", "P":
+		v.put(buf, key == "P", n)
+	case "J":
+		v.joinLines(buf, max(n, 2))
+	case "u", "ctrl+r":
+		undo := v.history.Undo
+		if key == "ctrl+r" {
+			undo = v.history.Redo
+		}
+		for i := 0; i < n; i++ {
+			if !undo(buf) {
+				break
+			}
+		}
+	case "v", "V", "ctrl+v":
+		v.anchor = cur
+		v.mode = visualModes[key]
+	default:
+		return Result{}
+	}
+	return handled
+}
+
+// applyMotion moves the cursor, or applies the pending operator over the motion
+func (v *Vim) applyMotion(buf *Buffer, m *motion) Result {
+	handled := Result{Handled: true}
+	if m == nil {
+		v.reset()
+		return handled
+	}
+
+	cur := buf.Cursor()
+	if v.operator == "" {
+		buf.SetCursor(buf.Clamp(m.pos, false))
+		return v.finish(handled)
+	}
+
+	start, end := cur, m.pos
+	if end.Before(start) {
+		start, end = end, start
+	}
+
+	if m.linewise {
+		v.applyOperator(buf, start, end, true)
+		return v.finish(handled)
+	}
+
+	if m.inclusive {
+		if end.Col < buf.LineLen(end.Row) {
+			end.Col++
+		}
+	} else if end.Col == 0 && end.Row > start.Row {
+		// An exclusive motion ending at the start of a line stops at the end of the previous one
+		end = Position{end.Row - 1, buf.LineLen(end.Row - 1)}
+	}
+
+	v.applyOperator(buf, start, end, false)
+	return v.finish(handled)
+}
+
+// applyOperator applies the pending operator to a range. Linewise ranges
+// cover the rows of start and end; charwise ranges exclude end.
+func (v *Vim) applyOperator(buf *Buffer, start, end Position, linewise bool) {
+	op := v.operator
+
+	if linewise {
+		first, last := start.Row, end.Row
+		text := buf.LinesText(first, last)
+		switch op {
+		case "y":
+			v.storeRegister(Register{Text: text, Linewise: true}, true)
+			buf.SetCursor(Position{first, buf.Clamp(start, false).Col})
+		case "d":
+			v.storeRegister(Register{Text: text, Linewise: true}, false)
+			buf.DeleteLines(first, last)
+		case "c":
+			v.storeRegister(Register{Text: text, Linewise: true}, false)
+			if last > first {
+				buf.DeleteLines(first+1, last)
+			}
+			buf.ReplaceLine(first, "")
+			buf.SetCursor(Position{first, 0})
+			v.mode = ModeInsert
+		}
+		return
+	}
+
+	text := buf.TextRange(start, end)
+	switch op {
+	case "y":
+		v.storeRegister(Register{Text: text}, true)
+		buf.SetCursor(start)
+	case "d":
+		v.storeRegister(Register{Text: text}, false)
+		buf.DeleteRange(start, end)
+		buf.SetCursor(buf.Clamp(start, false))
+	case "c":
+		v.storeRegister(Register{Text: text}, false)
+		buf.DeleteRange(start, end)
+		v.mode = ModeInsert
+	}
+}
+
+// validRegister reports whether name can be selected with "
+func validRegister(name rune) bool {
+	return (name >= 'a' && name <= 'z') || (name >= 'A' && name <= 'Z') ||
+		(name >= '0' && name <= '9') || strings.ContainsRune("\"-+*_", name)
+}
+
+// storeRegister saves yanked or deleted text like Vim does: in the register
+// selected with "x, or else in the numbered registers, and always in the
+// unnamed register. Uppercase names append to the lowercase register.
+func (v *Vim) storeRegister(reg Register, yank bool) {
+	name := v.register
+	if name == '_' {
+		return
+	}
+	v.regsChanged = true
+
+	switch {
+	case name >= 'A' && name <= 'Z':
+		name = unicode.ToLower(name)
+		if prev, ok := v.registers[name]; ok {
+			switch {
+			case prev.Linewise || reg.Linewise:
+				reg = Register{Text: prev.Text + "\n" + reg.Text, Linewise: true}
+			default:
+				reg = Register{Text: prev.Text + reg.Text, Blockwise: prev.Blockwise && reg.Blockwise}
+			}
+		}
+		v.registers[name] = reg
+	case name >= 'a' && name <= 'z':
+		v.registers[name] = reg
+	case name == '+' || name == '*':
+		v.registers['+'] = reg
+		v.registers['*'] = reg
+		if v.clipboard != nil {
+			// The register keeps the text even if the terminal can't be reached
+			_ = v.clipboard(reg.Text)
+		}
+	case yank:
+		v.registers['0'] = reg
+	case reg.Linewise || strings.Contains(reg.Text, "\n"):
+		for i := '9'; i > '1'; i-- {
+			if prev, ok := v.registers[i-1]; ok {
+				v.registers[i] = prev
+			}
+		}
+		v.registers['1'] = reg
+	default:
+		v.registers['-'] = reg
+	}
+	v.registers['"'] = reg
+}
+
+// selectedRegister returns the register chosen with "x, or the unnamed one
+func (v *Vim) selectedRegister() (Register, bool) {
+	name := v.register
+	if name == 0 {
+		name = '"'
+	}
+	reg, ok := v.registers[unicode.ToLower(name)]
+	return reg, ok && reg.Text != ""
+}
+
+// put pastes the selected register after (or before) the cursor
+func (v *Vim) put(buf *Buffer, before bool, count int) {
+	reg, ok := v.selectedRegister()
+	if !ok {
+		return
+	}
+	text := reg.Text
+	cur := buf.Cursor()
+
+	if reg.Blockwise {
+		col := cur.Col
+		if !before && buf.LineLen(cur.Row) > 0 {
+			col++
+		}
+		v.putBlock(buf, reg.Text, Position{cur.Row, col}, count)
+		return
+	}
+
+	if reg.Linewise {
+		lines := strings.TrimSuffix(strings.Repeat(text+"\n", count), "\n")
+		row := cur.Row + 1
+		if before {
+			row = cur.Row
+		}
+		buf.InsertLines(row, lines)
+		buf.SetCursor(Position{row, buf.FirstNonBlank(row)})
+		return
+	}
+
+	pos := cur
+	if !before && buf.LineLen(cur.Row) > 0 {
+		pos.Col++
+	}
+	end := buf.InsertText(pos, strings.Repeat(text, count))
+	prev, _ := buf.Prev(end)
+	buf.SetCursor(buf.Clamp(prev, false))
+}
+
+// joinLines joins count lines starting at the cursor, separated by a space
+func (v *Vim) joinLines(buf *Buffer, count int) {
+	row := buf.Cursor().Row
+	for i := 1; i < count && row+1 < buf.LineCount(); i++ {
+		left := strings.TrimRight(buf.Line(row), " \t")
+		right := strings.TrimLeft(buf.Line(row+1), " \t")
+		joined := left
+		if left != "" && right != "" && !strings.HasPrefix(right, ")") {
+			joined += " "
+		}
+		col := len([]rune(joined))
+		buf.DeleteLines(row+1, row+1)
+		buf.ReplaceLine(row, joined+right)
+		buf.SetCursor(Position{row, max(col-1, 0)})
+	}
+}
+
+// replaceChar replaces the character under the cursor
+func (v *Vim) replaceChar(buf *Buffer, r rune) {
+	cur := buf.Cursor()
+	line := []rune(buf.Line(cur.Row))
+	if cur.Col >= len(line) {
+		return
+	}
+	line[cur.Col] = r
+	buf.ReplaceLine(cur.Row, string(line))
+}
+
+// motion returns the target of a motion key, or nil if key is not a motion
+func (v *Vim) motion(buf *Buffer, key string) *motion {
+	n, hasCount := v.totalCount()
+	cur := buf.Cursor()
+	lineLen := buf.LineLen(cur.Row)
+
+	switch key {
+	case "h", "left", "backspace":
+		v.wantCol = max(cur.Col-n, 0)
+		return &motion{pos: Position{cur.Row, v.wantCol}}
+	case "l", "right", " ":
+		limit := max(lineLen-1, 0)
+		if v.operator != "" {
+			limit = lineLen
+		}
+		v.wantCol = min(cur.Col+n, limit)
+		return &motion{pos: Position{cur.Row, v.wantCol}}
+	case "j", "down", "k", "up":
+		row := cur.Row + n
+		if key == "k" || key == "up" {
+			row = cur.Row - n
+		}
+		row = max(0, min(row, buf.LineCount()-1))
+		return &motion{pos: Position{row, v.wantCol}, linewise: true}
+	case "0", "home":
+		v.wantCol = 0
+		return &motion{pos: Position{cur.Row, 0}}
+	case "^":
+		v.wantCol = buf.FirstNonBlank(cur.Row)
+		return &motion{pos: Position{cur.Row, v.wantCol}}
+	case "$", "end":
+		row := min(cur.Row+n-1, buf.LineCount()-1)
+		v.wantCol = endOfLine
+		return &motion{pos: Position{row, max(buf.LineLen(row)-1, 0)}, inclusive: buf.LineLen(row) > 0}
+	case "w", "W", "b", "B", "e", "E":
+		big := key == "W" || key == "B" || key == "E"
+		pos := cur
+		for i := 0; i < n; i++ {
+			switch strings.ToLower(key) {
+			case "w":
+				// cw on a word behaves like ce
+				if v.operator == "c" && wordClass(buf.RuneAt(pos), big) != 0 {
+					pos = v.wordEnd(buf, pos, big, i == 0)
+					if i == n-1 {
+						v.wantCol = pos.Col
+						return &motion{pos: pos, inclusive: true}
+					}
+					continue
+				}
+				pos = v.wordForward(buf, pos, big)
+			case "b":
+				pos = v.wordBackward(buf, pos, big)
+			case "e":
+				pos = v.wordEnd(buf, pos, big, false)
+			}
+		}
+		v.wantCol = pos.Col
+		return &motion{pos: pos, inclusive: strings.ToLower(key) == "e"}
+	case "gg", "G":
+		row := buf.LineCount() - 1
+		if key == "gg" {
+			row = 0
+		}
+		if hasCount {
+			row = max(0, min(n-1, buf.LineCount()-1))
+		}
+		v.wantCol = buf.FirstNonBlank(row)
+		return &motion{pos: Position{row, v.wantCol}, linewise: true}
+	case ";", ",":
+		if v.lastFind == "" {
+			return nil
+		}
+		kind, char := v.lastFind[:1], []rune(v.lastFind[1:])[0]
+		if key == "," {
+			kind = map[string]string{"f": "F", "F": "f", "t": "T", "T": "t"}[kind]
+		}
+		return v.findMotion(buf, kind, char)
+	}
+	return nil
+}
+
+// findMotion searches the current line for the count-th occurrence of char
+func (v *Vim) findMotion(buf *Buffer, kind string, char rune) *motion {
+	n, _ := v.totalCount()
+	cur := buf.Cursor()
+	line := []rune(buf.Line(cur.Row))
+
+	col := cur.Col
+	forward := kind == "f" || kind == "t"
+	for found := 0; found < n; {
+		if forward {
+			col++
+		} else {
+			col--
+		}
+		if col < 0 || col >= len(line) {
+			return nil
+		}
+		if line[col] == char {
+			found++
+		}
+	}
+
+	switch kind {
+	case "t":
+		col--
+	case "T":
+		col++
+	}
+	v.wantCol = col
+	return &motion{pos: Position{cur.Row, col}, inclusive: forward}
+}
+
+// wordClass classifies a rune for word motions: 0 blank, 1 word, 2 punctuation
+func wordClass(r rune, big bool) int {
+	if r == 0 || unicode.IsSpace(r) {
+		return 0
+	}
+	if big || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return 1
+	}
+	return 2
+}
+
+// wordForward returns the start of the next word. Empty lines count as words.
+func (v *Vim) wordForward(buf *Buffer, pos Position, big bool) Position {
+	p, ok := pos, true
+	if class := wordClass(buf.RuneAt(p), big); class != 0 {
+		for ok && wordClass(buf.RuneAt(p), big) == class {
+			p, ok = buf.Next(p)
+		}
+	}
+	for ok && wordClass(buf.RuneAt(p), big) == 0 {
+		if p.Row != pos.Row && buf.LineLen(p.Row) == 0 {
+			break
+		}
+		p, ok = buf.Next(p)
+	}
+	return p
+}
+
+// wordEnd returns the end of the current or next word. With stay, a cursor
+// already inside a word stops at the end of that word (used by cw).
+func (v *Vim) wordEnd(buf *Buffer, pos Position, big bool, stay bool) Position {
+	p, ok := pos, true
+	if !stay {
+		p, ok = buf.Next(pos)
+	}
+	for ok && wordClass(buf.RuneAt(p), big) == 0 {
+		p, ok = buf.Next(p)
+	}
+	if !ok {
+		return p
+	}
+	class := wordClass(buf.RuneAt(p), big)
+	for {
+		next, ok := buf.Next(p)
+		if !ok || wordClass(buf.RuneAt(next), big) != class {
+			return p
+		}
+		p = next
+	}
+}
+
+// wordBackward returns the start of the current or previous word
+func (v *Vim) wordBackward(buf *Buffer, pos Position, big bool) Position {
+	p, ok := buf.Prev(pos)
+	for ok && wordClass(buf.RuneAt(p), big) == 0 {
+		if p.Row != pos.Row && buf.LineLen(p.Row) == 0 {
+			return p
+		}
+		p, ok = buf.Prev(p)
+	}
+	if !ok {
+		return Position{}
+	}
+	class := wordClass(buf.RuneAt(p), big)
+	for {
+		prev, ok := buf.Prev(p)
+		if !ok || wordClass(buf.RuneAt(prev), big) != class {
+			return p
+		}
+		p = prev
+	}
+}
+
+// textObject returns the range of a text object as a motion from the range
+// start. kind is "i" (inner) or "a" (around). A count takes that many words
+// or paragraphs, or the count-th enclosing bracket pair; quotes don't take
+// one.
+func (v *Vim) textObject(buf *Buffer, kind, key string) (*motion, bool) {
+	cur := buf.Cursor()
+	count, hasCount := v.totalCount()
+	var start, end Position
+	var ok bool
+
+	switch key {
+	case "w", "W":
+		start, end, ok = wordObject(buf, cur, key == "W", kind == "a")
+		// The next words on the line
+		for i := 1; ok && i < count && end.Col < buf.LineLen(end.Row); i++ {
+			_, end, _ = wordObject(buf, end, key == "W", kind == "a")
+		}
+	case "\"", "'", "`":
+		if hasCount {
+			return nil, false
+		}
+		start, end, ok = quoteObject(buf, cur, []rune(key)[0], kind == "a")
+	case "(", ")", "b":
+		start, end, ok = bracketObject(buf, cur, '(', ')', kind == "a", count)
+	case "[", "]":
+		start, end, ok = bracketObject(buf, cur, '[', ']', kind == "a", count)
+	case "{", "}", "B":
+		start, end, ok = bracketObject(buf, cur, '{', '}', kind == "a", count)
+	case "p":
+		first, last := paragraphObject(buf, cur.Row, kind == "a")
+		for i := 1; i < count && last+1 < buf.LineCount(); i++ {
+			_, last = paragraphObject(buf, last+1, kind == "a")
+		}
+		buf.SetCursor(Position{first, 0})
+		return &motion{pos: Position{last, 0}, linewise: true}, true
+	}
+	if !ok {
+		return nil, false
+	}
+
+	// Operators apply from the cursor to the motion target
+	buf.SetCursor(start)
+	return &motion{pos: end}, true
+}
+
+// wordObject returns the word (or run of blanks) under the cursor
+func wordObject(buf *Buffer, cur Position, big, around bool) (Position, Position, bool) {
+	line := []rune(buf.Line(cur.Row))
+	if len(line) == 0 {
+		return cur, cur, false
+	}
+	col := min(cur.Col, len(line)-1)
+	class := wordClass(line[col], big)
+
+	start, end := col, col+1
+	for start > 0 && wordClass(line[start-1], big) == class {
+		start--
+	}
+	for end < len(line) && wordClass(line[end], big) == class {
+		end++
+	}
+
+	if around && class != 0 {
+		// Include trailing blanks, or leading ones if there are none
+		trail := end
+		for trail < len(line) && wordClass(line[trail], big) == 0 {
+			trail++
+		}
+		if trail > end {
+			end = trail
+		} else {
+			for start > 0 && wordClass(line[start-1], big) == 0 {
+				start--
+			}
+		}
+	}
+	return Position{cur.Row, start}, Position{cur.Row, end}, true
+}
+
+// quoteObject returns the quoted string containing (or following) the cursor
+func quoteObject(buf *Buffer, cur Position, quote rune, around bool) (Position, Position, bool) {
+	line := []rune(buf.Line(cur.Row))
+	var quotes []int
+	for i, r := range line {
+		if r == quote && (i == 0 || line[i-1] != '\\') {
+			quotes = append(quotes, i)
+		}
+	}
+
+	for i := 0; i+1 < len(quotes); i += 2 {
+		open, close := quotes[i], quotes[i+1]
+		if cur.Col > close {
+			continue
+		}
+		if around {
+			end := close + 1
+			for end < len(line) && unicode.IsSpace(line[end]) {
+				end++
+			}
+			return Position{cur.Row, open}, Position{cur.Row, end}, true
+		}
+		return Position{cur.Row, open + 1}, Position{cur.Row, close}, true
+	}
+	return cur, cur, false
+}
+
+// bracketObject returns the count-th bracket pair around the cursor, 1 for
+// the innermost
+func bracketObject(buf *Buffer, cur Position, open, close rune, around bool, count int) (Position, Position, bool) {
+	// Search backwards for the count-th unmatched opening bracket
+	depth := 0
+	p, ok := cur, true
+	if buf.RuneAt(p) == close {
+		p, ok = buf.Prev(p)
+	}
+	for ok {
+		switch buf.RuneAt(p) {
+		case close:
+			depth++
+		case open:
+			if depth == 0 {
+				if count--; count <= 0 {
+					goto found
+				}
+				break
+			}
+			depth--
+		}
+		p, ok = buf.Prev(p)
+	}
+	return cur, cur, false
+
+found:
+	start := p
+	depth = 0
+	q, ok := buf.Next(start)
+	for ok {
+		switch buf.RuneAt(q) {
+		case open:
+			depth++
+		case close:
+			if depth == 0 {
+				if around {
+					end, _ := buf.Next(q)
+					return start, end, true
+				}
+				inner, _ := buf.Next(start)
+				return inner, q, true
+			}
+			depth--
+		}
+		q, ok = buf.Next(q)
+	}
+	return cur, cur, false
+}
+
+// paragraphObject returns the rows of the paragraph (or blank block) at row
+func paragraphObject(buf *Buffer, row int, around bool) (int, int) {
+	blank := func(r int) bool { return strings.TrimSpace(buf.Line(r)) == "" }
+	isBlank := blank(row)
+
+	first, last := row, row
+	for first > 0 && blank(first-1) == isBlank {
+		first--
+	}
+	for last+1 < buf.LineCount() && blank(last+1) == isBlank {
+		last++
+	}
+	if around && !isBlank {
+		for last+1 < buf.LineCount() && blank(last+1) {
+			last++
+		}
+	}
+	return first, last
+}
@@ -0,0 +1,417 @@
+package editor
+
+import (
+	"strings"
+	"unicode"
+)
+
+// visualModes maps the keys that start (or switch between) visual modes
+var visualModes = map[string]Mode{
+	"v":      ModeVisual,
+	"V":      ModeVisualLine,
+	"ctrl+v": ModeVisualBlock,
+}
+
+// endOfLine is the wanted column after $, used by Visual-Block to select
+// up to the end of every row
+const endOfLine = 1 << 30
+
+// Selection is the text selected in a visual mode
+type Selection struct {
+	Mode  Mode
+	Start Position // First selected character
+	End   Position // Last selected character (inclusive)
+}
+
+// Columns returns the selected rune columns [from, to) of a row that has
+// lineLen runes. to may be lineLen+1 when the line break is selected.
+func (s Selection) Columns(row, lineLen int) (from, to int, ok bool) {
+	if row < s.Start.Row || row > s.End.Row {
+		return 0, 0, false
+	}
+	switch s.Mode {
+	case ModeVisualLine:
+		return 0, lineLen + 1, true
+	case ModeVisualBlock:
+		from = min(s.Start.Col, lineLen)
+		to = min(s.End.Col+1, lineLen)
+		return from, max(from, to), true
+	default:
+		from, to = 0, lineLen+1
+		if row == s.Start.Row {
+			from = min(s.Start.Col, lineLen)
+		}
+		if row == s.End.Row {
+			to = min(s.End.Col+1, lineLen+1)
+		}
+		return from, max(from, to), true
+	}
+}
+
+// Selection returns the visual selection with the cursor at cursor. ok is
+// false outside of the visual modes.
+func (v *Vim) Selection(cursor Position) (Selection, bool) {
+	if !v.mode.IsVisual() {
+		return Selection{}, false
+	}
+	start, end := v.anchor, cursor
+	if end.Before(start) {
+		start, end = end, start
+	}
+
+	switch v.mode {
+	case ModeVisualLine:
+		start.Col = 0
+		end.Col = endOfLine
+	case ModeVisualBlock:
+		left, right := min(v.anchor.Col, cursor.Col), max(v.anchor.Col, cursor.Col)
+		if v.wantCol == endOfLine {
+			right = endOfLine
+		}
+		start.Col, end.Col = left, right
+	}
+	return Selection{Mode: v.mode, Start: start, End: end}, true
+}
+
+// visualKey handles a key in a visual mode
+func (v *Vim) visualKey(buf *Buffer, key string) Result {
+	handled := Result{Handled: true}
+
+	switch key {
+	case "i", "a", "g", "f", "t", "F", "T", "r", "\"":
+		v.pending = key
+		return handled
+	case "o":
+		cur := buf.Cursor()
+		buf.SetCursor(v.anchor)
+		v.anchor = cur
+		v.wantCol = buf.Cursor().Col
+		return v.finish(handled)
+	case "v", "V", "ctrl+v":
+		if v.mode == visualModes[key] {
+			v.mode = ModeNormal
+		} else {
+			v.mode = visualModes[key]
+		}
+		return v.finish(handled)
+	}
+
+	if m := v.motion(buf, key); m != nil {
+		return v.applyMotion(buf, m)
+	}
+
+	if !v.visualCommand(buf, key) {
+		// Unknown keys are swallowed so they don't fall through as typing
+		v.reset()
+		return handled
+	}
+	v.wantCol = buf.Cursor().Col
+	return v.finish(handled)
+}
+
+// visualCommand applies a command to the selection and leaves visual mode.
+// It returns false if key is not a visual mode command.
+func (v *Vim) visualCommand(buf *Buffer, key string) bool {
+	sel, _ := v.Selection(buf.Cursor())
+	mode := v.mode
+
+	// Commands that work on whole lines, except D/C/X in Visual-Block which
+	// extend the block to the end of the rows
+	switch key {
+	case "D", "X", "C", "S", "R", "Y":
+		if mode == ModeVisualBlock && key != "S" && key != "R" && key != "Y" {
+			sel.End.Col = endOfLine
+		} else {
+			mode = ModeVisualLine
+			sel.Start.Col, sel.End.Col = 0, endOfLine
+		}
+	}
+
+	var op string
+	switch key {
+	case "d", "x", "delete", "D", "X":
+		op = "d"
+	case "c", "s", "C", "S", "R":
+		op = "c"
+	case "y", "Y":
+		op = "y"
+	case "~", "u", "U":
+		v.mode = ModeNormal
+		v.changeCase(buf, sel, mode, key)
+		return true
+	case "J":
+		v.mode = ModeNormal
+		buf.SetCursor(sel.Start)
+		v.joinLines(buf, max(sel.End.Row-sel.Start.Row+1, 2))
+		return true
+	case "p", "P":
+		v.mode = ModeNormal
+		v.putOverSelection(buf, sel, mode, key == "P")
+		return true
+	case "I", "A":
+		if mode != ModeVisualBlock {
+			// Like Vim, I and A in the other visual modes insert at the
+			// start or end of the selection
+			v.mode = ModeInsert
+			if key == "I" {
+				buf.SetCursor(Position{sel.Start.Row, min(sel.Start.Col, buf.LineLen(sel.Start.Row))})
+			} else {
+				buf.SetCursor(Position{sel.End.Row, min(sel.End.Col+1, buf.LineLen(sel.End.Row))})
+			}
+			return true
+		}
+		col := sel.Start.Col
+		if key == "A" {
+			col = min(sel.End.Col+1, endOfLine)
+		}
+		v.startBlockInsert(buf, sel.Start.Row, sel.End.Row, col, key == "A")
+		return true
+	default:
+		return false
+	}
+
+	v.mode = ModeNormal
+	v.operator = op
+	if mode == ModeVisualBlock {
+		v.blockOperator(buf, sel)
+		return true
+	}
+
+	start, end, linewise := selectionRange(buf, sel, mode)
+	v.applyOperator(buf, start, end, linewise)
+	return true
+}
+
+// selectionRange converts a charwise or linewise selection to an operator
+// range. Charwise ranges exclude end.
+func selectionRange(buf *Buffer, sel Selection, mode Mode) (Position, Position, bool) {
+	if mode == ModeVisualLine {
+		return sel.Start, sel.End, true
+	}
+	end := sel.End
+	if end.Col < buf.LineLen(end.Row) {
+		end.Col++
+	} else if next, ok := buf.Next(end); ok {
+		// The line break is selected
+		end = next
+	}
+	return sel.Start, end, false
+}
+
+// selectObject extends the selection over a text object
+func (v *Vim) selectObject(buf *Buffer, m *motion) {
+	start := buf.Cursor()
+	if m.linewise {
+		if v.mode == ModeVisual {
+			v.mode = ModeVisualLine
+		}
+		v.anchor = start
+		buf.SetCursor(Position{m.pos.Row, 0})
+		return
+	}
+
+	end := m.pos
+	if prev, ok := buf.Prev(end); ok && start.Before(end) {
+		end = prev
+	}
+	v.anchor = start
+	buf.SetCursor(buf.Clamp(end, false))
+}
+
+// blockRows calls fn with the runes of each row of a Visual-Block selection
+// and the selected columns [from, to) clamped to the row
+func blockRows(buf *Buffer, sel Selection, fn func(row int, line []rune, from, to int)) {
+	for row := sel.Start.Row; row <= sel.End.Row; row++ {
+		line := []rune(buf.Line(row))
+		from := min(sel.Start.Col, len(line))
+		to := min(sel.End.Col+1, len(line))
+		fn(row, line, from, max(from, to))
+	}
+}
+
+// blockOperator applies the pending operator to a Visual-Block selection
+func (v *Vim) blockOperator(buf *Buffer, sel Selection) {
+	op := v.operator
+	var parts []string
+	blockRows(buf, sel, func(row int, line []rune, from, to int) {
+		parts = append(parts, string(line[from:to]))
+		if op != "y" {
+			buf.ReplaceLine(row, string(line[:from])+string(line[to:]))
+		}
+	})
+	v.storeRegister(Register{Text: strings.Join(parts, "\n"), Blockwise: true}, op == "y")
+
+	buf.SetCursor(buf.Clamp(sel.Start, false))
+	if op == "c" {
+		v.startBlockInsert(buf, sel.Start.Row, sel.End.Row, sel.Start.Col, false)
+	}
+}
+
+// startBlockInsert enters insert mode at col of the top row. The text typed
+// there is repeated on the other rows by EndInsert. With pad, rows shorter
+// than col are padded with spaces first.
+func (v *Vim) startBlockInsert(buf *Buffer, top, bottom, col int, pad bool) {
+	toEnd := col == endOfLine
+	if toEnd {
+		// A after $ appends to the end of every row
+		col = buf.LineLen(top)
+		pad = false
+	}
+	if pad {
+		for row := top; row <= bottom; row++ {
+			if n := col - buf.LineLen(row); n > 0 {
+				buf.ReplaceLine(row, buf.Line(row)+strings.Repeat(" ", n))
+			}
+		}
+	}
+	col = min(col, buf.LineLen(top))
+
+	v.blockInsert = &blockInsert{top: top, bottom: bottom, col: col, before: buf.Line(top), toEnd: toEnd}
+	buf.SetCursor(Position{top, col})
+	v.mode = ModeInsert
+}
+
+// finishBlockInsert repeats the text inserted on the top row of a block
+// insert on the other rows. Nothing is repeated if the insert left the
+// line (e.g. a line break was typed).
+func (v *Vim) finishBlockInsert(buf *Buffer, bi *blockInsert) {
+	before, after := []rune(bi.before), []rune(buf.Line(bi.top))
+	added := len(after) - len(before)
+	if added <= 0 || buf.Cursor().Row != bi.top ||
+		string(after[:bi.col]) != string(before[:bi.col]) ||
+		string(after[bi.col+added:]) != string(before[bi.col:]) {
+		return
+	}
+
+	text := string(after[bi.col : bi.col+added])
+	for row := bi.top + 1; row <= bi.bottom && row < buf.LineCount(); row++ {
+		col := bi.col
+		if bi.toEnd {
+			col = buf.LineLen(row)
+		}
+		if buf.LineLen(row) < col {
+			continue
+		}
+		buf.InsertText(Position{row, col}, text)
+	}
+}
+
+// visualReplace replaces every selected character with r
+func (v *Vim) visualReplace(buf *Buffer, r rune) {
+	sel, _ := v.Selection(buf.Cursor())
+	v.mapSelection(buf, sel, v.mode, func(rune) rune { return r })
+	v.mode = ModeNormal
+}
+
+// changeCase toggles (~), lowers (u) or uppers (U) the selected text
+func (v *Vim) changeCase(buf *Buffer, sel Selection, mode Mode, key string) {
+	v.mapSelection(buf, sel, mode, func(r rune) rune {
+		switch {
+		case key == "u":
+			return unicode.ToLower(r)
+		case key == "U":
+			return unicode.ToUpper(r)
+		case unicode.IsUpper(r):
+			return unicode.ToLower(r)
+		default:
+			return unicode.ToUpper(r)
+		}
+	})
+}
+
+// mapSelection replaces each selected character (not line breaks) with
+// fn(char) and moves the cursor to the start of the selection
+func (v *Vim) mapSelection(buf *Buffer, sel Selection, mode Mode, fn func(rune) rune) {
+	for row := sel.Start.Row; row <= sel.End.Row; row++ {
+		line := []rune(buf.Line(row))
+		from, to, _ := sel.Columns(row, len(line))
+		if mode == ModeVisualLine {
+			from, to = 0, len(line)
+		}
+		to = min(to, len(line))
+		for i := from; i < to; i++ {
+			line[i] = fn(line[i])
+		}
+		buf.ReplaceLine(row, string(line))
+	}
+	buf.SetCursor(buf.Clamp(sel.Start, false))
+}
+
+// putOverSelection replaces the selection with the selected register. With
+// keep (P) the replaced text is dropped instead of going to the unnamed
+// register, so the same text can be put again.
+func (v *Vim) putOverSelection(buf *Buffer, sel Selection, mode Mode, keep bool) {
+	reg, ok := v.selectedRegister()
+	if !ok {
+		return
+	}
+
+	v.register = 0
+	if keep {
+		v.register = '_'
+	}
+	v.operator = "d"
+	if mode == ModeVisualBlock {
+		v.blockOperator(buf, sel)
+		v.putBlock(buf, reg.Text, buf.Clamp(sel.Start, true), 1)
+		return
+	}
+
+	start, end, linewise := selectionRange(buf, sel, mode)
+	switch {
+	case linewise:
+		lastRow := end.Row == buf.LineCount()-1
+		everything := start.Row == 0 && lastRow
+		v.applyOperator(buf, start, end, true)
+
+		row := start.Row
+		switch {
+		case everything:
+			// Only the empty line DeleteLines keeps is left
+			buf.InsertText(Position{}, reg.Text)
+		case lastRow:
+			row = buf.LineCount()
+			buf.InsertLines(row, reg.Text)
+		default:
+			buf.InsertLines(row, reg.Text)
+		}
+		buf.SetCursor(Position{row, buf.FirstNonBlank(row)})
+	default:
+		v.applyOperator(buf, start, end, false)
+		text := reg.Text
+		if reg.Linewise {
+			text = "\n" + text + "\n"
+		}
+		pos := buf.InsertText(start, text)
+		prev, _ := buf.Prev(pos)
+		buf.SetCursor(buf.Clamp(prev, false))
+	}
+}
+
+// putBlock pastes the rows of a blockwise register at pos, one per line
+// starting at pos.Row, padding short lines so the columns line up
+func (v *Vim) putBlock(buf *Buffer, text string, pos Position, count int) {
+	parts := strings.Split(text, "\n")
+	width := 0
+	for _, part := range parts {
+		width = max(width, len([]rune(part)))
+	}
+
+	for i, part := range parts {
+		row := pos.Row + i
+		if row >= buf.LineCount() {
+			buf.InsertLines(buf.LineCount(), "")
+		}
+		line := []rune(buf.Line(row))
+		if len(line) < pos.Col {
+			line = append(line, []rune(strings.Repeat(" ", pos.Col-len(line)))...)
+		}
+		piece := part + strings.Repeat(" ", width-len([]rune(part)))
+		piece = strings.Repeat(piece, count)
+		if pos.Col >= len(line) {
+			piece = strings.TrimRight(piece, " ")
+		}
+		buf.ReplaceLine(row, string(line[:pos.Col])+piece+string(line[pos.Col:]))
+	}
+	buf.SetCursor(buf.Clamp(pos, false))
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+)
+
+// GetCacheDir returns the directory for cached schema metadata
+func GetCacheDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	cacheDir := filepath.Join(homeDir, ".lazydb", "cache")
+
+	// Create directory if it doesn't exist
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", err
+	}
+
+	return cacheDir, nil
+}
+
+// SchemaCacheStore persists schema metadata as one JSON file per connection
+type SchemaCacheStore struct {
+	dir string
+}
+
+// NewSchemaCacheStore creates a store that writes into dir
+func NewSchemaCacheStore(dir string) *SchemaCacheStore {
+	return &SchemaCacheStore{dir: dir}
+}
+
+// NewDefaultSchemaCacheStore creates a store in ~/.lazydb/cache
+func NewDefaultSchemaCacheStore() (*SchemaCacheStore, error) {
+	dir, err := GetCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewSchemaCacheStore(dir), nil
+}
+
+// Load reads the cached metadata for a connection
+func (s *SchemaCacheStore) Load(connName string) (*db.SchemaMetadata, error) {
+	data, err := os.ReadFile(s.path(connName))
+	if err != nil {
+		return nil, err
+	}
+
+	var md db.SchemaMetadata
+	if err := json.Unmarshal(data, &md); err != nil {
+		return nil, err
+	}
+	return &md, nil
+}
+
+// Save writes the cached metadata for a connection
+func (s *SchemaCacheStore) Save(connName string, md *db.SchemaMetadata) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(md)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file first so a crash never leaves a half-written cache
+	tmpPath := s.path(connName) + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path(connName))
+}
+
+// Delete removes the cached metadata for a connection
+func (s *SchemaCacheStore) Delete(connName string) error {
+	err := os.Remove(s.path(connName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// path returns the cache file path for a connection. Connection names are
+// user-provided: they are kept readable but filesystem safe, and a hash of
+// the exact name tells apart names made alike, such as a/b and a_b.
+func (s *SchemaCacheStore) path(connName string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, connName)
+	sum := sha256.Sum256([]byte(connName))
+	return filepath.Join(s.dir, fmt.Sprintf("%s-%x.json", safe, sum[:4]))
+}
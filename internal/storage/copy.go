@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"os"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/jackc/pgx/v5"
+)
+
+// copyBufferSize is the buffer between the connection and the file
+const copyBufferSize = 256 * 1024
+
+// CopyToFile writes the rows of a query to a CSV file with COPY TO STDOUT.
+// The file is only replaced once the COPY succeeded.
+func CopyToFile(ctx context.Context, conn *pgx.Conn, query, path string, opts db.CopyOptions, progress func(db.CopyProgress)) (db.CopyProgress, error) {
+	path, err := expandHome(path)
+	if err != nil {
+		return db.CopyProgress{}, err
+	}
+	var done db.CopyProgress
+	err = writeFileAtomic(path, func(f *os.File) error {
+		buf := bufio.NewWriterSize(f, copyBufferSize)
+		var err error
+		if done, err = db.CopyOut(ctx, conn, query, buf, opts, progress); err != nil {
+			return err
+		}
+		return buf.Flush()
+	})
+	return done, err
+}
+
+// CopyFromFile streams a CSV file into a table with COPY FROM STDIN
+func CopyFromFile(ctx context.Context, conn *pgx.Conn, table, path string, opts db.CopyOptions, progress func(db.CopyProgress)) (db.CopyProgress, error) {
+	path, err := expandHome(path)
+	if err != nil {
+		return db.CopyProgress{}, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return db.CopyProgress{}, err
+	}
+	defer f.Close()
+
+	var total int64
+	if info, err := f.Stat(); err == nil {
+		total = info.Size()
+	}
+	return db.CopyIn(ctx, conn, table, bufio.NewReaderSize(f, copyBufferSize), total, opts, progress)
+}
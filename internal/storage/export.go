@@ -0,0 +1,548 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/jackc/pgx/v5"
+)
+
+// ExportFormats are the formats ExportResult can write
+var ExportFormats = []string{"csv", "tsv", "json", "ndjson", "markdown", "table", "sql"}
+
+// ExportOptions select what an export writes
+type ExportOptions struct {
+	Columns  []string // Columns to write, in this order; all when empty
+	Null     string   // Text of NULL values in CSV, TSV, Markdown and tables
+	Table    string   // Target table of the sql format's INSERT statements
+	NoHeader bool     // Leave out the header line of CSV and TSV
+}
+
+// ExportResult writes a query result to the file at path in the given
+// format. A leading "~/" in path is the home directory.
+func ExportResult(path, format string, result db.QueryResult, opts ExportOptions) error {
+	if result.Error != nil {
+		return fmt.Errorf("the query failed, nothing to export")
+	}
+	path, err := expandHome(path)
+	if err != nil {
+		return err
+	}
+	if _, err := newRowWriter(io.Discard, format, opts); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(f *os.File) error {
+		return WriteResult(f, format, result, opts)
+	})
+}
+
+// FormatResult returns a query result in the given format, for the
+// clipboard
+func FormatResult(format string, result db.QueryResult, opts ExportOptions) (string, error) {
+	if result.Error != nil {
+		return "", fmt.Errorf("the query failed, nothing to export")
+	}
+	var b strings.Builder
+	if err := WriteResult(&b, format, result, opts); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// WriteResult writes a query result to w in the given format
+func WriteResult(w io.Writer, format string, result db.QueryResult, opts ExportOptions) error {
+	rw, err := newRowWriter(w, format, opts)
+	if err != nil {
+		return err
+	}
+	if err := rw.header(result.Columns, result.ColumnTypes); err != nil {
+		return err
+	}
+	for i, row := range result.Rows {
+		var nulls []bool
+		if i < len(result.Nulls) {
+			nulls = result.Nulls[i]
+		}
+		if err := rw.row(row, nulls); err != nil {
+			return err
+		}
+	}
+	return rw.close()
+}
+
+// ExportQuery runs a SELECT again and writes its rows to the file at path
+// as they arrive, without holding the result in memory. It returns the
+// number of rows written.
+func ExportQuery(ctx context.Context, conn *pgx.Conn, query, path, format string, opts ExportOptions) (int, error) {
+	// Running an UPDATE ... RETURNING again would write twice
+	if !db.IsSelectQuery(query) {
+		return 0, errors.New("only a single SELECT can be run again")
+	}
+	path, err := expandHome(path)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := newRowWriter(io.Discard, format, opts); err != nil {
+		return 0, err
+	}
+	count := 0
+	err = writeFileAtomic(path, func(f *os.File) error {
+		buf := bufio.NewWriter(f)
+		rw, _ := newRowWriter(buf, format, opts)
+		n, err := db.StreamQuery(ctx, conn, query, rw.header, rw.row)
+		if err != nil {
+			return err
+		}
+		count = n
+		if err := rw.close(); err != nil {
+			return err
+		}
+		return buf.Flush()
+	})
+	return count, err
+}
+
+// rowWriter writes a result in an export format: the columns, then each
+// row, then whatever ends the document
+type rowWriter interface {
+	header(columns, types []string) error
+	row(values []string, nulls []bool) error
+	close() error
+}
+
+// newRowWriter returns the writer of a format, selecting the columns of
+// opts
+func newRowWriter(w io.Writer, format string, opts ExportOptions) (rowWriter, error) {
+	var rw rowWriter
+	switch format {
+	case "csv", "tsv":
+		cw := csv.NewWriter(w)
+		if format == "tsv" {
+			cw.Comma = '\t'
+		}
+		rw = &csvWriter{w: cw, opts: opts}
+	case "json", "ndjson":
+		rw = &jsonWriter{w: w, lines: format == "ndjson"}
+	case "markdown", "table":
+		rw = &tableWriter{w: w, markdown: format == "markdown", opts: opts}
+	case "sql":
+		if opts.Table == "" {
+			return nil, fmt.Errorf("the sql format needs a target table")
+		}
+		rw = &insertWriter{w: w, table: opts.Table}
+	default:
+		return nil, fmt.Errorf("unknown export format %q (use %s)", format, strings.Join(ExportFormats, ", "))
+	}
+	if len(opts.Columns) > 0 {
+		rw = &columnSelector{next: rw, names: opts.Columns}
+	}
+	return rw, nil
+}
+
+// columnSelector passes on the named columns of each row, in their order
+type columnSelector struct {
+	next    rowWriter
+	names   []string
+	indexes []int
+}
+
+func (s *columnSelector) header(columns, types []string) error {
+	var selected, selectedTypes []string
+	for _, name := range s.names {
+		i := indexFold(columns, name)
+		if i < 0 {
+			return fmt.Errorf("no column %q", name)
+		}
+		s.indexes = append(s.indexes, i)
+		selected = append(selected, columns[i])
+		if i < len(types) {
+			selectedTypes = append(selectedTypes, types[i])
+		}
+	}
+	if len(selectedTypes) != len(selected) {
+		selectedTypes = nil
+	}
+	return s.next.header(selected, selectedTypes)
+}
+
+func (s *columnSelector) row(values []string, nulls []bool) error {
+	selected := make([]string, len(s.indexes))
+	var selectedNulls []bool
+	for j, i := range s.indexes {
+		if i < len(values) {
+			selected[j] = values[i]
+		}
+		if i < len(nulls) && nulls[i] {
+			if selectedNulls == nil {
+				selectedNulls = make([]bool, len(s.indexes))
+			}
+			selectedNulls[j] = true
+		}
+	}
+	return s.next.row(selected, selectedNulls)
+}
+
+func (s *columnSelector) close() error {
+	return s.next.close()
+}
+
+// indexFold finds a name in names, exactly or else ignoring case
+func indexFold(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	for i, n := range names {
+		if strings.EqualFold(n, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isNull reports whether the value at col is NULL
+func isNull(nulls []bool, col int) bool {
+	return col < len(nulls) && nulls[col]
+}
+
+// typeAt returns the type of a column, empty when unknown
+func typeAt(types []string, col int) string {
+	if col < len(types) {
+		return types[col]
+	}
+	return ""
+}
+
+// csvWriter writes CSV or TSV, quoting the values that need it
+type csvWriter struct {
+	w    *csv.Writer
+	opts ExportOptions
+}
+
+func (c *csvWriter) header(columns, types []string) error {
+	if c.opts.NoHeader {
+		return nil
+	}
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) row(values []string, nulls []bool) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		if isNull(nulls, i) {
+			v = c.opts.Null
+		}
+		record[i] = v
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonWriter writes a JSON array of objects, or one object per line
+// (NDJSON). Numbers, booleans and json values keep their JSON type.
+type jsonWriter struct {
+	w       io.Writer
+	lines   bool
+	columns []string
+	types   []string
+	rows    int
+}
+
+func (j *jsonWriter) header(columns, types []string) error {
+	j.columns, j.types = columns, types
+	if j.lines {
+		return nil
+	}
+	_, err := io.WriteString(j.w, "[")
+	return err
+}
+
+func (j *jsonWriter) row(values []string, nulls []bool) error {
+	var b strings.Builder
+	switch {
+	case j.lines:
+	case j.rows == 0:
+		b.WriteString("\n  ")
+	default:
+		b.WriteString(",\n  ")
+	}
+	b.WriteByte('{')
+	for i, column := range j.columns {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(jsonString(column) + ":")
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		b.WriteString(jsonValue(value, typeAt(j.types, i), isNull(nulls, i)))
+	}
+	b.WriteByte('}')
+	if j.lines {
+		b.WriteByte('\n')
+	}
+	j.rows++
+	_, err := io.WriteString(j.w, b.String())
+	return err
+}
+
+func (j *jsonWriter) close() error {
+	if j.lines {
+		return nil
+	}
+	end := "]\n"
+	if j.rows > 0 {
+		end = "\n]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
+
+// numberPattern matches the numbers valid both in JSON and in SQL, unlike
+// NaN and Infinity
+var numberPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// jsonValue encodes a value of a column type as JSON
+func jsonValue(value, typeName string, null bool) string {
+	switch {
+	case null:
+		return "null"
+	case db.IsNumericType(typeName):
+		if numberPattern.MatchString(value) {
+			return value
+		}
+	case typeName == "bool":
+		if value == "true" || value == "false" {
+			return value
+		}
+	case typeName == "json" || typeName == "jsonb":
+		if json.Valid([]byte(value)) {
+			return value
+		}
+	}
+	return jsonString(value)
+}
+
+// jsonString quotes a string as JSON without escaping HTML characters
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// tableWriter writes a Markdown table, or a plain text table like psql's.
+// It keeps the rows until close to size the columns.
+type tableWriter struct {
+	w        io.Writer
+	markdown bool
+	opts     ExportOptions
+	columns  []string
+	types    []string
+	rows     [][]string
+}
+
+func (t *tableWriter) header(columns, types []string) error {
+	t.columns, t.types = columns, types
+	return nil
+}
+
+func (t *tableWriter) row(values []string, nulls []bool) error {
+	cells := make([]string, len(t.columns))
+	for i := range cells {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+		if isNull(nulls, i) {
+			v = t.opts.Null
+		}
+		cells[i] = t.cell(v)
+	}
+	t.rows = append(t.rows, cells)
+	return nil
+}
+
+// cell keeps a value on one line
+func (t *tableWriter) cell(v string) string {
+	if t.markdown {
+		return strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>").Replace(v)
+	}
+	return strings.NewReplacer("\r\n", "↵", "\n", "↵", "\t", "→").Replace(v)
+}
+
+func (t *tableWriter) close() error {
+	header := make([]string, len(t.columns))
+	widths := make([]int, len(t.columns))
+	for i, column := range t.columns {
+		header[i] = t.cell(column)
+		widths[i] = ansi.StringWidth(header[i])
+		if t.markdown {
+			widths[i] = max(widths[i], 3)
+		}
+	}
+	for _, row := range t.rows {
+		for i, v := range row {
+			widths[i] = max(widths[i], ansi.StringWidth(v))
+		}
+	}
+	right := make([]bool, len(t.columns))
+	for i := range right {
+		right[i] = db.IsNumericType(typeAt(t.types, i))
+	}
+
+	var b strings.Builder
+	line := func(cells []string, align func(i int) bool) {
+		var l strings.Builder
+		for i, v := range cells {
+			pad := strings.Repeat(" ", widths[i]-ansi.StringWidth(v))
+			if align(i) {
+				v = pad + v
+			} else {
+				v += pad
+			}
+			if t.markdown || i > 0 {
+				l.WriteString("| " + v + " ")
+			} else {
+				l.WriteString(" " + v + " ")
+			}
+		}
+		if t.markdown {
+			b.WriteString(l.String() + "|\n")
+		} else {
+			// Like psql, without padding after the last column
+			b.WriteString(strings.TrimRight(l.String(), " ") + "\n")
+		}
+	}
+
+	line(header, func(int) bool { return false })
+	for i, width := range widths {
+		switch {
+		case t.markdown && right[i]:
+			b.WriteString("| " + strings.Repeat("-", width-1) + ": ")
+		case t.markdown:
+			b.WriteString("| " + strings.Repeat("-", width) + " ")
+		case i > 0:
+			b.WriteString("+" + strings.Repeat("-", width+2))
+		default:
+			b.WriteString(strings.Repeat("-", width+2))
+		}
+	}
+	if t.markdown {
+		b.WriteString("|")
+	}
+	b.WriteString("\n")
+	for _, row := range t.rows {
+		line(row, func(i int) bool { return right[i] })
+	}
+	if !t.markdown {
+		noun := "rows"
+		if len(t.rows) == 1 {
+			noun = "row"
+		}
+		fmt.Fprintf(&b, "(%d %s)\n", len(t.rows), noun)
+	}
+	_, err := io.WriteString(t.w, b.String())
+	return err
+}
+
+// insertWriter writes one INSERT statement per row
+type insertWriter struct {
+	w      io.Writer
+	table  string
+	prefix string
+	types  []string
+}
+
+func (s *insertWriter) header(columns, types []string) error {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = db.QuoteIdent(column)
+	}
+	s.prefix = fmt.Sprintf("INSERT INTO %s (%s) VALUES (", db.QuoteQualifiedName(s.table), strings.Join(quoted, ", "))
+	s.types = types
+	return nil
+}
+
+func (s *insertWriter) row(values []string, nulls []bool) error {
+	literals := make([]string, len(values))
+	for i, v := range values {
+		literals[i] = sqlLiteral(v, typeAt(s.types, i), isNull(nulls, i))
+	}
+	_, err := io.WriteString(s.w, s.prefix+strings.Join(literals, ", ")+");\n")
+	return err
+}
+
+func (s *insertWriter) close() error {
+	return nil
+}
+
+// sqlLiteral writes a value of a column type as an SQL literal: numbers
+// and booleans bare, anything else quoted
+func sqlLiteral(value, typeName string, null bool) string {
+	switch {
+	case null:
+		return "NULL"
+	case db.IsNumericType(typeName):
+		if numberPattern.MatchString(value) {
+			return value
+		}
+	case typeName == "bool":
+		if value == "true" || value == "false" {
+			return strings.ToUpper(value)
+		}
+	}
+	return db.QuoteLiteral(value)
+}
+
+// expandHome replaces a leading "~/" with the home directory
+func expandHome(path string) (string, error) {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, rest), nil
+}
+
+// writeFileAtomic writes a file through write, replacing path only once
+// the write succeeded
+func writeFileAtomic(path string, write func(f *os.File) error) error {
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
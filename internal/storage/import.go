@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+)
+
+// ImportFormats are the formats ReadImportFile reads, by file extension
+var ImportFormats = map[string]string{
+	".csv":    "csv",
+	".tsv":    "tsv",
+	".tab":    "tsv",
+	".txt":    "csv",
+	".json":   "json",
+	".ndjson": "ndjson",
+	".jsonl":  "ndjson",
+}
+
+// ImportFormat returns the format of a file from its extension, csv when
+// unknown
+func ImportFormat(path string) string {
+	if format, ok := ImportFormats[strings.ToLower(filepath.Ext(path))]; ok {
+		return format
+	}
+	return "csv"
+}
+
+// ReadImportFile reads a CSV, TSV, JSON or NDJSON file as a result, with
+// the column types inferred from the values. CSV files use the delimiter,
+// header and NULL text of opts; without a delimiter the most frequent of
+// , ; | and tab on the first line is used. JSON files hold an array of
+// objects, NDJSON files one object per line; their columns are the keys in
+// order of appearance.
+func ReadImportFile(path string, opts db.CopyOptions) (db.QueryResult, error) {
+	path, err := expandHome(path)
+	if err != nil {
+		return db.QueryResult{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return db.QueryResult{}, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var result db.QueryResult
+	switch format := ImportFormat(path); format {
+	case "json", "ndjson":
+		result, err = readJSONRows(data, format == "ndjson")
+	default:
+		if opts.Delimiter == "" && format == "tsv" {
+			opts.Delimiter = "\t"
+		}
+		result, err = readCSVRows(data, opts)
+	}
+	if err != nil {
+		return db.QueryResult{}, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	result.RowCount = len(result.Rows)
+	db.InferColumnTypes(&result)
+	return result, nil
+}
+
+// sniffDelimiter picks the most frequent delimiter on the first line
+func sniffDelimiter(data []byte) rune {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	best, count := ',', 0
+	for _, r := range []rune{',', '\t', ';', '|'} {
+		if n := bytes.Count(line, []byte(string(r))); n > count {
+			best, count = r, n
+		}
+	}
+	return best
+}
+
+// readCSVRows reads delimited rows. Fields equal to the NULL text are NULL.
+func readCSVRows(data []byte, opts db.CopyOptions) (db.QueryResult, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = sniffDelimiter(data)
+	if opts.Delimiter != "" {
+		r.Comma, _ = utf8.DecodeRuneInString(opts.Delimiter)
+	}
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var result db.QueryResult
+	width := 0
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+		if opts.Header && result.Columns == nil {
+			result.Columns = record
+			width = len(record)
+			continue
+		}
+		width = max(width, len(record))
+		var nulls []bool
+		for i, v := range record {
+			if v == opts.Null {
+				if nulls == nil {
+					nulls = make([]bool, len(record))
+				}
+				nulls[i] = true
+			}
+		}
+		result.Rows = append(result.Rows, record)
+		result.Nulls = append(result.Nulls, nulls)
+	}
+
+	// Name the columns missing from the header, and fill short rows
+	for i := len(result.Columns); i < width; i++ {
+		result.Columns = append(result.Columns, fmt.Sprintf("column%d", i+1))
+	}
+	for i, row := range result.Rows {
+		if len(row) == width {
+			continue
+		}
+		nulls := make([]bool, width)
+		copy(nulls, result.Nulls[i])
+		for j := len(row); j < width; j++ {
+			nulls[j] = true
+		}
+		result.Rows[i] = append(row, make([]string, width-len(row))...)
+		result.Nulls[i] = nulls
+	}
+	return result, nil
+}
+
+// readJSONRows reads an array of objects, or one object per line
+func readJSONRows(data []byte, lines bool) (db.QueryResult, error) {
+	var objects []json.RawMessage
+	if lines {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) > 0 {
+				objects = append(objects, json.RawMessage(bytes.Clone(line)))
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return db.QueryResult{}, err
+		}
+	} else if err := json.Unmarshal(data, &objects); err != nil {
+		return db.QueryResult{}, fmt.Errorf("expected an array of objects: %w", err)
+	}
+
+	var result db.QueryResult
+	index := map[string]int{}
+	type member struct {
+		key   string
+		value string
+		null  bool
+	}
+	var records [][]member
+	for n, raw := range objects {
+		members, err := jsonObjectMembers(raw)
+		if err != nil {
+			return result, fmt.Errorf("row %d: %w", n+1, err)
+		}
+		record := make([]member, 0, len(members))
+		for _, m := range members {
+			if _, ok := index[m[0]]; !ok {
+				index[m[0]] = len(result.Columns)
+				result.Columns = append(result.Columns, m[0])
+			}
+			value, null := jsonText(json.RawMessage(m[1]))
+			record = append(record, member{key: m[0], value: value, null: null})
+		}
+		records = append(records, record)
+	}
+
+	// Keys missing from an object are NULL
+	for _, record := range records {
+		row := make([]string, len(result.Columns))
+		nulls := make([]bool, len(result.Columns))
+		for i := range nulls {
+			nulls[i] = true
+		}
+		for _, m := range record {
+			row[index[m.key]] = m.value
+			nulls[index[m.key]] = m.null
+		}
+		result.Rows = append(result.Rows, row)
+		result.Nulls = append(result.Nulls, nulls)
+	}
+	return result, nil
+}
+
+// jsonObjectMembers returns the key and raw value of each member of an
+// object, in order
+func jsonObjectMembers(raw json.RawMessage) ([][2]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, errors.New("expected an object")
+	}
+	var members [][2]string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		members = append(members, [2]string{token.(string), string(value)})
+	}
+	return members, nil
+}
+
+// jsonText returns the text of a JSON value to load: strings unquoted,
+// objects and arrays as compact JSON, and whether it is null
+func jsonText(raw json.RawMessage) (string, bool) {
+	var s string
+	switch {
+	case string(raw) == "null":
+		return "", true
+	case json.Unmarshal(raw, &s) == nil:
+		return s, false
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(raw), false
+	}
+	return buf.String(), false
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/editor"
+)
+
+// RegisterStore persists the editor's Vim registers across restarts
+type RegisterStore struct {
+	path string
+}
+
+// NewRegisterStore creates a store that reads and writes the file at path
+func NewRegisterStore(path string) *RegisterStore {
+	return &RegisterStore{path: path}
+}
+
+// NewDefaultRegisterStore creates a store in ~/.lazydb/registers.json
+func NewDefaultRegisterStore() (*RegisterStore, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewRegisterStore(filepath.Join(homeDir, ".lazydb", "registers.json")), nil
+}
+
+// Load reads the saved registers. A missing file yields no registers.
+func (s *RegisterStore) Load() (map[rune]editor.Register, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return map[rune]editor.Register{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var saved map[string]editor.Register
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+
+	regs := make(map[rune]editor.Register, len(saved))
+	for name, reg := range saved {
+		if runes := []rune(name); len(runes) == 1 {
+			regs[runes[0]] = reg
+		}
+	}
+	return regs, nil
+}
+
+// Save writes the registers. The clipboard registers ("+ and "*) mirror
+// the system clipboard and are not saved.
+func (s *RegisterStore) Save(regs map[rune]editor.Register) error {
+	saved := make(map[string]editor.Register, len(regs))
+	for name, reg := range regs {
+		if name == '+' || name == '*' {
+			continue
+		}
+		saved[string(name)] = reg
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	// Registers may hold query text with credentials, keep them private
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// ScriptFile gives line access to a large SQL file without loading it into
+// memory. Opening it streams through the file once to index where each line
+// starts; lines are then read from disk on demand.
+type ScriptFile struct {
+	path    string
+	size    int64
+	offsets []int64 // Byte offset of the start of each line
+}
+
+// OpenScript indexes the lines of the file at path
+func OpenScript(path string) (*ScriptFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	offsets := []int64{0}
+	var pos int64
+	r := bufio.NewReaderSize(f, 64*1024)
+	for {
+		chunk, err := r.ReadSlice('\n')
+		pos += int64(len(chunk))
+		if len(chunk) > 0 && chunk[len(chunk)-1] == '\n' {
+			offsets = append(offsets, pos)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+	}
+
+	return &ScriptFile{path: path, size: pos, offsets: offsets}, nil
+}
+
+// Path returns the path of the file
+func (s *ScriptFile) Path() string {
+	return s.path
+}
+
+// Size returns the size of the file in bytes when it was indexed
+func (s *ScriptFile) Size() int64 {
+	return s.size
+}
+
+// LineCount returns the number of lines. A final newline starts an empty
+// last line, like in the editor.
+func (s *ScriptFile) LineCount() int {
+	return len(s.offsets)
+}
+
+// Lines reads lines [from, to), without their line breaks
+func (s *ScriptFile) Lines(from, to int) ([]string, error) {
+	from = max(0, from)
+	to = min(to, len(s.offsets))
+	if from >= to {
+		return nil, nil
+	}
+
+	start := s.offsets[from]
+	end := s.size
+	if to < len(s.offsets) {
+		end = s.offsets[to]
+	}
+	data, err := s.readAt(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	if to == len(s.offsets) && len(lines) < to-from {
+		lines = append(lines, "") // The empty line after a final newline
+	}
+	return lines, nil
+}
+
+// ReadAll reads the whole file
+func (s *ScriptFile) ReadAll() (string, error) {
+	data, err := s.readAt(0, s.size)
+	return string(data), err
+}
+
+// readAt reads the bytes [start, end) of the file
+func (s *ScriptFile) readAt(start, end int64) ([]byte, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data := make([]byte, end-start)
+	n, err := f.ReadAt(data, start)
+	if err == io.EOF {
+		// The file shrank since it was indexed
+		err = nil
+	}
+	return data[:n], err
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/config"
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+)
+
+// sessionVersion is the version of the session file format
+const sessionVersion = 1
+
+// Session is the UI state saved on quit (and periodically) and restored on startup
+type Session struct {
+	Version      int             `json:"version"`
+	SavedAt      time.Time       `json:"saved_at"`
+	Buffers      []SessionBuffer `json:"buffers"`
+	ActiveBuffer int             `json:"active_buffer"`
+	Layout       SessionLayout   `json:"layout"`
+	FocusedPanel string          `json:"focused_panel,omitempty"`
+	// Expanded schema tree nodes per connection, as "schema" or "schema/category" paths
+	ExpandedNodes map[string][]string `json:"expanded_nodes,omitempty"`
+}
+
+// SessionBuffer is an editor buffer in the session
+type SessionBuffer struct {
+	Name       string         `json:"name,omitempty"`   // File in the queries directory, empty for a scratch buffer
+	Script     string         `json:"script,omitempty"` // Path of a large file shown read-only
+	Text       string         `json:"text"`             // Contents, including unsaved changes
+	Connection string         `json:"connection,omitempty"`
+	CursorRow  int            `json:"cursor_row"`
+	CursorCol  int            `json:"cursor_col"`
+	LastResult *SessionResult `json:"last_result,omitempty"`
+}
+
+// SessionLayout holds the panel width ratios
+type SessionLayout struct {
+	Connections int `json:"connections"`
+	Editor      int `json:"editor"`
+	Results     int `json:"results"`
+}
+
+// SessionResult is the last query result of a buffer
+type SessionResult struct {
+	Query       string     `json:"query,omitempty"`
+	Columns     []string   `json:"columns"`
+	ColumnTypes []string   `json:"column_types,omitempty"`
+	Rows        [][]string `json:"rows"`
+	Nulls       [][]bool   `json:"nulls,omitempty"`
+	RowCount    int        `json:"row_count"` // Rows returned, even if fewer were kept
+	ExecutionMs int64      `json:"execution_ms"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// NewSessionResult converts a query result for the session, keeping at
+// most maxRows rows
+func NewSessionResult(result db.QueryResult, maxRows int) *SessionResult {
+	sr := &SessionResult{
+		Query:       result.Query,
+		Columns:     result.Columns,
+		ColumnTypes: result.ColumnTypes,
+		Rows:        result.Rows,
+		Nulls:       result.Nulls,
+		RowCount:    result.RowCount,
+		ExecutionMs: result.ExecutionMs,
+	}
+	if len(sr.Rows) > maxRows {
+		sr.Rows = sr.Rows[:maxRows]
+	}
+	if len(sr.Nulls) > maxRows {
+		sr.Nulls = sr.Nulls[:maxRows]
+	}
+	if result.Error != nil {
+		sr.Error = result.Error.Error()
+	}
+	return sr
+}
+
+// QueryResult converts the saved result back to a query result
+func (sr *SessionResult) QueryResult() db.QueryResult {
+	result := db.QueryResult{
+		Query:       sr.Query,
+		Columns:     sr.Columns,
+		ColumnTypes: sr.ColumnTypes,
+		Rows:        sr.Rows,
+		Nulls:       sr.Nulls,
+		RowCount:    sr.RowCount,
+		ExecutionMs: sr.ExecutionMs,
+	}
+	if sr.Error != "" {
+		result.Error = errors.New(sr.Error)
+	}
+	return result
+}
+
+// SessionStore reads and writes the session file
+type SessionStore struct {
+	path string
+}
+
+// NewSessionStore creates a store for the session file at path
+func NewSessionStore(path string) *SessionStore {
+	return &SessionStore{path: path}
+}
+
+// NewDefaultSessionStore creates a store for session.json in the config dir
+func NewDefaultSessionStore() (*SessionStore, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewSessionStore(filepath.Join(configDir, "session.json")), nil
+}
+
+// Load reads the saved session. It returns nil without an error if no
+// session was saved yet.
+func (s *SessionStore) Load() (*Session, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Save writes the session. The file is replaced atomically so a crash
+// while saving keeps the previous session.
+func (s *SessionStore) Save(session *Session) error {
+	session.Version = sessionVersion
+	session.SavedAt = time.Now()
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	// Buffers may hold queries with credentials, keep the file private
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
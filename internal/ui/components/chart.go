@@ -0,0 +1,264 @@
+package components
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Chart kinds
+const (
+	ChartBar       = "bar"
+	ChartLine      = "line"
+	ChartHistogram = "hist"
+)
+
+// ChartKinds are the charts the results panel draws, in the order Tab
+// cycles through them
+var ChartKinds = []string{ChartBar, ChartLine, ChartHistogram}
+
+// Eighths of a character cell, for bars drawn to a fraction of a cell
+var (
+	horizontalEighths = []rune(" ▏▎▍▌▋▊▉█")
+	verticalEighths   = []rune(" ▁▂▃▄▅▆▇█")
+)
+
+// BarChart draws one horizontal bar per value, under its label, scaled to
+// the largest magnitude. Negative values are drawn in red. It draws at most
+// height bars.
+func BarChart(labels []string, values []float64, width, height int) []string {
+	n := min(len(values), height)
+	if n <= 0 {
+		return nil
+	}
+	labelWidth, valueWidth, largest := 0, 0, 0.0
+	texts := make([]string, n)
+	for i := 0; i < n; i++ {
+		if i < len(labels) {
+			labelWidth = max(labelWidth, ansi.StringWidth(EscapeControls(labels[i])))
+		}
+		texts[i] = FormatNumber(values[i])
+		valueWidth = max(valueWidth, len(texts[i]))
+		largest = max(largest, math.Abs(values[i]))
+	}
+	labelWidth = min(labelWidth, width/3)
+	barWidth := max(1, width-labelWidth-valueWidth-3)
+	negativeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+
+	lines := make([]string, n)
+	for i := 0; i < n; i++ {
+		label := ""
+		if i < len(labels) {
+			label = labels[i]
+		}
+		eighths := 0
+		if largest > 0 {
+			eighths = int(math.Round(math.Abs(values[i]) / largest * float64(barWidth*8)))
+		}
+		bar := strings.Repeat("█", eighths/8)
+		if eighths%8 > 0 {
+			bar += string(horizontalEighths[eighths%8])
+		}
+		if values[i] < 0 {
+			bar = negativeStyle.Render(bar)
+		}
+		lines[i] = FitCell(label, labelWidth) + " │" + bar + " " + texts[i]
+	}
+	return lines
+}
+
+// braille is a canvas of braille dots, two by four per character cell
+type braille struct {
+	width, height int // In cells
+	cells         [][]rune
+}
+
+func newBraille(width, height int) *braille {
+	b := &braille{width: width, height: height, cells: make([][]rune, height)}
+	for i := range b.cells {
+		b.cells[i] = []rune(strings.Repeat(string(rune(0x2800)), width))
+	}
+	return b
+}
+
+// brailleDots are the bits of the dots of a cell, by row then column
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// set turns on the dot at x, y, counted from the top left
+func (b *braille) set(x, y int) {
+	if x < 0 || y < 0 || x >= b.width*2 || y >= b.height*4 {
+		return
+	}
+	b.cells[y/4][x/2] |= brailleDots[y%4][x%2]
+}
+
+// line draws a straight line between two dots
+func (b *braille) line(x0, y0, x1, y1 int) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		b.set(x0, y0)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		if e2 := 2 * err; e2 >= dy {
+			err += dy
+			x0 += sx
+		} else {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// yAxis returns the labels of the top and bottom lines of a plot and the
+// width of the gutter holding them
+func yAxis(top, bottom float64) (string, string, int) {
+	high, low := FormatNumber(top), FormatNumber(bottom)
+	return high, low, max(len(high), len(low)) + 1
+}
+
+// LineChart plots values in order with braille dots joined by lines, the
+// largest and smallest values labelled on the left and first and last on
+// the bottom line
+func LineChart(values []float64, first, last string, width, height int) []string {
+	if len(values) == 0 || height < 2 {
+		return nil
+	}
+	low, high := values[0], values[0]
+	for _, v := range values {
+		low, high = min(low, v), max(high, v)
+	}
+	highText, lowText, gutter := yAxis(high, low)
+	plotWidth, plotHeight := max(1, width-gutter-1), height-1
+	canvas := newBraille(plotWidth, plotHeight)
+
+	dotX := func(i int) int {
+		if len(values) == 1 {
+			return 0
+		}
+		return int(math.Round(float64(i) / float64(len(values)-1) * float64(plotWidth*2-1)))
+	}
+	dotY := func(v float64) int {
+		if high == low {
+			return plotHeight * 2
+		}
+		return int(math.Round((high - v) / (high - low) * float64(plotHeight*4-1)))
+	}
+	for i := range values {
+		if i == 0 {
+			canvas.set(dotX(0), dotY(values[0]))
+			continue
+		}
+		canvas.line(dotX(i-1), dotY(values[i-1]), dotX(i), dotY(values[i]))
+	}
+
+	lines := make([]string, 0, height)
+	for row, cells := range canvas.cells {
+		label := ""
+		switch row {
+		case 0:
+			label = highText
+		case plotHeight - 1:
+			label = lowText
+		}
+		lines = append(lines, strings.Repeat(" ", gutter-1-len(label))+label+" ┤"+string(cells))
+	}
+	axis := strings.Repeat(" ", gutter) + "└" + strings.Repeat("─", plotWidth)
+	lines = append(lines, axis)
+	return append(lines, xLabels(first, last, gutter+1, plotWidth))
+}
+
+// xLabels puts the first label under the start of the plot and the last
+// under its end
+func xLabels(first, last string, indent, width int) string {
+	first, last = EscapeControls(first), EscapeControls(last)
+	if ansi.StringWidth(first)+ansi.StringWidth(last)+1 > width {
+		return strings.Repeat(" ", indent) + ansi.Truncate(first+" … "+last, width, "…")
+	}
+	gap := width - ansi.StringWidth(first) - ansi.StringWidth(last)
+	return strings.Repeat(" ", indent) + first + strings.Repeat(" ", gap) + last
+}
+
+// Histogram counts values into bins of equal width, as many as fit up to
+// the square root of the number of values (bins when positive), and draws
+// a vertical bar per bin
+func Histogram(values []float64, bins, width, height int) []string {
+	if len(values) == 0 || height < 3 {
+		return nil
+	}
+	if bins <= 0 {
+		bins = int(math.Ceil(math.Sqrt(float64(len(values)))))
+	}
+	// No count is above the number of values, so its gutter is the widest
+	_, _, widest := yAxis(float64(len(values)), 0)
+	counts, low, high := BinValues(values, min(bins, max(1, width-widest-1)))
+	largest := slices.Max(counts)
+
+	highText, lowText, gutter := yAxis(float64(largest), 0)
+	plotWidth, plotHeight := max(1, width-gutter-1), height-2
+	binWidth := max(1, plotWidth/len(counts))
+
+	lines := make([]string, 0, height)
+	for row := 0; row < plotHeight; row++ {
+		// Eighths of a cell filled below the top of this row
+		floor := (plotHeight - row - 1) * 8
+		var b strings.Builder
+		for _, count := range counts {
+			eighths := int(math.Round(float64(count) / float64(largest) * float64(plotHeight*8)))
+			cell := verticalEighths[max(0, min(8, eighths-floor))]
+			b.WriteString(strings.Repeat(string(cell), max(1, binWidth-1)))
+			if binWidth > 1 {
+				b.WriteByte(' ')
+			}
+		}
+		label := ""
+		switch row {
+		case 0:
+			label = highText
+		case plotHeight - 1:
+			label = lowText
+		}
+		lines = append(lines, strings.Repeat(" ", gutter-1-len(label))+label+" ┤"+b.String())
+	}
+	lines = append(lines, strings.Repeat(" ", gutter)+"└"+strings.Repeat("─", plotWidth))
+	return append(lines, xLabels(FormatNumber(low), FormatNumber(high), gutter+1, binWidth*len(counts)))
+}
+
+// BinValues counts values into bins of equal width from the lowest value
+// to the highest, which it also returns
+func BinValues(values []float64, bins int) (counts []int, low, high float64) {
+	counts = make([]int, max(1, bins))
+	if len(values) == 0 {
+		return counts, 0, 0
+	}
+	low, high = values[0], values[0]
+	for _, v := range values {
+		low, high = min(low, v), max(high, v)
+	}
+	for _, v := range values {
+		bin := 0
+		if high > low {
+			bin = min(len(counts)-1, int((v-low)/(high-low)*float64(len(counts))))
+		}
+		counts[bin]++
+	}
+	return counts, low, high
+}
@@ -0,0 +1,248 @@
+package components
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// DefaultCommandHistory is the number of command lines kept in the history
+const DefaultCommandHistory = 100
+
+// CommandLine is the ":" command line: it runs commands from a registry,
+// keeps a history of them and completes them with Tab. Between commands it
+// shows the last result message.
+type CommandLine struct {
+	input    textinput.Model
+	active   bool
+	registry *CommandRegistry
+
+	history []string // Oldest first
+	histPos int      // Entry shown while browsing, len(history) for the typed line
+	typed   string   // The typed line, restored after browsing
+
+	completions []string // Candidates cycled through by Tab
+	compPos     int
+
+	message string
+	isError bool
+}
+
+// NewCommandLine creates a command line running commands from registry
+func NewCommandLine(registry *CommandRegistry) *CommandLine {
+	input := textinput.New()
+	input.Prompt = ":"
+	return &CommandLine{input: input, registry: registry}
+}
+
+// Registry returns the registry commands are run from
+func (c *CommandLine) Registry() *CommandRegistry {
+	return c.registry
+}
+
+// SetRegistry makes the command line run commands from registry
+func (c *CommandLine) SetRegistry(registry *CommandRegistry) {
+	c.registry = registry
+}
+
+// Open starts typing a command
+func (c *CommandLine) Open() tea.Cmd {
+	c.input.SetValue("")
+	c.active = true
+	c.message = ""
+	c.histPos = len(c.history)
+	c.completions = nil
+	return c.input.Focus()
+}
+
+// Close stops typing without running the command
+func (c *CommandLine) Close() {
+	c.active = false
+	c.completions = nil
+	c.input.Blur()
+}
+
+// IsActive reports whether a command is being typed
+func (c *CommandLine) IsActive() bool {
+	return c.active
+}
+
+// Update handles a key while the command line is open. Enter runs the
+// command, Up/Down browse the history of lines starting with what was
+// typed, Tab and Shift+Tab cycle through completions.
+func (c *CommandLine) Update(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		c.Close()
+		return nil
+	case "enter":
+		line := c.input.Value()
+		c.Close()
+		c.addHistory(line)
+		cmd, err := c.registry.Run(line)
+		if err != nil {
+			c.SetMessage(err.Error(), true)
+		}
+		return cmd
+	case "backspace":
+		// Backspace on an empty command line closes it, like Vim
+		if c.input.Value() == "" {
+			c.Close()
+			return nil
+		}
+	case "up", "ctrl+p":
+		c.browseHistory(-1)
+		return nil
+	case "down", "ctrl+n":
+		c.browseHistory(1)
+		return nil
+	case "tab":
+		c.complete(1)
+		return nil
+	case "shift+tab":
+		c.complete(-1)
+		return nil
+	}
+
+	c.completions = nil
+	c.histPos = len(c.history)
+	var cmd tea.Cmd
+	c.input, cmd = c.input.Update(msg)
+	return cmd
+}
+
+// addHistory records a command line, moving a repeated one to the end
+func (c *CommandLine) addHistory(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	for i, entry := range c.history {
+		if entry == line {
+			c.history = append(c.history[:i], c.history[i+1:]...)
+			break
+		}
+	}
+	c.history = append(c.history, line)
+	if len(c.history) > DefaultCommandHistory {
+		c.history = c.history[len(c.history)-DefaultCommandHistory:]
+	}
+}
+
+// browseHistory shows the previous (-1) or next (1) history entry that
+// starts with the typed text
+func (c *CommandLine) browseHistory(dir int) {
+	if c.histPos == len(c.history) {
+		c.typed = c.input.Value()
+	}
+	for pos := c.histPos + dir; pos >= 0 && pos <= len(c.history); pos += dir {
+		if pos == len(c.history) {
+			c.histPos = pos
+			c.setValue(c.typed)
+			return
+		}
+		if strings.HasPrefix(c.history[pos], c.typed) {
+			c.histPos = pos
+			c.setValue(c.history[pos])
+			return
+		}
+	}
+}
+
+// complete replaces the line with the next (1) or previous (-1) completion
+func (c *CommandLine) complete(dir int) {
+	if c.completions == nil {
+		c.completions = c.registry.Complete(c.input.Value())
+		if len(c.completions) == 0 {
+			c.completions = nil
+			return
+		}
+		c.compPos = -1
+		if dir < 0 {
+			c.compPos = 0
+		}
+	}
+	c.compPos = (c.compPos + dir + len(c.completions)) % len(c.completions)
+	c.setValue(c.completions[c.compPos])
+}
+
+// setValue replaces the typed line, with the cursor at its end
+func (c *CommandLine) setValue(value string) {
+	c.input.SetValue(value)
+	c.input.CursorEnd()
+}
+
+// Value returns the line being typed
+func (c *CommandLine) Value() string {
+	return c.input.Value()
+}
+
+// History returns the command history, oldest first
+func (c *CommandLine) History() []string {
+	return append([]string(nil), c.history...)
+}
+
+// SetHistory replaces the command history, e.g. from a saved session
+func (c *CommandLine) SetHistory(history []string) {
+	c.history = append([]string(nil), history...)
+	if len(c.history) > DefaultCommandHistory {
+		c.history = c.history[len(c.history)-DefaultCommandHistory:]
+	}
+	c.histPos = len(c.history)
+}
+
+// SetMessage shows a message until the next key or command
+func (c *CommandLine) SetMessage(msg string, isError bool) {
+	c.message = msg
+	c.isError = isError
+}
+
+// ClearMessage removes the message
+func (c *CommandLine) ClearMessage() {
+	c.message = ""
+}
+
+// Message returns the message shown and whether it is an error
+func (c *CommandLine) Message() (string, bool) {
+	return c.message, c.isError
+}
+
+// View renders the command being typed, with the completions when there
+// are several, or else the last message
+func (c *CommandLine) View() string {
+	if c.active {
+		view := c.input.View()
+		if len(c.completions) > 1 {
+			view += lipgloss.NewStyle().Foreground(lipgloss.Color("240")).
+				Render("  (" + completionHint(c.completions, c.compPos) + ")")
+		}
+		return view
+	}
+	if c.message == "" {
+		return ""
+	}
+	if c.isError {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(c.message)
+	}
+	return c.message
+}
+
+// completionHint lists the last word of each completion, the selected one
+// in brackets
+func completionHint(completions []string, selected int) string {
+	words := make([]string, len(completions))
+	for i, completion := range completions {
+		fields := strings.Fields(completion)
+		word := completion
+		if len(fields) > 0 {
+			word = fields[len(fields)-1]
+		}
+		if i == selected {
+			word = "[" + word + "]"
+		}
+		words[i] = word
+	}
+	return strings.Join(words, " ")
+}
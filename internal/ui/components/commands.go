@@ -0,0 +1,159 @@
+package components
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Command is an ex command run from the ":" command line
+type Command struct {
+	Name    string   // Full name, e.g. "write"
+	Aliases []string // Other names, e.g. "w"
+	Usage   string   // Arguments, e.g. "[file]"
+	Help    string   // One line description
+
+	// Run runs the command. The returned command is run by Bubble Tea.
+	Run func(args CommandArgs) (tea.Cmd, error)
+	// Complete returns candidates for the argument being typed (optional).
+	// fields are the arguments before it.
+	Complete func(fields []string, arg string) []string
+}
+
+// CommandArgs is a parsed command line
+type CommandArgs struct {
+	Name   string   // Command name as typed, without "!"
+	Bang   bool     // The name ended with "!"
+	Arg    string   // Everything after the name, trimmed
+	Fields []string // Arg split on whitespace
+}
+
+// ParseCommandLine splits a command line (without the leading colon) into
+// the command name and its arguments
+func ParseCommandLine(line string) CommandArgs {
+	line = strings.TrimSpace(line)
+	name, arg, _ := strings.Cut(line, " ")
+	name, bang := strings.CutSuffix(name, "!")
+	arg = strings.TrimSpace(arg)
+	return CommandArgs{Name: name, Bang: bang, Arg: arg, Fields: strings.Fields(arg)}
+}
+
+// CommandRegistry holds the ex commands. Panels register their commands
+// into a registry shared by the application.
+type CommandRegistry struct {
+	commands map[string]*Command // By name and alias
+}
+
+// NewCommandRegistry creates an empty registry
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{commands: make(map[string]*Command)}
+}
+
+// Register adds a command. A command registered later under the same name
+// or alias replaces the earlier one for that name.
+func (r *CommandRegistry) Register(cmd Command) {
+	c := &cmd
+	r.commands[cmd.Name] = c
+	for _, alias := range cmd.Aliases {
+		r.commands[alias] = c
+	}
+}
+
+// Lookup returns the command with the given name or alias
+func (r *CommandRegistry) Lookup(name string) (Command, bool) {
+	c, ok := r.commands[name]
+	if !ok {
+		return Command{}, false
+	}
+	return *c, true
+}
+
+// Commands returns the registered commands sorted by name
+func (r *CommandRegistry) Commands() []Command {
+	seen := make(map[*Command]bool)
+	var commands []Command
+	for _, c := range r.commands {
+		if !seen[c] {
+			seen[c] = true
+			commands = append(commands, *c)
+		}
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands
+}
+
+// Run parses and runs a command line
+func (r *CommandRegistry) Run(line string) (tea.Cmd, error) {
+	args := ParseCommandLine(line)
+	if args.Name == "" {
+		return nil, nil
+	}
+	c, ok := r.commands[args.Name]
+	if !ok {
+		return nil, fmt.Errorf("not an editor command: %s", strings.TrimSpace(line))
+	}
+	return c.Run(args)
+}
+
+// Complete returns the completed command lines for line: command names
+// while the name is typed, then the command's argument candidates
+func (r *CommandRegistry) Complete(line string) []string {
+	line = strings.TrimLeft(line, " ")
+	name, rest, hasArg := strings.Cut(line, " ")
+	if !hasArg {
+		var names []string
+		for n := range r.commands {
+			if strings.HasPrefix(n, name) {
+				names = append(names, n)
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	c, ok := r.commands[strings.TrimSuffix(name, "!")]
+	if !ok || c.Complete == nil {
+		return nil
+	}
+
+	// Complete the last argument, keeping the ones before it
+	fields := strings.Fields(rest)
+	arg := ""
+	if len(fields) > 0 && !strings.HasSuffix(rest, " ") {
+		arg = fields[len(fields)-1]
+		fields = fields[:len(fields)-1]
+	}
+	prefix := name + " " + strings.Join(append(fields, ""), " ")
+
+	var lines []string
+	for _, candidate := range c.Complete(fields, arg) {
+		if strings.HasPrefix(candidate, arg) {
+			lines = append(lines, prefix+candidate)
+		}
+	}
+	return lines
+}
+
+// CompleteFrom returns a Complete function offering the same candidates
+// for every argument
+func CompleteFrom(candidates func() []string) func(fields []string, arg string) []string {
+	return func(_ []string, _ string) []string {
+		return candidates()
+	}
+}
+
+// CommandMessageMsg shows the outcome of a command on the command line
+type CommandMessageMsg struct {
+	Text    string
+	IsError bool
+}
+
+// CommandMessage returns a command showing text on the command line, for
+// commands of panels that don't own the command line
+func CommandMessage(text string) tea.Cmd {
+	return func() tea.Msg {
+		return CommandMessageMsg{Text: text}
+	}
+}
@@ -0,0 +1,159 @@
+package components
+
+import (
+	"strings"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// CompletionPopup shows completion candidates below the editor
+type CompletionPopup struct {
+	items        []db.CompletionItem
+	selected     int
+	scrollOffset int
+	visible      bool
+	maxVisible   int
+	width        int
+}
+
+// NewCompletionPopup creates a new completion popup
+func NewCompletionPopup() *CompletionPopup {
+	return &CompletionPopup{
+		maxVisible: 6,
+		width:      60,
+	}
+}
+
+// Show displays the given candidates, hiding the popup if there are none
+func (cp *CompletionPopup) Show(items []db.CompletionItem) {
+	cp.items = items
+	cp.selected = 0
+	cp.scrollOffset = 0
+	cp.visible = len(items) > 0
+}
+
+// Hide closes the popup
+func (cp *CompletionPopup) Hide() {
+	cp.visible = false
+	cp.items = nil
+}
+
+// IsVisible returns true if the popup is shown
+func (cp *CompletionPopup) IsVisible() bool {
+	return cp.visible
+}
+
+// Next selects the next candidate (wrapping around)
+func (cp *CompletionPopup) Next() {
+	if len(cp.items) == 0 {
+		return
+	}
+	cp.selected = (cp.selected + 1) % len(cp.items)
+	cp.adjustScroll()
+}
+
+// Prev selects the previous candidate (wrapping around)
+func (cp *CompletionPopup) Prev() {
+	if len(cp.items) == 0 {
+		return
+	}
+	cp.selected = (cp.selected - 1 + len(cp.items)) % len(cp.items)
+	cp.adjustScroll()
+}
+
+// Selected returns the selected candidate
+func (cp *CompletionPopup) Selected() (db.CompletionItem, bool) {
+	if !cp.visible || cp.selected >= len(cp.items) {
+		return db.CompletionItem{}, false
+	}
+	return cp.items[cp.selected], true
+}
+
+// SetWidth sets the popup width
+func (cp *CompletionPopup) SetWidth(width int) {
+	cp.width = width
+}
+
+// Height returns the number of lines the popup occupies when visible
+func (cp *CompletionPopup) Height() int {
+	if !cp.visible {
+		return 0
+	}
+	return min(len(cp.items), cp.maxVisible) + 2 // + border
+}
+
+// adjustScroll keeps the selection visible
+func (cp *CompletionPopup) adjustScroll() {
+	if cp.selected < cp.scrollOffset {
+		cp.scrollOffset = cp.selected
+	} else if cp.selected >= cp.scrollOffset+cp.maxVisible {
+		cp.scrollOffset = cp.selected - cp.maxVisible + 1
+	}
+}
+
+// View renders the popup
+func (cp *CompletionPopup) View() string {
+	if !cp.visible {
+		return ""
+	}
+
+	innerWidth := max(cp.width-2, 20)
+	kindStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	detailStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245")).Italic(true)
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(lipgloss.Color("86"))
+
+	end := min(cp.scrollOffset+cp.maxVisible, len(cp.items))
+	var lines []string
+	for i := cp.scrollOffset; i < end; i++ {
+		item := cp.items[i]
+
+		kind := completionKindIcon(item.Kind)
+		label := item.Label
+		detail := item.Detail
+
+		// Keep the label, shorten the detail first
+		room := innerWidth - len([]rune(kind)) - len([]rune(label)) - 3
+		if room < len([]rune(detail)) {
+			if room > 1 {
+				detail = string([]rune(detail)[:room-1]) + "…"
+			} else {
+				detail = ""
+			}
+		}
+		padding := max(innerWidth-len([]rune(kind))-len([]rune(label))-len([]rune(detail))-2, 1)
+
+		if i == cp.selected {
+			line := kind + " " + label + strings.Repeat(" ", padding) + detail + " "
+			lines = append(lines, selectedStyle.Render(line))
+		} else {
+			line := kindStyle.Render(kind) + " " + label + strings.Repeat(" ", padding) + detailStyle.Render(detail) + " "
+			lines = append(lines, line)
+		}
+	}
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("86")).
+		Render(strings.Join(lines, "\n"))
+}
+
+// completionKindIcon returns a short marker for a completion kind
+func completionKindIcon(kind db.CompletionKind) string {
+	switch kind {
+	case db.CompletionJoin:
+		return "⋈"
+	case db.CompletionColumn:
+		return "c"
+	case db.CompletionTable:
+		return "t"
+	case db.CompletionView:
+		return "v"
+	case db.CompletionSchema:
+		return "s"
+	case db.CompletionFunction:
+		return "ƒ"
+	default:
+		return "k"
+	}
+}
@@ -15,12 +15,40 @@ type SQLHighlighter struct {
 	lexer     chroma.Lexer
 	formatter chroma.Formatter
 	style     *chroma.Style
+
+	// Last HighlightRange input and output, reused while the window is unchanged
+	rangeSource string
+	rangeLines  [][]Span
+}
+
+// TokenStyle is the theme style of a highlighted token
+type TokenStyle struct {
+	Color     string // Hex color, empty for the terminal's default
+	Bold      bool
+	Italic    bool
+	Underline bool
 }
 
+// Span is a run of text in one token style
+type Span struct {
+	Text  string
+	Style TokenStyle
+}
+
+// HighlightContextLines is how far back HighlightRange looks for the end of
+// a statement to start lexing from, so multi-line strings and comments that
+// begin above the range are still recognized
+const HighlightContextLines = 200
+
 // NewSQLHighlighter creates a new SQL syntax highlighter
 func NewSQLHighlighter() *SQLHighlighter {
-	// Get PostgreSQL lexer
-	lexer := lexers.Get("postgresql")
+	return NewHighlighter("postgresql")
+}
+
+// NewHighlighter creates a highlighter for another chroma language, such as
+// "json" or "xml"
+func NewHighlighter(language string) *SQLHighlighter {
+	lexer := lexers.Get(language)
 	if lexer == nil {
 		lexer = lexers.Fallback
 	}
@@ -73,11 +101,92 @@ func (h *SQLHighlighter) HighlightLines(code string) []string {
 	return strings.Split(highlighted, "\n")
 }
 
+// HighlightSpans splits code into lines of spans styled by the theme, for
+// renderers that combine token colors with their own styling
+func (h *SQLHighlighter) HighlightSpans(code string) [][]Span {
+	lines := [][]Span{nil}
+	iterator, err := h.lexer.Tokenise(nil, code)
+	if err != nil {
+		for i, line := range strings.Split(code, "\n") {
+			if i > 0 {
+				lines = append(lines, nil)
+			}
+			lines[i] = []Span{{Text: line}}
+		}
+		return lines
+	}
+
+	for _, token := range iterator.Tokens() {
+		style := h.tokenStyle(token.Type)
+		for i, text := range strings.Split(token.Value, "\n") {
+			if i > 0 {
+				lines = append(lines, nil)
+			}
+			if text != "" {
+				last := len(lines) - 1
+				lines[last] = append(lines[last], Span{Text: text, Style: style})
+			}
+		}
+	}
+	return lines
+}
+
+// tokenStyle returns the theme style of a token type
+func (h *SQLHighlighter) tokenStyle(tokenType chroma.TokenType) TokenStyle {
+	entry := h.style.Get(tokenType)
+	style := TokenStyle{
+		Bold:      entry.Bold == chroma.Yes,
+		Italic:    entry.Italic == chroma.Yes,
+		Underline: entry.Underline == chroma.Yes,
+	}
+	if entry.Colour.IsSet() {
+		style.Color = entry.Colour.String()
+	}
+	return style
+}
+
+// HighlightRange highlights lines[from:to] of a script as spans. Only the
+// range and the lines above it back to the previous statement end are
+// lexed, so the cost depends on the range rather than the script size.
+func (h *SQLHighlighter) HighlightRange(lines []string, from, to int) [][]Span {
+	from = max(0, from)
+	to = min(to, len(lines))
+	if from >= to {
+		return nil
+	}
+
+	// A line ending a statement leaves the lexer in its initial state
+	start := from
+	for start > 0 && from-start < HighlightContextLines {
+		if strings.HasSuffix(strings.TrimSpace(lines[start-1]), ";") {
+			break
+		}
+		start--
+	}
+
+	source := strings.Join(lines[start:to], "\n")
+	if h.rangeLines == nil || source != h.rangeSource {
+		h.rangeSource = source
+		h.rangeLines = h.HighlightSpans(source)
+	}
+
+	out := make([][]Span, to-from)
+	for i := range out {
+		if j := from - start + i; j < len(h.rangeLines) {
+			out[i] = h.rangeLines[j]
+		} else {
+			out[i] = []Span{{Text: lines[from+i]}}
+		}
+	}
+	return out
+}
+
 // SetTheme changes the color scheme
 func (h *SQLHighlighter) SetTheme(themeName string) {
 	style := styles.Get(themeName)
 	if style != nil {
 		h.style = style
+		h.rangeLines = nil
 	}
 }
 
@@ -0,0 +1,741 @@
+package components
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// ImportStep is a page of the import wizard
+type ImportStep int
+
+const (
+	ImportStepPreview ImportStep = iota // The file's first rows and inferred types
+	ImportStepTarget                    // A new table, or an existing one
+	ImportStepColumns                   // Which file column goes where
+	ImportStepOptions                   // On conflict and on error behaviour
+	ImportStepReview                    // The statements that will run
+	ImportStepReport                    // The outcome, row by row
+)
+
+var importStepNames = []string{"Preview", "Table", "Columns", "Options", "Review", "Report"}
+
+// ImportWizardClosedMsg reports that the wizard was closed
+type ImportWizardClosedMsg struct{}
+
+// ImportTableColumnsMsg asks the application for the columns of an
+// existing table (GetTableColumns), to be passed to SetTargetColumns
+type ImportTableColumnsMsg struct {
+	Schema string
+	Table  string
+}
+
+// ImportStartMsg asks the application to run the import (db.RunImport)
+// and pass the outcome to SetReport
+type ImportStartMsg struct {
+	Plan db.ImportPlan
+	Data db.QueryResult
+}
+
+// importMapping is what happens to one column of the file
+type importMapping struct {
+	include bool
+	name    string // Column of a new table
+	typ     string // Type of a new table's column
+	target  int    // Column of an existing table, -1 for none
+	key     bool
+}
+
+// ImportWizard previews a file, maps its columns onto a new or existing
+// table and starts the import
+type ImportWizard struct {
+	path   string
+	data   db.QueryResult
+	step   ImportStep
+	width  int
+	height int
+
+	tables    []db.SchemaObject // Existing tables to import into
+	choice    int               // 0 for a new table, else tables[choice-1]
+	tableName textinput.Model   // Name of the new table
+	target    []db.TableColumn  // Columns of the chosen existing table
+	loading   bool              // Waiting for target
+
+	columns   []importMapping // One per file column
+	cursor    int             // Selected line of the current step
+	offset    int             // First line shown of the current step
+	renaming  bool
+	nameInput textinput.Model
+
+	onConflict db.ImportConflict
+	skipErrors bool
+
+	running bool
+	report  db.ImportReport
+	err     error
+	message string
+}
+
+// NewImportWizard opens the wizard on rows read from a file, with their
+// column types inferred
+func NewImportWizard(path string, data db.QueryResult) *ImportWizard {
+	if len(data.ColumnTypes) != len(data.Columns) {
+		db.InferColumnTypes(&data)
+	}
+	tableName := textinput.New()
+	tableName.Placeholder = "schema.table"
+	tableName.CharLimit = 128
+	tableName.Width = 40
+	tableName.SetValue(db.ImportIdentifier(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))))
+	nameInput := textinput.New()
+	nameInput.CharLimit = 63
+	nameInput.Width = 30
+
+	w := &ImportWizard{
+		path:      path,
+		data:      data,
+		width:     100,
+		height:    30,
+		tableName: tableName,
+		nameInput: nameInput,
+	}
+	w.newTableColumns()
+	return w
+}
+
+// SetSize sets the size of the dialog
+func (w *ImportWizard) SetSize(width, height int) {
+	w.width = width
+	w.height = height
+}
+
+// SetTables sets the existing tables offered as targets
+func (w *ImportWizard) SetTables(tables []db.SchemaObject) {
+	w.tables = tables
+}
+
+// SetTargetColumns sets the columns of the existing table chosen and maps
+// the file's columns onto those of the same name
+func (w *ImportWizard) SetTargetColumns(columns []db.TableColumn, err error) {
+	w.loading = false
+	if err != nil {
+		w.message = err.Error()
+		w.step = ImportStepTarget
+		return
+	}
+	w.target = columns
+	for i, column := range w.data.Columns {
+		m := importMapping{target: -1}
+		name := db.ImportIdentifier(column)
+		for j, col := range columns {
+			if col.Name == column || strings.EqualFold(col.Name, name) {
+				m.target, m.include = j, true
+				break
+			}
+		}
+		w.columns[i] = m
+	}
+}
+
+// SetReport shows the outcome of the import
+func (w *ImportWizard) SetReport(report db.ImportReport, err error) {
+	w.running = false
+	w.report, w.err = report, err
+	w.step = ImportStepReport
+	w.cursor, w.offset = 0, 0
+}
+
+// Step returns the page shown
+func (w *ImportWizard) Step() ImportStep {
+	return w.step
+}
+
+// newTableColumns maps every file column to a new table column named
+// after its header, with the inferred type
+func (w *ImportWizard) newTableColumns() {
+	w.columns = make([]importMapping, len(w.data.Columns))
+	used := map[string]int{}
+	for i, column := range w.data.Columns {
+		name := db.ImportIdentifier(column)
+		if n := used[name]; n > 0 {
+			used[name]++
+			name = fmt.Sprintf("%s_%d", name, n+1)
+		}
+		used[name]++
+		w.columns[i] = importMapping{include: true, name: name, typ: w.data.ColumnTypes[i], target: -1}
+	}
+}
+
+// creating reports whether the import creates a table
+func (w *ImportWizard) creating() bool {
+	return w.choice == 0
+}
+
+// Plan returns the import as currently set up
+func (w *ImportWizard) Plan() db.ImportPlan {
+	plan := db.ImportPlan{Create: w.creating(), OnConflict: w.onConflict, SkipErrors: w.skipErrors}
+	if w.creating() {
+		plan.Table = strings.TrimSpace(w.tableName.Value())
+	} else {
+		t := w.tables[w.choice-1]
+		plan.Table = db.QuoteIdent(t.Schema) + "." + db.QuoteIdent(t.Name)
+	}
+	for i, m := range w.columns {
+		if !m.include {
+			continue
+		}
+		col := db.ImportColumn{Source: i, Name: m.name, Type: m.typ, Key: m.key}
+		if !w.creating() {
+			if m.target < 0 || m.target >= len(w.target) {
+				continue
+			}
+			col.Name, col.Type = w.target[m.target].Name, w.target[m.target].Type
+		}
+		plan.Columns = append(plan.Columns, col)
+	}
+	return plan
+}
+
+// Update handles a key
+func (w *ImportWizard) Update(msg tea.Msg) tea.Cmd {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok || w.running {
+		return nil
+	}
+	w.message = ""
+
+	if w.renaming {
+		switch key.String() {
+		case "enter":
+			if name := strings.TrimSpace(w.nameInput.Value()); name != "" {
+				w.columns[w.cursor].name = name
+			}
+			w.renaming = false
+		case "esc":
+			w.renaming = false
+		default:
+			var cmd tea.Cmd
+			w.nameInput, cmd = w.nameInput.Update(msg)
+			return cmd
+		}
+		return nil
+	}
+
+	switch key.String() {
+	case "esc":
+		return func() tea.Msg { return ImportWizardClosedMsg{} }
+	case "enter", "tab":
+		return w.next()
+	case "shift+tab":
+		if w.step > ImportStepPreview && w.step < ImportStepReport {
+			w.setStep(w.step - 1)
+		}
+		return nil
+	}
+
+	switch w.step {
+	case ImportStepTarget:
+		return w.targetKey(key)
+	case ImportStepColumns:
+		w.columnsKey(key.String())
+	case ImportStepOptions:
+		w.optionsKey(key.String())
+	default:
+		w.scrollKey(key.String(), w.lineCount())
+		// Pages without a selection scroll with the cursor at the top
+		w.cursor = max(0, min(w.cursor, w.lineCount()-w.pageLines()))
+		w.offset = w.cursor
+	}
+	return nil
+}
+
+// setStep shows a page from its top
+func (w *ImportWizard) setStep(step ImportStep) {
+	w.step = step
+	w.cursor, w.offset = 0, 0
+	if step == ImportStepTarget {
+		w.cursor = w.choice
+	}
+	w.syncTableInput()
+}
+
+// syncTableInput focuses the new table's name while it is selected
+func (w *ImportWizard) syncTableInput() {
+	if w.step == ImportStepTarget && w.choice == 0 {
+		w.tableName.Focus()
+	} else {
+		w.tableName.Blur()
+	}
+}
+
+// next validates the page and goes to the next one, starting the import
+// from the review
+func (w *ImportWizard) next() tea.Cmd {
+	switch w.step {
+	case ImportStepPreview:
+		w.setStep(ImportStepTarget)
+	case ImportStepTarget:
+		if w.creating() && strings.TrimSpace(w.tableName.Value()) == "" {
+			w.message = "The new table needs a name"
+			return nil
+		}
+		w.setStep(ImportStepColumns)
+		if w.creating() {
+			if w.target != nil {
+				w.target = nil
+				w.newTableColumns()
+			}
+			return nil
+		}
+		// Ask for the columns of the table
+		t := w.tables[w.choice-1]
+		w.target, w.loading = nil, true
+		for i := range w.columns {
+			w.columns[i] = importMapping{target: -1}
+		}
+		return func() tea.Msg { return ImportTableColumnsMsg{Schema: t.Schema, Table: t.Name} }
+	case ImportStepColumns:
+		if w.loading {
+			return nil
+		}
+		// The conflict handling is chosen on the next step
+		if err := w.Plan().ValidateColumns(); err != nil {
+			w.message = err.Error()
+			return nil
+		}
+		w.setStep(ImportStepOptions)
+	case ImportStepOptions:
+		if err := w.Plan().Validate(); err != nil {
+			w.message = err.Error()
+			return nil
+		}
+		w.setStep(ImportStepReview)
+	case ImportStepReview:
+		plan := w.Plan()
+		if err := plan.Validate(); err != nil {
+			w.message = err.Error()
+			return nil
+		}
+		w.running = true
+		data := w.data
+		return func() tea.Msg { return ImportStartMsg{Plan: plan, Data: data} }
+	case ImportStepReport:
+		return func() tea.Msg { return ImportWizardClosedMsg{} }
+	}
+	return nil
+}
+
+// targetKey moves between the new table and the existing ones; typing
+// edits the new table's name
+func (w *ImportWizard) targetKey(key tea.KeyMsg) tea.Cmd {
+	switch key.String() {
+	case "up", "ctrl+p":
+		w.choice--
+	case "down", "ctrl+n":
+		w.choice++
+	default:
+		if w.choice == 0 {
+			var cmd tea.Cmd
+			w.tableName, cmd = w.tableName.Update(key)
+			return cmd
+		}
+		switch key.String() {
+		case "k":
+			w.choice--
+		case "j":
+			w.choice++
+		}
+	}
+	w.choice = max(0, min(w.choice, len(w.tables)))
+	w.cursor = w.choice
+	w.syncTableInput()
+	return nil
+}
+
+// columnsKey changes the mapping of the selected column
+func (w *ImportWizard) columnsKey(key string) {
+	if len(w.columns) == 0 || w.loading {
+		return
+	}
+	m := &w.columns[w.cursor]
+	switch key {
+	case " ", "x":
+		m.include = !m.include
+		if m.include && !w.creating() && m.target < 0 && len(w.target) > 0 {
+			m.target = 0
+		}
+	case "h", "left", "l", "right":
+		delta := 1
+		if key == "h" || key == "left" {
+			delta = -1
+		}
+		if w.creating() {
+			m.typ = cycle(db.ImportTypes, m.typ, delta)
+		} else if len(w.target) > 0 {
+			if m.target < 0 && delta < 0 {
+				m.target = len(w.target)
+			}
+			m.target = (m.target + delta + len(w.target)) % len(w.target)
+			m.include = true
+		}
+	case "p":
+		m.key = !m.key
+	case "r":
+		if w.creating() {
+			w.renaming = true
+			w.nameInput.SetValue(m.name)
+			w.nameInput.CursorEnd()
+			w.nameInput.Focus()
+		}
+	default:
+		w.scrollKey(key, len(w.columns))
+	}
+}
+
+// optionsKey changes the selected option
+func (w *ImportWizard) optionsKey(key string) {
+	switch key {
+	case "j", "down":
+		w.cursor = 1
+	case "k", "up":
+		w.cursor = 0
+	case "h", "left", "l", "right", " ":
+		delta := 1
+		if key == "h" || key == "left" {
+			delta = -1
+		}
+		if w.cursor == 0 {
+			w.onConflict = db.ImportConflict((int(w.onConflict) + delta + 3) % 3)
+		} else {
+			w.skipErrors = !w.skipErrors
+		}
+	}
+}
+
+// cycle returns the value delta places from current in values
+func cycle(values []string, current string, delta int) string {
+	for i, v := range values {
+		if v == current {
+			return values[(i+delta+len(values))%len(values)]
+		}
+	}
+	return values[0]
+}
+
+// scrollKey moves the cursor over count lines
+func (w *ImportWizard) scrollKey(key string, count int) {
+	page := w.pageLines()
+	switch key {
+	case "j", "down":
+		w.cursor++
+	case "k", "up":
+		w.cursor--
+	case "ctrl+d", "pgdown":
+		w.cursor += page / 2
+	case "ctrl+u", "pgup":
+		w.cursor -= page / 2
+	case "g", "home":
+		w.cursor = 0
+	case "G", "end":
+		w.cursor = count - 1
+	}
+	w.cursor = max(0, min(w.cursor, count-1))
+	if w.cursor < w.offset {
+		w.offset = w.cursor
+	}
+	if w.cursor >= w.offset+page {
+		w.offset = w.cursor - page + 1
+	}
+}
+
+// pageLines is how many lines of a list fit in the dialog
+func (w *ImportWizard) pageLines() int {
+	return max(3, w.height-12)
+}
+
+// lineCount is how many lines the scrolling pages have
+func (w *ImportWizard) lineCount() int {
+	switch w.step {
+	case ImportStepPreview:
+		return len(w.data.Rows)
+	case ImportStepReport:
+		return len(w.report.Errors)
+	}
+	return 0
+}
+
+// View renders the dialog
+func (w *ImportWizard) View() string {
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("5")).Padding(0, 1)
+	stepStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	currentStepStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("2"))
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+
+	inner := max(20, w.width-6)
+	content := titleStyle.Render("Import "+filepath.Base(w.path)) + "\n"
+	var steps []string
+	for i, name := range importStepNames {
+		label := fmt.Sprintf("%d %s", i+1, name)
+		if ImportStep(i) == w.step {
+			steps = append(steps, currentStepStyle.Render(label))
+		} else {
+			steps = append(steps, stepStyle.Render(label))
+		}
+	}
+	content += strings.Join(steps, stepStyle.Render(" › ")) + "\n\n"
+
+	var body, help string
+	switch w.step {
+	case ImportStepPreview:
+		body = w.viewPreview(inner)
+		help = "[j/k] Scroll  [Enter] Next  [Esc] Cancel"
+	case ImportStepTarget:
+		body = w.viewTarget(inner)
+		help = "[↑/↓] Choose  [Enter] Next  [Shift-Tab] Back  [Esc] Cancel"
+	case ImportStepColumns:
+		body = w.viewColumns(inner)
+		help = "[j/k] Move  [Space] Include  [h/l] Type  [r] Rename  [p] Key  [Enter] Next  [Shift-Tab] Back"
+		if !w.creating() {
+			help = "[j/k] Move  [Space] Include  [h/l] Target column  [p] Conflict key  [Enter] Next  [Shift-Tab] Back"
+		}
+	case ImportStepOptions:
+		body = w.viewOptions()
+		help = "[j/k] Move  [h/l] Change  [Enter] Next  [Shift-Tab] Back  [Esc] Cancel"
+	case ImportStepReview:
+		body = w.viewReview(inner)
+		help = "[Enter] Import  [Shift-Tab] Back  [Esc] Cancel"
+		if w.running {
+			help = fmt.Sprintf("Importing %s rows…", FormatCount(len(w.data.Rows)))
+		}
+	case ImportStepReport:
+		body = w.viewReport(inner)
+		help = "[j/k] Scroll  [Enter/Esc] Close"
+	}
+	content += body + "\n\n"
+	if w.message != "" {
+		content += errorStyle.Render(w.message) + "\n"
+	}
+	content += help
+
+	borderStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("5")).
+		Padding(1, 2)
+	return borderStyle.Render(content)
+}
+
+// viewPreview shows the first rows with the inferred type of each column
+func (w *ImportWizard) viewPreview(width int) string {
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	headerStyle := lipgloss.NewStyle().Bold(true)
+
+	widths := make([]int, len(w.data.Columns))
+	for i, column := range w.data.Columns {
+		widths[i] = max(ansi.StringWidth(column), len(w.data.ColumnTypes[i]))
+	}
+	end := min(w.offset+w.pageLines(), len(w.data.Rows))
+	for _, row := range w.data.Rows[w.offset:end] {
+		for i, v := range row {
+			widths[i] = max(widths[i], ansi.StringWidth(v))
+		}
+	}
+	for i := range widths {
+		widths[i] = min(widths[i], 24)
+	}
+
+	line := func(cells []string, style func(i int, s string) string) string {
+		var parts []string
+		for i, cell := range cells {
+			parts = append(parts, style(i, FitCell(cell, widths[i])))
+		}
+		return ansi.Truncate(strings.Join(parts, " │ "), width, "…")
+	}
+	plain := func(_ int, s string) string { return s }
+
+	lines := []string{
+		fmt.Sprintf("%s rows, %d columns", FormatCount(len(w.data.Rows)), len(w.data.Columns)),
+		"",
+		line(w.data.Columns, func(_ int, s string) string { return headerStyle.Render(s) }),
+		line(w.data.ColumnTypes, func(_ int, s string) string { return dimStyle.Render(s) }),
+	}
+	for r := w.offset; r < end; r++ {
+		cells := make([]string, len(w.data.Columns))
+		for i := range cells {
+			if i < len(w.data.Rows[r]) {
+				cells[i] = w.data.Rows[r][i]
+			}
+			if w.data.IsNull(r, i) {
+				cells[i] = db.NullText
+			}
+		}
+		lines = append(lines, line(cells, plain))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// viewTarget lists the new table and the existing tables
+func (w *ImportWizard) viewTarget(width int) string {
+	selected := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("2"))
+	marker := func(i int) string {
+		if i == w.choice {
+			return selected.Render("▸ ")
+		}
+		return "  "
+	}
+
+	lines := []string{marker(0) + "New table: " + w.tableName.View()}
+	if len(w.tables) > 0 {
+		lines = append(lines, "", "Existing tables:")
+	}
+	page := w.pageLines() - 3
+	first := max(0, min(w.choice-page, len(w.tables)-page))
+	for i := first; i < min(len(w.tables), first+page); i++ {
+		t := w.tables[i]
+		name := t.Schema + "." + t.Name
+		if i+1 == w.choice {
+			name = selected.Render(name)
+		}
+		lines = append(lines, ansi.Truncate(marker(i+1)+name, width, "…"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// viewColumns lists each file column with where it goes
+func (w *ImportWizard) viewColumns(width int) string {
+	if w.loading {
+		return "Loading the columns of " + w.Plan().Table + "…"
+	}
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	cursorStyle := lipgloss.NewStyle().Reverse(true)
+
+	nameWidth := 6
+	for _, column := range w.data.Columns {
+		nameWidth = max(nameWidth, min(ansi.StringWidth(column), 24))
+	}
+	header := fmt.Sprintf("    %s   %s", FitCell("File", nameWidth), "Table column")
+	lines := []string{dimStyle.Render(header)}
+	end := min(w.offset+w.pageLines()-1, len(w.columns))
+	for i := w.offset; i < end; i++ {
+		m := w.columns[i]
+		check := "[x]"
+		if !m.include {
+			check = "[ ]"
+		}
+		var target string
+		switch {
+		case !m.include:
+			target = dimStyle.Render("skipped")
+		case w.creating():
+			target = fmt.Sprintf("%s %s", m.name, m.typ)
+			if m.name != "" && m.typ != w.data.ColumnTypes[i] {
+				target += dimStyle.Render(" (inferred " + w.data.ColumnTypes[i] + ")")
+			}
+		case m.target >= 0 && m.target < len(w.target):
+			target = fmt.Sprintf("%s %s", w.target[m.target].Name, w.target[m.target].Type)
+		default:
+			target = dimStyle.Render("no column")
+		}
+		if m.key {
+			target += " 🔑"
+		}
+		line := fmt.Sprintf("%s %s → %s", check, FitCell(w.data.Columns[i], nameWidth), target)
+		if w.renaming && i == w.cursor {
+			line = fmt.Sprintf("%s %s → %s", check, FitCell(w.data.Columns[i], nameWidth), w.nameInput.View())
+		}
+		line = ansi.Truncate(line, width, "…")
+		if i == w.cursor && !w.renaming {
+			line = cursorStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// importConflictHelp describes each conflict behaviour
+var importConflictHelp = []string{
+	"the row fails",
+	"skip the row (ON CONFLICT DO NOTHING)",
+	"update the existing row (ON CONFLICT ... DO UPDATE)",
+}
+
+// viewOptions shows the conflict and error behaviour
+func (w *ImportWizard) viewOptions() string {
+	selected := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("2"))
+	onError := "roll back the whole import"
+	if w.skipErrors {
+		onError = "skip the row and commit the others"
+	}
+	options := []string{
+		fmt.Sprintf("On conflict: %s — %s", w.onConflict, importConflictHelp[w.onConflict]),
+		"On error:    " + onError,
+	}
+	for i := range options {
+		if i == w.cursor {
+			options[i] = selected.Render("▸ " + options[i])
+		} else {
+			options[i] = "  " + options[i]
+		}
+	}
+	return strings.Join(options, "\n") + "\n\nThe import runs in one transaction; failed rows are listed in the report."
+}
+
+// viewReview shows what will run
+func (w *ImportWizard) viewReview(width int) string {
+	plan := w.Plan()
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	lines := []string{fmt.Sprintf("%s rows into %s, on conflict %s", FormatCount(len(w.data.Rows)), plan.Table, plan.OnConflict), ""}
+	if plan.Create {
+		lines = append(lines, strings.Split(plan.CreateStatement(), "\n")...)
+		lines = append(lines, "")
+	}
+	if len(w.data.Rows) > 0 {
+		var nulls []bool
+		if len(w.data.Nulls) > 0 {
+			nulls = w.data.Nulls[0]
+		}
+		lines = append(lines, plan.InsertStatement(w.data.Rows[0], nulls)+";")
+		if len(w.data.Rows) > 1 {
+			lines = append(lines, dimStyle.Render(fmt.Sprintf("-- and %s more", FormatCount(len(w.data.Rows)-1))))
+		}
+	}
+	for i, line := range lines {
+		lines[i] = ansi.Truncate(line, width, "…")
+	}
+	if limit := w.pageLines() + 2; len(lines) > limit {
+		lines = append(lines[:limit-1], dimStyle.Render("…"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// viewReport shows the outcome and the rows that failed
+func (w *ImportWizard) viewReport(width int) string {
+	okStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("2"))
+	errorStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("9"))
+	if w.err != nil {
+		return errorStyle.Render("Import failed: " + w.err.Error())
+	}
+
+	r := w.report
+	summary := fmt.Sprintf("%s inserted, %s skipped, %s failed in %s", FormatCount(r.Inserted), FormatCount(r.Skipped), FormatCount(len(r.Errors)), r.Elapsed.Round(1e6))
+	var status string
+	if r.Committed {
+		status = okStyle.Render("Committed")
+	} else {
+		status = errorStyle.Render("Rolled back: no row was imported")
+	}
+	lines := []string{status, summary}
+	if len(r.Errors) > 0 {
+		lines = append(lines, "")
+		end := min(w.offset+w.pageLines()-3, len(r.Errors))
+		for _, e := range r.Errors[w.offset:end] {
+			lines = append(lines, ansi.Truncate(fmt.Sprintf("row %d: %s", e.Row, e.Err), width, "…"))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,677 @@
+package components
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/editor"
+	"github.com/alecthomas/chroma/v2"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// InspectorKind is how the cell inspector shows a value
+type InspectorKind int
+
+const (
+	InspectText InspectorKind = iota
+	InspectJSON
+	InspectXML
+	InspectArray
+	InspectBytes
+)
+
+var inspectorKindNames = []string{"text", "JSON", "XML", "array", "bytes"}
+
+// String returns the kind's name
+func (k InspectorKind) String() string {
+	return inspectorKindNames[k]
+}
+
+// DetectInspectorKind picks the view of a value from its column type and
+// content. JSON and XML are recognized in text columns too.
+func DetectInspectorKind(value, columnType string) InspectorKind {
+	switch {
+	case columnType == "bytea" && strings.HasPrefix(value, `\x`):
+		if _, err := hex.DecodeString(value[2:]); err == nil {
+			return InspectBytes
+		}
+	case strings.HasPrefix(columnType, "_") || strings.HasSuffix(columnType, "[]"):
+		if _, ok := parseArrayLiteral(value); ok {
+			return InspectArray
+		}
+	}
+
+	trimmed := strings.TrimSpace(value)
+	if columnType == "json" || columnType == "jsonb" || strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		if json.Valid([]byte(trimmed)) {
+			return InspectJSON
+		}
+	}
+	if columnType == "xml" || strings.HasPrefix(trimmed, "<") {
+		if _, err := prettyXML(trimmed); err == nil {
+			return InspectXML
+		}
+	}
+	return InspectText
+}
+
+// inspectorLine is a line of the inspector's body
+type inspectorLine struct {
+	spans []Span
+	node  *jsonNode // JSON node of the line, nil for other kinds
+}
+
+// Inspector colors
+const (
+	inspectorCursorColor = lipgloss.Color("237")
+	inspectorDimColor    = lipgloss.Color("240")
+)
+
+// inspectorChromeLines is the height of the header and footer: the title,
+// two rules, the path or position, and the message or help
+const inspectorChromeLines = 5
+
+// CellInspector shows one value of a result in full: JSON as a collapsible
+// tree, XML indented, arrays one element per line, bytes as a hexdump and
+// text wrapped
+type CellInspector struct {
+	column     string
+	columnType string
+	value      string
+	kind       InspectorKind
+
+	tree  *jsonNode // Root of the JSON tree
+	lines []inspectorLine
+
+	cursor  int    // Selected line
+	offset  int    // First line shown
+	pending string // First key of a two-key command ("g")
+	width   int
+	height  int
+
+	clipboard     func(text string) error
+	editorCommand string
+	message       string // Shown in the footer until the next key
+}
+
+// NewCellInspector creates an inspector for the value of a column
+func NewCellInspector(column, columnType, value string) *CellInspector {
+	i := &CellInspector{
+		column:     column,
+		columnType: columnType,
+		value:      value,
+		kind:       DetectInspectorKind(value, columnType),
+		width:      80,
+		height:     24,
+	}
+	if i.kind == InspectJSON {
+		tree, err := parseJSONTree(value)
+		if err != nil {
+			i.kind = InspectText
+		}
+		i.tree = tree
+	}
+	i.build()
+	return i
+}
+
+// SetSize sets the inspector dimensions
+func (i *CellInspector) SetSize(width, height int) {
+	resized := width != i.width
+	i.width = max(10, width)
+	i.height = max(inspectorChromeLines+1, height)
+	if resized && i.kind == InspectText {
+		i.build()
+	}
+	i.scroll()
+}
+
+// SetClipboard sets where the copy actions write
+func (i *CellInspector) SetClipboard(clipboard func(text string) error) {
+	i.clipboard = clipboard
+}
+
+// SetEditorCommand sets the external editor command (see editor.ResolveEditor)
+func (i *CellInspector) SetEditorCommand(command string) {
+	i.editorCommand = command
+}
+
+// Kind returns how the value is shown
+func (i *CellInspector) Kind() InspectorKind {
+	return i.kind
+}
+
+// Path returns the jq path of the selected JSON node, empty for other kinds
+func (i *CellInspector) Path() string {
+	if node := i.selectedNode(); node != nil {
+		return node.path()
+	}
+	return ""
+}
+
+// selectedNode returns the JSON node of the selected line
+func (i *CellInspector) selectedNode() *jsonNode {
+	if i.cursor < len(i.lines) {
+		return i.lines[i.cursor].node
+	}
+	return nil
+}
+
+// bodyHeight returns how many lines of the value fit
+func (i *CellInspector) bodyHeight() int {
+	return max(1, i.height-inspectorChromeLines)
+}
+
+// Update handles keyboard input: j/k move, Enter/Space fold JSON nodes,
+// h/l collapse/expand, H/L fold all, y/Y copy the node/value, p copies the
+// path and e opens the value in the external editor
+func (i *CellInspector) Update(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case editor.EditorClosedMsg:
+		if msg.Err != nil {
+			i.message = msg.Err.Error()
+		}
+		return nil
+	case tea.KeyMsg:
+		return i.handleKey(msg.String())
+	}
+	return nil
+}
+
+// handleKey runs the action of a key
+func (i *CellInspector) handleKey(key string) tea.Cmd {
+	i.message = ""
+	pending := i.pending
+	i.pending = ""
+	if pending == "g" {
+		if key == "g" {
+			i.moveTo(0)
+		}
+		return nil
+	}
+
+	page := i.bodyHeight()
+	node := i.selectedNode()
+	switch key {
+	case "down", "j":
+		i.moveTo(i.cursor + 1)
+	case "up", "k":
+		i.moveTo(i.cursor - 1)
+	case "ctrl+d":
+		i.moveTo(i.cursor + max(1, page/2))
+	case "ctrl+u":
+		i.moveTo(i.cursor - max(1, page/2))
+	case "pgdown", "ctrl+f":
+		i.moveTo(i.cursor + page)
+	case "pgup", "ctrl+b":
+		i.moveTo(i.cursor - page)
+	case "g":
+		i.pending = "g"
+	case "home":
+		i.moveTo(0)
+	case "G", "end":
+		i.moveTo(len(i.lines) - 1)
+
+	case "enter", " ":
+		if node != nil && len(node.children) > 0 {
+			node.collapsed = !node.collapsed
+			i.rebuild(node)
+		}
+	case "right", "l":
+		if node != nil && node.collapsed {
+			node.collapsed = false
+			i.rebuild(node)
+		} else if node != nil {
+			i.moveTo(i.cursor + 1)
+		}
+	case "left", "h":
+		if node != nil && node.container() && !node.collapsed && len(node.children) > 0 {
+			node.collapsed = true
+			i.rebuild(node)
+		} else if node != nil && node.parent != nil {
+			i.rebuild(node.parent)
+		}
+	case "L":
+		if i.tree != nil {
+			i.tree.setCollapsed(false)
+			i.rebuild(node)
+		}
+	case "H":
+		if i.tree != nil {
+			i.tree.setCollapsed(true)
+			i.tree.collapsed = false
+			i.rebuild(node)
+		}
+
+	case "y":
+		if node != nil {
+			i.copy(node.text(), node.path())
+		} else {
+			i.copy(i.value, "value")
+		}
+	case "Y":
+		i.copy(i.value, "value")
+	case "p":
+		if node != nil {
+			i.copy(node.path(), "path "+node.path())
+		}
+	case "e":
+		return editor.ViewInEditorCmd(i.editorCommand, i.editorText(), i.tempFilePattern())
+	}
+	return nil
+}
+
+// moveTo selects a line, clamped to the value, and scrolls to it
+func (i *CellInspector) moveTo(line int) {
+	i.cursor = max(0, min(line, len(i.lines)-1))
+	i.scroll()
+}
+
+// scroll keeps the selected line in view
+func (i *CellInspector) scroll() {
+	page := i.bodyHeight()
+	if i.cursor < i.offset {
+		i.offset = i.cursor
+	} else if i.cursor >= i.offset+page {
+		i.offset = i.cursor - page + 1
+	}
+	i.offset = max(0, min(i.offset, len(i.lines)-page))
+}
+
+// rebuild redraws the lines after folding and selects the first line of
+// a node
+func (i *CellInspector) rebuild(selected *jsonNode) {
+	i.build()
+	// Folding hides the nodes below; select their nearest visible parent
+	for n := selected; n != nil; n = n.parent {
+		for line, l := range i.lines {
+			if l.node == n {
+				i.moveTo(line)
+				return
+			}
+		}
+	}
+	i.moveTo(i.cursor)
+}
+
+// copy writes text to the clipboard, naming what was copied in the message
+func (i *CellInspector) copy(text, what string) {
+	if i.clipboard == nil {
+		return
+	}
+	if err := i.clipboard(text); err != nil {
+		i.message = fmt.Sprintf("Copy failed: %v", err)
+		return
+	}
+	i.message = fmt.Sprintf("Copied %s (%d bytes)", what, len(text))
+}
+
+// editorText returns the text opened in the external editor: the value as
+// the inspector shows it, without colors
+func (i *CellInspector) editorText() string {
+	switch i.kind {
+	case InspectJSON:
+		return i.tree.text()
+	case InspectText:
+		return i.value
+	}
+	lines := make([]string, len(i.lines))
+	for n, line := range i.lines {
+		for _, span := range line.spans {
+			lines[n] += span.Text
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// tempFilePattern names the external editor's file after the kind
+func (i *CellInspector) tempFilePattern() string {
+	switch i.kind {
+	case InspectJSON:
+		return "lazydb_value_*.json"
+	case InspectXML:
+		return "lazydb_value_*.xml"
+	}
+	return "lazydb_value_*.txt"
+}
+
+// build splits the value into the lines of the body
+func (i *CellInspector) build() {
+	i.lines = nil
+	dim := TokenStyle{Color: string(inspectorDimColor)}
+
+	switch i.kind {
+	case InspectJSON:
+		i.appendJSON(NewHighlighter("json"), i.tree, 0, true)
+
+	case InspectXML:
+		pretty, _ := prettyXML(strings.TrimSpace(i.value))
+		for _, spans := range NewHighlighter("xml").HighlightSpans(pretty) {
+			for n := range spans {
+				spans[n].Text = EscapeControls(spans[n].Text)
+			}
+			i.lines = append(i.lines, inspectorLine{spans: spans})
+		}
+
+	case InspectArray:
+		elements, _ := parseArrayLiteral(i.value)
+		digits := len(fmt.Sprint(len(elements)))
+		for n, element := range elements {
+			spans := []Span{{Text: fmt.Sprintf("[%*d] ", digits, n+1), Style: dim}}
+			if element == nil {
+				spans = append(spans, Span{Text: "NULL", Style: dim})
+			} else {
+				spans = append(spans, Span{Text: EscapeControls(*element)})
+			}
+			i.lines = append(i.lines, inspectorLine{spans: spans})
+		}
+
+	case InspectBytes:
+		data, _ := hex.DecodeString(i.value[2:])
+		dump := strings.TrimSuffix(hex.Dump(data), "\n")
+		for _, line := range strings.Split(dump, "\n") {
+			if len(line) > 10 {
+				i.lines = append(i.lines, inspectorLine{spans: []Span{{Text: line[:10], Style: dim}, {Text: line[10:]}}})
+			}
+		}
+
+	default:
+		// Control characters are shown, not sent to the terminal
+		lines := strings.Split(strings.ReplaceAll(i.value, "\t", "    "), "\n")
+		for n, line := range lines {
+			lines[n] = EscapeControls(strings.TrimSuffix(line, "\r"))
+		}
+		for _, line := range strings.Split(ansi.Wrap(strings.Join(lines, "\n"), i.width, ""), "\n") {
+			i.lines = append(i.lines, inspectorLine{spans: []Span{{Text: line}}})
+		}
+	}
+
+	if len(i.lines) == 0 {
+		i.lines = []inspectorLine{{spans: []Span{{Text: "(empty)", Style: dim}}}}
+	}
+}
+
+// appendJSON adds the lines of a JSON node: one for a scalar or a folded
+// node, or its opening line, its children and its closing line
+func (i *CellInspector) appendJSON(h *SQLHighlighter, n *jsonNode, depth int, last bool) {
+	punctuation := h.tokenStyle(chroma.Punctuation)
+	var prefix []Span
+	prefix = append(prefix, Span{Text: strings.Repeat("  ", depth)})
+	if n.parent != nil && n.parent.object {
+		prefix = append(prefix, Span{Text: EscapeControls(jsonString(n.key)), Style: h.tokenStyle(chroma.NameTag)}, Span{Text: ": ", Style: punctuation})
+	}
+	var comma []Span
+	if !last {
+		comma = []Span{{Text: ",", Style: punctuation}}
+	}
+	line := func(spans ...Span) {
+		all := append(append([]Span{}, prefix...), spans...)
+		i.lines = append(i.lines, inspectorLine{spans: append(all, comma...), node: n})
+	}
+
+	if !n.container() {
+		style := h.tokenStyle(chroma.KeywordConstant)
+		switch n.value.(type) {
+		case string:
+			style = h.tokenStyle(chroma.LiteralStringDouble)
+		case json.Number:
+			style = h.tokenStyle(chroma.LiteralNumber)
+		}
+		line(Span{Text: EscapeControls(jsonScalar(n.value)), Style: style})
+		return
+	}
+
+	open, close, unit := "[", "]", "item"
+	if n.object {
+		open, close, unit = "{", "}", "key"
+	}
+	if len(n.children) == 0 {
+		line(Span{Text: open + close, Style: punctuation})
+		return
+	}
+	if n.collapsed {
+		count := fmt.Sprintf("%d %s", len(n.children), unit)
+		if len(n.children) > 1 {
+			count += "s"
+		}
+		line(Span{Text: open + "…" + close, Style: punctuation})
+		last := len(i.lines) - 1
+		i.lines[last].spans = append(i.lines[last].spans, Span{Text: "  " + count, Style: h.tokenStyle(chroma.Comment)})
+		return
+	}
+
+	i.lines = append(i.lines, inspectorLine{spans: append(append([]Span{}, prefix...), Span{Text: open, Style: punctuation}), node: n})
+	for c, child := range n.children {
+		i.appendJSON(h, child, depth+1, c == len(n.children)-1)
+	}
+	closing := []Span{{Text: strings.Repeat("  ", depth)}, {Text: close, Style: punctuation}}
+	i.lines = append(i.lines, inspectorLine{spans: append(closing, comma...), node: n})
+}
+
+// View renders the inspector
+func (i *CellInspector) View() string {
+	dim := lipgloss.NewStyle().Foreground(inspectorDimColor)
+	rule := dim.Render(strings.Repeat("─", i.width))
+
+	title := lipgloss.NewStyle().Bold(true).Render(EscapeControls(i.column))
+	details := []string{}
+	if i.columnType != "" {
+		details = append(details, i.columnType)
+	}
+	size := len(i.value)
+	if i.kind == InspectBytes {
+		size = (len(i.value) - 2) / 2
+	}
+	details = append(details, i.kind.String(), FormatSize(int64(size)))
+	title += "  " + dim.Render(strings.Join(details, " · "))
+
+	lines := []string{ansi.Truncate(title, i.width, "…"), rule}
+	end := min(i.offset+i.bodyHeight(), len(i.lines))
+	for n := i.offset; n < end; n++ {
+		lines = append(lines, i.renderLine(i.lines[n], n == i.cursor))
+	}
+	lines = append(lines, rule)
+
+	position := fmt.Sprintf("line %d/%d", i.cursor+1, len(i.lines))
+	if path := i.Path(); path != "" {
+		position = "path " + path
+	}
+	lines = append(lines, ansi.Truncate(position, i.width, "…"))
+
+	footer := i.message
+	if footer == "" {
+		footer = dim.Render(i.Help())
+	}
+	lines = append(lines, ansi.Truncate(footer, i.width, "…"))
+	return strings.Join(lines, "\n")
+}
+
+// renderLine draws a line of the body cut to the width, with a background
+// across the width when selected
+func (i *CellInspector) renderLine(line inspectorLine, selected bool) string {
+	var b strings.Builder
+	for _, span := range line.spans {
+		style := lipgloss.NewStyle().Bold(span.Style.Bold).Italic(span.Style.Italic).Underline(span.Style.Underline)
+		if span.Style.Color != "" {
+			style = style.Foreground(lipgloss.Color(span.Style.Color))
+		}
+		if selected {
+			style = style.Background(inspectorCursorColor)
+		}
+		b.WriteString(style.Render(span.Text))
+	}
+	text := ansi.Truncate(b.String(), i.width, "…")
+	if width := ansi.StringWidth(text); selected && width < i.width {
+		text += lipgloss.NewStyle().Background(inspectorCursorColor).Render(strings.Repeat(" ", i.width-width))
+	}
+	return text
+}
+
+// Help returns the inspector's keys
+func (i *CellInspector) Help() string {
+	if i.kind == InspectJSON {
+		return "[Enter] Fold  [h/l] Collapse/expand  [H/L] All  [y] Copy node  [Y] Copy value  [p] Copy path  [e] Editor  [q] Close"
+	}
+	return "[j/k] Scroll  [y] Copy value  [e] Editor  [q] Close"
+}
+
+// prettyXML indents an XML document or fragment by two spaces per level.
+// Namespace prefixes are kept as written.
+func prettyXML(text string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(text))
+	var buf bytes.Buffer
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	elements := 0
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			elements++
+			t.Name = prefixedName(t.Name)
+			attrs := make([]xml.Attr, len(t.Attr))
+			for n, attr := range t.Attr {
+				attrs[n] = xml.Attr{Name: prefixedName(attr.Name), Value: attr.Value}
+			}
+			t.Attr = attrs
+			token = t
+		case xml.EndElement:
+			t.Name = prefixedName(t.Name)
+			token = t
+		case xml.CharData:
+			trimmed := bytes.TrimSpace(t)
+			if len(trimmed) == 0 {
+				continue
+			}
+			token = xml.CharData(trimmed)
+		}
+		if err := encoder.EncodeToken(xml.CopyToken(token)); err != nil {
+			return "", err
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return "", err
+	}
+	if elements == 0 {
+		return "", fmt.Errorf("no XML elements")
+	}
+	return buf.String(), nil
+}
+
+// prefixedName folds a namespace prefix into the local name so the
+// encoder writes it back unchanged
+func prefixedName(name xml.Name) xml.Name {
+	if name.Space == "" {
+		return name
+	}
+	return xml.Name{Local: name.Space + ":" + name.Local}
+}
+
+// parseArrayLiteral splits an array literal such as {1,"a b",NULL,{2,3}}
+// into its elements. Nested arrays are kept as literals and NULL elements
+// are nil.
+func parseArrayLiteral(s string) ([]*string, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, false
+	}
+	inner := s[1 : len(s)-1]
+	if strings.TrimSpace(inner) == "" {
+		return nil, true
+	}
+
+	var elements []*string
+	pos := 0
+	for {
+		element, next, quoted, ok := nextArrayElement(inner, pos)
+		if !ok {
+			return nil, false
+		}
+		if !quoted && strings.EqualFold(element, "NULL") {
+			elements = append(elements, nil)
+		} else {
+			elements = append(elements, &element)
+		}
+		if next == len(inner) {
+			return elements, true
+		}
+		if inner[next] != ',' {
+			return nil, false
+		}
+		pos = next + 1
+	}
+}
+
+// nextArrayElement reads the element of an array literal's content
+// starting at pos. It returns the element, the position after it, and
+// whether it was quoted or a nested array (so never NULL).
+func nextArrayElement(s string, pos int) (string, int, bool, bool) {
+	for pos < len(s) && (s[pos] == ' ' || s[pos] == '\t') {
+		pos++
+	}
+	if pos >= len(s) {
+		return "", pos, false, false
+	}
+
+	switch s[pos] {
+	case '"':
+		var b strings.Builder
+		for pos++; pos < len(s) && s[pos] != '"'; pos++ {
+			if s[pos] == '\\' {
+				pos++
+				if pos >= len(s) {
+					return "", pos, false, false
+				}
+			}
+			b.WriteByte(s[pos])
+		}
+		if pos >= len(s) {
+			return "", pos, false, false
+		}
+		return b.String(), pos + 1, true, true
+
+	case '{':
+		start, depth, inQuote := pos, 0, false
+		for ; pos < len(s); pos++ {
+			c := s[pos]
+			if inQuote {
+				if c == '\\' {
+					pos++
+				} else if c == '"' {
+					inQuote = false
+				}
+				continue
+			}
+			if c == '"' {
+				inQuote = true
+			} else if c == '{' {
+				depth++
+			} else if c == '}' {
+				depth--
+				if depth == 0 {
+					return s[start : pos+1], pos + 1, true, true
+				}
+			}
+		}
+		return "", pos, false, false
+	}
+
+	start := pos
+	for pos < len(s) && s[pos] != ',' {
+		pos++
+	}
+	return strings.TrimSpace(s[start:pos]), pos, false, true
+}
@@ -0,0 +1,179 @@
+package components
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// jsonNode is a JSON value in the inspector's tree. Object members keep
+// their order in the document.
+type jsonNode struct {
+	key       string // Member name in the parent object
+	index     int    // Element index in the parent array, -1 otherwise
+	value     any    // Scalar value: string, json.Number, bool or nil
+	object    bool   // Object, with children as members
+	array     bool   // Array, with children as elements
+	children  []*jsonNode
+	parent    *jsonNode
+	collapsed bool
+}
+
+// parseJSONTree parses a JSON document into a tree
+func parseJSONTree(text string) (*jsonNode, error) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	root, err := parseJSONNode(decoder, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return root, nil
+}
+
+// parseJSONNode reads the next value of a decoder
+func parseJSONNode(decoder *json.Decoder, parent *jsonNode) (*jsonNode, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	node := &jsonNode{index: -1, parent: parent}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		node.value = token
+		return node, nil
+	}
+
+	node.object = delim == '{'
+	node.array = delim == '['
+	for decoder.More() {
+		key := ""
+		if node.object {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key = token.(string)
+		}
+		child, err := parseJSONNode(decoder, node)
+		if err != nil {
+			return nil, err
+		}
+		child.key = key
+		if node.array {
+			child.index = len(node.children)
+		}
+		node.children = append(node.children, child)
+	}
+	// The closing delimiter
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// container reports whether the node is an object or an array
+func (n *jsonNode) container() bool {
+	return n.object || n.array
+}
+
+// jqIdentifier matches the member names jq accepts after a dot
+var jqIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// path returns the node's jq path, such as .users[0].name
+func (n *jsonNode) path() string {
+	if n.parent == nil {
+		return "."
+	}
+	parent := n.parent.path()
+	if n.parent.object && jqIdentifier.MatchString(n.key) {
+		if parent == "." {
+			return "." + n.key
+		}
+		return parent + "." + n.key
+	}
+	if n.parent.array {
+		return fmt.Sprintf("%s[%d]", parent, n.index)
+	}
+	return parent + "[" + jsonString(n.key) + "]"
+}
+
+// setCollapsed collapses or expands the node and all nodes below it
+func (n *jsonNode) setCollapsed(collapsed bool) {
+	if n.container() {
+		n.collapsed = collapsed
+	}
+	for _, child := range n.children {
+		child.setCollapsed(collapsed)
+	}
+}
+
+// text returns the node as indented JSON, or the raw text of a string
+// (like jq -r)
+func (n *jsonNode) text() string {
+	if s, ok := n.value.(string); ok && !n.container() {
+		return s
+	}
+	var b strings.Builder
+	n.write(&b, "")
+	return b.String()
+}
+
+// write writes the node as JSON indented by two spaces per level
+func (n *jsonNode) write(b *strings.Builder, indent string) {
+	if !n.container() {
+		b.WriteString(jsonScalar(n.value))
+		return
+	}
+	open, close := "[", "]"
+	if n.object {
+		open, close = "{", "}"
+	}
+	if len(n.children) == 0 {
+		b.WriteString(open + close)
+		return
+	}
+	b.WriteString(open + "\n")
+	for i, child := range n.children {
+		b.WriteString(indent + "  ")
+		if n.object {
+			b.WriteString(jsonString(child.key) + ": ")
+		}
+		child.write(b, indent+"  ")
+		if i < len(n.children)-1 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString(indent + close)
+}
+
+// jsonScalar encodes a scalar value
+func jsonScalar(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return jsonString(v)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// jsonString quotes a string as JSON without escaping HTML characters
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
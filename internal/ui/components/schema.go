@@ -23,6 +23,8 @@ type SchemaNode struct {
 // SchemaTree manages the schema exploration tree
 type SchemaTree struct {
 	conn            db.Connection
+	cache           *db.MetadataCache // Shared metadata cache (optional)
+	connName        string            // Cache key for this connection
 	root            *SchemaNode
 	flatList        []*SchemaNode // Flat list for navigation
 	selectedIndex   int
@@ -35,6 +37,7 @@ type SchemaTree struct {
 	searchCommitted bool // Search committed (results mode)
 	searchLoading   bool // Loading all schemas for search
 	matchCount      int
+	pendingExpanded map[string]bool // Restored expanded paths not loaded yet
 }
 
 // NewSchemaTree creates a new schema tree
@@ -47,19 +50,155 @@ func NewSchemaTree(conn db.Connection) *SchemaTree {
 	}
 }
 
-// LoadSchemas loads schemas from the database
+// SetCache makes the tree read from and populate the shared metadata cache
+func (st *SchemaTree) SetCache(cache *db.MetadataCache, connName string) {
+	st.cache = cache
+	st.connName = connName
+}
+
+// LoadSchemas loads schemas from the cache, falling back to the database
 func (st *SchemaTree) LoadSchemas(ctx context.Context) tea.Cmd {
+	if st.cache != nil {
+		if schemas, ok := st.cache.Schemas(st.connName); ok {
+			return func() tea.Msg {
+				return SchemasLoadedMsg{Schemas: schemas}
+			}
+		}
+	}
+
 	return func() tea.Msg {
 		schemas, err := st.conn.ListSchemas(ctx)
 		if err != nil {
 			return SchemaErrorMsg{Err: err}
 		}
+		if st.cache != nil {
+			st.cache.PutSchemas(st.connName, schemas)
+		}
 		return SchemasLoadedMsg{Schemas: schemas}
 	}
 }
 
+// RefreshInBackground reloads stale cached metadata: the schema list and
+// the schemas expanded or cached. An empty cache is left to LoadSchemas.
+// The tree keeps showing cached data until SchemaCacheRefreshedMsg arrives.
+func (st *SchemaTree) RefreshInBackground(ctx context.Context) tea.Cmd {
+	if st.cache == nil || !st.cache.IsStale(st.connName) {
+		return nil
+	}
+	if _, ok := st.cache.Schemas(st.connName); !ok {
+		return nil
+	}
+
+	expanded := st.ExpandedPaths()
+	for path := range st.pendingExpanded {
+		expanded = append(expanded, path)
+	}
+	schemas := make([]string, 0, len(expanded))
+	for _, path := range expanded {
+		if !strings.Contains(path, "/") {
+			schemas = append(schemas, path)
+		}
+	}
+
+	cache, connName, conn := st.cache, st.connName, st.conn
+	return func() tea.Msg {
+		if err := cache.Refresh(ctx, connName, conn, schemas); err != nil {
+			return SchemaErrorMsg{Err: err}
+		}
+		return SchemaCacheRefreshedMsg{ConnName: connName}
+	}
+}
+
+// HandleCacheRefreshed rebuilds the tree from freshly cached metadata,
+// keeping schemas and categories expanded where they were
+func (st *SchemaTree) HandleCacheRefreshed() {
+	if st.cache == nil {
+		return
+	}
+	schemas, ok := st.cache.Schemas(st.connName)
+	if !ok {
+		return
+	}
+
+	// Remember which nodes were expanded
+	expanded := make(map[string]bool)
+	for _, path := range st.ExpandedPaths() {
+		expanded[path] = true
+	}
+
+	selected := st.selectedIndex
+	st.HandleSchemasLoaded(schemas)
+	for _, schemaNode := range st.root.Children {
+		if !expanded[schemaNode.Name] {
+			continue
+		}
+		if objects, ok := st.cache.Objects(st.connName, schemaNode.Name); ok {
+			st.HandleSchemaObjectsLoaded(schemaNode.Name, objects.Tables, objects.Views, objects.Functions)
+		}
+		schemaNode.Expanded = true
+		for _, categoryNode := range schemaNode.Children {
+			categoryNode.Expanded = expanded[schemaNode.Name+"/"+categoryNode.Type]
+		}
+	}
+
+	if st.searchMode || st.searchCommitted {
+		st.rebuildFilteredList()
+	} else {
+		st.rebuildFlatList()
+	}
+	if selected < len(st.flatList) {
+		st.selectedIndex = selected
+	}
+	st.adjustScroll()
+}
+
+// ExpandedPaths returns the expanded schemas and categories, as "schema"
+// and "schema/category" paths (e.g. "public/tables")
+func (st *SchemaTree) ExpandedPaths() []string {
+	var paths []string
+	for _, schemaNode := range st.root.Children {
+		if !schemaNode.Expanded {
+			continue
+		}
+		paths = append(paths, schemaNode.Name)
+		for _, categoryNode := range schemaNode.Children {
+			if categoryNode.Expanded {
+				paths = append(paths, schemaNode.Name+"/"+categoryNode.Type)
+			}
+		}
+	}
+	return paths
+}
+
+// RestoreExpanded expands the given ExpandedPaths once their schemas are
+// loaded. Call it before LoadSchemas and return LoadPendingExpanded when
+// the schemas arrive.
+func (st *SchemaTree) RestoreExpanded(paths []string) {
+	st.pendingExpanded = make(map[string]bool, len(paths))
+	for _, path := range paths {
+		st.pendingExpanded[path] = true
+	}
+}
+
+// LoadPendingExpanded loads the objects of the loaded schemas that are
+// waiting to be expanded by RestoreExpanded
+func (st *SchemaTree) LoadPendingExpanded(ctx context.Context) tea.Cmd {
+	var cmds []tea.Cmd
+	for _, schemaNode := range st.root.Children {
+		if st.pendingExpanded[schemaNode.Name] {
+			cmds = append(cmds, st.LoadSchemaObjects(ctx, schemaNode.Name))
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
 // RefreshSchemas reloads all schemas from the database
 func (st *SchemaTree) RefreshSchemas(ctx context.Context) tea.Cmd {
+	// Manual refresh always bypasses the cache
+	if st.cache != nil {
+		st.cache.Invalidate(st.connName)
+	}
+
 	// Clear existing data
 	st.root.Children = []*SchemaNode{}
 	st.flatList = []*SchemaNode{}
@@ -78,40 +217,54 @@ func (st *SchemaTree) RefreshSchemas(ctx context.Context) tea.Cmd {
 // LoadSchemaObjects loads tables, views, and functions for a schema
 func (st *SchemaTree) LoadSchemaObjects(ctx context.Context, schema string) tea.Cmd {
 	return func() tea.Msg {
-		// Load tables
-		tables, err := st.conn.ListTables(ctx, schema)
+		objects, err := st.schemaObjects(ctx, schema)
 		if err != nil {
 			return SchemaErrorMsg{Err: err}
 		}
 
-		// Load views
-		views, err := st.conn.ListViews(ctx, schema)
-		if err != nil {
-			return SchemaErrorMsg{Err: err}
+		return SchemaObjectsLoadedMsg{
+			Schema:    schema,
+			Tables:    objects.Tables,
+			Views:     objects.Views,
+			Functions: objects.Functions,
 		}
+	}
+}
 
-		// Load functions
-		functions, err := st.conn.ListFunctions(ctx, schema)
-		if err != nil {
-			return SchemaErrorMsg{Err: err}
+// schemaObjects returns the objects of a schema from the cache or the database
+func (st *SchemaTree) schemaObjects(ctx context.Context, schema string) (db.SchemaObjects, error) {
+	if st.cache != nil {
+		if objects, ok := st.cache.Objects(st.connName, schema); ok {
+			return objects, nil
 		}
+	}
 
-		return SchemaObjectsLoadedMsg{
-			Schema:    schema,
-			Tables:    tables,
-			Views:     views,
-			Functions: functions,
-		}
+	objects, err := db.LoadSchemaObjects(ctx, st.conn, schema)
+	if err != nil {
+		return db.SchemaObjects{}, err
 	}
+	if st.cache != nil {
+		st.cache.PutObjects(st.connName, schema, objects)
+	}
+	return objects, nil
 }
 
 // LoadTableColumns loads columns for a table
 func (st *SchemaTree) LoadTableColumns(ctx context.Context, schema, table string) tea.Cmd {
 	return func() tea.Msg {
+		if st.cache != nil {
+			if columns, ok := st.cache.Columns(st.connName, schema, table); ok {
+				return TableColumnsLoadedMsg{Schema: schema, Table: table, Columns: columns}
+			}
+		}
+
 		columns, err := st.conn.GetTableColumns(ctx, schema, table)
 		if err != nil {
 			return SchemaErrorMsg{Err: err}
 		}
+		if st.cache != nil {
+			st.cache.PutColumns(st.connName, schema, table, columns)
+		}
 		return TableColumnsLoadedMsg{
 			Schema:  schema,
 			Table:   table,
@@ -294,6 +447,16 @@ func (st *SchemaTree) HandleSchemaObjectsLoaded(schema string, tables, views, fu
 		schemaNode.Children = append(schemaNode.Children, functionsNode)
 	}
 
+	// Expand the schema as it was in the restored session
+	if st.pendingExpanded[schema] {
+		schemaNode.Expanded = true
+		for _, categoryNode := range schemaNode.Children {
+			categoryNode.Expanded = st.pendingExpanded[schema+"/"+categoryNode.Type]
+			delete(st.pendingExpanded, schema+"/"+categoryNode.Type)
+		}
+		delete(st.pendingExpanded, schema)
+	}
+
 	// Preserve search mode if active
 	if st.searchMode {
 		st.rebuildFilteredList()
@@ -580,21 +743,12 @@ func (st *SchemaTree) ExpandAndLoadAllSchemas(ctx context.Context) tea.Cmd {
 				continue
 			}
 
-			// Load tables, views, functions from database
-			tables, err := st.conn.ListTables(ctx, schemaNode.Name)
+			// Load tables, views, functions from cache or database
+			objects, err := st.schemaObjects(ctx, schemaNode.Name)
 			if err != nil {
 				continue // Skip schemas with errors
 			}
-
-			views, err := st.conn.ListViews(ctx, schemaNode.Name)
-			if err != nil {
-				views = []db.SchemaObject{} // Continue with empty views
-			}
-
-			functions, err := st.conn.ListFunctions(ctx, schemaNode.Name)
-			if err != nil {
-				functions = []db.SchemaObject{} // Continue with empty functions
-			}
+			tables, views, functions := objects.Tables, objects.Views, objects.Functions
 
 			// Build children (same logic as HandleSchemaObjectsLoaded)
 			schemaNode.Children = []*SchemaNode{}
@@ -810,3 +964,8 @@ type SchemaErrorMsg struct {
 }
 
 type SchemaExpandCompleteMsg struct{}
+
+// SchemaCacheRefreshedMsg is sent when a background metadata refresh finishes
+type SchemaCacheRefreshedMsg struct {
+	ConnName string
+}
@@ -0,0 +1,99 @@
+package components
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// EscapeControls keeps a value on one line and out of the terminal's
+// control: line breaks show as ↵, tabs as →, other control characters
+// (escape sequences included) as their Unicode control pictures or \x
+// codes
+func EscapeControls(s string) string {
+	if !strings.ContainsFunc(s, isControl) {
+		return s
+	}
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\r' && strings.HasPrefix(s[i+1:], "\n"):
+			// Shown with the \n
+		case r == '\n':
+			b.WriteRune('↵')
+		case r == '\t':
+			b.WriteRune('→')
+		case r < 0x20:
+			b.WriteRune(0x2400 + r)
+		case r == 0x7f:
+			b.WriteRune('␡')
+		case isControl(r):
+			fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isControl reports whether a rune is a C0 or C1 control character
+func isControl(r rune) bool {
+	return r < 0x20 || (r >= 0x7f && r < 0xa0)
+}
+
+// FitCell fits a value in a table cell of the given display width: control
+// characters escaped, wide characters counted as two columns, cut between
+// grapheme clusters with an ellipsis and padded with spaces
+func FitCell(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	s = ansi.Truncate(EscapeControls(s), width, "…")
+	return s + strings.Repeat(" ", max(0, width-ansi.StringWidth(s)))
+}
+
+// FormatSize returns a byte count for display
+func FormatSize(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	case n < 1024*1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	}
+	return fmt.Sprintf("%.2f GB", float64(n)/(1024*1024*1024))
+}
+
+// FormatCount writes a count with thousands separators
+func FormatCount(n int) string {
+	if n < 0 {
+		return "-" + FormatCount(-n)
+	}
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// FormatNumber writes a number compactly for axes and statistics: whole
+// numbers with thousands separators, others with four significant digits
+func FormatNumber(f float64) string {
+	abs := math.Abs(f)
+	switch {
+	case f == math.Trunc(f) && abs < 1e15:
+		return FormatCount(int(f))
+	case abs >= 1e15 || abs < 1e-4:
+		return strconv.FormatFloat(f, 'g', 4, 64)
+	}
+	decimals := max(0, min(6, 3-int(math.Floor(math.Log10(abs)))))
+	s := strconv.FormatFloat(f, 'f', decimals, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
@@ -25,7 +25,9 @@ type ConnectionsPanel struct {
 	selectedIndex int // Currently selected connection (for navigation)
 	viewMode      ViewMode
 	schemaTree    *components.SchemaTree
+	metaCache     *db.MetadataCache // Shared schema metadata cache
 	ctx           context.Context
+	expandedNodes map[string][]string // Expanded schema tree paths per connection
 }
 
 // NewConnectionsPanel creates a new connections panel
@@ -35,9 +37,77 @@ func NewConnectionsPanel(connMgr *db.ConnectionManager, ctx context.Context) *Co
 		selectedIndex: 0,
 		viewMode:      ViewConnections,
 		ctx:           ctx,
+		expandedNodes: make(map[string][]string),
 	}
 }
 
+// SetMetadataCache sets the schema metadata cache shared with the editor
+func (p *ConnectionsPanel) SetMetadataCache(cache *db.MetadataCache) {
+	p.metaCache = cache
+}
+
+// MetadataCache returns the schema metadata cache (may be nil)
+func (p *ConnectionsPanel) MetadataCache() *db.MetadataCache {
+	return p.metaCache
+}
+
+// SetSessionExpanded sets the schema tree nodes to expand, per connection,
+// when the schema view is opened
+func (p *ConnectionsPanel) SetSessionExpanded(expanded map[string][]string) {
+	p.expandedNodes = make(map[string][]string, len(expanded))
+	for name, paths := range expanded {
+		p.expandedNodes[name] = paths
+	}
+}
+
+// SessionExpanded returns the expanded schema tree nodes per connection,
+// including the tree that is open now
+func (p *ConnectionsPanel) SessionExpanded() map[string][]string {
+	p.rememberExpanded()
+	expanded := make(map[string][]string, len(p.expandedNodes))
+	for name, paths := range p.expandedNodes {
+		if len(paths) > 0 {
+			expanded[name] = paths
+		}
+	}
+	return expanded
+}
+
+// rememberExpanded records the expanded nodes of the open schema tree
+func (p *ConnectionsPanel) rememberExpanded() {
+	if p.schemaTree != nil {
+		p.expandedNodes[p.connMgr.ActiveName()] = p.schemaTree.ExpandedPaths()
+	}
+}
+
+// closeSchemaView returns to the connection list
+func (p *ConnectionsPanel) closeSchemaView() {
+	p.rememberExpanded()
+	p.viewMode = ViewConnections
+	p.schemaTree = nil
+}
+
+// RegisterCommands adds the connections panel's commands to a registry
+func (p *ConnectionsPanel) RegisterCommands(registry *components.CommandRegistry) {
+	registry.Register(components.Command{
+		Name: "connect", Usage: "<name>",
+		Help:     "Connect to a connection and make it the active one",
+		Complete: components.CompleteFrom(p.connMgr.ListConnections),
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if args.Arg == "" {
+				return nil, fmt.Errorf("usage: connect <name>")
+			}
+			if _, err := p.connMgr.GetConnection(args.Arg); err != nil {
+				return nil, err
+			}
+			name := args.Arg
+			return func() tea.Msg {
+				return ConnectMsg{Name: name}
+			}, nil
+		},
+	})
+}
+
 // SetSize sets the panel dimensions
 func (p *ConnectionsPanel) SetSize(width, height int) {
 	p.width = width
@@ -51,6 +121,7 @@ func (p *ConnectionsPanel) Update(msg tea.Msg) tea.Cmd {
 	case components.SchemasLoadedMsg:
 		if p.schemaTree != nil {
 			p.schemaTree.HandleSchemasLoaded(msg.Schemas)
+			return p.schemaTree.LoadPendingExpanded(p.ctx)
 		}
 		return nil
 	case components.SchemaObjectsLoadedMsg:
@@ -68,6 +139,12 @@ func (p *ConnectionsPanel) Update(msg tea.Msg) tea.Cmd {
 			p.schemaTree.SetLoadingComplete()
 		}
 		return nil
+	case components.SchemaCacheRefreshedMsg:
+		// Only apply if the tree still shows the refreshed connection
+		if p.schemaTree != nil && msg.ConnName == p.connMgr.ActiveName() {
+			p.schemaTree.HandleCacheRefreshed()
+		}
+		return nil
 	case components.SchemaErrorMsg:
 		// Handle error - could add error display
 		return nil
@@ -83,13 +160,21 @@ func (p *ConnectionsPanel) Update(msg tea.Msg) tea.Cmd {
 			if err == nil && activeConn.Status() == db.StatusConnected {
 				p.viewMode = ViewSchema
 				p.schemaTree = components.NewSchemaTree(activeConn)
+				if p.metaCache != nil {
+					p.schemaTree.SetCache(p.metaCache, p.connMgr.ActiveName())
+				}
+				p.schemaTree.RestoreExpanded(p.expandedNodes[p.connMgr.ActiveName()])
 				// Calculate visible rows (leave space for header)
 				visibleRows := p.height - 4
 				if visibleRows < 5 {
 					visibleRows = 5
 				}
 				p.schemaTree.SetMaxVisibleRows(visibleRows)
-				return p.schemaTree.LoadSchemas(p.ctx)
+				// Show cached metadata right away and refresh it if stale
+				return tea.Batch(
+					p.schemaTree.LoadSchemas(p.ctx),
+					p.schemaTree.RefreshInBackground(p.ctx),
+				)
 			}
 			return nil
 		}
@@ -101,8 +186,7 @@ func (p *ConnectionsPanel) Update(msg tea.Msg) tea.Cmd {
 				switch msg.String() {
 				case "q":
 					// Exit schema view, return to connections
-					p.viewMode = ViewConnections
-					p.schemaTree = nil
+					p.closeSchemaView()
 					return nil
 				case "esc":
 					// Cancel search, return to normal mode
@@ -142,8 +226,7 @@ func (p *ConnectionsPanel) Update(msg tea.Msg) tea.Cmd {
 				switch msg.String() {
 				case "q":
 					// Exit schema view, return to connections
-					p.viewMode = ViewConnections
-					p.schemaTree = nil
+					p.closeSchemaView()
 					return nil
 				case "esc":
 					// Clear filter, return to normal mode with full list
@@ -183,8 +266,7 @@ func (p *ConnectionsPanel) Update(msg tea.Msg) tea.Cmd {
 			switch msg.String() {
 			case "q":
 				// Exit schema view, return to connections
-				p.viewMode = ViewConnections
-				p.schemaTree = nil
+				p.closeSchemaView()
 				return nil
 			case "/":
 				// Enter search input mode
@@ -424,3 +506,8 @@ type TablePreviewMsg struct {
 	Schema string
 	Table  string
 }
+
+// ConnectMsg is sent when the user asks to connect to a connection by name
+type ConnectMsg struct {
+	Name string
+}
@@ -0,0 +1,257 @@
+package panels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/storage"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v5"
+)
+
+// CopyRequest is a COPY between a local CSV file and the server: the rows
+// of Query to the file, or the file into Table
+type CopyRequest struct {
+	Query   string // COPY (Query) TO the file; empty for an import
+	Table   string // COPY Table FROM the file, e.g. users or users (id, email)
+	Path    string
+	Options db.CopyOptions
+}
+
+// Import reports whether the request reads the file into a table
+func (r CopyRequest) Import() bool {
+	return r.Query == ""
+}
+
+// describe names the transfer for the status line
+func (r CopyRequest) describe() string {
+	if r.Import() {
+		return fmt.Sprintf("COPY %s from %s", r.Table, r.Path)
+	}
+	return "COPY to " + r.Path
+}
+
+// CopyRequestMsg asks the application to start a COPY on the active
+// connection with StartCopy
+type CopyRequestMsg struct {
+	Request CopyRequest
+}
+
+// CopyCancelMsg asks the application to cancel the running COPY
+type CopyCancelMsg struct{}
+
+// CopyProgressMsg reports how far a COPY got
+type CopyProgressMsg struct {
+	Job      *CopyJob
+	Progress db.CopyProgress
+}
+
+// CopyDoneMsg reports the end of a COPY. Err is set if it failed or was
+// cancelled.
+type CopyDoneMsg struct {
+	Job      *CopyJob
+	Progress db.CopyProgress
+	Err      error
+}
+
+// CopyJob is a COPY running in the background. Its progress and its end
+// arrive through Listen, and Update keeps the job's state.
+type CopyJob struct {
+	Request CopyRequest
+
+	cancel   context.CancelFunc
+	updates  chan db.CopyProgress // Latest progress, dropped while one is pending
+	finished chan struct{}        // Closed once result and err are set
+	result   db.CopyProgress
+	err      error
+
+	progress db.CopyProgress
+	done     bool
+}
+
+// StartCopy starts a COPY on a connection. The returned command waits for
+// its first message; pass every message to Update to keep listening.
+func StartCopy(ctx context.Context, conn *pgx.Conn, req CopyRequest) (*CopyJob, tea.Cmd) {
+	return startCopyJob(ctx, req, func(ctx context.Context, progress func(db.CopyProgress)) (db.CopyProgress, error) {
+		if req.Import() {
+			return storage.CopyFromFile(ctx, conn, req.Table, req.Path, req.Options, progress)
+		}
+		return storage.CopyToFile(ctx, conn, req.Query, req.Path, req.Options, progress)
+	})
+}
+
+// startCopyJob runs a transfer in a goroutine
+func startCopyJob(ctx context.Context, req CopyRequest, run func(ctx context.Context, progress func(db.CopyProgress)) (db.CopyProgress, error)) (*CopyJob, tea.Cmd) {
+	ctx, cancel := context.WithCancel(ctx)
+	j := &CopyJob{
+		Request:  req,
+		cancel:   cancel,
+		updates:  make(chan db.CopyProgress, 1),
+		finished: make(chan struct{}),
+	}
+	go func() {
+		defer cancel()
+		result, err := run(ctx, func(p db.CopyProgress) {
+			select {
+			case j.updates <- p:
+			default:
+			}
+		})
+		if err != nil && ctx.Err() != nil {
+			err = context.Canceled
+		}
+		j.result, j.err = result, err
+		close(j.finished)
+	}()
+	return j, j.Listen()
+}
+
+// Listen waits for the next message of the job
+func (j *CopyJob) Listen() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case p := <-j.updates:
+			return CopyProgressMsg{Job: j, Progress: p}
+		case <-j.finished:
+			return CopyDoneMsg{Job: j, Progress: j.result, Err: j.err}
+		}
+	}
+}
+
+// Update records a message of the job and keeps listening until it ends
+func (j *CopyJob) Update(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case CopyProgressMsg:
+		if msg.Job == j && !j.done {
+			j.progress = msg.Progress
+			return j.Listen()
+		}
+	case CopyDoneMsg:
+		if msg.Job == j {
+			j.progress, j.done = msg.Progress, true
+		}
+	}
+	return nil
+}
+
+// Cancel stops the COPY. An export leaves no file behind and an import
+// adds no rows.
+func (j *CopyJob) Cancel() {
+	j.cancel()
+}
+
+// Done reports whether the COPY ended
+func (j *CopyJob) Done() bool {
+	return j.done
+}
+
+// Err returns why the COPY failed, once done
+func (j *CopyJob) Err() error {
+	if !j.done {
+		return nil
+	}
+	return j.err
+}
+
+// Status describes the transfer for the status line
+func (j *CopyJob) Status() string {
+	p := j.progress
+	name := j.Request.describe()
+	switch {
+	case j.done && errors.Is(j.err, context.Canceled):
+		return name + " cancelled"
+	case j.done && j.err != nil:
+		return fmt.Sprintf("%s failed: %v", name, j.err)
+	case j.done:
+		return fmt.Sprintf("%s: %s rows, %s in %s", name, components.FormatCount(int(p.Rows)), components.FormatSize(p.Bytes), p.Elapsed.Round(100*time.Millisecond))
+	}
+
+	size := components.FormatSize(p.Bytes)
+	if p.Total > 0 {
+		size = fmt.Sprintf("%d%% %s of %s", p.Bytes*100/p.Total, size, components.FormatSize(p.Total))
+	}
+	status := fmt.Sprintf("%s: %s, %s rows, %s/s", name, size, components.FormatCount(int(p.Rows)), components.FormatSize(int64(p.Throughput())))
+	if rate := p.Throughput(); p.Total > 0 && rate > 0 {
+		left := time.Duration(float64(p.Total-p.Bytes) / rate * float64(time.Second))
+		status += fmt.Sprintf(", %s left", left.Round(time.Second))
+	}
+	return status + " (:copy! cancels)"
+}
+
+// copyOptionNames are the options of :copy
+var copyOptionNames = []string{"delimiter=", "null=", "header", "noheader"}
+
+// parseCopyOptions takes the options out of the fields of :copy. CSV
+// files have a header line unless noheader is given.
+func parseCopyOptions(fields []string) (db.CopyOptions, []string, error) {
+	opts := db.CopyOptions{Header: true}
+	var rest []string
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		switch {
+		case ok && key == "delimiter":
+			switch value {
+			case "tab", `\t`:
+				value = "\t"
+			case "space":
+				value = " "
+			}
+			if utf8.RuneCountInString(value) != 1 {
+				return opts, nil, errors.New("the delimiter must be one character")
+			}
+			opts.Delimiter = value
+		case ok && key == "null":
+			opts.Null = value
+		case field == "header":
+			opts.Header = true
+		case field == "noheader":
+			opts.Header = false
+		default:
+			rest = append(rest, field)
+		}
+	}
+	return opts, rest, nil
+}
+
+// copyCommand parses :copy to <file> and :copy from <file> <table>; the
+// export copies the statement under the cursor
+func (p *EditorPanel) copyCommand(args components.CommandArgs) (tea.Cmd, error) {
+	if args.Bang {
+		return func() tea.Msg { return CopyCancelMsg{} }, nil
+	}
+	usage := errors.New("usage: copy to <file> | copy from <file> <table> [delimiter=,] [null=text] [noheader]")
+	opts, fields, err := parseCopyOptions(args.Fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 2 {
+		return nil, usage
+	}
+
+	req := CopyRequest{Path: fields[1], Options: opts}
+	var flash tea.Cmd
+	switch fields[0] {
+	case "to":
+		if len(fields) > 2 {
+			return nil, usage
+		}
+		req.Query, _, flash = p.QueryToExecute(false)
+		if req.Query == "" {
+			return nil, errors.New("no statement under the cursor")
+		}
+	case "from":
+		if len(fields) < 3 {
+			return nil, usage
+		}
+		req.Table = strings.Join(fields[2:], " ")
+	default:
+		return nil, usage
+	}
+	return tea.Batch(flash, func() tea.Msg { return CopyRequestMsg{Request: req} }), nil
+}
@@ -1,16 +1,20 @@
 package panels
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/MachineLearning-Nerd/lazydb/internal/config"
 	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/editor"
+	"github.com/MachineLearning-Nerd/lazydb/internal/storage"
 	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
 )
 
@@ -36,47 +40,167 @@ type EditorMode int
 const (
 	ModeNormal EditorMode = iota
 	ModeInsert
+	ModeVisual
+	ModeVisualLine
+	ModeVisualBlock
 )
 
+// editorModes maps the Vim engine modes to editor modes
+var editorModes = map[editor.Mode]EditorMode{
+	editor.ModeNormal:      ModeNormal,
+	editor.ModeInsert:      ModeInsert,
+	editor.ModeVisual:      ModeVisual,
+	editor.ModeVisualLine:  ModeVisualLine,
+	editor.ModeVisualBlock: ModeVisualBlock,
+}
+
 // EditorPanel represents the center panel for query editing
 type EditorPanel struct {
 	width            int
 	height           int
-	textarea         textarea.Model
+	textarea         textarea.Model // Active buffer's textarea
+	buffers          []*queryBuffer
+	active           int // Index of the active buffer
+	connMgr          *db.ConnectionManager
+	cmdline          *components.CommandLine // ":" commands and their messages
 	mode             EditorMode
-	clipboard        string // For yank/paste operations
+	vim              *editor.Vim    // Normal mode engine, holds the registers and undo history
+	vimBuf           *editor.Buffer // The engine's copy of the textarea, reused while the text is unchanged
+	vimText          string         // Textarea text vimBuf was made from
+	registerStore    *storage.RegisterStore
 	highlighter      *components.SQLHighlighter
 	validator        *db.SQLValidator
 	validationResult db.ValidationResult
+	serverError      *db.ValidationError // Last execution error, cleared on edit
 	enableHighlight  bool
 	enableLinting    bool
+	completer        *db.Completer
+	completion       *components.CompletionPopup
+	completionConn   db.Connection // Used to load missing columns (optional)
+	metaCache        *db.MetadataCache
+	metaConnName     string // Cache key of the active connection
+	completionStart  int           // Byte offset of the prefix being completed
+	completionPrefix string
+	formatter        *db.Formatter
+	executed         *executedRange // Range of the last execution, shown briefly
+	executedSeq      int
+	scriptPending    string           // First key of a two-key command in a read-only script
+	nvim             *editor.NvimSync // Live sync with Neovim, nil when not attached
+	nvimBuffer       *queryBuffer     // Buffer mirrored into Neovim
+	nvimAddress      string           // Neovim socket used when $NVIM is unset
+	editorCommand    string           // External editor command, empty for $VISUAL/$EDITOR
+}
+
+// executedRange is the byte range [start, end) of the buffer last sent to the server
+type executedRange struct {
+	start, end int
+	seq        int
+}
+
+// executedFlashDuration is how long the executed range stays highlighted
+const executedFlashDuration = 600 * time.Millisecond
+
+// executedFlashDoneMsg ends the highlight of an executed range
+type executedFlashDoneMsg struct {
+	seq int
 }
 
 // NewEditorPanel creates a new editor panel
 func NewEditorPanel() *EditorPanel {
-	ta := textarea.New()
-	ta.Placeholder = "Enter SQL query here... (Press ESC for Vim Normal mode, i for Insert mode)"
-	ta.SetValue("SELECT * FROM pg_database;")
-	ta.ShowLineNumbers = true
-	ta.CharLimit = 10000 // Reasonable limit for SQL queries
+	ta := newQueryTextarea("SELECT * FROM pg_database;")
 	ta.Focus()
 
-	// Set some style preferences
-	ta.FocusedStyle.CursorLine = ta.FocusedStyle.CursorLine
-
 	// Initialize highlighter and validator
 	highlighter := components.NewSQLHighlighter()
 	validator := db.NewSQLValidator()
 
-	return &EditorPanel{
+	// Yanks to "+ reach the host terminal's clipboard, even over SSH
+	vim := editor.NewVim()
+	vim.SetClipboard(editor.OSC52Clipboard(os.Stderr))
+	vim.StartInsert(editor.NewBuffer(ta.Value()))
+
+	p := &EditorPanel{
 		textarea:        ta,
+		buffers:         []*queryBuffer{{saved: ta.Value(), textarea: ta, history: vim.History()}},
+		cmdline:         components.NewCommandLine(components.NewCommandRegistry()),
 		mode:            ModeInsert, // Start in insert mode for easier use
-		clipboard:       "",
+		vim:             vim,
 		highlighter:     highlighter,
 		validator:       validator,
 		enableHighlight: true,  // Enable by default
 		enableLinting:   true,  // Enable by default
+		completer:       db.NewCompleter(nil, ""), // Keywords only until a connection is set
+		completion:      components.NewCompletionPopup(),
+		formatter:       db.NewFormatter(db.DefaultFormatOptions()),
+	}
+	p.RegisterCommands(p.cmdline.Registry())
+	return p
+}
+
+// SetRegisterStore loads the Vim registers saved by a previous session and
+// saves them whenever they change
+func (p *EditorPanel) SetRegisterStore(store *storage.RegisterStore) error {
+	p.registerStore = store
+	regs, err := store.Load()
+	if err != nil {
+		return err
+	}
+	for name, reg := range regs {
+		p.vim.SetRegister(name, reg)
+	}
+	return nil
+}
+
+// SetLintConfig applies the lint rule settings from the config
+func (p *EditorPanel) SetLintConfig(cfg config.LintConfig) {
+	linter := p.validator.Linter()
+	for _, rule := range linter.Rules() {
+		linter.SetEnabled(rule.Name(), true)
+	}
+	for _, name := range cfg.Disabled {
+		linter.SetEnabled(name, false)
+	}
+	for name, value := range cfg.Severity {
+		if severity, err := db.ParseLintSeverity(value); err == nil {
+			linter.SetSeverity(name, severity)
+		}
+	}
+	p.validationResult = p.validate(p.textarea.Value())
+}
+
+// SetFormatConfig applies the formatter settings from the config
+func (p *EditorPanel) SetFormatConfig(cfg config.FormatConfig) {
+	opts := db.FormatOptions{
+		KeywordCase: cfg.KeywordCase,
+		IndentWidth: cfg.IndentWidth,
+		CommaStyle:  cfg.CommaStyle,
+		LineWidth:   cfg.LineWidth,
 	}
+	p.formatter = db.NewFormatter(opts)
+	p.validator.SetFormatOptions(opts)
+}
+
+// SetThemeConfig applies the theme settings from the config: the token
+// colors, and whether to highlight and lint at all
+func (p *EditorPanel) SetThemeConfig(cfg config.ThemeConfig) {
+	if cfg.Name != "" {
+		p.highlighter.SetTheme(cfg.Name)
+	}
+	p.enableHighlight = cfg.SyntaxHighlighting
+	p.enableLinting = cfg.SQLLinting
+	if p.enableLinting {
+		p.validationResult = p.validate(p.textarea.Value())
+	}
+}
+
+// SetCompletionSource sets the metadata used for autocompletion.
+// conn is used to load columns that are not cached yet and may be nil.
+func (p *EditorPanel) SetCompletionSource(cache *db.MetadataCache, conn db.Connection, connName string) {
+	p.completer = db.NewCompleter(cache, connName)
+	p.completionConn = conn
+	p.metaCache, p.metaConnName = cache, connName
+	p.validator.Linter().SetSchemaSource(cache, connName)
+	p.hideCompletion()
 }
 
 // SetSize sets the panel dimensions
@@ -84,12 +208,21 @@ func (p *EditorPanel) SetSize(width, height int) {
 	p.width = width
 	p.height = height
 
-	// Update textarea size (leave room for title)
-	if height > 4 {
-		p.textarea.SetHeight(height - 2)
-	}
 	if width > 4 {
 		p.textarea.SetWidth(width - 2)
+		p.completion.SetWidth(min(width-2, 70))
+	}
+	p.resizeTextarea()
+}
+
+// resizeTextarea fits the textarea into the panel, leaving room for the
+// title, the tab bar, the command line and the completion popup
+func (p *EditorPanel) resizeTextarea() {
+	height := p.height - 3 - p.completion.Height()
+	if height > 2 {
+		p.textarea.SetHeight(height)
+		// Let the textarea scroll the cursor back into view
+		p.textarea, _ = p.textarea.Update(nil)
 	}
 }
 
@@ -113,32 +246,92 @@ func (p *EditorPanel) Update(msg tea.Msg) tea.Cmd {
 			logDebug("[LOG5] EditorPanel received key='%s' mode=%s", key, modeStr)
 		}
 
-		// ESC always switches to normal mode
-		if key == "esc" {
-			p.mode = ModeNormal
-			return nil
+		// The command line takes all keys while it is open
+		if p.cmdline.IsActive() {
+			return p.cmdline.Update(msg)
 		}
+		p.cmdline.ClearMessage()
 
-		// Handle keys based on current mode
-		if p.mode == ModeNormal {
-			return p.handleNormalMode(msg)
+		// Read-only scripts only scroll
+		if p.current().script != nil {
+			return p.handleScriptKey(msg)
+		}
+
+		// Completion popup takes navigation keys while it is open
+		if p.mode == ModeInsert && p.completion.IsVisible() {
+			switch key {
+			case "esc":
+				p.hideCompletion()
+				return nil
+			case "tab", "enter":
+				p.acceptCompletion()
+				return nil
+			case "down", "ctrl+n":
+				p.completion.Next()
+				return nil
+			case "up", "ctrl+p":
+				p.completion.Prev()
+				return nil
+			}
+		}
+
+		// ESC leaves insert mode, moving back onto the last typed character like Vim
+		if key == "esc" && p.mode == ModeInsert {
+			buf := p.buffer()
+			p.vim.EndInsert(buf)
+			p.applyBuffer(buf)
+			p.mode = ModeNormal
+		} else if p.mode != ModeInsert {
+			cmd = p.handleNormalMode(msg)
 		} else {
 			// DEBUG: Show when keys are being consumed in INSERT mode
 			if key == "=" || key == "-" || key == "[" || key == "]" {
 				logDebug("[LOG5] INSERT mode - passing key='%s' to textarea (will be typed)", key)
 			}
+			// Ctrl+Space opens the completion popup
+			if key == "ctrl+@" {
+				return p.updateCompletion(true)
+			}
+			// The textarea would silently drop the lines past its limit
+			if msg.Paste && editableLines(p.textarea.LineCount()+strings.Count(string(msg.Runes), "\n")) != nil {
+				offset := p.cursorOffset()
+				p.openOversized("paste", oldValue[:offset]+string(msg.Runes)+oldValue[offset:])
+				return nil
+			}
+
 			// In insert mode, pass all keys to textarea
 			p.textarea, cmd = p.textarea.Update(msg)
+			cmd = tea.Batch(cmd, p.completeAfterKey(msg))
+		}
+
+	case editor.NvimAttachedMsg, editor.NvimTextMsg, editor.NvimExecuteMsg, editor.NvimSyncErrorMsg, editor.NvimDetachedMsg:
+		cmd = p.handleNeovim(msg)
+
+	case editor.EditorSuccessMsg:
+		if p.current().script == nil {
+			if editableText(msg.Text) != nil {
+				p.openOversized("external", msg.Text)
+			} else {
+				p.SetQuery(msg.Text)
+			}
 		}
 
-	case deleteLineMsg:
-		p.deleteLine()
+	case editor.EditorErrorMsg:
+		p.setMessage(msg.Err.Error(), true)
+
+	case components.CommandMessageMsg:
+		p.setMessage(msg.Text, msg.IsError)
 
-	case yankLineMsg:
-		p.yankLine()
+	case executedFlashDoneMsg:
+		if p.executed != nil && p.executed.seq == msg.seq {
+			p.executed = nil
+		}
 
-	case gotoFirstLineMsg:
-		p.moveCursorToStart()
+	case completionColumnsLoadedMsg:
+		// Columns arrived, refresh the open popup
+		if p.completion.IsVisible() {
+			cmd = p.updateCompletion(false)
+		}
 
 	default:
 		// For non-key messages, always update textarea
@@ -147,192 +340,395 @@ func (p *EditorPanel) Update(msg tea.Msg) tea.Cmd {
 
 	// Validate query if text changed and linting is enabled
 	newValue := p.textarea.Value()
-	if p.enableLinting && oldValue != newValue {
-		p.validationResult = p.validator.Validate(newValue)
+	if oldValue != newValue {
+		p.serverError = nil
+		if p.enableLinting {
+			p.validationResult = p.validate(newValue)
+		}
+		p.syncNeovim()
 	}
 
 	return cmd
 }
 
-// handleNormalMode handles Vim normal mode keybindings
+// validate lints value. Buffers too large to lint on every change are
+// reported as valid.
+func (p *EditorPanel) validate(value string) db.ValidationResult {
+	if len(value) > maxLintBytes {
+		return db.ValidationResult{Valid: true}
+	}
+	return p.validator.Validate(value)
+}
+
+// handleNormalMode runs Vim normal and visual mode keys through the Vim
+// engine on the textarea contents
 func (p *EditorPanel) handleNormalMode(msg tea.KeyMsg) tea.Cmd {
-	var cmd tea.Cmd
+	key := msg.String()
 
-	switch msg.String() {
-	// Mode switching
-	case "i":
-		p.mode = ModeInsert
-		return nil
-	case "a":
-		p.mode = ModeInsert
-		// Send right arrow key to textarea to move after current char
-		p.textarea, cmd = p.textarea.Update(tea.KeyMsg{Type: tea.KeyRight})
-		return cmd
-
-	// Basic movement - pass through to textarea
-	case "h":
-		p.textarea, cmd = p.textarea.Update(tea.KeyMsg{Type: tea.KeyLeft})
-		return cmd
-	case "j":
-		p.textarea, cmd = p.textarea.Update(tea.KeyMsg{Type: tea.KeyDown})
-		return cmd
-	case "k":
-		p.textarea, cmd = p.textarea.Update(tea.KeyMsg{Type: tea.KeyUp})
-		return cmd
-	case "l":
-		p.textarea, cmd = p.textarea.Update(tea.KeyMsg{Type: tea.KeyRight})
-		return cmd
-	case "left":
-		p.textarea, cmd = p.textarea.Update(tea.KeyMsg{Type: tea.KeyLeft})
-		return cmd
-	case "down":
-		p.textarea, cmd = p.textarea.Update(tea.KeyMsg{Type: tea.KeyDown})
-		return cmd
-	case "up":
-		p.textarea, cmd = p.textarea.Update(tea.KeyMsg{Type: tea.KeyUp})
-		return cmd
-	case "right":
-		p.textarea, cmd = p.textarea.Update(tea.KeyMsg{Type: tea.KeyRight})
-		return cmd
-
-	// Line movement
-	case "0":
-		p.textarea, cmd = p.textarea.Update(tea.KeyMsg{Type: tea.KeyHome})
-		return cmd
-	case "$":
-		p.textarea, cmd = p.textarea.Update(tea.KeyMsg{Type: tea.KeyEnd})
-		return cmd
-
-	// Word movement (approximate with Ctrl+Left/Right)
-	case "w":
-		p.textarea, cmd = p.textarea.Update(tea.KeyMsg{Type: tea.KeyRight, Alt: true})
-		return cmd
-	case "b":
-		p.textarea, cmd = p.textarea.Update(tea.KeyMsg{Type: tea.KeyLeft, Alt: true})
-		return cmd
-
-	// Delete operations
-	case "d":
-		// Wait for next 'd' to delete line
-		return p.waitForSecondD()
-
-	// Yank (copy) operations
-	case "y":
-		// Wait for next 'y' to yank line
-		return p.waitForSecondY()
-
-	// Paste
-	case "p":
-		p.pasteLine()
-		return nil
+	if key == ":" && p.mode == ModeNormal && p.vim.Pending() == "" {
+		return p.cmdline.Open()
+	}
+
+	buf := p.buffer()
+	result := p.vim.HandleKey(buf, key)
+	p.applyBuffer(buf)
+	p.mode = editorModes[p.vim.Mode()]
+
+	if result.RegistersChanged && p.registerStore != nil {
+		if err := p.registerStore.Save(p.vim.Registers()); err != nil {
+			logDebug("saving registers: %v", err)
+		}
+	}
 
-	// Document movement
-	case "g":
-		return p.waitForSecondG()
-	case "G":
-		p.moveCursorToEnd()
+	switch result.Command {
+	case editor.CommandFormatStatement:
+		p.recordUndo(p.formatStatement)
+	}
+	if result.Handled {
 		return nil
 	}
 
+	// Editor commands outside of Vim
+	switch key {
+	case "ctrl+g":
+		// Jump to the current error
+		if err, ok := p.currentError(); ok && err.HasPosition() {
+			p.setCursorOffset(err.Offset)
+		}
+	case "ctrl+f":
+		p.recordUndo(p.formatBuffer)
+	}
 	return nil
 }
 
-// Vim operation helpers
-func (p *EditorPanel) deleteLine() {
-	// Get current content
-	value := p.textarea.Value()
-	lines := strings.Split(value, "\n")
+// buffer returns the textarea contents and cursor as a Vim buffer. The
+// buffer of the previous key is reused while the text is the same, so
+// moving around a large query doesn't split it into lines on every key.
+func (p *EditorPanel) buffer() *editor.Buffer {
+	text := p.textarea.Value()
+	if p.vimBuf == nil || p.vimBuf.Changed() || text != p.vimText {
+		p.vimBuf, p.vimText = editor.NewBuffer(text), text
+	}
+	p.vimBuf.SetCursor(p.cursorPosition())
+	return p.vimBuf
+}
 
-	if len(lines) == 0 {
-		return
+// applyBuffer copies a Vim buffer's text (if the engine changed it) and
+// cursor back to the textarea. A change making the text longer than the
+// textarea holds, e.g. a large put, is dropped.
+func (p *EditorPanel) applyBuffer(buf *editor.Buffer) {
+	if buf.Changed() {
+		text := buf.Text()
+		if err := editableText(text); err != nil {
+			p.vim.History().DropLast()
+			p.setMessage(err.Error(), true)
+			p.vimBuf = nil
+			return
+		}
+		p.textarea.SetValue(text)
+		p.vimBuf = nil
 	}
+	p.moveCursorTo(buf.Cursor())
+}
 
-	// For simplicity, delete first line and save to clipboard
-	// (Proper implementation would track cursor position)
-	if len(lines) > 0 {
-		p.clipboard = lines[0]
-		if len(lines) > 1 {
-			p.textarea.SetValue(strings.Join(lines[1:], "\n"))
-		} else {
-			p.textarea.SetValue("")
+// recordUndo runs an edit made outside of the Vim engine (formatting,
+// replacing the query) as its own undo step. An open insert session is
+// closed first and continues as a new step.
+func (p *EditorPanel) recordUndo(edit func()) {
+	history := p.vim.History()
+	history.Commit(p.buffer())
+	history.Begin(p.buffer())
+	edit()
+	history.Commit(p.buffer())
+	if p.mode == ModeInsert {
+		history.Begin(p.buffer())
+	}
+}
+
+// CapturesKey reports whether the editor uses key itself in its current
+// mode, so a global binding for the same key should be skipped. Ctrl+R is
+// redo in normal mode.
+func (p *EditorPanel) CapturesKey(key string) bool {
+	return key == "ctrl+r" && p.mode == ModeNormal && p.current().script == nil
+}
+
+// completeAfterKey updates the completion popup after a key was typed in insert mode
+func (p *EditorPanel) completeAfterKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyRunes:
+		if len(msg.Runes) == 1 && (isCompletionRune(msg.Runes[0]) || msg.Runes[0] == '.') {
+			return p.updateCompletion(false)
+		}
+	case tea.KeyBackspace:
+		if p.completion.IsVisible() {
+			return p.updateCompletion(false)
 		}
 	}
+	p.hideCompletion()
+	return nil
 }
 
-func (p *EditorPanel) yankLine() {
-	// Get current content
+// updateCompletion recomputes the candidates for the cursor position.
+// Unless forced, the popup only opens while a word or "qualifier." is being typed.
+func (p *EditorPanel) updateCompletion(force bool) tea.Cmd {
 	value := p.textarea.Value()
-	lines := strings.Split(value, "\n")
+	offset := p.cursorOffset()
 
-	// For simplicity, yank first line
-	if len(lines) > 0 {
-		p.clipboard = lines[0]
+	result := p.completer.Complete(value, offset)
+	afterDot := offset > 0 && value[offset-1] == '.'
+	if !force && result.Prefix == "" && !afterDot {
+		p.hideCompletion()
+		return nil
 	}
+
+	p.completionStart = result.Start
+	p.completionPrefix = result.Prefix
+	p.completion.Show(result.Items)
+	p.resizeTextarea()
+
+	if len(result.MissingColumns) > 0 && p.completionConn != nil {
+		return loadCompletionColumns(p.completionConn, p.completer, result.MissingColumns)
+	}
+	return nil
 }
 
-func (p *EditorPanel) pasteLine() {
-	if p.clipboard == "" {
+// acceptCompletion replaces the typed prefix with the selected candidate
+func (p *EditorPanel) acceptCompletion() {
+	item, ok := p.completion.Selected()
+	p.hideCompletion()
+	if !ok {
 		return
 	}
 
-	// Append clipboard content to the end
-	value := p.textarea.Value()
-	if value == "" {
-		p.textarea.SetValue(p.clipboard)
-	} else {
-		p.textarea.SetValue(value + "\n" + p.clipboard)
+	for range []rune(p.completionPrefix) {
+		p.textarea, _ = p.textarea.Update(tea.KeyMsg{Type: tea.KeyBackspace})
 	}
+	p.textarea.InsertString(item.Insert)
 }
 
-func (p *EditorPanel) moveCursorToStart() {
-	// Move to beginning of document
+// hideCompletion closes the popup and gives its space back to the textarea
+func (p *EditorPanel) hideCompletion() {
+	if p.completion.IsVisible() {
+		p.completion.Hide()
+		p.resizeTextarea()
+	}
+}
+
+// cursorOffset returns the byte offset of the cursor in the query text
+func (p *EditorPanel) cursorOffset() int {
+	lines := strings.Split(p.textarea.Value(), "\n")
+	row := p.textarea.Line()
+	if row >= len(lines) {
+		return len(p.textarea.Value())
+	}
+
+	offset := 0
+	for i := 0; i < row; i++ {
+		offset += len(lines[i]) + 1
+	}
+
+	info := p.textarea.LineInfo()
+	col := info.StartColumn + info.ColumnOffset
+	runes := []rune(lines[row])
+	if col > len(runes) {
+		col = len(runes)
+	}
+	return offset + len(string(runes[:col]))
+}
+
+// setCursorOffset moves the cursor to a byte offset in the query text
+func (p *EditorPanel) setCursorOffset(offset int) {
+	buf := editor.NewBuffer(p.textarea.Value())
+	p.moveCursorTo(buf.PositionAt(offset))
+}
+
+// cursorPosition returns the textarea cursor as a buffer position
+func (p *EditorPanel) cursorPosition() editor.Position {
+	info := p.textarea.LineInfo()
+	return editor.Position{Row: p.textarea.Line(), Col: info.StartColumn + info.ColumnOffset}
+}
+
+// moveCursorTo moves the textarea cursor to a buffer position
+func (p *EditorPanel) moveCursorTo(pos editor.Position) {
+	// CursorUp/Down move by wrapped line, so step until the logical row matches
+	for i := 0; p.textarea.Line() < pos.Row && i < 1<<20; i++ {
+		p.textarea.CursorDown()
+	}
+	for i := 0; p.textarea.Line() > pos.Row && i < 1<<20; i++ {
+		p.textarea.CursorUp()
+	}
+	p.textarea.SetCursor(pos.Col)
+	// Let the textarea scroll the cursor into view
+	p.textarea, _ = p.textarea.Update(nil)
+}
+
+// formatBuffer reformats the whole query
+func (p *EditorPanel) formatBuffer() {
 	value := p.textarea.Value()
-	lines := strings.Split(value, "\n")
+	formatted, err := p.formatter.Format(value)
+	p.applyFormat(value, formatted, 0, err)
+}
 
-	// Send up key multiple times to reach top
-	for i := 0; i < len(lines)*2; i++ { // *2 to ensure we reach top
-		p.textarea, _ = p.textarea.Update(tea.KeyMsg{Type: tea.KeyUp})
+// formatStatement reformats the statement under the cursor
+func (p *EditorPanel) formatStatement() {
+	value := p.textarea.Value()
+	tokens, err := db.Tokenize(value)
+	if err != nil {
+		p.applyFormat(value, value, 0, err)
+		return
 	}
-	// Then move to start of line
-	p.textarea, _ = p.textarea.Update(tea.KeyMsg{Type: tea.KeyHome})
+
+	start, end := db.StatementRange(tokens, len(value), p.cursorOffset())
+	formatted, err := p.formatter.FormatRange(value, start, end)
+	// Keep the cursor at the start of the statement
+	offset := start + len(value[start:end]) - len(strings.TrimLeft(value[start:end], " \t\r\n"))
+	p.applyFormat(value, formatted, offset, err)
 }
 
-func (p *EditorPanel) moveCursorToEnd() {
-	// Move to end of document
+// applyFormat replaces the query with its formatted version, or opens it
+// read-only when too long to edit. Formatting errors (invalid SQL) are
+// shown like validation errors.
+func (p *EditorPanel) applyFormat(value, formatted string, cursor int, err error) {
+	if err != nil {
+		p.validationResult = p.validate(value)
+		return
+	}
+	if formatted == value {
+		return
+	}
+	if editableText(formatted) != nil {
+		p.openOversized("format", formatted)
+		return
+	}
+	p.textarea.SetValue(formatted)
+	p.serverError = nil
+	p.setCursorOffset(cursor)
+	if p.enableLinting {
+		p.validationResult = p.validate(formatted)
+	}
+}
+
+// ShowServerError shows an error returned by the server when executing the
+// query text starting at byte offset start of the editor buffer
+func (p *EditorPanel) ShowServerError(err error, start int) {
+	if p.current().script != nil {
+		return // Positions refer to a part of the script read from disk
+	}
 	value := p.textarea.Value()
-	lines := strings.Split(value, "\n")
+	if start < 0 || start > len(value) {
+		start = 0
+	}
 
-	// Send down key multiple times to reach bottom
-	for i := 0; i < len(lines)*2; i++ { // *2 to ensure we reach bottom
-		p.textarea, _ = p.textarea.Update(tea.KeyMsg{Type: tea.KeyDown})
+	verr, ok := db.ServerError(value[start:], err)
+	if !ok {
+		return
+	}
+	if verr.HasPosition() {
+		verr.Offset += start
+		verr.Line, verr.Column = db.OffsetToLineColumn(value, verr.Offset)
 	}
-	// Then move to end of line
-	p.textarea, _ = p.textarea.Update(tea.KeyMsg{Type: tea.KeyEnd})
+	verr.Query = value
+	p.serverError = &verr
 }
 
-// Command handlers for multi-key sequences
-func (p *EditorPanel) waitForSecondD() tea.Cmd {
-	return func() tea.Msg {
-		return deleteLineMsg{}
+// ClearServerError removes the last execution error
+func (p *EditorPanel) ClearServerError() {
+	p.serverError = nil
+}
+
+// currentError returns the error to show: the execution error if there is
+// one, otherwise the first validation error
+func (p *EditorPanel) currentError() (db.ValidationError, bool) {
+	if p.serverError != nil {
+		return *p.serverError, true
+	}
+	if p.enableLinting && !p.validationResult.Valid && len(p.validationResult.Errors) > 0 {
+		return p.validationResult.Errors[0], true
 	}
+	return db.ValidationError{}, false
 }
 
-func (p *EditorPanel) waitForSecondY() tea.Cmd {
-	return func() tea.Msg {
-		return yankLineMsg{}
+// editorMarker is a gutter marker with an underlined span of the query
+type editorMarker struct {
+	line   int // 1-based
+	offset int // Byte offset in the query
+	length int
+	symbol string
+	color  lipgloss.Color
+}
+
+// markers returns the gutter markers for the current error and lint issues,
+// most severe first
+func (p *EditorPanel) markers() []editorMarker {
+	var markers []editorMarker
+	if err, ok := p.currentError(); ok && err.HasPosition() {
+		markers = append(markers, editorMarker{err.Line, err.Offset, err.Length, "●", lipgloss.Color("196")})
 	}
+	if !p.enableLinting {
+		return markers
+	}
+
+	for _, severity := range []db.LintSeverity{db.SeverityError, db.SeverityWarning, db.SeverityInfo} {
+		for _, issue := range p.validationResult.Issues {
+			if issue.Severity == severity {
+				symbol, color := lintMarkerStyle(severity)
+				markers = append(markers, editorMarker{issue.Line, issue.Offset, issue.Length, symbol, color})
+			}
+		}
+	}
+	return markers
 }
 
-func (p *EditorPanel) waitForSecondG() tea.Cmd {
+// lintMarkerStyle returns the gutter symbol and color for a lint severity
+func lintMarkerStyle(severity db.LintSeverity) (string, lipgloss.Color) {
+	switch severity {
+	case db.SeverityError:
+		return "●", lipgloss.Color("196")
+	case db.SeverityWarning:
+		return "▲", lipgloss.Color("214")
+	default:
+		return "ℹ", lipgloss.Color("39")
+	}
+}
+
+// isCompletionRune reports whether typing r continues a completable word
+func isCompletionRune(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// loadCompletionColumns loads columns of tables in scope into the metadata cache
+func loadCompletionColumns(conn db.Connection, completer *db.Completer, tables []db.TableRef) tea.Cmd {
 	return func() tea.Msg {
-		return gotoFirstLineMsg{}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		for _, table := range tables {
+			columns, err := conn.GetTableColumns(ctx, table.Schema, table.Name)
+			if err != nil {
+				continue
+			}
+			completer.CacheColumns(table.Schema, table.Name, columns)
+		}
+		return completionColumnsLoadedMsg{}
 	}
 }
 
-// Message types for Vim operations
-type deleteLineMsg struct{}
-type yankLineMsg struct{}
-type gotoFirstLineMsg struct{}
+// completionColumnsLoadedMsg is sent when columns for completion were loaded
+type completionColumnsLoadedMsg struct{}
+
+// visibleLines returns the range of lines in view, scrolling the active
+// buffer just enough to keep the cursor line visible
+func (p *EditorPanel) visibleLines(cursorRow, lineCount int) (int, int) {
+	b := p.current()
+	height := max(1, p.textarea.Height())
+	if cursorRow < b.scrollTop {
+		b.scrollTop = cursorRow
+	} else if cursorRow >= b.scrollTop+height {
+		b.scrollTop = cursorRow - height + 1
+	}
+	b.scrollTop = max(0, min(b.scrollTop, lineCount-height))
+	return b.scrollTop, min(lineCount, b.scrollTop+height)
+}
 
 // View renders the editor panel with mode indicator
 func (p *EditorPanel) View() string {
@@ -347,64 +743,174 @@ func (p *EditorPanel) View() string {
 
 	// Show mode indicator
 	modeIndicator := ""
-	if p.mode == ModeNormal {
-		modeIndicator = " -- NORMAL --"
-	} else {
+	switch p.mode {
+	case ModeInsert:
 		modeIndicator = " -- INSERT --"
+	case ModeVisual:
+		modeIndicator = " -- VISUAL --"
+	case ModeVisualLine:
+		modeIndicator = " -- VISUAL LINE --"
+	case ModeVisualBlock:
+		modeIndicator = " -- VISUAL BLOCK --"
+	default:
+		modeIndicator = " -- NORMAL --"
+	}
+	if pending := p.vim.Pending(); pending != "" && p.mode != ModeInsert {
+		modeIndicator += " " + pending
 	}
 
 	// Status bar with query stats and validation
 	queryText := p.textarea.Value()
-	lineCount := len(strings.Split(queryText, "\n"))
+	lineCount := strings.Count(queryText, "\n") + 1
 	charCount := len(queryText)
+	if script := p.current().script; script != nil {
+		lineCount, charCount = script.LineCount(), int(script.Size())
+	}
 
 	statusBar := ""
-	if p.enableLinting {
-		if !p.validationResult.Valid && len(p.validationResult.Errors) > 0 {
-			// Show error
-			err := p.validationResult.Errors[0]
-			statusBar = fmt.Sprintf(" %s Syntax Error (Line %d): %s",
-				errorStyle.Render("✗"),
-				err.Line,
-				err.Message)
-		} else if queryText != "" {
-			// Show success
-			statusBar = fmt.Sprintf(" %s Valid SQL", successStyle.Render("✓"))
+	currentErr, hasErr := p.currentError()
+	if hasErr {
+		label := "Syntax Error"
+		if p.serverError != nil {
+			label = "Error"
+		}
+		position := fmt.Sprintf("Line %d", currentErr.Line)
+		if currentErr.HasPosition() {
+			position = fmt.Sprintf("Line %d, Col %d", currentErr.Line, currentErr.Column)
 		}
+		statusBar = fmt.Sprintf(" %s %s (%s): %s",
+			errorStyle.Render("✗"),
+			label,
+			position,
+			currentErr.Message)
+	} else if p.enableLinting && len(p.validationResult.Issues) > 0 {
+		// Show the most severe lint issue
+		issue := p.validationResult.Issues[0]
+		for _, other := range p.validationResult.Issues {
+			if other.Severity > issue.Severity {
+				issue = other
+			}
+		}
+		symbol, color := lintMarkerStyle(issue.Severity)
+		statusBar = fmt.Sprintf(" %s %s (Line %d): %s",
+			lipgloss.NewStyle().Foreground(color).Bold(true).Render(symbol),
+			issue.Rule,
+			issue.Line,
+			issue.Message)
+		if more := len(p.validationResult.Issues) - 1; more > 0 {
+			statusBar += statusStyle.Render(fmt.Sprintf(" (+%d more)", more))
+		}
+	} else if p.enableLinting && len(queryText) > maxLintBytes {
+		statusBar = statusStyle.Render(" Linting off for large scripts")
+	} else if p.enableLinting && queryText != "" {
+		// Show success
+		statusBar = fmt.Sprintf(" %s Valid SQL", successStyle.Render("✓"))
 	}
 
 	statsInfo := statusStyle.Render(fmt.Sprintf(" | %d lines | %d chars", lineCount, charCount))
 
-	// Header
-	content := "QUERY EDITOR" + modeIndicator + statusBar + statsInfo + "\n\n"
-
-	// Get query text for display
-	editorView := p.textarea.View()
+	// Header and tab bar
+	content := "QUERY EDITOR" + modeIndicator + statusBar + statsInfo + "\n" + p.renderBufferTabs() + "\n"
 
-	// Apply syntax highlighting if enabled
-	if p.enableHighlight && queryText != "" {
-		highlighted, err := p.highlighter.Highlight(queryText)
-		if err == nil {
-			// Replace textarea content with highlighted version
-			// We need to preserve the textarea structure but with highlighted content
-			lines := strings.Split(highlighted, "\n")
-			editorView = strings.Join(lines, "\n")
-		}
+	var editorView string
+	if p.current().script != nil {
+		editorView = p.renderScript()
+	} else if queryText != "" {
+		editorView = p.renderText(queryText)
+	} else {
+		// The textarea shows the placeholder
+		editorView = p.textarea.View()
 	}
 
 	content += editorView
 
+	// Completion popup below the editor
+	if p.completion.IsVisible() {
+		content += "\n" + p.completion.View()
+	}
+
+	content += "\n" + p.cmdline.View()
+
 	return content
 }
 
+// QueryToExecute returns the query to send to the server: the visual
+// selection, or else the statement under the cursor (statement boundaries
+// come from the parser). With all, the whole buffer is returned. start is
+// the byte offset of the query in the buffer, for ShowServerError. The
+// returned command ends the brief highlight of the executed range.
+func (p *EditorPanel) QueryToExecute(all bool) (query string, start int, cmd tea.Cmd) {
+	if p.current().script != nil {
+		return p.scriptQuery(all), 0, nil
+	}
+	value := p.textarea.Value()
+	end := len(value)
+
+	sel, selected := p.vim.Selection(p.cursorPosition())
+	if selected && !all {
+		start, end = selectionOffsets(p.buffer(), sel)
+		// Executing a selection leaves visual mode like an operator does
+		p.vim.SetMode(editor.ModeNormal)
+		p.mode = ModeNormal
+	} else if !all {
+		stmt, found := db.StatementAt(value, p.cursorOffset())
+		if !found {
+			return "", 0, nil
+		}
+		start, end = stmt.Start, stmt.End
+	}
+
+	query = value[start:end]
+	if strings.TrimSpace(query) == "" {
+		return "", 0, nil
+	}
+
+	return query, start, p.flashExecuted(start, end)
+}
+
+// flashExecuted highlights the executed byte range [start, end) briefly
+func (p *EditorPanel) flashExecuted(start, end int) tea.Cmd {
+	p.executedSeq++
+	p.executed = &executedRange{start: start, end: end, seq: p.executedSeq}
+	seq := p.executedSeq
+	return tea.Tick(executedFlashDuration, func(time.Time) tea.Msg {
+		return executedFlashDoneMsg{seq: seq}
+	})
+}
+
+// selectionOffsets converts a visual selection to a byte range of the
+// buffer. A block selection runs from its first to its last character.
+func selectionOffsets(buf *editor.Buffer, sel editor.Selection) (int, int) {
+	start := buf.Clamp(sel.Start, true)
+	end := buf.Clamp(sel.End, true)
+	if sel.Mode == editor.ModeVisualLine {
+		start.Col, end.Col = 0, buf.LineLen(end.Row)
+	} else if end.Col < buf.LineLen(end.Row) {
+		end.Col++
+	}
+	return buf.Offset(start), buf.Offset(end)
+}
+
 // GetQuery returns the current query text
 func (p *EditorPanel) GetQuery() string {
 	return p.textarea.Value()
 }
 
-// SetQuery sets the query text
-func (p *EditorPanel) SetQuery(query string) {
-	p.textarea.SetValue(query)
+// SetQuery sets the query text. Replacing the query can be undone. Text
+// longer than the editor holds is refused.
+func (p *EditorPanel) SetQuery(query string) error {
+	if err := editableText(query); err != nil {
+		return err
+	}
+	p.recordUndo(func() {
+		p.textarea.SetValue(query)
+	})
+	p.serverError = nil
+	if p.enableLinting {
+		p.validationResult = p.validate(query)
+	}
+	p.syncNeovim()
+	return nil
 }
 
 // Focus sets focus on the textarea
@@ -420,7 +926,13 @@ func (p *EditorPanel) Blur() {
 // Help returns help text for the editor panel
 func (p *EditorPanel) Help() string {
 	if p.mode == ModeNormal {
-		return "[Ctrl-R] Execute  [F2] Save  [Ctrl-E] Neovim  [i/a] Insert  [hjkl] Move  [dd] Delete  [yy] Yank  [p] Paste"
+		return "[Alt-R] Run all  [F2] Save  [Ctrl-E] External editor  [i/a/o] Insert  [v/V/Ctrl-V] Visual  [hjkl/w/b/e] Move  [d/c/y]+motion  [p] Paste  [u/Ctrl-R] Undo/Redo  [:e/:w/:bn/:bp] Buffers  [\"x] Register  [gq] Format statement  [Ctrl-F] Format all  [Ctrl-G] Go to error"
+	}
+	if p.mode != ModeInsert {
+		return "[Ctrl-R] Run selection  [hjkl/w/b/e] Extend  [o] Other end  [d/c/y] Cut/Change/Yank  [p] Replace  [~/u/U] Case  [I/A] Block insert  [\"+y] Copy to clipboard  [ESC] Normal mode"
+	}
+	if p.completion.IsVisible() {
+		return "[Tab/Enter] Accept  [↑↓] Select  [ESC] Close completion"
 	}
-	return "[Ctrl-R] Execute  [F2] Save  [Ctrl-E] Neovim  [ESC] Normal mode"
+	return "[Ctrl-R] Run statement  [Alt-R] Run all  [F2] Save  [Ctrl-E] External editor  [Ctrl-Space] Complete  [ESC] Normal mode"
 }
@@ -0,0 +1,565 @@
+package panels
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/editor"
+	"github.com/MachineLearning-Nerd/lazydb/internal/storage"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// maxEditableLines is the most lines the textarea holds, a limit fixed
+	// by the bubbles textarea. Longer text is never cut: files, pastes and
+	// formatted or externally edited text open read-only through a
+	// storage.ScriptFile instead.
+	maxEditableLines = 10000
+	// streamScriptBytes is the file size from which a file opens read-only
+	// without loading it into the textarea
+	streamScriptBytes = 2 << 20
+	// maxLintBytes is the longest buffer linted on every change
+	maxLintBytes = 256 << 10
+	// scriptStatementLines is how far around the cursor line the statement
+	// to run is looked for in a read-only script
+	scriptStatementLines = 1000
+)
+
+// queryBuffer is one open query in the editor. The active buffer's editing
+// state lives in the EditorPanel fields and is stashed here while another
+// buffer is shown.
+type queryBuffer struct {
+	name       string // File name in the queries directory, empty for a scratch buffer
+	connection string // Connection the buffer runs against, empty for the active one
+	saved      string // Text at the last load or save, for the dirty marker
+
+	textarea         textarea.Model
+	history          *editor.History
+	validationResult db.ValidationResult
+	serverError      *db.ValidationError
+	lastResult       *db.QueryResult // Result of the last query run from the buffer
+	scrollTop        int             // First line shown
+	scrollLeft       int             // First display column shown
+
+	script    *storage.ScriptFile // Large file shown read-only, instead of the textarea
+	scriptRow int                 // Cursor line in the script
+}
+
+// editableText checks that text fits in the textarea, whose SetValue drops
+// the lines past maxEditableLines without a word
+func editableText(text string) error {
+	return editableLines(strings.Count(text, "\n") + 1)
+}
+
+// editableLines checks that a number of lines fits in the textarea
+func editableLines(lines int) error {
+	if lines > maxEditableLines {
+		return fmt.Errorf("%s lines is more than the editor's %s, save the text to a file and open it with :view",
+			components.FormatCount(lines), components.FormatCount(maxEditableLines))
+	}
+	return nil
+}
+
+// saveOversized saves text too long for the textarea to a new file in the
+// queries directory, named after prefix, and returns its path
+func saveOversized(prefix, text string) (string, error) {
+	name := fmt.Sprintf("%s-%s.sql", prefix, time.Now().Format("20060102-150405"))
+	if err := storage.SaveQuery(text, name); err != nil {
+		return "", err
+	}
+	queriesDir, err := storage.GetQueriesDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(queriesDir, name), nil
+}
+
+// openOversized shows text too long for the textarea read-only in a new
+// buffer, from a file saved for it, so that none of it is lost
+func (p *EditorPanel) openOversized(prefix, text string) {
+	path, err := saveOversized(prefix, text)
+	if err != nil {
+		p.setMessage(fmt.Sprintf("%v; the text was not kept", err), true)
+		return
+	}
+	if err := p.OpenScript(path); err != nil {
+		p.setMessage(err.Error(), true)
+		return
+	}
+	p.setMessage(fmt.Sprintf("Too long to edit, opened read-only from %s", path), false)
+}
+
+// newQueryTextarea creates the textarea of a buffer
+func newQueryTextarea(value string) textarea.Model {
+	ta := textarea.New()
+	ta.Placeholder = "Enter SQL query here... (Press ESC for Vim Normal mode, i for Insert mode)"
+	ta.ShowLineNumbers = true
+	ta.CharLimit = 0                // No limit, long scripts are normal
+	ta.MaxHeight = maxEditableLines // The default of 99 stops new lines early
+	ta.SetValue(value)
+	return ta
+}
+
+// displayName returns the buffer name shown in the tab bar
+func (b *queryBuffer) displayName() string {
+	if b.script != nil {
+		return filepath.Base(b.script.Path())
+	}
+	if b.name == "" {
+		return "[No Name]"
+	}
+	return b.name
+}
+
+// current returns the active buffer
+func (p *EditorPanel) current() *queryBuffer {
+	return p.buffers[p.active]
+}
+
+// stashBuffer saves the active buffer's editing state
+func (p *EditorPanel) stashBuffer() {
+	b := p.current()
+	b.textarea = p.textarea
+	b.history = p.vim.History()
+	b.validationResult = p.validationResult
+	b.serverError = p.serverError
+}
+
+// showBuffer makes buffer i the active one. Insert and visual mode end
+// first, like leaving a window in Vim.
+func (p *EditorPanel) showBuffer(i int) {
+	if p.mode == ModeInsert {
+		buf := p.buffer()
+		p.vim.EndInsert(buf)
+		p.applyBuffer(buf)
+	}
+	p.vim.SetMode(editor.ModeNormal)
+	p.mode = ModeNormal
+	p.hideCompletion()
+
+	focused := p.textarea.Focused()
+	p.stashBuffer()
+	p.active = i
+
+	b := p.current()
+	p.textarea = b.textarea
+	p.vim.SetHistory(b.history)
+	p.validationResult = b.validationResult
+	p.serverError = b.serverError
+	p.executed = nil
+
+	if focused {
+		p.textarea.Focus()
+	} else {
+		p.textarea.Blur()
+	}
+	if p.width > 4 {
+		p.textarea.SetWidth(p.width - 2)
+	}
+	p.resizeTextarea()
+}
+
+// addBuffer opens a new buffer holding text and shows it
+func (p *EditorPanel) addBuffer(name, text string) {
+	b := &queryBuffer{
+		name:     name,
+		saved:    text,
+		textarea: newQueryTextarea(text),
+		history:  editor.NewHistory(editor.DefaultHistoryLimit),
+	}
+	if p.enableLinting {
+		b.validationResult = p.validate(text)
+	}
+	p.buffers = append(p.buffers, b)
+	p.showBuffer(len(p.buffers) - 1)
+}
+
+// NewBuffer opens an empty scratch buffer
+func (p *EditorPanel) NewBuffer() {
+	p.addBuffer("", "")
+}
+
+// OpenBuffer shows the buffer of a file in the queries directory, loading
+// it if it isn't open yet. A file that doesn't exist yet opens empty and is
+// created by the first save.
+func (p *EditorPanel) OpenBuffer(name string) error {
+	name, err := queryFileName(name)
+	if err != nil {
+		return err
+	}
+	for i, b := range p.buffers {
+		if b.name == name {
+			p.showBuffer(i)
+			return nil
+		}
+	}
+
+	// Files too large for the textarea open read-only
+	queriesDir, err := storage.GetQueriesDir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(queriesDir, name)
+	if info, err := os.Stat(path); err == nil && info.Size() > streamScriptBytes {
+		return p.OpenScript(path)
+	}
+
+	text, err := storage.LoadQuery(name)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if editableText(text) != nil {
+		return p.OpenScript(path)
+	}
+	p.addBuffer(name, text)
+	return nil
+}
+
+// OpenScript shows the file at path read-only, streaming the lines in view
+// from disk instead of loading it into the textarea. This is meant for
+// dumps and migrations too large to edit.
+func (p *EditorPanel) OpenScript(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	for i, b := range p.buffers {
+		if b.script != nil && b.script.Path() == path {
+			p.showBuffer(i)
+			return nil
+		}
+	}
+
+	script, err := storage.OpenScript(path)
+	if err != nil {
+		return err
+	}
+	p.addBuffer("", "")
+	p.current().script = script
+	p.validationResult = db.ValidationResult{Valid: true}
+	return nil
+}
+
+// IsReadOnly reports whether the active buffer is a read-only script
+func (p *EditorPanel) IsReadOnly() bool {
+	return p.current().script != nil
+}
+
+// SaveBuffer writes the active buffer to its file. A name saves the buffer
+// under that file name (and names a scratch buffer).
+func (p *EditorPanel) SaveBuffer(name string) error {
+	b := p.current()
+	if b.script != nil {
+		return fmt.Errorf("%s is read-only", b.displayName())
+	}
+	if name != "" {
+		var err error
+		if name, err = queryFileName(name); err != nil {
+			return err
+		}
+		for i, other := range p.buffers {
+			if i != p.active && other.name == name {
+				return fmt.Errorf("%s is open in another buffer", name)
+			}
+		}
+		b.name = name
+	}
+	if b.name == "" {
+		return errors.New("no file name (use :w <name>)")
+	}
+
+	text := p.textarea.Value()
+	if err := storage.SaveQuery(text, b.name); err != nil {
+		return err
+	}
+	b.saved = text
+	return nil
+}
+
+// NextBuffer shows the next buffer, wrapping around
+func (p *EditorPanel) NextBuffer() {
+	p.showBuffer((p.active + 1) % len(p.buffers))
+}
+
+// PrevBuffer shows the previous buffer, wrapping around
+func (p *EditorPanel) PrevBuffer() {
+	p.showBuffer((p.active + len(p.buffers) - 1) % len(p.buffers))
+}
+
+// CloseBuffer closes the active buffer. Unsaved changes are only dropped
+// with force. Closing the last buffer leaves an empty scratch buffer.
+func (p *EditorPanel) CloseBuffer(force bool) error {
+	if p.IsDirty() && !force {
+		return fmt.Errorf("%s has unsaved changes (add ! to discard them)", p.current().displayName())
+	}
+
+	closing := p.active
+	if p.current() == p.nvimBuffer {
+		p.detachNeovim()
+	}
+	if len(p.buffers) == 1 {
+		p.NewBuffer()
+	} else if closing == len(p.buffers)-1 {
+		p.PrevBuffer()
+	} else {
+		p.NextBuffer()
+	}
+
+	p.buffers = append(p.buffers[:closing], p.buffers[closing+1:]...)
+	if p.active > closing {
+		p.active--
+	}
+	return nil
+}
+
+// IsDirty reports whether the active buffer has unsaved changes
+func (p *EditorPanel) IsDirty() bool {
+	return p.current().script == nil && p.textarea.Value() != p.current().saved
+}
+
+// BufferName returns the file name of the active buffer, empty for a scratch buffer
+func (p *EditorPanel) BufferName() string {
+	return p.current().name
+}
+
+// SetConnectionManager sets the connections buffers can be bound to
+func (p *EditorPanel) SetConnectionManager(cm *db.ConnectionManager) {
+	p.connMgr = cm
+}
+
+// BindConnection makes the active buffer always run against the named
+// connection, whatever the active connection is. An empty name removes the
+// binding.
+func (p *EditorPanel) BindConnection(name string) error {
+	if name != "" {
+		if p.connMgr == nil {
+			return errors.New("no connections available")
+		}
+		if _, err := p.connMgr.GetConnection(name); err != nil {
+			return err
+		}
+	}
+	p.current().connection = name
+	return nil
+}
+
+// BufferConnection returns the connection the active buffer runs against:
+// its bound connection, or else the active connection. A bound connection
+// that no longer exists is an error rather than a silent fallback.
+func (p *EditorPanel) BufferConnection() (db.Connection, error) {
+	if p.connMgr == nil {
+		return nil, errors.New("no connections available")
+	}
+	if name := p.current().connection; name != "" {
+		return p.connMgr.GetConnection(name)
+	}
+	return p.connMgr.GetActive()
+}
+
+// SetLastResult records the result of a query run from the active buffer,
+// so it can be shown again when switching back to the buffer. DDL in the
+// query invalidates the cached metadata of the connection it ran on.
+func (p *EditorPanel) SetLastResult(result db.QueryResult) {
+	p.current().lastResult = &result
+	if p.metaCache != nil {
+		name := p.current().connection
+		if name == "" {
+			name = p.metaConnName
+		}
+		p.metaCache.ObserveQuery(name, result.Query)
+	}
+}
+
+// LastResult returns the result of the last query run from the active buffer
+func (p *EditorPanel) LastResult() (db.QueryResult, bool) {
+	if r := p.current().lastResult; r != nil {
+		return *r, true
+	}
+	return db.QueryResult{}, false
+}
+
+// SessionBuffers returns the open buffers for the session file, keeping at
+// most maxResultRows rows of each last result, and the active buffer index
+func (p *EditorPanel) SessionBuffers(maxResultRows int) ([]storage.SessionBuffer, int) {
+	p.stashBuffer()
+
+	buffers := make([]storage.SessionBuffer, len(p.buffers))
+	for i, b := range p.buffers {
+		info := b.textarea.LineInfo()
+		buffers[i] = storage.SessionBuffer{
+			Name:       b.name,
+			Text:       b.textarea.Value(),
+			Connection: b.connection,
+			CursorRow:  b.textarea.Line(),
+			CursorCol:  info.StartColumn + info.ColumnOffset,
+		}
+		if b.script != nil {
+			buffers[i].Script = b.script.Path()
+			buffers[i].CursorRow, buffers[i].CursorCol = b.scriptRow, 0
+		}
+		if b.lastResult != nil {
+			buffers[i].LastResult = storage.NewSessionResult(*b.lastResult, maxResultRows)
+		}
+	}
+	return buffers, p.active
+}
+
+// RestoreSession replaces the open buffers with those of a saved session.
+// Unsaved changes come back as unsaved: a file buffer is compared with the
+// file on disk. A binding to a connection that no longer exists is dropped,
+// and so is a read-only script that can't be opened anymore. Text too long
+// for the editor is saved to a file and comes back read-only.
+func (p *EditorPanel) RestoreSession(buffers []storage.SessionBuffer, active int) {
+	var restored []*queryBuffer
+	var cursors []editor.Position
+	for i, sb := range buffers {
+		var script *storage.ScriptFile
+		if sb.Script != "" {
+			var err error
+			if script, err = storage.OpenScript(sb.Script); err != nil {
+				if i < active {
+					active--
+				}
+				continue
+			}
+		}
+
+		saved := sb.Text
+		if sb.Name != "" {
+			if text, err := storage.LoadQuery(sb.Name); err == nil {
+				saved = text
+			} else {
+				saved = ""
+			}
+		}
+
+		// Unsaved text too long for the textarea comes back read-only
+		if script == nil && editableText(sb.Text) != nil {
+			prefix := strings.TrimSuffix(sb.Name, filepath.Ext(sb.Name))
+			if prefix == "" {
+				prefix = "session"
+			}
+			path, err := saveOversized(prefix, sb.Text)
+			if err == nil {
+				script, err = storage.OpenScript(path)
+			}
+			if err != nil {
+				logDebug("restoring a buffer of %d bytes: %v", len(sb.Text), err)
+				if i < active {
+					active--
+				}
+				continue
+			}
+			sb.Name, sb.Text, saved = "", "", ""
+		}
+
+		b := &queryBuffer{
+			name:     sb.Name,
+			saved:    saved,
+			textarea: newQueryTextarea(sb.Text),
+			history:  editor.NewHistory(editor.DefaultHistoryLimit),
+			script:   script,
+		}
+		if script != nil {
+			b.scriptRow = min(max(0, sb.CursorRow), script.LineCount()-1)
+		}
+		if sb.Connection != "" && p.connMgr != nil {
+			if _, err := p.connMgr.GetConnection(sb.Connection); err == nil {
+				b.connection = sb.Connection
+			}
+		}
+		if sb.LastResult != nil {
+			result := sb.LastResult.QueryResult()
+			b.lastResult = &result
+		}
+		if p.enableLinting {
+			b.validationResult = p.validate(sb.Text)
+		}
+		restored = append(restored, b)
+		cursors = append(cursors, editor.Position{Row: sb.CursorRow, Col: sb.CursorCol})
+	}
+	if len(restored) == 0 {
+		return
+	}
+	focused := p.textarea.Focused()
+	p.buffers = restored
+
+	// Restore the cursors through the panel, which owns the textarea while
+	// a buffer is shown
+	p.active = 0
+	first := p.buffers[0]
+	p.textarea = first.textarea
+	if focused {
+		p.textarea.Focus()
+	}
+	p.vim.SetHistory(first.history)
+	p.validationResult = first.validationResult
+	p.serverError = nil
+	for i, cursor := range cursors {
+		p.showBuffer(i)
+		p.moveCursorTo(cursor)
+	}
+	if active < 0 || active >= len(p.buffers) {
+		active = 0
+	}
+	p.showBuffer(active)
+}
+
+// queryFileName checks a buffer file name, which must name a file directly
+// in the queries directory, and adds the .sql extension
+func queryFileName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("no file name")
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("%q: buffers are files in the queries directory, use a plain file name", name)
+	}
+	if filepath.Ext(name) != ".sql" {
+		name += ".sql"
+	}
+	return name, nil
+}
+
+// renderBufferTabs renders the tab bar with a * for unsaved buffers and
+// the bound connection after an @
+func (p *EditorPanel) renderBufferTabs() string {
+	activeStyle := lipgloss.NewStyle().Reverse(true).Bold(true)
+	inactiveStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+
+	tabs := make([]string, len(p.buffers))
+	for i, b := range p.buffers {
+		label := fmt.Sprintf(" %d %s", i+1, b.displayName())
+		dirty := b.textarea.Value() != b.saved
+		if i == p.active {
+			dirty = p.IsDirty()
+		}
+		if dirty {
+			label += "*"
+		}
+		if b.script != nil {
+			label += " [RO]"
+		}
+		if p.nvim != nil && b == p.nvimBuffer {
+			label += " [nvim]"
+		}
+		if b.connection != "" {
+			label += " @" + b.connection
+		}
+		label += " "
+
+		if i == p.active {
+			tabs[i] = activeStyle.Render(label)
+		} else {
+			tabs[i] = inactiveStyle.Render(label)
+		}
+	}
+	return strings.Join(tabs, "│")
+}
@@ -0,0 +1,351 @@
+package panels
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/storage"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ExecuteQueryMsg asks the application to run a query built by a command,
+// as if it had been run from the editor
+type ExecuteQueryMsg struct {
+	Query string
+}
+
+// explainOptions are the EXPLAIN options offered by :explain completion
+var explainOptions = []string{"analyze", "verbose", "costs", "settings", "buffers", "wal", "timing", "summary", "format", "serialize"}
+
+// explainOptionList builds the option list of EXPLAIN from :explain's
+// fields. A field that isn't an option name is the value of the option
+// before it, e.g. format json or analyze off.
+func explainOptionList(fields []string) (string, error) {
+	errFormat := errors.New("format needs a value, e.g. format json")
+	var options []string
+	option, valued := "", true
+	for _, field := range fields {
+		name := strings.ToLower(field)
+		if !valued && !slices.Contains(explainOptions, name) {
+			options[len(options)-1] += " " + strings.ToUpper(field)
+			valued = true
+			continue
+		}
+		if !valued && option == "format" {
+			return "", errFormat
+		}
+		options = append(options, strings.ToUpper(field))
+		option, valued = name, false
+	}
+	if !valued && option == "format" {
+		return "", errFormat
+	}
+	return strings.Join(options, ", "), nil
+}
+
+// editorOption is an option changed with :set
+type editorOption struct {
+	get func(p *EditorPanel) bool
+	set func(p *EditorPanel, on bool)
+}
+
+// editorOptions are the :set options by name
+var editorOptions = map[string]editorOption{
+	"syntax": {
+		get: func(p *EditorPanel) bool { return p.enableHighlight },
+		set: func(p *EditorPanel, on bool) { p.enableHighlight = on },
+	},
+	"lint": {
+		get: func(p *EditorPanel) bool { return p.enableLinting },
+		set: func(p *EditorPanel, on bool) {
+			p.enableLinting = on
+			if on {
+				p.validationResult = p.validate(p.textarea.Value())
+			}
+		},
+	},
+}
+
+// SetCommandRegistry makes the command line run commands from a registry
+// shared with the rest of the application, and adds the editor's commands
+// to it
+func (p *EditorPanel) SetCommandRegistry(registry *components.CommandRegistry) {
+	p.RegisterCommands(registry)
+	p.cmdline.SetRegistry(registry)
+}
+
+// CommandLine returns the ":" command line shown below the editor
+func (p *EditorPanel) CommandLine() *components.CommandLine {
+	return p.cmdline
+}
+
+// OpenCommandLine starts typing a command, e.g. when ":" is pressed in
+// another panel
+func (p *EditorPanel) OpenCommandLine() tea.Cmd {
+	return p.cmdline.Open()
+}
+
+// setMessage shows a message in the command line row until the next key
+func (p *EditorPanel) setMessage(msg string, isError bool) {
+	p.cmdline.SetMessage(msg, isError)
+}
+
+// RegisterCommands adds the editor's commands to a registry
+func (p *EditorPanel) RegisterCommands(registry *components.CommandRegistry) {
+	queryFiles := components.CompleteFrom(func() []string {
+		files, _ := storage.ListQueries()
+		return files
+	})
+
+	registry.Register(components.Command{
+		Name: "edit", Aliases: []string{"e"}, Usage: "<file>",
+		Help:     "Open a query file in a buffer",
+		Complete: queryFiles,
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if args.Arg == "" {
+				return nil, errors.New("no file name")
+			}
+			return nil, p.OpenBuffer(args.Arg)
+		},
+	})
+	registry.Register(components.Command{
+		Name: "write", Aliases: []string{"w"}, Usage: "[file]",
+		Help:     "Save the buffer, or save it as file",
+		Complete: queryFiles,
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			return nil, p.writeBuffer(args.Arg)
+		},
+	})
+	registry.Register(components.Command{
+		Name: "wq", Usage: "[file]",
+		Help:     "Save the buffer and quit",
+		Complete: queryFiles,
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if err := p.writeBuffer(args.Arg); err != nil {
+				return nil, err
+			}
+			return p.quit(args.Bang)
+		},
+	})
+	registry.Register(components.Command{
+		Name: "quit", Aliases: []string{"q", "qa", "qall"}, Usage: "[!]",
+		Help: "Quit LazyDB (! discards unsaved buffers)",
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			return p.quit(args.Bang)
+		},
+	})
+	registry.Register(components.Command{
+		Name: "view", Usage: "<path>",
+		Help: "Open a large file read-only",
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if args.Arg == "" {
+				return nil, errors.New("no file name")
+			}
+			return nil, p.OpenScript(args.Arg)
+		},
+	})
+	registry.Register(components.Command{
+		Name: "enew",
+		Help: "Open a new scratch buffer",
+		Run: func(components.CommandArgs) (tea.Cmd, error) {
+			p.NewBuffer()
+			return nil, nil
+		},
+	})
+	registry.Register(components.Command{
+		Name: "bnext", Aliases: []string{"bn"},
+		Help: "Show the next buffer",
+		Run: func(components.CommandArgs) (tea.Cmd, error) {
+			p.NextBuffer()
+			return nil, nil
+		},
+	})
+	registry.Register(components.Command{
+		Name: "bprevious", Aliases: []string{"bp", "bN", "bNext"},
+		Help: "Show the previous buffer",
+		Run: func(components.CommandArgs) (tea.Cmd, error) {
+			p.PrevBuffer()
+			return nil, nil
+		},
+	})
+	registry.Register(components.Command{
+		Name: "bdelete", Aliases: []string{"bd"}, Usage: "[!]",
+		Help: "Close the buffer (! discards unsaved changes)",
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			return nil, p.CloseBuffer(args.Bang)
+		},
+	})
+	registry.Register(components.Command{
+		Name: "connection", Aliases: []string{"conn"}, Usage: "[name]",
+		Help: "Bind the buffer to a connection, or unbind it",
+		Complete: components.CompleteFrom(func() []string {
+			if p.connMgr == nil {
+				return nil
+			}
+			return p.connMgr.ListConnections()
+		}),
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if err := p.BindConnection(args.Arg); err != nil {
+				return nil, err
+			}
+			if args.Arg == "" {
+				p.setMessage("Buffer uses the active connection", false)
+			} else {
+				p.setMessage(fmt.Sprintf("Buffer bound to %s", args.Arg), false)
+			}
+			return nil, nil
+		},
+	})
+	registry.Register(components.Command{
+		Name: "format", Aliases: []string{"fmt"},
+		Help: "Format the buffer",
+		Run: func(components.CommandArgs) (tea.Cmd, error) {
+			if p.current().script != nil {
+				return nil, fmt.Errorf("%s is read-only", p.current().displayName())
+			}
+			p.recordUndo(p.formatBuffer)
+			return nil, nil
+		},
+	})
+	registry.Register(components.Command{
+		Name: "copy", Usage: "to <file> | from <file> <table> [delimiter=,] [null=text] [noheader] | !",
+		Help: "Stream the statement under the cursor to a CSV file, or a CSV file into a table, with COPY (! cancels)",
+		Complete: func(fields []string, arg string) []string {
+			if len(fields) == 0 {
+				return []string{"to", "from"}
+			}
+			return copyOptionNames
+		},
+		Run: p.copyCommand,
+	})
+	registry.Register(components.Command{
+		Name: "import", Usage: "<file> [delimiter=,] [null=text] [noheader]",
+		Help: "Preview a CSV, TSV or JSON file and load it into a new or existing table",
+		Complete: func(fields []string, arg string) []string {
+			return copyOptionNames
+		},
+		Run: p.importCommand,
+	})
+	registry.Register(components.Command{
+		Name: "explain", Usage: "[option...]",
+		Help:     "Run EXPLAIN on the statement under the cursor, e.g. :explain analyze buffers",
+		Complete: components.CompleteFrom(func() []string { return explainOptions }),
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			query, _, flash := p.QueryToExecute(false)
+			if query == "" {
+				return nil, errors.New("no statement under the cursor")
+			}
+			explain := "EXPLAIN "
+			if len(args.Fields) > 0 {
+				options, err := explainOptionList(args.Fields)
+				if err != nil {
+					return nil, err
+				}
+				explain = "EXPLAIN (" + options + ") "
+			}
+			return tea.Batch(flash, func() tea.Msg {
+				return ExecuteQueryMsg{Query: explain + query}
+			}), nil
+		},
+	})
+	registry.Register(components.Command{
+		Name: "nvim", Usage: "[address] | !",
+		Help: "Sync the buffer with a running Neovim (! stops syncing)",
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if args.Bang {
+				if p.nvim == nil {
+					return nil, errors.New("not synced with Neovim")
+				}
+				p.detachNeovim()
+				p.setMessage("Neovim sync ended", false)
+				return nil, nil
+			}
+			return p.AttachNeovim(args.Arg)
+		},
+	})
+	registry.Register(components.Command{
+		Name: "set", Aliases: []string{"se"}, Usage: "[option|nooption|option!|option?]",
+		Help: "Show or change editor options",
+		Complete: components.CompleteFrom(func() []string {
+			var names []string
+			for name := range editorOptions {
+				names = append(names, name, "no"+name)
+			}
+			sort.Strings(names)
+			return names
+		}),
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			return nil, p.setOptions(args.Fields)
+		},
+	})
+}
+
+// writeBuffer saves the active buffer and reports it
+func (p *EditorPanel) writeBuffer(name string) error {
+	if err := p.SaveBuffer(name); err != nil {
+		return err
+	}
+	p.setMessage(fmt.Sprintf("%q written", p.BufferName()), false)
+	return nil
+}
+
+// quit quits the application unless a buffer has unsaved changes, which
+// force discards
+func (p *EditorPanel) quit(force bool) (tea.Cmd, error) {
+	if !force {
+		p.stashBuffer()
+		for _, b := range p.buffers {
+			if b.script == nil && b.textarea.Value() != b.saved {
+				return nil, fmt.Errorf("%s has unsaved changes (add ! to discard them)", b.displayName())
+			}
+		}
+	}
+	return tea.Quit, nil
+}
+
+// setOptions runs :set. Without arguments it shows all options.
+func (p *EditorPanel) setOptions(fields []string) error {
+	if len(fields) == 0 {
+		for name := range editorOptions {
+			fields = append(fields, name+"?")
+		}
+		sort.Strings(fields)
+	}
+
+	var shown []string
+	for _, field := range fields {
+		name, query := strings.CutSuffix(field, "?")
+		name, toggle := strings.CutSuffix(name, "!")
+		on := true
+		if _, known := editorOptions[name]; !known {
+			if after, ok := strings.CutPrefix(name, "no"); ok {
+				name, on = after, false
+			} else if after, ok := strings.CutPrefix(name, "inv"); ok {
+				name, toggle = after, true
+			}
+		}
+
+		opt, ok := editorOptions[name]
+		if !ok {
+			return fmt.Errorf("unknown option: %s", field)
+		}
+		switch {
+		case query:
+		case toggle:
+			opt.set(p, !opt.get(p))
+		default:
+			opt.set(p, on)
+		}
+		if opt.get(p) {
+			shown = append(shown, name)
+		} else {
+			shown = append(shown, "no"+name)
+		}
+	}
+	p.setMessage(strings.Join(shown, "  "), false)
+	return nil
+}
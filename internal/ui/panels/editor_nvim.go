@@ -0,0 +1,195 @@
+package panels
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/config"
+	"github.com/MachineLearning-Nerd/lazydb/internal/editor"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SetEditorConfig applies the external editor settings
+func (p *EditorPanel) SetEditorConfig(cfg config.EditorConfig) {
+	p.editorCommand = cfg.Command
+	p.nvimAddress = cfg.NvimAddress
+}
+
+// OpenExternalEditor edits the active buffer in the external editor, opened
+// at the cursor. The result arrives as an editor.EditorSuccessMsg.
+func (p *EditorPanel) OpenExternalEditor() tea.Cmd {
+	if b := p.current(); b.script != nil {
+		p.setMessage(fmt.Sprintf("%s is read-only", b.displayName()), true)
+		return nil
+	}
+	buf := p.buffer()
+	cursor := buf.Cursor()
+	return editor.OpenInEditorCmd(p.editorCommand, p.textarea.Value(), cursor.Row+1, editor.ByteColumn(buf.Line(cursor.Row), cursor.Col))
+}
+
+// AttachNeovim mirrors the active buffer into the Neovim listening at
+// address, or at $NVIM or the configured address when empty
+func (p *EditorPanel) AttachNeovim(address string) (tea.Cmd, error) {
+	b := p.current()
+	if b.script != nil {
+		return nil, fmt.Errorf("%s is read-only", b.displayName())
+	}
+	if address == "" && os.Getenv("NVIM") == "" {
+		address = p.nvimAddress
+	}
+	if p.nvim != nil {
+		p.detachNeovim()
+	}
+
+	name := b.name
+	if name == "" {
+		name = "scratch.sql"
+	}
+	p.nvimBuffer = b
+	p.setMessage("Connecting to Neovim...", false)
+	return editor.AttachNeovimCmd(address, name, p.textarea.Value()), nil
+}
+
+// IsNeovimAttached reports whether a buffer is synced with Neovim
+func (p *EditorPanel) IsNeovimAttached() bool {
+	return p.nvim != nil
+}
+
+// detachNeovim ends the sync with Neovim
+func (p *EditorPanel) detachNeovim() {
+	if p.nvim != nil {
+		p.nvim.Detach()
+	}
+	p.nvim = nil
+	p.nvimBuffer = nil
+}
+
+// syncNeovim sends the active buffer's text to Neovim if it is the synced one
+func (p *EditorPanel) syncNeovim() {
+	if p.nvim == nil || p.current() != p.nvimBuffer {
+		return
+	}
+	if err := p.nvim.SetText(p.textarea.Value()); err != nil {
+		p.setMessage(fmt.Sprintf("Neovim: %v", err), true)
+	}
+}
+
+// handleNeovim handles the messages of the Neovim sync. All but the last
+// message of a sync wait for the next one.
+func (p *EditorPanel) handleNeovim(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case editor.NvimAttachedMsg:
+		if msg.Err != nil {
+			p.nvimBuffer = nil
+			p.setMessage(msg.Err.Error(), true)
+			return nil
+		}
+		if p.nvimBuffer == nil {
+			// The buffer was closed while connecting
+			msg.Sync.Detach()
+			return nil
+		}
+		p.nvim = msg.Sync
+		p.setMessage(fmt.Sprintf("Synced with Neovim, run :%s there to execute", editor.NvimExecuteCommand), false)
+		// Send what was typed while connecting
+		p.syncNeovim()
+
+	case editor.NvimTextMsg:
+		if p.nvim == nil {
+			return nil
+		}
+		p.applyNeovimText(msg.Text)
+
+	case editor.NvimExecuteMsg:
+		if p.nvim == nil {
+			return nil
+		}
+		query, flash := p.neovimQuery(msg)
+		if query == "" {
+			p.setMessage("No statement under the Neovim cursor", true)
+			return p.nvim.Listen()
+		}
+		return tea.Batch(p.nvim.Listen(), flash, func() tea.Msg {
+			return ExecuteQueryMsg{Query: query}
+		})
+
+	case editor.NvimSyncErrorMsg:
+		if p.nvim == nil {
+			return nil
+		}
+		p.setMessage(msg.Err.Error(), true)
+
+	case editor.NvimDetachedMsg:
+		if p.nvim == nil {
+			return nil
+		}
+		p.nvim = nil
+		p.nvimBuffer = nil
+		if msg.Err != nil && !errors.Is(msg.Err, editor.ErrNvimClosed) {
+			p.setMessage(fmt.Sprintf("Neovim sync ended: %v", msg.Err), true)
+		} else {
+			p.setMessage("Neovim sync ended", false)
+		}
+		return nil
+	}
+	return p.nvim.Listen()
+}
+
+// applyNeovimText replaces the synced buffer's text with an edit made in
+// Neovim, keeping the cursor where it was. Text longer than the editor
+// holds is left out.
+func (p *EditorPanel) applyNeovimText(text string) {
+	if err := editableText(text); err != nil {
+		p.setMessage(fmt.Sprintf("Neovim edit not synced: %v", err), true)
+		return
+	}
+	b := p.nvimBuffer
+	if p.current() != b {
+		b.textarea.SetValue(text)
+		return
+	}
+
+	cursor := p.cursorPosition()
+	p.recordUndo(func() {
+		p.textarea.SetValue(text)
+	})
+	p.moveCursorTo(editor.NewBuffer(text).Clamp(cursor, true))
+}
+
+// neovimQuery returns the SQL a :LazyDBExecute asks for: the lines of its
+// range, or the statement under the Neovim cursor. The synced buffer is
+// shown first.
+func (p *EditorPanel) neovimQuery(msg editor.NvimExecuteMsg) (string, tea.Cmd) {
+	for i, b := range p.buffers {
+		if b == p.nvimBuffer && i != p.active {
+			p.showBuffer(i)
+		}
+	}
+
+	buf := editor.NewBuffer(p.textarea.Value())
+	if msg.Range {
+		from := max(0, msg.FromRow)
+		to := min(msg.ToRow, buf.LineCount()-1)
+		if from > to {
+			return "", nil
+		}
+		start := buf.Offset(editor.Position{Row: from})
+		end := buf.Offset(editor.Position{Row: to, Col: buf.LineLen(to)})
+		query := buf.Text()[start:end]
+		if strings.TrimSpace(query) == "" {
+			return "", nil
+		}
+		return query, p.flashExecuted(start, end)
+	}
+
+	// Neovim columns count bytes, the editor's count runes
+	row := min(max(0, msg.Row), buf.LineCount()-1)
+	line := buf.Line(row)
+	col := utf8.RuneCountInString(line[:min(msg.Col, len(line))])
+	p.moveCursorTo(editor.Position{Row: row, Col: col})
+	query, _, flash := p.QueryToExecute(false)
+	return query, flash
+}
@@ -0,0 +1,284 @@
+package panels
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Editor view colors
+const (
+	currentLineColor = lipgloss.Color("235")
+	executedColor    = lipgloss.Color("237")
+	lineNumberColor  = lipgloss.Color("240")
+	currentNumColor  = lipgloss.Color("252")
+)
+
+// cell is the style of one character of the editor view
+type cell struct {
+	fg        string
+	bg        lipgloss.Color
+	bold      bool
+	italic    bool
+	underline bool
+	reverse   bool
+}
+
+// style returns the lipgloss style drawing the cell
+func (c cell) style() lipgloss.Style {
+	s := lipgloss.NewStyle().Bold(c.bold).Italic(c.italic).Underline(c.underline).Reverse(c.reverse)
+	if c.fg != "" {
+		s = s.Foreground(lipgloss.Color(c.fg))
+	}
+	if c.bg != "" {
+		s = s.Background(c.bg)
+	}
+	return s
+}
+
+// span is a range [from, to) of rune columns of a line
+type span struct {
+	from, to int
+}
+
+// empty reports whether the span covers no columns
+func (s span) empty() bool {
+	return s.to <= s.from
+}
+
+// noSpan marks an absent span
+var noSpan = span{-1, -1}
+
+// lineDecor is what is drawn over the text of one line
+type lineDecor struct {
+	current     bool // Cursor line, drawn with a background
+	cursor      int  // Rune column of the cursor, -1 for none
+	selection   span // Columns in the visual selection; len+1 includes the line break
+	executed    span // Columns of the last executed range
+	marker      span // Columns underlined by the gutter marker
+	markerColor lipgloss.Color
+}
+
+// renderTextLine draws one line of text: the token styles of spans (nil for
+// plain text) with the decorations on top, scrolled left by left display
+// columns and cut to width columns
+func renderTextLine(text string, spans []components.Span, decor lineDecor, left, width int) string {
+	runes := []rune(text)
+
+	// One cell per rune, plus one past the end for the cursor or a selected line break
+	cells := make([]cell, len(runes)+1)
+	col := 0
+	for _, s := range spans {
+		n := utf8.RuneCountInString(s.Text)
+		for i := col; i < col+n && i < len(runes); i++ {
+			cells[i] = cell{fg: s.Style.Color, bold: s.Style.Bold, italic: s.Style.Italic, underline: s.Style.Underline}
+		}
+		col += n
+	}
+
+	for i := range cells {
+		if decor.current {
+			cells[i].bg = currentLineColor
+		}
+		if i >= decor.executed.from && i < decor.executed.to {
+			cells[i].bg = executedColor
+		}
+		if i >= decor.marker.from && i < decor.marker.to {
+			cells[i].fg = string(decor.markerColor)
+			cells[i].underline = true
+		}
+		if i >= decor.selection.from && i < decor.selection.to {
+			cells[i].reverse = true
+		}
+	}
+	if decor.cursor >= 0 && decor.cursor < len(cells) {
+		// Inside a selection the cursor shows as a gap in it
+		cells[decor.cursor].reverse = !cells[decor.cursor].reverse
+	}
+
+	// The extra cell is only drawn when it shows something
+	last := len(runes)
+	if decor.cursor == last || (decor.selection.from <= last && decor.selection.to > last) || (decor.marker.from == last && decor.marker.to > last) {
+		last++
+	}
+
+	var out strings.Builder
+	var run strings.Builder
+	var runCell cell
+	flush := func() {
+		if run.Len() > 0 {
+			out.WriteString(runCell.style().Render(run.String()))
+			run.Reset()
+		}
+	}
+
+	pos, drawn := 0, 0
+	for i := 0; i < last; i++ {
+		r := ' '
+		if i < len(runes) && runes[i] != '\t' {
+			r = runes[i]
+		}
+		w := cellWidth(r)
+		if pos < left {
+			pos += w
+			continue
+		}
+		if drawn+w > width {
+			break
+		}
+		if cells[i] != runCell {
+			flush()
+			runCell = cells[i]
+		}
+		run.WriteRune(r)
+		pos += w
+		drawn += w
+	}
+	flush()
+
+	// The cursor line background spans the whole width
+	if decor.current && drawn < width {
+		out.WriteString(cell{bg: currentLineColor}.style().Render(strings.Repeat(" ", width-drawn)))
+	}
+	return out.String()
+}
+
+// renderGutter draws the marker column and the line number of a row
+func renderGutter(row, digits int, current bool, marker string, markerColor lipgloss.Color) string {
+	gutter := "  "
+	if marker != "" {
+		gutter = lipgloss.NewStyle().Foreground(markerColor).Bold(true).Render(marker) + " "
+	}
+
+	number := fmt.Sprintf("%*d ", digits, row+1)
+	if current {
+		return gutter + lipgloss.NewStyle().Foreground(currentNumColor).Bold(true).Render(number)
+	}
+	return gutter + lipgloss.NewStyle().Foreground(lineNumberColor).Render(number)
+}
+
+// gutterWidth returns the width of the gutter for a buffer of lineCount lines
+func gutterWidth(lineCount int) (width, digits int) {
+	digits = max(2, len(fmt.Sprint(lineCount)))
+	return 2 + digits + 1, digits
+}
+
+// renderText draws the lines of value in view, highlighted when enabled,
+// with the gutter, cursor, current line, selection, last executed range and
+// lint markers. Long lines scroll horizontally to keep the cursor visible.
+func (p *EditorPanel) renderText(value string) string {
+	lines := strings.Split(value, "\n")
+	cursor := p.cursorPosition()
+	top, bottom := p.visibleLines(cursor.Row, len(lines))
+
+	var spans [][]components.Span
+	if p.enableHighlight {
+		spans = p.highlighter.HighlightRange(lines, top, bottom)
+	}
+
+	gutter, digits := gutterWidth(len(lines))
+	width := max(1, p.width-2-gutter)
+	left := p.scrollLeft(lines[min(cursor.Row, len(lines)-1)], cursor.Col, width)
+
+	// Byte offset of the first line in view
+	lineStart := 0
+	for _, line := range lines[:top] {
+		lineStart += len(line) + 1
+	}
+
+	markers := make(map[int]editorMarker)
+	for _, m := range p.markers() {
+		if _, ok := markers[m.line-1]; !ok {
+			markers[m.line-1] = m
+		}
+	}
+	sel, selected := p.vim.Selection(cursor)
+	focused := p.textarea.Focused()
+
+	out := make([]string, 0, bottom-top)
+	for row := top; row < bottom; row++ {
+		line := lines[row]
+		decor := lineDecor{
+			current:   focused && row == cursor.Row,
+			cursor:    -1,
+			selection: noSpan,
+			executed:  noSpan,
+			marker:    noSpan,
+		}
+		if focused && row == cursor.Row {
+			decor.cursor = min(cursor.Col, utf8.RuneCountInString(line))
+		}
+		if selected {
+			n := utf8.RuneCountInString(line)
+			if from, to, ok := sel.Columns(row, n); ok {
+				if from == to && n == 0 {
+					to++ // An empty selected line shows as one cell
+				}
+				decor.selection = span{from, to}
+			}
+		}
+		if p.executed != nil {
+			decor.executed = byteSpan(line, p.executed.start-lineStart, p.executed.end-lineStart)
+		}
+
+		symbol, color := "", lipgloss.Color("")
+		if m, ok := markers[row]; ok {
+			symbol, color = m.symbol, m.color
+			decor.marker = byteSpan(line, m.offset-lineStart, m.offset-lineStart+m.length)
+			if decor.marker.empty() {
+				// An empty span underlines one cell, e.g. at the end of the line
+				n := utf8.RuneCountInString(line[:max(0, min(m.offset-lineStart, len(line)))])
+				decor.marker = span{n, n + 1}
+			}
+			decor.markerColor = color
+		}
+
+		var lineSpans []components.Span
+		if spans != nil {
+			lineSpans = spans[row-top]
+		}
+		out = append(out, renderGutter(row, digits, decor.current, symbol, color)+
+			renderTextLine(line, lineSpans, decor, left, width))
+		lineStart += len(line) + 1
+	}
+	return strings.Join(out, "\n")
+}
+
+// byteSpan converts the byte range [from, to) of line, which may extend
+// past either end, to a span of rune columns
+func byteSpan(line string, from, to int) span {
+	from, to = max(0, from), min(to, len(line))
+	if from >= to {
+		return noSpan
+	}
+	return span{utf8.RuneCountInString(line[:from]), utf8.RuneCountInString(line[:to])}
+}
+
+// scrollLeft returns the horizontal scroll of the active buffer, moving it
+// just enough to keep the cursor column of line visible in width columns
+func (p *EditorPanel) scrollLeft(line string, col, width int) int {
+	b := p.current()
+	cursorCol := 0
+	for i, r := range []rune(line) {
+		if i >= col {
+			break
+		}
+		cursorCol += cellWidth(r)
+	}
+	if cursorCol < b.scrollLeft {
+		b.scrollLeft = cursorCol
+	} else if cursorCol >= b.scrollLeft+width {
+		b.scrollLeft = cursorCol - width + 1
+	}
+	return b.scrollLeft
+}
+
+// cellWidth returns the display width of a rune in the editor view. Tabs
+// and zero-width runes take one column.
+func cellWidth(r rune) int {
+	return max(1, ansi.StringWidth(string(r)))
+}
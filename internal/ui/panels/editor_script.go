@@ -0,0 +1,130 @@
+package panels
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// handleScriptKey handles a key in a read-only script: Vim-style scrolling,
+// and ":" for commands. Editing keys only explain why they don't work.
+func (p *EditorPanel) handleScriptKey(msg tea.KeyMsg) tea.Cmd {
+	b := p.current()
+	page := max(1, p.textarea.Height())
+	last := b.script.LineCount() - 1
+
+	key := msg.String()
+	pending := p.scriptPending
+	p.scriptPending = ""
+
+	switch {
+	case pending == "g" && key == "g":
+		b.scriptRow = 0
+	case key == "g":
+		p.scriptPending = "g"
+	case key == "G":
+		b.scriptRow = last
+	case key == "j" || key == "down" || key == "enter":
+		b.scriptRow++
+	case key == "k" || key == "up":
+		b.scriptRow--
+	case key == "ctrl+d":
+		b.scriptRow += page / 2
+		b.scrollTop += page / 2
+	case key == "ctrl+u":
+		b.scriptRow -= page / 2
+		b.scrollTop -= page / 2
+	case key == "ctrl+f" || key == "pgdown":
+		b.scriptRow += page
+		b.scrollTop += page
+	case key == "ctrl+b" || key == "pgup":
+		b.scriptRow -= page
+		b.scrollTop -= page
+	case key == ":":
+		return p.cmdline.Open()
+	case len(msg.Runes) > 0 || key == "backspace" || key == "delete":
+		p.setMessage(fmt.Sprintf("%s is read-only (:e a smaller file to edit it)", b.displayName()), true)
+	}
+
+	b.scriptRow = max(0, min(b.scriptRow, last))
+	return nil
+}
+
+// renderScript renders the lines of a read-only script that are in view,
+// with the same gutter and current line as the editor
+func (p *EditorPanel) renderScript() string {
+	b := p.current()
+	top, bottom := p.visibleLines(b.scriptRow, b.script.LineCount())
+
+	// Read some lines above the view so highlighting knows the context
+	from := max(0, top-components.HighlightContextLines)
+	lines, err := b.script.Lines(from, bottom)
+	if err != nil {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(err.Error())
+	}
+	var spans [][]components.Span
+	if p.enableHighlight {
+		spans = p.highlighter.HighlightRange(lines, top-from, len(lines))
+	}
+
+	gutter, digits := gutterWidth(b.script.LineCount())
+	width := max(1, p.width-2-gutter)
+	focused := p.textarea.Focused()
+
+	out := make([]string, 0, bottom-top)
+	for i, line := range lines[min(top-from, len(lines)):] {
+		row := top + i
+		decor := lineDecor{
+			current:   focused && row == b.scriptRow,
+			cursor:    -1,
+			selection: noSpan,
+			executed:  noSpan,
+			marker:    noSpan,
+		}
+		var lineSpans []components.Span
+		if spans != nil {
+			lineSpans = spans[i]
+		}
+		out = append(out, renderGutter(row, digits, decor.current, "", "")+
+			renderTextLine(line, lineSpans, decor, 0, width))
+	}
+	return strings.Join(out, "\n")
+}
+
+// scriptQuery returns the query to run from a read-only script: the whole
+// file, or the statement on the cursor line. The statement is looked for
+// within scriptStatementLines of the cursor, so it isn't necessary to read
+// the whole file.
+func (p *EditorPanel) scriptQuery(all bool) string {
+	b := p.current()
+	if all {
+		query, err := b.script.ReadAll()
+		if err != nil {
+			p.setMessage(err.Error(), true)
+			return ""
+		}
+		return query
+	}
+
+	from := max(0, b.scriptRow-scriptStatementLines)
+	lines, err := b.script.Lines(from, b.scriptRow+scriptStatementLines)
+	if err != nil {
+		p.setMessage(err.Error(), true)
+		return ""
+	}
+	text := strings.Join(lines, "\n")
+
+	offset := 0
+	for _, line := range lines[:b.scriptRow-from] {
+		offset += len(line) + 1
+	}
+	stmt, found := db.StatementAt(text, offset)
+	if !found {
+		return ""
+	}
+	return stmt.Text
+}
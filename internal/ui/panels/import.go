@@ -0,0 +1,55 @@
+package panels
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/storage"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v5"
+)
+
+// ImportFileMsg asks the application to open the import wizard on the rows
+// read from a file
+type ImportFileMsg struct {
+	Path string
+	Data db.QueryResult
+}
+
+// ImportDoneMsg reports the end of an import started with StartImport, to
+// be passed to the wizard's SetReport
+type ImportDoneMsg struct {
+	Report db.ImportReport
+	Err    error
+}
+
+// StartImport runs the import the wizard asked for on a connection
+func StartImport(ctx context.Context, conn *pgx.Conn, start components.ImportStartMsg) tea.Cmd {
+	return func() tea.Msg {
+		report, err := db.RunImport(ctx, conn, start.Plan, start.Data, nil)
+		return ImportDoneMsg{Report: report, Err: err}
+	}
+}
+
+// importCommand reads the file of :import <file> and opens the wizard on it
+func (p *EditorPanel) importCommand(args components.CommandArgs) (tea.Cmd, error) {
+	opts, fields, err := parseCopyOptions(args.Fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("usage: import <file> [delimiter=,] [null=text] [noheader]")
+	}
+	path := strings.Join(fields, " ")
+	data, err := storage.ReadImportFile(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(data.Columns) == 0 {
+		return nil, errors.New(path + " has no columns")
+	}
+	return func() tea.Msg { return ImportFileMsg{Path: path, Data: data} }, nil
+}
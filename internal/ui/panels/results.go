@@ -1,29 +1,79 @@
 package panels
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/MachineLearning-Nerd/lazydb/internal/config"
 	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/editor"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 // ResultsPanel represents the right panel showing query results
 type ResultsPanel struct {
-	width       int
-	height      int
-	result      *db.QueryResult
-	hasData     bool
-	scrollX     int // horizontal scroll offset
-	scrollY     int // vertical scroll offset
+	width     int
+	height    int
+	result    *db.QueryResult
+	hasData   bool
+	colWidths []int // Display width of each shown column
+
+	minColWidth int // Bounds of a column's width, whatever its values
+	maxColWidth int
+
+	// The rows and columns shown: result rows filtered and sorted, with the
+	// visible columns in display order. Cursor positions index the view.
+	view       *db.QueryResult
+	viewRows   []int      // Result row of each view row
+	columns    []int      // Result column of each view column
+	pinned     int        // Leading view columns pinned to the left
+	baseWidths []int      // Display width of each result column
+	sortCol    int        // Result column sorted by, -1 for the query's order
+	sortDesc   bool
+	filter     *rowFilter // nil for all rows
+
+	cursorRow int // Focused cell
+	cursorCol int
+	rowOffset int    // First row shown
+	colOffset int    // First column shown
+	pending   string // First key of a two-key command ("g")
+
+	expanded    string // Record view mode: ExpandedOff, ExpandedOn or ExpandedAuto
+	fieldOffset int    // First field shown in the record view
+
+	clipboard     func(text string) error
+	editorCommand string
+	message       string // Shown in the status line until the next key
+
+	inspector *components.CellInspector // Open on the focused cell, or nil
+
+	history    []*ResultEntry // Past results, oldest first
+	shown      int            // Entry of history shown, -1 for a result not kept
+	nextID     int            // ID of the last entry
+	connection string         // Connection of the results being set
+	diff       *resultDiff    // Comparison shown instead of the table, or nil
+
+	chart *resultChart // Chart shown instead of the table, or nil
+	stats bool         // Statistics shown instead of the table
 }
 
 // NewResultsPanel creates a new results panel
 func NewResultsPanel() *ResultsPanel {
 	return &ResultsPanel{
-		hasData: false,
-		scrollX: 0,
-		scrollY: 0,
+		hasData:   false,
+		clipboard: editor.OSC52Clipboard(os.Stderr),
+		expanded:  ExpandedOff,
+		sortCol:   -1,
+		shown:     -1,
+
+		minColWidth: defaultMinColWidth,
+		maxColWidth: defaultMaxColWidth,
 	}
 }
 
@@ -31,74 +81,344 @@ func NewResultsPanel() *ResultsPanel {
 func (p *ResultsPanel) SetSize(width, height int) {
 	p.width = width
 	p.height = height
+	if p.inspector != nil {
+		p.inspector.SetSize(p.tableWidth(), p.height-4)
+	}
+}
+
+// SetClipboard sets where y copies the focused value
+func (p *ResultsPanel) SetClipboard(clipboard func(text string) error) {
+	p.clipboard = clipboard
+}
+
+// SetEditorConfig sets the external editor the inspector opens values in
+func (p *ResultsPanel) SetEditorConfig(cfg config.EditorConfig) {
+	p.editorCommand = cfg.Command
 }
 
-// SetResult sets the query result to display
+// Default bounds of a column's width
+const (
+	defaultMinColWidth = 10
+	defaultMaxColWidth = 30
+)
+
+// SetColumnWidths sets the narrowest and widest a table column gets.
+// Values wider than the maximum are cut with an ellipsis.
+func (p *ResultsPanel) SetColumnWidths(minWidth, maxWidth int) error {
+	if minWidth < 1 || maxWidth < minWidth {
+		return fmt.Errorf("column widths must be 1 <= min <= max, got %d and %d", minWidth, maxWidth)
+	}
+	p.minColWidth, p.maxColWidth = minWidth, maxWidth
+	if p.result != nil {
+		p.baseWidths = columnWidths(*p.result, minWidth, maxWidth)
+		p.applyView()
+	}
+	return nil
+}
+
+// ColumnWidths returns the narrowest and widest a table column gets
+func (p *ResultsPanel) ColumnWidths() (minWidth, maxWidth int) {
+	return p.minColWidth, p.maxColWidth
+}
+
+// SetResult sets the query result to display and adds it to the history
 func (p *ResultsPanel) SetResult(result db.QueryResult) {
+	if entry := p.record(result); entry != nil {
+		p.showEntry(len(p.history) - 1)
+		p.message = ""
+		return
+	}
+	p.shown = -1
+	p.diff = nil
 	p.result = &result
 	p.hasData = true
-	p.scrollX = 0
-	p.scrollY = 0
+	p.resetView()
+	p.resetCursor()
 }
 
-// Clear clears the current results
+// Clear clears the current results. The history is kept.
 func (p *ResultsPanel) Clear() {
 	p.result = nil
 	p.hasData = false
-	p.scrollX = 0
-	p.scrollY = 0
+	p.shown = -1
+	p.diff = nil
+	p.resetView()
+	p.resetCursor()
+}
+
+// resetCursor moves the cursor back to the first cell
+func (p *ResultsPanel) resetCursor() {
+	p.cursorRow, p.cursorCol = 0, 0
+	p.rowOffset, p.colOffset, p.fieldOffset = 0, 0, 0
+	p.pending = ""
+	p.message = ""
+	p.inspector = nil
+	p.chart, p.stats = nil, false
+}
+
+// Cursor returns the row and column of the focused cell
+func (p *ResultsPanel) Cursor() (row, col int) {
+	return p.cursorRow, p.cursorCol
+}
+
+// FocusedCell returns the value, column name and column type of the
+// focused cell. The type is empty when unknown.
+func (p *ResultsPanel) FocusedCell() (value, column, columnType string, ok bool) {
+	if !p.hasTable() || len(p.view.Rows) == 0 {
+		return "", "", "", false
+	}
+	value = cellAt(p.view.Rows[p.cursorRow], p.cursorCol)
+	column = p.view.Columns[p.cursorCol]
+	if p.cursorCol < len(p.view.ColumnTypes) {
+		columnType = p.view.ColumnTypes[p.cursorCol]
+	}
+	return value, column, columnType, true
+}
+
+// hasTable reports whether a result with columns is shown
+func (p *ResultsPanel) hasTable() bool {
+	return p.hasData && p.result != nil && p.result.Error == nil && len(p.result.Columns) > 0
+}
+
+// Update handles keyboard input: hjkl (and w/b) move the cell cursor,
+// gg/G jump to the first/last row, 0/$ to the first/last column, y copies
+// the focused value and Enter inspects it
+func (p *ResultsPanel) Update(msg tea.Msg) tea.Cmd {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && p.inspector == nil {
+		if p.diff != nil {
+			p.diffKey(keyMsg.String())
+			return nil
+		}
+		if p.IsCharting() {
+			p.chartKey(keyMsg.String())
+			return nil
+		}
+		// [ and ] browse the history, whatever is shown
+		switch keyMsg.String() {
+		case "[":
+			p.browseHistory(-1)
+			return nil
+		case "]":
+			p.browseHistory(1)
+			return nil
+		}
+	}
+	if !p.hasTable() {
+		return nil
+	}
+	if p.inspector != nil {
+		if keyMsg, ok := msg.(tea.KeyMsg); ok && (keyMsg.String() == "q" || keyMsg.String() == "esc") {
+			p.CloseInspector()
+			return nil
+		}
+		return p.inspector.Update(msg)
+	}
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+	key := keyMsg.String()
+	p.message = ""
+
+	pending := p.pending
+	p.pending = ""
+	if pending == "g" {
+		switch key {
+		case "g":
+			p.moveTo(0, p.cursorCol)
+		case "t":
+			p.switchTab(1)
+		case "T":
+			p.switchTab(-1)
+		}
+		return nil
+	}
+	if p.IsExpanded() && p.recordKey(key) {
+		return nil
+	}
+
+	rows := len(p.view.Rows)
+	page := p.visibleRows()
+	switch key {
+	case "left", "h", "b", "shift+tab":
+		p.moveTo(p.cursorRow, p.cursorCol-1)
+	case "right", "l", "w", "tab":
+		p.moveTo(p.cursorRow, p.cursorCol+1)
+	case "up", "k":
+		p.moveTo(p.cursorRow-1, p.cursorCol)
+	case "down", "j":
+		p.moveTo(p.cursorRow+1, p.cursorCol)
+	case "home", "0", "^":
+		p.moveTo(p.cursorRow, 0)
+	case "end", "$":
+		p.moveTo(p.cursorRow, len(p.view.Columns)-1)
+	case "g":
+		p.pending = "g"
+	case "G":
+		p.moveTo(rows-1, p.cursorCol)
+	case "ctrl+d":
+		p.moveTo(p.cursorRow+max(1, page/2), p.cursorCol)
+	case "ctrl+u":
+		p.moveTo(p.cursorRow-max(1, page/2), p.cursorCol)
+	case "pgdown", "ctrl+f":
+		p.moveTo(p.cursorRow+page, p.cursorCol)
+	case "pgup", "ctrl+b":
+		p.moveTo(p.cursorRow-page, p.cursorCol)
+	case "y":
+		p.yankCell()
+	case "enter":
+		p.Inspect()
+	case "x":
+		p.toggleExpanded()
+	case "s":
+		p.cycleSort()
+	case "S":
+		p.SortBy(-1, false)
+		p.message = "Sort cleared"
+	case "f":
+		p.filterByFocusedValue()
+	case "F":
+		p.SetFilter("", -1)
+		p.message = "Filter cleared"
+	case "-":
+		if err := p.HideColumn(p.focusedColumn()); err != nil {
+			p.message = err.Error()
+		}
+	case "+":
+		p.ShowAllColumns()
+	case "<":
+		p.moveColumn(-1)
+	case ">":
+		p.moveColumn(1)
+	case "P":
+		p.PinColumn(p.focusedColumn(), !p.isPinned(p.cursorCol))
+	}
+	return nil
 }
 
-// Update handles keyboard input for scrolling
-func (p *ResultsPanel) Update(msg tea.Msg) {
-	if !p.hasData || p.result == nil {
+// Inspect opens the inspector on the focused cell
+func (p *ResultsPanel) Inspect() bool {
+	value, column, columnType, ok := p.FocusedCell()
+	if !ok {
+		return false
+	}
+	p.inspector = components.NewCellInspector(column, columnType, value)
+	p.inspector.SetClipboard(p.clipboard)
+	p.inspector.SetEditorCommand(p.editorCommand)
+	p.inspector.SetSize(p.tableWidth(), p.height-4)
+	return true
+}
+
+// IsInspecting reports whether the inspector is open
+func (p *ResultsPanel) IsInspecting() bool {
+	return p.inspector != nil
+}
+
+// CloseInspector goes back from the inspector to the table
+func (p *ResultsPanel) CloseInspector() {
+	p.inspector = nil
+}
+
+// moveTo moves the cursor to a cell, clamped to the table, and scrolls the
+// rows to keep it in view. Columns scroll when rendered, once widths are
+// known.
+func (p *ResultsPanel) moveTo(row, col int) {
+	p.cursorRow = max(0, min(row, len(p.view.Rows)-1))
+	p.cursorCol = max(0, min(col, len(p.view.Columns)-1))
+
+	page := p.visibleRows()
+	if p.cursorRow < p.rowOffset {
+		p.rowOffset = p.cursorRow
+	} else if p.cursorRow >= p.rowOffset+page {
+		p.rowOffset = p.cursorRow - page + 1
+	}
+
+	fields := p.visibleFields()
+	if p.cursorCol < p.fieldOffset {
+		p.fieldOffset = p.cursorCol
+	} else if p.cursorCol >= p.fieldOffset+fields {
+		p.fieldOffset = p.cursorCol - fields + 1
+	}
+}
+
+// yankCell copies the focused value to the clipboard
+func (p *ResultsPanel) yankCell() {
+	value, column, _, ok := p.FocusedCell()
+	if !ok || p.clipboard == nil {
+		return
+	}
+	if err := p.clipboard(value); err != nil {
+		p.message = fmt.Sprintf("Copy failed: %v", err)
 		return
 	}
+	p.message = fmt.Sprintf("Copied %s (%d bytes)", column, len(value))
+}
 
-	switch msg := msg.(type) {
-	case tea.KeyMsg:
-		switch msg.String() {
-		case "left", "h":
-			if p.scrollX > 0 {
-				p.scrollX -= 5
+// RegisterCommands adds the results panel's commands to a registry
+func (p *ResultsPanel) RegisterCommands(registry *components.CommandRegistry) {
+	p.registerExportCommand(registry)
+	p.registerViewCommands(registry)
+	p.registerHistoryCommands(registry)
+	p.registerChartCommands(registry)
+	registry.Register(components.Command{
+		Name: "expanded", Usage: "[on|off|auto]",
+		Help: "Show rows as records, one field per line, like psql's \\x; auto when the table is too wide",
+		Complete: func(fields []string, arg string) []string {
+			if len(fields) == 0 {
+				return []string{ExpandedOn, ExpandedOff, ExpandedAuto}
 			}
-		case "right", "l":
-			p.scrollX += 5
-		case "up", "k":
-			if p.scrollY > 0 {
-				p.scrollY--
+			return nil
+		},
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if len(args.Fields) == 0 {
+				p.toggleExpanded()
+			} else if err := p.SetExpanded(args.Fields[0]); err != nil {
+				return nil, err
 			}
-		case "down", "j":
-			if p.result != nil && p.scrollY < len(p.result.Rows)-1 {
-				p.scrollY++
+			return components.CommandMessage(fmt.Sprintf("Expanded display is %s", p.expanded)), nil
+		},
+	})
+	registry.Register(components.Command{
+		Name: "colwidth", Usage: "[min] max",
+		Help: "Set the narrowest and widest a column gets, e.g. :colwidth 4 60",
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			minWidth, maxWidth := p.minColWidth, 0
+			var err error
+			switch len(args.Fields) {
+			case 0:
+				return components.CommandMessage(fmt.Sprintf("Column widths %d to %d", p.minColWidth, p.maxColWidth)), nil
+			case 1:
+				maxWidth, err = strconv.Atoi(args.Fields[0])
+				minWidth = min(minWidth, maxWidth)
+			case 2:
+				if minWidth, err = strconv.Atoi(args.Fields[0]); err == nil {
+					maxWidth, err = strconv.Atoi(args.Fields[1])
+				}
+			default:
+				return nil, errors.New("usage: colwidth [min] max")
 			}
-		case "home":
-			p.scrollX = 0
-		case "end":
-			p.scrollX = 1000 // scroll far right
-		case "pgup":
-			p.scrollY -= 10
-			if p.scrollY < 0 {
-				p.scrollY = 0
+			if err != nil {
+				return nil, errors.New("usage: colwidth [min] max")
 			}
-		case "pgdown":
-			if p.result != nil {
-				p.scrollY += 10
-				if p.scrollY >= len(p.result.Rows) {
-					p.scrollY = len(p.result.Rows) - 1
-				}
+			if err := p.SetColumnWidths(minWidth, maxWidth); err != nil {
+				return nil, err
 			}
-		}
-	}
+			return components.CommandMessage(fmt.Sprintf("Column widths %d to %d", minWidth, maxWidth)), nil
+		},
+	})
 }
 
+// resultsStatusLines is the height of the status line below the table: the
+// focused cell's position and column, then its value on up to two lines
+const resultsStatusLines = 3
+
 // View renders the results panel
 func (p *ResultsPanel) View() string {
 	if p.width == 0 || p.height == 0 {
 		return ""
 	}
 
-	content := "RESULTS\n\n"
+	content := "RESULTS\n" + p.renderTabs() + "\n"
 
 	if !p.hasData {
 		content += "No results yet.\n\n"
@@ -118,94 +438,227 @@ func (p *ResultsPanel) View() string {
 		return content
 	}
 
-	// Calculate column widths (min 10, max 30 characters per column)
-	colWidths := make([]int, len(p.result.Columns))
-	for i, col := range p.result.Columns {
-		colWidths[i] = max(10, len(col))
+	if p.inspector != nil {
+		return content + p.inspector.View()
+	}
+	if p.diff != nil {
+		return content + p.renderDiff()
+	}
+	if p.chart != nil {
+		return content + p.renderChart()
+	}
+	if p.stats {
+		return content + p.renderStats()
+	}
+	if p.IsExpanded() {
+		return content + p.renderRecord()
+	}
+
+	tableLines, lastCol := p.renderTable()
+	content += strings.Join(tableLines, "\n")
+	content += "\n\n"
+
+	// Add summary with scroll indicators
+	startRow := p.rowOffset
+	endRow := min(startRow+p.visibleRows(), len(p.view.Rows))
+	scrollInfo := ""
+	if p.colOffset > p.pinned {
+		scrollInfo += "◄ "
+	}
+	scrollInfo += p.viewSummary()
+	if len(p.view.Rows) > endRow || startRow > 0 {
+		scrollInfo += fmt.Sprintf(" (showing %d-%d)", startRow+1, endRow)
 	}
-	for _, row := range p.result.Rows {
+	scrollInfo += fmt.Sprintf(", %dms", p.result.ExecutionMs) + p.viewDetails()
+	if lastCol < len(p.view.Columns)-1 {
+		scrollInfo += " ►"
+	}
+
+	content += ansi.Truncate(scrollInfo, p.tableWidth(), "…")
+	content += "\n" + p.renderStatus()
+
+	return content
+}
+
+// visibleRows returns how many data rows fit in the panel
+func (p *ResultsPanel) visibleRows() int {
+	return max(1, p.height-8-resultsStatusLines)
+}
+
+// tableWidth returns the width available to table lines
+func (p *ResultsPanel) tableWidth() int {
+	return max(10, p.width-4)
+}
+
+// columnWidths returns the display width of each column: that of the
+// header or the longest value, at least minWidth and at most maxWidth
+func columnWidths(result db.QueryResult, minWidth, maxWidth int) []int {
+	colWidths := make([]int, len(result.Columns))
+	for i, col := range result.Columns {
+		colWidths[i] = max(minWidth, min(displayWidth(col), maxWidth))
+	}
+	for _, row := range result.Rows {
 		for i, cell := range row {
-			if i < len(colWidths) {
-				cellLen := len(cell)
-				if cellLen > colWidths[i] && colWidths[i] < 30 {
-					colWidths[i] = min(30, cellLen)
-				}
+			if i < len(colWidths) && colWidths[i] < maxWidth {
+				colWidths[i] = max(colWidths[i], min(maxWidth, displayWidth(cell)))
 			}
 		}
 	}
+	return colWidths
+}
+
+// displayWidth returns the number of terminal columns a value takes in a cell
+func displayWidth(s string) int {
+	return ansi.StringWidth(components.EscapeControls(s))
+}
+
+// renderTable draws the header, separator and rows in view with a row
+// number gutter and the focused cell highlighted. Pinned columns stay on
+// the left while the others scroll. It returns the lines and the last
+// column fully shown.
+func (p *ResultsPanel) renderTable() ([]string, int) {
+	width := p.tableWidth()
+	digits := len(strconv.Itoa(len(p.view.Rows)))
+	gutter := digits + 1
+	n := len(p.view.Columns)
+
+	// Scroll the columns just enough to show the focused one
+	p.colOffset = max(p.colOffset, p.pinned)
+	if p.cursorCol >= p.pinned {
+		p.colOffset = min(p.colOffset, p.cursorCol)
+		for p.colOffset < p.cursorCol && p.shownWidth(p.cursorCol) > width-gutter {
+			p.colOffset++
+		}
+	}
+	lastCol := p.colOffset - 1
+	for lastCol+1 < n && p.shownWidth(lastCol+1) <= width-gutter {
+		lastCol++
+	}
+	shown := make([]int, 0, n)
+	for col := 0; col < n; col++ {
+		if col < p.pinned || col >= p.colOffset {
+			shown = append(shown, col)
+		}
+	}
+
+	cursorStyle := lipgloss.NewStyle().Reverse(true)
+	numberStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	currentNumberStyle := lipgloss.NewStyle().Bold(true)
+	nullStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	// separator returns the border after a column: doubled after the
+	// pinned columns
+	separator := func(col int, single, double string) string {
+		if col == p.pinned-1 {
+			return double
+		}
+		return single
+	}
 
-	// Build table as a string, then apply horizontal scroll
 	var tableLines []string
 
-	// Header row
-	headerLine := "│ "
-	for i, col := range p.result.Columns {
-		headerLine += padOrTruncate(col, colWidths[i]) + " │ "
+	// Header row, with an arrow on the sorted column
+	headerLine := strings.Repeat(" ", gutter) + "│ "
+	for _, col := range shown {
+		name := components.FitCell(p.view.Columns[col], p.colWidths[col])
+		if p.columns[col] == p.sortCol {
+			arrow := "▲"
+			if p.sortDesc {
+				arrow = "▼"
+			}
+			name = strings.TrimRight(components.FitCell(p.view.Columns[col], p.colWidths[col]-2), " ") + " " + arrow
+			name += strings.Repeat(" ", max(0, p.colWidths[col]-ansi.StringWidth(name)))
+		}
+		headerLine += name + separator(col, " │ ", " ║ ")
 	}
 	tableLines = append(tableLines, headerLine)
 
 	// Separator
-	separatorLine := "├─"
-	for i := range p.result.Columns {
-		separatorLine += strings.Repeat("─", colWidths[i]) + "─┼─"
+	separatorLine := strings.Repeat(" ", gutter) + "├─"
+	for _, col := range shown {
+		separatorLine += strings.Repeat("─", p.colWidths[col]) + separator(col, "─┼─", "─╫─")
 	}
-	separatorLine = separatorLine[:len(separatorLine)-2] + "┤"
+	separatorLine = strings.TrimSuffix(separatorLine, "┼─") + "┤"
 	tableLines = append(tableLines, separatorLine)
 
 	// Data rows
-	maxDisplayRows := p.height - 8 // Leave room for header, footer, etc.
-	if maxDisplayRows < 1 {
-		maxDisplayRows = 5
-	}
-
-	startRow := p.scrollY
-	endRow := min(startRow+maxDisplayRows, len(p.result.Rows))
+	endRow := min(p.rowOffset+p.visibleRows(), len(p.view.Rows))
+	for rowIdx := p.rowOffset; rowIdx < endRow; rowIdx++ {
+		number := fmt.Sprintf("%*d ", digits, rowIdx+1)
+		if rowIdx == p.cursorRow {
+			number = currentNumberStyle.Render(number)
+		} else {
+			number = numberStyle.Render(number)
+		}
 
-	for rowIdx := startRow; rowIdx < endRow; rowIdx++ {
-		row := p.result.Rows[rowIdx]
-		rowLine := "│ "
-		for i, cell := range row {
-			if i < len(colWidths) {
-				rowLine += padOrTruncate(cell, colWidths[i]) + " │ "
+		row := p.view.Rows[rowIdx]
+		rowLine := number + "│ "
+		for _, col := range shown {
+			cell := components.FitCell(cellAt(row, col), p.colWidths[col])
+			if rowIdx == p.cursorRow && col == p.cursorCol {
+				cell = cursorStyle.Render(cell)
+			} else if p.view.IsNull(rowIdx, col) {
+				cell = nullStyle.Render(cell)
 			}
+			rowLine += cell + separator(col, " │ ", " ║ ")
 		}
 		tableLines = append(tableLines, rowLine)
 	}
 
-	// Apply horizontal scrolling
-	scrolledLines := make([]string, len(tableLines))
+	// Truncate to panel width
 	for i, line := range tableLines {
-		if p.scrollX < len(line) {
-			scrolledLines[i] = line[p.scrollX:]
-		} else {
-			scrolledLines[i] = ""
-		}
-		// Truncate to panel width
-		if len(scrolledLines[i]) > p.width-4 {
-			scrolledLines[i] = scrolledLines[i][:p.width-4]
-		}
+		tableLines[i] = ansi.Truncate(line, width, "")
 	}
+	return tableLines, lastCol
+}
 
-	content += strings.Join(scrolledLines, "\n")
-	content += "\n\n"
-
-	// Add summary with scroll indicators
-	scrollInfo := ""
-	if p.scrollX > 0 {
-		scrollInfo += "◄ "
+// columnsWidth returns the width of the table lines showing columns from
+// first to last
+func (p *ResultsPanel) columnsWidth(first, last int) int {
+	width := 1
+	for col := first; col <= last; col++ {
+		width += p.colWidths[col] + 3
 	}
-	if len(p.result.Rows) > endRow {
-		scrollInfo += fmt.Sprintf("%d rows (showing %d-%d), %dms", p.result.RowCount, startRow+1, endRow, p.result.ExecutionMs)
-	} else {
-		scrollInfo += fmt.Sprintf("%d rows, %dms", p.result.RowCount, p.result.ExecutionMs)
+	return width
+}
+
+// shownWidth returns the width of the table lines showing the pinned
+// columns, then the columns from colOffset to last
+func (p *ResultsPanel) shownWidth(last int) int {
+	width := p.columnsWidth(p.colOffset, last)
+	if p.pinned > 0 {
+		width += p.columnsWidth(0, p.pinned-1) - 1
 	}
-	if p.scrollX > 0 || len(p.result.Rows) > maxDisplayRows {
-		scrollInfo += " ►"
+	return width
+}
+
+// renderStatus draws the focused cell's position, column and type, and its
+// full value (or a message), wrapped to at most two lines
+func (p *ResultsPanel) renderStatus() string {
+	value, column, columnType, ok := p.FocusedCell()
+	if !ok {
+		return p.message
 	}
+	width := p.tableWidth()
 
-	content += scrollInfo
+	info := fmt.Sprintf("Row %d/%d  Col %d/%d  %s", p.cursorRow+1, len(p.view.Rows), p.cursorCol+1, len(p.view.Columns), components.EscapeControls(column))
+	if columnType != "" {
+		info += " " + lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(columnType)
+	}
+	if p.message != "" {
+		info = p.message
+	}
 
-	return content
+	// Line breaks and control characters show as symbols to keep the
+	// value on its lines
+	value = components.EscapeControls(value)
+	lines := strings.Split(ansi.Hardwrap(value, width, false), "\n")
+	if len(lines) > resultsStatusLines-1 {
+		lines = lines[:resultsStatusLines-1]
+		last := len(lines) - 1
+		lines[last] = ansi.Truncate(lines[last], width-1, "") + "…"
+	}
+	return ansi.Truncate(info, width, "…") + "\n" + strings.Join(lines, "\n")
 }
 
 // Helper functions
@@ -223,18 +676,7 @@ func min(a, b int) int {
 	return b
 }
 
-// padOrTruncate pads or truncates a string to the specified width
-func padOrTruncate(s string, width int) string {
-	if len(s) > width {
-		if width > 3 {
-			return s[:width-3] + "..."
-		}
-		return s[:width]
-	}
-	return s + strings.Repeat(" ", width-len(s))
-}
-
 // Help returns help text for the results panel
 func (p *ResultsPanel) Help() string {
-	return "[←→] scroll horizontal  [↑↓] scroll vertical  [Home/End] jump"
+	return "[hjkl] Move  [w/b] Next/prev column  [0/$] First/last column  [gg/G] First/last row  [Ctrl-D/U] Half page  [y] Copy value  [Enter] Inspect  [x] Record view  [s/S] Sort  [f/F] Filter by value  [-/+] Hide/show  [</>] Move  [P] Pin  [[/]] History  [gt/gT] Tabs"
 }
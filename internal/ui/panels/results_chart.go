@@ -0,0 +1,354 @@
+package panels
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// resultChart is a chart of the rows shown, drawn instead of the table.
+// Columns index the view; x is -1 for the row numbers.
+type resultChart struct {
+	kind string
+	x, y int
+}
+
+// numericColumns returns the view columns holding numbers
+func (p *ResultsPanel) numericColumns() []int {
+	var columns []int
+	for col := range p.view.Columns {
+		if db.IsNumericColumn(*p.view, col) {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
+
+// viewColumn returns the view column of a name
+func (p *ResultsPanel) viewColumn(name string) (int, error) {
+	if i := slices.Index(p.view.Columns, name); i >= 0 {
+		return i, nil
+	}
+	if i := slices.IndexFunc(p.view.Columns, func(c string) bool { return strings.EqualFold(c, name) }); i >= 0 {
+		return i, nil
+	}
+	return -1, fmt.Errorf("no column %s", name)
+}
+
+// ShowChart draws the rows shown as a chart: a bar or line chart of y over
+// x, or a histogram of y. Empty column names pick the focused numeric
+// column (or the first) for y and the first other text column for x.
+func (p *ResultsPanel) ShowChart(kind, x, y string) error {
+	if !p.hasTable() {
+		return errors.New("no result to chart")
+	}
+	if !slices.Contains(components.ChartKinds, kind) {
+		return fmt.Errorf("unknown chart %s, expected one of %s", kind, strings.Join(components.ChartKinds, ", "))
+	}
+	numeric := p.numericColumns()
+	chart := &resultChart{kind: kind, x: -1, y: -1}
+
+	var err error
+	switch {
+	case y != "":
+		if chart.y, err = p.viewColumn(y); err != nil {
+			return err
+		}
+		if !slices.Contains(numeric, chart.y) {
+			return fmt.Errorf("%s is not a numeric column", y)
+		}
+	case slices.Contains(numeric, p.cursorCol):
+		chart.y = p.cursorCol
+	case len(numeric) > 0:
+		chart.y = numeric[0]
+	default:
+		return errors.New("no numeric column to chart")
+	}
+
+	if x != "" {
+		if chart.x, err = p.viewColumn(x); err != nil {
+			return err
+		}
+	} else {
+		for col := range p.view.Columns {
+			if col != chart.y && !slices.Contains(numeric, col) {
+				chart.x = col
+				break
+			}
+		}
+	}
+
+	p.chart = chart
+	p.stats = false
+	return nil
+}
+
+// ShowStats shows the statistics of the numeric columns instead of the
+// table
+func (p *ResultsPanel) ShowStats() error {
+	if !p.hasTable() {
+		return errors.New("no result to summarize")
+	}
+	if len(p.numericColumns()) == 0 {
+		return errors.New("no numeric column")
+	}
+	p.stats = true
+	p.chart = nil
+	return nil
+}
+
+// IsCharting reports whether a chart or the statistics are shown
+func (p *ResultsPanel) IsCharting() bool {
+	return p.chart != nil || p.stats
+}
+
+// CloseChart goes back from the chart or statistics to the table
+func (p *ResultsPanel) CloseChart() {
+	p.chart = nil
+	p.stats = false
+}
+
+// chartKey changes the chart: Tab its kind, x/X the column along the
+// bottom and y/Y the column charted
+func (p *ResultsPanel) chartKey(key string) {
+	if key == "q" || key == "esc" {
+		p.CloseChart()
+		return
+	}
+	c := p.chart
+	if c == nil {
+		return
+	}
+	numeric := p.numericColumns()
+	switch key {
+	case "tab":
+		c.kind = cycleString(components.ChartKinds, c.kind, 1)
+	case "shift+tab":
+		c.kind = cycleString(components.ChartKinds, c.kind, -1)
+	case "x", "X":
+		// Through the row numbers and every column
+		delta := 1
+		if key == "X" {
+			delta = -1
+		}
+		n := len(p.view.Columns) + 1
+		c.x = (c.x+1+delta+n)%n - 1
+	case "y", "Y":
+		delta := 1
+		if key == "Y" {
+			delta = -1
+		}
+		if i := slices.Index(numeric, c.y); i >= 0 {
+			c.y = numeric[(i+delta+len(numeric))%len(numeric)]
+		}
+	}
+}
+
+// cycleString returns the value delta places from current in values
+func cycleString(values []string, current string, delta int) string {
+	i := slices.Index(values, current)
+	return values[(i+delta+len(values))%len(values)]
+}
+
+// chartHeight is how many lines a chart takes
+func (p *ResultsPanel) chartHeight() int {
+	return max(4, p.height-10)
+}
+
+// renderChart draws the chart, the statistics of its column and the keys
+func (p *ResultsPanel) renderChart() string {
+	c := p.chart
+	width := p.tableWidth()
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	titleStyle := lipgloss.NewStyle().Bold(true)
+
+	xName := "row"
+	if c.x >= 0 {
+		xName = p.view.Columns[c.x]
+	}
+	label := func(row int) string {
+		if c.x < 0 {
+			return strconv.Itoa(row + 1)
+		}
+		if p.view.IsNull(row, c.x) {
+			return db.NullText
+		}
+		return cellAt(p.view.Rows[row], c.x)
+	}
+
+	// The rows with a value to chart
+	var rows []int
+	var values []float64
+	for i, row := range p.view.Rows {
+		if p.view.IsNull(i, c.y) {
+			continue
+		}
+		if v, ok := db.ParseNumber(cellAt(row, c.y)); ok {
+			rows = append(rows, i)
+			values = append(values, v)
+		}
+	}
+
+	height := p.chartHeight()
+	var title string
+	var lines []string
+	switch c.kind {
+	case components.ChartBar:
+		title = fmt.Sprintf("%s by %s", p.view.Columns[c.y], xName)
+		labels := make([]string, len(rows))
+		for i, row := range rows {
+			labels[i] = label(row)
+		}
+		lines = components.BarChart(labels, values, width, height)
+		if len(values) > height {
+			title += fmt.Sprintf(" (first %d of %s rows)", height, components.FormatCount(len(values)))
+		}
+	case components.ChartLine:
+		title = fmt.Sprintf("%s over %s", p.view.Columns[c.y], xName)
+		first, last := "", ""
+		if len(rows) > 0 {
+			first, last = label(rows[0]), label(rows[len(rows)-1])
+		}
+		lines = components.LineChart(values, first, last, width, height)
+	case components.ChartHistogram:
+		title = fmt.Sprintf("Distribution of %s", p.view.Columns[c.y])
+		lines = components.Histogram(values, 0, width, height)
+	}
+	if len(values) == 0 {
+		lines = []string{dimStyle.Render("No values to chart")}
+	}
+
+	content := ansi.Truncate(titleStyle.Render(title), width, "…") + "\n\n"
+	for _, line := range lines {
+		content += ansi.Truncate(line, width, "") + "\n"
+	}
+	if stats, ok := db.NumericStats(*p.view, c.y); ok && stats.Count > 0 {
+		summary := fmt.Sprintf("min %s · max %s · avg %s · p50 %s · p95 %s",
+			components.FormatNumber(stats.Min), components.FormatNumber(stats.Max), components.FormatNumber(stats.Mean),
+			components.FormatNumber(stats.P50), components.FormatNumber(stats.P95))
+		if stats.Nulls > 0 {
+			summary += fmt.Sprintf(" · %s NULL", components.FormatCount(stats.Nulls))
+		}
+		if stats.NonFinite > 0 {
+			summary += fmt.Sprintf(" · %s NaN or infinite", components.FormatCount(stats.NonFinite))
+		}
+		content += "\n" + ansi.Truncate(summary, width, "…")
+	}
+	help := "[Tab] Chart  [x/X] X column  [y/Y] Y column  [q] Close"
+	return content + "\n" + ansi.Truncate(dimStyle.Render(help), width, "…")
+}
+
+// statsColumns are the headers of the statistics table
+var statsColumns = []string{"column", "count", "nulls", "nan/inf", "min", "max", "avg", "stddev", "p25", "p50", "p75", "p95"}
+
+// renderStats draws a line of statistics per numeric column
+func (p *ResultsPanel) renderStats() string {
+	width := p.tableWidth()
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	headerStyle := lipgloss.NewStyle().Bold(true)
+
+	table := db.QueryResult{Columns: statsColumns}
+	for _, col := range p.numericColumns() {
+		s, ok := db.NumericStats(*p.view, col)
+		if !ok {
+			continue
+		}
+		row := []string{s.Column, components.FormatCount(s.Count), components.FormatCount(s.Nulls), components.FormatCount(s.NonFinite)}
+		for _, v := range []float64{s.Min, s.Max, s.Mean, s.StdDev, s.P25, s.P50, s.P75, s.P95} {
+			if s.Count == 0 {
+				row = append(row, "")
+			} else {
+				row = append(row, components.FormatNumber(v))
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	widths := make([]int, len(statsColumns))
+	for i, name := range statsColumns {
+		widths[i] = len(name)
+		for _, row := range table.Rows {
+			widths[i] = max(widths[i], displayWidth(row[i]))
+		}
+	}
+	widths[0] = min(widths[0], p.maxColWidth)
+
+	// Names on the left, numbers on the right
+	cell := func(i int, s string) string {
+		if i == 0 {
+			return components.FitCell(s, widths[i])
+		}
+		return strings.Repeat(" ", max(0, widths[i]-displayWidth(s))) + s
+	}
+	line := func(row []string) string {
+		cells := make([]string, len(row))
+		for i, s := range row {
+			cells[i] = cell(i, s)
+		}
+		return ansi.Truncate(strings.Join(cells, "  "), width, "")
+	}
+
+	lines := []string{headerStyle.Render(line(statsColumns))}
+	for _, row := range table.Rows {
+		lines = append(lines, line(row))
+	}
+	summary := fmt.Sprintf("Statistics of %s rows", components.FormatCount(len(p.view.Rows)))
+	if p.filter != nil {
+		summary += " (filtered)"
+	}
+	return strings.Join(lines, "\n") + "\n\n" + summary + "\n" + dimStyle.Render("[q] Close")
+}
+
+// registerChartCommands adds :chart and :stats
+func (p *ResultsPanel) registerChartCommands(registry *components.CommandRegistry) {
+	registry.Register(components.Command{
+		Name: "chart", Usage: "[bar|line|hist] [x] [y] | !",
+		Help: "Chart the rows shown: bars or a line of y over x, or a histogram of y, e.g. :chart line day total",
+		Complete: func(fields []string, arg string) []string {
+			if len(fields) == 0 {
+				return components.ChartKinds
+			}
+			if p.view != nil {
+				return p.view.Columns
+			}
+			return nil
+		},
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if args.Bang {
+				p.CloseChart()
+				return nil, nil
+			}
+			kind, fields := components.ChartBar, args.Fields
+			if len(fields) > 0 && slices.Contains(components.ChartKinds, fields[0]) {
+				kind, fields = fields[0], fields[1:]
+			}
+			var x, y string
+			switch {
+			case len(fields) > 2:
+				return nil, errors.New("usage: chart [bar|line|hist] [x] [y]")
+			case len(fields) == 2:
+				x, y = fields[0], fields[1]
+			case len(fields) == 1 && kind == components.ChartHistogram:
+				y = fields[0]
+			case len(fields) == 1:
+				x = fields[0]
+			}
+			return nil, p.ShowChart(kind, x, y)
+		},
+	})
+	registry.Register(components.Command{
+		Name: "stats",
+		Help: "Show the count, NULLs, min, max, average and percentiles of each numeric column",
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			return nil, p.ShowStats()
+		},
+	})
+}
@@ -0,0 +1,193 @@
+package panels
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/storage"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ExportQueryMsg asks the application to run the query of the result again
+// and stream all its rows to a file with storage.ExportQuery, for results
+// larger than what was fetched
+type ExportQueryMsg struct {
+	Query   string
+	Format  string
+	Path    string
+	Options storage.ExportOptions
+}
+
+// exportOptionNames are the key=value options of :export
+var exportOptionNames = []string{"columns=", "null=", "table=", "noheader"}
+
+// exportRequest is a parsed :export command line
+type exportRequest struct {
+	format  string
+	path    string // Empty or "+" for the clipboard
+	options storage.ExportOptions
+	columns bool // columns= was given
+}
+
+// parseExport parses the arguments of :export: the format, then the file
+// and options in any order
+func parseExport(fields []string) (exportRequest, error) {
+	if len(fields) == 0 {
+		return exportRequest{}, errors.New("usage: export <format> [file|+] [columns=a,b] [null=text] [table=name] [noheader]")
+	}
+	req := exportRequest{format: strings.ToLower(fields[0])}
+	nullSet := false
+	var path []string
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		switch {
+		case ok && key == "columns":
+			req.options.Columns = strings.Split(value, ",")
+			req.columns = true
+		case ok && key == "null":
+			req.options.Null = value
+			nullSet = true
+		case ok && key == "table":
+			req.options.Table = value
+		case field == "noheader":
+			req.options.NoHeader = true
+		default:
+			path = append(path, field)
+		}
+	}
+	req.path = strings.Join(path, " ")
+	if !nullSet && (req.format == "markdown" || req.format == "table") {
+		req.options.Null = db.NullText
+	}
+	if req.format == "sql" && req.options.Table == "" {
+		return req, errors.New("the sql format needs a target table: export sql <file> table=<name>")
+	}
+	return req, nil
+}
+
+// toClipboard reports whether the export goes to the clipboard
+func (r exportRequest) toClipboard() bool {
+	return r.path == "" || r.path == "+"
+}
+
+// exportSource returns the rows to export: those shown, filtered and
+// sorted, with the visible columns, or all columns when the export picks
+// its own
+func (p *ResultsPanel) exportSource(allColumns bool) db.QueryResult {
+	if !allColumns {
+		return *p.view
+	}
+	columns := make([]int, len(p.result.Columns))
+	for i := range columns {
+		columns[i] = i
+	}
+	return *p.project(p.viewRows, columns)
+}
+
+// Export writes the rows shown to a file, or to the clipboard when path is
+// empty or "+", and returns a message for the status line
+func (p *ResultsPanel) Export(fields []string) (string, error) {
+	if !p.hasTable() {
+		return "", errors.New("no result to export")
+	}
+	req, err := parseExport(fields)
+	if err != nil {
+		return "", err
+	}
+	result := p.exportSource(req.columns)
+
+	var what string
+	if req.toClipboard() {
+		if p.clipboard == nil {
+			return "", errors.New("no clipboard")
+		}
+		text, err := storage.FormatResult(req.format, result, req.options)
+		if err != nil {
+			return "", err
+		}
+		if err := p.clipboard(text); err != nil {
+			return "", fmt.Errorf("copy failed: %w", err)
+		}
+		what = fmt.Sprintf("Copied %s rows as %s", components.FormatCount(len(result.Rows)), req.format)
+	} else {
+		if err := storage.ExportResult(req.path, req.format, result, req.options); err != nil {
+			return "", err
+		}
+		what = fmt.Sprintf("%s rows written to %s", components.FormatCount(len(result.Rows)), req.path)
+	}
+	if p.result.RowCount > len(p.result.Rows) {
+		hint := ""
+		if db.IsSelectQuery(p.result.Query) {
+			hint = "; :export! runs the query again for all"
+		}
+		what += fmt.Sprintf(" (%s fetched of %s%s)", components.FormatCount(len(p.result.Rows)), components.FormatCount(p.result.RowCount), hint)
+	}
+	return what, nil
+}
+
+// errNotSelect is returned by exportQuery for results of statements that
+// can't safely run again, e.g. UPDATE ... RETURNING
+var errNotSelect = errors.New("only a SELECT runs again")
+
+// exportQuery builds the request to run the query again server-side and
+// stream every row to a file; only a single SELECT qualifies. The columns
+// shown are kept unless columns= picks others; the client-side sort and
+// filter don't apply.
+func (p *ResultsPanel) exportQuery(fields []string) (ExportQueryMsg, error) {
+	if !p.hasData || p.result == nil || p.result.Query == "" {
+		return ExportQueryMsg{}, errors.New("no query to export")
+	}
+	req, err := parseExport(fields)
+	if err != nil {
+		return ExportQueryMsg{}, err
+	}
+	if req.toClipboard() {
+		return ExportQueryMsg{}, errors.New("export! writes to a file")
+	}
+	if !db.IsSelectQuery(p.result.Query) {
+		return ExportQueryMsg{}, errNotSelect
+	}
+	if !req.columns && len(p.columns) < len(p.result.Columns) {
+		req.options.Columns = p.view.Columns
+	}
+	return ExportQueryMsg{Query: p.result.Query, Format: req.format, Path: req.path, Options: req.options}, nil
+}
+
+// registerExportCommand adds :export
+func (p *ResultsPanel) registerExportCommand(registry *components.CommandRegistry) {
+	registry.Register(components.Command{
+		Name: "export", Usage: "<format> [file|+] [columns=a,b] [null=text] [table=name] [noheader]",
+		Help: "Write the rows shown to a file, or copy them without one, e.g. :export csv ~/users.csv; export! runs the query again for all rows",
+		Complete: func(fields []string, arg string) []string {
+			if len(fields) == 0 {
+				return storage.ExportFormats
+			}
+			return exportOptionNames
+		},
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if args.Bang {
+				msg, err := p.exportQuery(args.Fields)
+				if errors.Is(err, errNotSelect) {
+					// Export what was fetched rather than write again
+					text, err := p.Export(args.Fields)
+					if err != nil {
+						return nil, err
+					}
+					return components.CommandMessage(text + "; only a SELECT runs again, so the fetched rows were exported"), nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				return func() tea.Msg { return msg }, nil
+			}
+			text, err := p.Export(args.Fields)
+			if err != nil {
+				return nil, err
+			}
+			return components.CommandMessage(text), nil
+		},
+	})
+}
@@ -0,0 +1,481 @@
+package panels
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// resultHistoryLimit is how many results the history keeps, besides the
+// pinned ones
+const resultHistoryLimit = 20
+
+// ResultEntry is a result kept in the history
+type ResultEntry struct {
+	ID         int // Shown as #ID, increasing from 1
+	Result     db.QueryResult
+	Connection string
+	At         time.Time
+	Pinned     bool
+	Name       string // Label of a pinned result's tab
+}
+
+// label names the entry in tabs and messages
+func (e *ResultEntry) label() string {
+	if e.Name != "" {
+		return fmt.Sprintf("#%d %s", e.ID, e.Name)
+	}
+	return fmt.Sprintf("#%d", e.ID)
+}
+
+// SetConnection sets the name of the connection the next results come from
+func (p *ResultsPanel) SetConnection(name string) {
+	p.connection = name
+}
+
+// History returns the results kept, oldest first
+func (p *ResultsPanel) History() []ResultEntry {
+	entries := make([]ResultEntry, len(p.history))
+	for i, e := range p.history {
+		entries[i] = *e
+	}
+	return entries
+}
+
+// ShownEntry returns the history entry shown, if any
+func (p *ResultsPanel) ShownEntry() (ResultEntry, bool) {
+	if p.shown < 0 {
+		return ResultEntry{}, false
+	}
+	return *p.history[p.shown], true
+}
+
+// record adds a result to the history, dropping the oldest unpinned one
+// beyond the limit, and returns its entry. Errors and results without rows
+// aren't kept.
+func (p *ResultsPanel) record(result db.QueryResult) *ResultEntry {
+	if result.Error != nil || len(result.Columns) == 0 {
+		return nil
+	}
+	p.nextID++
+	entry := &ResultEntry{ID: p.nextID, Result: result, Connection: p.connection, At: time.Now()}
+	p.history = append(p.history, entry)
+
+	unpinned := 0
+	for _, e := range p.history {
+		if !e.Pinned {
+			unpinned++
+		}
+	}
+	if unpinned > resultHistoryLimit {
+		oldest := slices.IndexFunc(p.history, func(e *ResultEntry) bool { return !e.Pinned })
+		p.history = slices.Delete(p.history, oldest, oldest+1)
+	}
+	return entry
+}
+
+// showEntry displays a result of the history
+func (p *ResultsPanel) showEntry(i int) {
+	p.shown = i
+	p.diff = nil
+	p.result = &p.history[i].Result
+	p.hasData = true
+	p.resetView()
+	p.resetCursor()
+	p.message = p.entryDescription(p.history[i])
+}
+
+// entryDescription describes a result for the status line
+func (p *ResultsPanel) entryDescription(e *ResultEntry) string {
+	parts := []string{e.label(), e.At.Format("15:04:05")}
+	if e.Connection != "" {
+		parts = append(parts, e.Connection)
+	}
+	if query := strings.Join(strings.Fields(e.Result.Query), " "); query != "" {
+		parts = append(parts, query)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// findEntry returns the index of the entry named by #ID, ID or a pinned
+// result's name
+func (p *ResultsPanel) findEntry(name string) (int, error) {
+	if id, err := strconv.Atoi(strings.TrimPrefix(name, "#")); err == nil {
+		if i := slices.IndexFunc(p.history, func(e *ResultEntry) bool { return e.ID == id }); i >= 0 {
+			return i, nil
+		}
+		return -1, fmt.Errorf("no result #%d in the history", id)
+	}
+	if i := slices.IndexFunc(p.history, func(e *ResultEntry) bool { return e.Pinned && e.Name == name }); i >= 0 {
+		return i, nil
+	}
+	return -1, fmt.Errorf("no pinned result %s", name)
+}
+
+// tabs returns the entries shown as tabs: the pinned results and the
+// latest one
+func (p *ResultsPanel) tabs() []int {
+	var tabs []int
+	for i, e := range p.history {
+		if e.Pinned || i == len(p.history)-1 {
+			tabs = append(tabs, i)
+		}
+	}
+	return tabs
+}
+
+// switchTab shows the next or previous tab
+func (p *ResultsPanel) switchTab(delta int) {
+	tabs := p.tabs()
+	if len(tabs) == 0 {
+		return
+	}
+	current := slices.Index(tabs, p.shown)
+	if current < 0 {
+		// Browsing the history: go to the nearest tab that way
+		current = len(tabs)
+		for j, i := range tabs {
+			if i > p.shown {
+				current = j
+				break
+			}
+		}
+		if delta > 0 {
+			current--
+		}
+	}
+	p.showEntry(tabs[(current+delta+len(tabs))%len(tabs)])
+}
+
+// browseHistory shows an older (delta < 0) or newer result of the history
+func (p *ResultsPanel) browseHistory(delta int) {
+	if len(p.history) == 0 {
+		return
+	}
+	i := p.shown + delta
+	if p.shown < 0 {
+		i = len(p.history) - 1
+	}
+	if i < 0 || i >= len(p.history) {
+		p.message = "No more results in the history"
+		return
+	}
+	p.showEntry(i)
+}
+
+// PinResult keeps the result shown in a tab, out of the history's limit
+func (p *ResultsPanel) PinResult(name string) error {
+	if p.shown < 0 {
+		return errors.New("no result to pin")
+	}
+	e := p.history[p.shown]
+	if name != "" && slices.ContainsFunc(p.history, func(other *ResultEntry) bool {
+		return other != e && other.Pinned && other.Name == name
+	}) {
+		return fmt.Errorf("a pinned result is already named %s", name)
+	}
+	e.Pinned, e.Name = true, name
+	return nil
+}
+
+// UnpinResult returns the result shown to the history
+func (p *ResultsPanel) UnpinResult() error {
+	if p.shown < 0 || !p.history[p.shown].Pinned {
+		return errors.New("the result shown is not pinned")
+	}
+	p.history[p.shown].Pinned = false
+	p.history[p.shown].Name = ""
+	return nil
+}
+
+// renderTabs draws the tabs of the pinned results and where the result
+// shown comes from, empty while there is only one result
+func (p *ResultsPanel) renderTabs() string {
+	if len(p.history) < 2 && p.diff == nil {
+		return ""
+	}
+	activeStyle := lipgloss.NewStyle().Reverse(true)
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	var parts []string
+	for _, i := range p.tabs() {
+		label := " " + p.history[i].label() + " "
+		if i == p.shown {
+			label = activeStyle.Render(label)
+		}
+		parts = append(parts, label)
+	}
+	line := strings.Join(parts, "│")
+	if p.shown >= 0 {
+		e := p.history[p.shown]
+		if !slices.Contains(p.tabs(), p.shown) {
+			line += " " + activeStyle.Render(" "+e.label()+" ")
+		}
+		from := " " + e.At.Format("15:04:05")
+		if e.Connection != "" {
+			from += " · " + e.Connection
+		}
+		line += dimStyle.Render(from)
+	}
+	return ansi.Truncate(line, p.tableWidth(), "…")
+}
+
+// resultDiff is the comparison of two results shown instead of the table
+type resultDiff struct {
+	diff      db.ResultDiff
+	before    *ResultEntry
+	after     *ResultEntry
+	rows      []int // Rows of diff shown
+	all       bool  // Show unchanged rows too
+	offset    int   // First row shown
+	colOffset int   // First column shown after the key
+}
+
+// filterRows picks the rows shown: the differences, or all of them
+func (d *resultDiff) filterRows() {
+	d.rows = d.rows[:0]
+	for i, row := range d.diff.Rows {
+		if d.all || row.Kind != db.DiffSame {
+			d.rows = append(d.rows, i)
+		}
+	}
+	d.offset = 0
+}
+
+// DiffWith compares the result shown with another of the history (the
+// previous one when other is empty), matching rows on a key column (the
+// focused column when empty)
+func (p *ResultsPanel) DiffWith(other, key string) error {
+	if p.shown < 0 {
+		return errors.New("no result to compare")
+	}
+	before := p.shown - 1
+	if other != "" {
+		var err error
+		if before, err = p.findEntry(other); err != nil {
+			return err
+		}
+	}
+	if before < 0 {
+		return errors.New("no earlier result to compare with")
+	}
+	if before == p.shown {
+		return errors.New("that is the result shown")
+	}
+	if key == "" {
+		if col := p.focusedColumn(); col >= 0 {
+			key = p.result.Columns[col]
+		}
+	}
+
+	d := &resultDiff{before: p.history[before], after: p.history[p.shown]}
+	var err error
+	d.diff, err = db.DiffResults(d.before.Result, d.after.Result, key)
+	if err != nil {
+		return err
+	}
+	d.filterRows()
+	p.diff = d
+	p.message = ""
+	return nil
+}
+
+// CloseDiff goes back from the comparison to the table
+func (p *ResultsPanel) CloseDiff() {
+	p.diff = nil
+}
+
+// IsDiffing reports whether a comparison is shown
+func (p *ResultsPanel) IsDiffing() bool {
+	return p.diff != nil
+}
+
+// diffKey scrolls the comparison; = shows or hides unchanged rows
+func (p *ResultsPanel) diffKey(key string) {
+	d := p.diff
+	page := p.visibleRows()
+	switch key {
+	case "q", "esc":
+		p.CloseDiff()
+		return
+	case "j", "down":
+		d.offset++
+	case "k", "up":
+		d.offset--
+	case "ctrl+d":
+		d.offset += page / 2
+	case "ctrl+u":
+		d.offset -= page / 2
+	case "g", "home":
+		d.offset = 0
+	case "G", "end":
+		d.offset = len(d.rows)
+	case "l", "right", "w":
+		d.colOffset++
+	case "h", "left", "b":
+		d.colOffset--
+	case "=":
+		d.all = !d.all
+		d.filterRows()
+	}
+	d.offset = max(0, min(d.offset, len(d.rows)-page))
+	d.colOffset = max(0, min(d.colOffset, len(d.diff.Columns)-2))
+}
+
+// renderDiff draws the rows that differ between two results: added rows
+// in green, removed ones in red, and changed values as old → new
+func (p *ResultsPanel) renderDiff() string {
+	d := p.diff
+	width := p.tableWidth()
+	addedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	removedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	changedStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("3"))
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	// The key column first, then the others from colOffset
+	keyCol := slices.Index(d.diff.Columns, d.diff.Key)
+	columns := []int{keyCol}
+	for col := range d.diff.Columns {
+		if col != keyCol {
+			columns = append(columns, col)
+		}
+	}
+	columns = append(columns[:1], columns[1+min(d.colOffset, len(columns)-1):]...)
+
+	end := min(d.offset+p.visibleRows(), len(d.rows))
+	cells := db.QueryResult{}
+	for _, col := range columns {
+		cells.Columns = append(cells.Columns, d.diff.Columns[col])
+	}
+	for _, r := range d.rows[d.offset:end] {
+		row := d.diff.Rows[r]
+		line := make([]string, len(columns))
+		for i, col := range columns {
+			switch row.Kind {
+			case db.DiffRemoved:
+				line[i] = row.Before[col]
+			case db.DiffChanged:
+				line[i] = row.After[col]
+				if row.Changed[col] {
+					line[i] = row.Before[col] + " → " + row.After[col]
+				}
+			default:
+				line[i] = row.After[col]
+			}
+		}
+		cells.Rows = append(cells.Rows, line)
+	}
+	widths := columnWidths(cells, p.minColWidth, p.maxColWidth)
+
+	header := "  │ "
+	separator := "──┼─"
+	for i, name := range cells.Columns {
+		header += components.FitCell(name, widths[i]) + " │ "
+		separator += strings.Repeat("─", widths[i]) + "─┼─"
+	}
+	lines := []string{header, strings.TrimSuffix(separator, "┼─") + "┤"}
+	markers := map[db.DiffKind]string{db.DiffAdded: "+", db.DiffRemoved: "-", db.DiffChanged: "~", db.DiffSame: " "}
+	for j, r := range d.rows[d.offset:end] {
+		row := d.diff.Rows[r]
+		line := markers[row.Kind] + " │ "
+		for i, col := range columns {
+			cell := components.FitCell(cells.Rows[j][i], widths[i])
+			switch {
+			case row.Kind == db.DiffAdded:
+				cell = addedStyle.Render(cell)
+			case row.Kind == db.DiffRemoved:
+				cell = removedStyle.Render(cell)
+			case row.Kind == db.DiffChanged && row.Changed[col]:
+				cell = changedStyle.Render(cell)
+			case row.Kind == db.DiffSame:
+				cell = dimStyle.Render(cell)
+			}
+			line += cell + " │ "
+		}
+		lines = append(lines, line)
+	}
+	if len(d.rows) == 0 {
+		lines = append(lines, dimStyle.Render("  No differences"))
+	}
+	for i, line := range lines {
+		lines[i] = ansi.Truncate(line, width, "")
+	}
+
+	summary := fmt.Sprintf("%s → %s by %s: %d added, %d removed, %d changed, %d same",
+		d.before.label(), d.after.label(), d.diff.Key, d.diff.Added, d.diff.Removed, d.diff.Changed, d.diff.Same)
+	if len(d.rows) > end-d.offset {
+		summary += fmt.Sprintf(" (showing %d-%d of %d)", d.offset+1, end, len(d.rows))
+	}
+	help := "[j/k] Scroll  [h/l] Columns  [=] Unchanged rows  [q] Close"
+	return strings.Join(lines, "\n") + "\n\n" + ansi.Truncate(summary, width, "…") + "\n" + ansi.Truncate(help, width, "…")
+}
+
+// registerHistoryCommands adds the commands browsing, pinning and
+// comparing past results
+func (p *ResultsPanel) registerHistoryCommands(registry *components.CommandRegistry) {
+	registry.Register(components.Command{
+		Name: "history", Usage: "[#id|name]",
+		Help: "Show a past result, or list the results kept ([ and ] browse them)",
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if args.Arg == "" {
+				if len(p.history) == 0 {
+					return nil, errors.New("no result in the history")
+				}
+				var labels []string
+				for _, e := range p.history {
+					labels = append(labels, e.label())
+				}
+				return components.CommandMessage(fmt.Sprintf("%d results: %s", len(p.history), strings.Join(labels, ", "))), nil
+			}
+			i, err := p.findEntry(args.Arg)
+			if err != nil {
+				return nil, err
+			}
+			p.showEntry(i)
+			return nil, nil
+		},
+	})
+	registry.Register(components.Command{
+		Name: "keep", Usage: "[name] | !",
+		Help: "Pin the result shown in a tab, kept until unpinned with keep! (gt/gT switch tabs)",
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if args.Bang {
+				return nil, p.UnpinResult()
+			}
+			if err := p.PinResult(args.Arg); err != nil {
+				return nil, err
+			}
+			return components.CommandMessage("Pinned " + p.history[p.shown].label()), nil
+		},
+	})
+	registry.Register(components.Command{
+		Name: "diff", Usage: "[#id|name] [key=column] | !",
+		Help: "Compare the result shown with an earlier one (the previous by default), row by row on a key column (the focused one by default)",
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if args.Bang {
+				p.CloseDiff()
+				return nil, nil
+			}
+			var other, key string
+			for _, field := range args.Fields {
+				if k, ok := strings.CutPrefix(field, "key="); ok {
+					key = k
+				} else if other == "" {
+					other = field
+				} else {
+					return nil, errors.New("usage: diff [#id|name] [key=column]")
+				}
+			}
+			return nil, p.DiffWith(other, key)
+		},
+	})
+}
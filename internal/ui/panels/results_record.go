@@ -0,0 +1,163 @@
+package panels
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/config"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Record view modes, as psql's \x
+const (
+	ExpandedOff  = "off"
+	ExpandedOn   = "on"
+	ExpandedAuto = "auto" // Only when the table is wider than the panel
+)
+
+// SetResultsConfig applies the results panel settings
+func (p *ResultsPanel) SetResultsConfig(cfg config.ResultsConfig) {
+	if err := p.SetExpanded(cfg.Expanded); err != nil {
+		p.expanded = ExpandedOff
+	}
+	if err := p.SetColumnWidths(cfg.MinColumnWidth, cfg.MaxColumnWidth); err != nil {
+		p.SetColumnWidths(defaultMinColWidth, defaultMaxColWidth)
+	}
+}
+
+// SetExpanded sets the record view mode
+func (p *ResultsPanel) SetExpanded(mode string) error {
+	switch mode {
+	case ExpandedOff, ExpandedOn, ExpandedAuto:
+		p.expanded = mode
+		return nil
+	}
+	return fmt.Errorf("expanded mode must be off, on or auto, got %q", mode)
+}
+
+// Expanded returns the record view mode
+func (p *ResultsPanel) Expanded() string {
+	return p.expanded
+}
+
+// IsExpanded reports whether the focused row is shown as a record, one
+// field per line, instead of the table
+func (p *ResultsPanel) IsExpanded() bool {
+	if !p.hasTable() || len(p.view.Rows) == 0 {
+		return false
+	}
+	switch p.expanded {
+	case ExpandedOn:
+		return true
+	case ExpandedAuto:
+		return p.tableOverflows()
+	}
+	return false
+}
+
+// tableOverflows reports whether the table is wider than the panel
+func (p *ResultsPanel) tableOverflows() bool {
+	gutter := len(strconv.Itoa(len(p.view.Rows))) + 1
+	return gutter+p.columnsWidth(0, len(p.view.Columns)-1) > p.tableWidth()
+}
+
+// toggleExpanded switches between the table and the record view
+func (p *ResultsPanel) toggleExpanded() {
+	if p.IsExpanded() {
+		p.expanded = ExpandedOff
+	} else {
+		p.expanded = ExpandedOn
+	}
+	p.moveTo(p.cursorRow, p.cursorCol)
+	p.message = fmt.Sprintf("Expanded display is %s", p.expanded)
+}
+
+// visibleFields returns how many fields of a record fit in the panel: the
+// rows of the table plus its separator line
+func (p *ResultsPanel) visibleFields() int {
+	return p.visibleRows() + 1
+}
+
+// recordKey handles the keys that move differently in the record view: j/k
+// move between fields and h/l (or w/b) between records. It reports whether
+// the key was handled.
+func (p *ResultsPanel) recordKey(key string) bool {
+	fields := p.visibleFields()
+	switch key {
+	case "down", "j":
+		p.moveTo(p.cursorRow, p.cursorCol+1)
+	case "up", "k":
+		p.moveTo(p.cursorRow, p.cursorCol-1)
+	case "right", "l", "w", "tab":
+		p.moveTo(p.cursorRow+1, p.cursorCol)
+	case "left", "h", "b", "shift+tab":
+		p.moveTo(p.cursorRow-1, p.cursorCol)
+	case "ctrl+d":
+		p.moveTo(p.cursorRow, p.cursorCol+max(1, fields/2))
+	case "ctrl+u":
+		p.moveTo(p.cursorRow, p.cursorCol-max(1, fields/2))
+	case "pgdown", "ctrl+f":
+		p.moveTo(p.cursorRow, p.cursorCol+fields)
+	case "pgup", "ctrl+b":
+		p.moveTo(p.cursorRow, p.cursorCol-fields)
+	default:
+		return false
+	}
+	return true
+}
+
+// renderRecord draws the focused row as column name and value pairs under
+// a record header, then the summary and status lines
+func (p *ResultsPanel) renderRecord() string {
+	width := p.tableWidth()
+	columns := p.view.Columns
+	row := p.view.Rows[p.cursorRow]
+
+	header := fmt.Sprintf("─[ RECORD %d of %d ]", p.cursorRow+1, len(p.view.Rows))
+	header += strings.Repeat("─", max(0, width-ansi.StringWidth(header)))
+	lines := []string{ansi.Truncate(header, width, "")}
+
+	nameWidth := 0
+	for _, col := range columns {
+		nameWidth = max(nameWidth, ansi.StringWidth(components.EscapeControls(col)))
+	}
+	nameWidth = min(min(nameWidth, p.maxColWidth), width/2)
+
+	cursorStyle := lipgloss.NewStyle().Reverse(true)
+	nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	currentNameStyle := lipgloss.NewStyle().Bold(true)
+
+	end := min(p.fieldOffset+p.visibleFields(), len(columns))
+	for col := p.fieldOffset; col < end; col++ {
+		value := ""
+		if col < len(row) {
+			value = components.EscapeControls(row[col])
+		}
+		value = ansi.Truncate(value, max(1, width-nameWidth-3), "…")
+		name := components.FitCell(columns[col], nameWidth)
+		if col == p.cursorCol {
+			name = currentNameStyle.Render(name)
+			if value == "" {
+				value = " "
+			}
+			value = cursorStyle.Render(value)
+		} else {
+			name = nameStyle.Render(name)
+		}
+		lines = append(lines, name+" │ "+value)
+	}
+
+	summary := fmt.Sprintf("%s, %dms", p.viewSummary(), p.result.ExecutionMs)
+	if end-p.fieldOffset < len(columns) {
+		summary += fmt.Sprintf(" · fields %d-%d of %d", p.fieldOffset+1, end, len(columns))
+	}
+	if p.expanded == ExpandedAuto {
+		summary += " · expanded auto"
+	}
+	summary = ansi.Truncate(summary+p.viewDetails(), width, "…")
+
+	return strings.Join(lines, "\n") + "\n\n" + summary + "\n" + p.renderStatus()
+}
@@ -0,0 +1,533 @@
+package panels
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// rowFilter keeps the rows with a value matching a substring or a regexp
+type rowFilter struct {
+	pattern string         // As typed, for display
+	text    string         // Substring, lowercased unless caseSensitive
+	re      *regexp.Regexp // Regexp for a /pattern/, nil for a substring
+	column  int            // Column of the result to match, -1 for all
+
+	caseSensitive bool
+}
+
+// newRowFilter parses a filter pattern: /regexp/ or a substring, matched
+// ignoring case unless it has an upper case letter
+func newRowFilter(pattern string, column int) (*rowFilter, error) {
+	f := &rowFilter{pattern: pattern, column: column}
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+		f.re = re
+		return f, nil
+	}
+	f.caseSensitive = strings.IndexFunc(pattern, unicode.IsUpper) >= 0
+	f.text = pattern
+	if !f.caseSensitive {
+		f.text = strings.ToLower(pattern)
+	}
+	return f, nil
+}
+
+// matchValue reports whether a value matches the filter
+func (f *rowFilter) matchValue(value string) bool {
+	if f.re != nil {
+		return f.re.MatchString(value)
+	}
+	if !f.caseSensitive {
+		value = strings.ToLower(value)
+	}
+	return strings.Contains(value, f.text)
+}
+
+// match reports whether a row of the result matches the filter
+func (f *rowFilter) match(row []string) bool {
+	if f.column >= 0 {
+		return f.column < len(row) && f.matchValue(row[f.column])
+	}
+	return slices.ContainsFunc(row, f.matchValue)
+}
+
+// resetView shows all rows and columns of a new result in their order
+func (p *ResultsPanel) resetView() {
+	p.sortCol, p.sortDesc = -1, false
+	p.filter = nil
+	p.columns = nil
+	p.pinned = 0
+	if p.result != nil {
+		for col := range p.result.Columns {
+			p.columns = append(p.columns, col)
+		}
+		p.baseWidths = columnWidths(*p.result, p.minColWidth, p.maxColWidth)
+	}
+	p.applyView()
+}
+
+// applyView derives the rows and columns shown from the result: the rows
+// matching the filter, sorted, with the visible columns in display order.
+// The cursor stays on the same column.
+func (p *ResultsPanel) applyView() {
+	if p.result == nil {
+		p.view, p.viewRows, p.colWidths = nil, nil, nil
+		return
+	}
+	r := p.result
+
+	rows := make([]int, 0, len(r.Rows))
+	for i, row := range r.Rows {
+		if p.filter == nil || p.filter.match(row) {
+			rows = append(rows, i)
+		}
+	}
+	if p.sortCol >= 0 {
+		typeName := ""
+		if p.sortCol < len(r.ColumnTypes) {
+			typeName = r.ColumnTypes[p.sortCol]
+		}
+		sort.SliceStable(rows, func(a, b int) bool {
+			// NULLs sort last in both directions
+			nullA, nullB := r.IsNull(rows[a], p.sortCol), r.IsNull(rows[b], p.sortCol)
+			if nullA || nullB {
+				return !nullA && nullB
+			}
+			c := db.CompareValues(cellAt(r.Rows[rows[a]], p.sortCol), cellAt(r.Rows[rows[b]], p.sortCol), typeName)
+			if p.sortDesc {
+				return c > 0
+			}
+			return c < 0
+		})
+	}
+
+	p.colWidths = make([]int, len(p.columns))
+	for i, col := range p.columns {
+		p.colWidths[i] = p.baseWidths[col]
+		if col == p.sortCol {
+			// Room for the sort arrow after the name
+			p.colWidths[i] = max(p.colWidths[i], min(displayWidth(r.Columns[col]), p.maxColWidth)+2)
+		}
+	}
+	p.view = p.project(rows, p.columns)
+	p.viewRows = rows
+}
+
+// project returns the given rows and columns of the result
+func (p *ResultsPanel) project(rows, columns []int) *db.QueryResult {
+	r := p.result
+	view := &db.QueryResult{Query: r.Query, RowCount: len(rows), ExecutionMs: r.ExecutionMs, Error: r.Error}
+	for _, col := range columns {
+		view.Columns = append(view.Columns, r.Columns[col])
+		if col < len(r.ColumnTypes) {
+			view.ColumnTypes = append(view.ColumnTypes, r.ColumnTypes[col])
+		}
+	}
+	if len(view.ColumnTypes) != len(view.Columns) {
+		view.ColumnTypes = nil
+	}
+	for _, i := range rows {
+		row := make([]string, len(columns))
+		var nulls []bool
+		for j, col := range columns {
+			row[j] = cellAt(r.Rows[i], col)
+			if r.IsNull(i, col) {
+				if nulls == nil {
+					nulls = make([]bool, len(columns))
+				}
+				nulls[j] = true
+			}
+		}
+		view.Rows = append(view.Rows, row)
+		view.Nulls = append(view.Nulls, nulls)
+	}
+	return view
+}
+
+// cellAt returns a value of a row, empty past its end
+func cellAt(row []string, col int) string {
+	if col < len(row) {
+		return row[col]
+	}
+	return ""
+}
+
+// focusedColumn returns the result column under the cursor, -1 for none
+func (p *ResultsPanel) focusedColumn() int {
+	if p.cursorCol < len(p.columns) {
+		return p.columns[p.cursorCol]
+	}
+	return -1
+}
+
+// focusColumn moves the cursor to a result column if it is shown
+func (p *ResultsPanel) focusColumn(col int) {
+	if i := slices.Index(p.columns, col); i >= 0 {
+		p.moveTo(p.cursorRow, i)
+		return
+	}
+	p.moveTo(p.cursorRow, p.cursorCol)
+}
+
+// columnIndex finds a result column by name, ignoring case
+func (p *ResultsPanel) columnIndex(name string) (int, error) {
+	for col, column := range p.result.Columns {
+		if column == name {
+			return col, nil
+		}
+	}
+	for col, column := range p.result.Columns {
+		if strings.EqualFold(column, name) {
+			return col, nil
+		}
+	}
+	return -1, fmt.Errorf("no column %q", name)
+}
+
+// columnNames completes column names
+func (p *ResultsPanel) columnNames() []string {
+	if !p.hasTable() {
+		return nil
+	}
+	return p.result.Columns
+}
+
+// SortBy sorts the rows by a result column, or restores the query's order
+// for -1
+func (p *ResultsPanel) SortBy(col int, desc bool) {
+	focused := p.focusedColumn()
+	p.sortCol, p.sortDesc = col, desc
+	p.applyView()
+	p.cursorRow, p.rowOffset = 0, 0
+	p.focusColumn(focused)
+}
+
+// cycleSort sorts by the focused column ascending, then descending, then
+// not at all
+func (p *ResultsPanel) cycleSort() {
+	col := p.focusedColumn()
+	switch {
+	case col != p.sortCol:
+		p.SortBy(col, false)
+	case !p.sortDesc:
+		p.SortBy(col, true)
+	default:
+		p.SortBy(-1, false)
+	}
+	if p.sortCol < 0 {
+		p.message = "Sort cleared"
+	} else {
+		p.message = fmt.Sprintf("Sorted by %s %s", p.result.Columns[col], sortDirection(p.sortDesc))
+	}
+}
+
+// sortDirection names a sort direction
+func sortDirection(desc bool) string {
+	if desc {
+		return "desc"
+	}
+	return "asc"
+}
+
+// SetFilter keeps the rows matching a pattern (/regexp/ or a substring) in
+// a result column, or in any column for -1. An empty pattern clears the
+// filter.
+func (p *ResultsPanel) SetFilter(pattern string, col int) error {
+	var filter *rowFilter
+	if pattern != "" {
+		var err error
+		if filter, err = newRowFilter(pattern, col); err != nil {
+			return err
+		}
+	}
+	focused := p.focusedColumn()
+	p.filter = filter
+	p.applyView()
+	p.cursorRow, p.rowOffset = 0, 0
+	p.focusColumn(focused)
+	return nil
+}
+
+// filterByFocusedValue keeps the rows with the focused value in its column
+func (p *ResultsPanel) filterByFocusedValue() {
+	value, column, _, ok := p.FocusedCell()
+	if !ok {
+		return
+	}
+	p.SetFilter("/^"+regexp.QuoteMeta(value)+"$/", p.focusedColumn())
+	p.message = fmt.Sprintf("Filtered %s = %s", column, value)
+}
+
+// HideColumn hides a result column. The last shown column can't be hidden.
+func (p *ResultsPanel) HideColumn(col int) error {
+	i := slices.Index(p.columns, col)
+	if i < 0 {
+		return nil
+	}
+	if len(p.columns) == 1 {
+		return errors.New("can't hide the last column")
+	}
+	p.columns = slices.Delete(p.columns, i, i+1)
+	if i < p.pinned {
+		p.pinned--
+	}
+	p.applyView()
+	p.moveTo(p.cursorRow, min(p.cursorCol, len(p.columns)-1))
+	return nil
+}
+
+// ShowColumn shows a hidden result column again
+func (p *ResultsPanel) ShowColumn(col int) {
+	if !slices.Contains(p.columns, col) {
+		focused := p.focusedColumn()
+		p.insertColumn(col)
+		p.applyView()
+		p.focusColumn(focused)
+	}
+}
+
+// ShowAllColumns shows the hidden columns again
+func (p *ResultsPanel) ShowAllColumns() {
+	focused := p.focusedColumn()
+	for col := range p.result.Columns {
+		if !slices.Contains(p.columns, col) {
+			p.insertColumn(col)
+		}
+	}
+	p.applyView()
+	p.focusColumn(focused)
+}
+
+// insertColumn adds a column to the scrolling columns, before the first
+// one that comes after it in the result
+func (p *ResultsPanel) insertColumn(col int) {
+	pos := len(p.columns)
+	for i := p.pinned; i < len(p.columns); i++ {
+		if p.columns[i] > col {
+			pos = i
+			break
+		}
+	}
+	p.columns = slices.Insert(p.columns, pos, col)
+}
+
+// PinColumn pins a result column to the left of the table, where it stays
+// when scrolling, or unpins it
+func (p *ResultsPanel) PinColumn(col int, pin bool) {
+	i := slices.Index(p.columns, col)
+	if i < 0 || (i < p.pinned) == pin {
+		return
+	}
+	focused := p.focusedColumn()
+	p.columns = slices.Delete(p.columns, i, i+1)
+	if pin {
+		p.columns = slices.Insert(p.columns, p.pinned, col)
+		p.pinned++
+	} else {
+		p.pinned--
+		p.columns = slices.Insert(p.columns, p.pinned, col)
+	}
+	p.applyView()
+	p.focusColumn(focused)
+}
+
+// moveColumn moves the focused column left or right by one, within the
+// pinned or the scrolling columns
+func (p *ResultsPanel) moveColumn(delta int) {
+	i, j := p.cursorCol, p.cursorCol+delta
+	if j < 0 || j >= len(p.columns) || (i < p.pinned) != (j < p.pinned) {
+		return
+	}
+	p.columns[i], p.columns[j] = p.columns[j], p.columns[i]
+	p.applyView()
+	p.moveTo(p.cursorRow, j)
+}
+
+// isPinned reports whether the view column i is pinned
+func (p *ResultsPanel) isPinned(i int) bool {
+	return i < p.pinned
+}
+
+// viewSummary describes the rows shown: filtered and sorted, with the
+// hidden column count
+func (p *ResultsPanel) viewSummary() string {
+	summary := ""
+	if p.filter != nil {
+		summary = fmt.Sprintf("filtered %s of %s rows", components.FormatCount(len(p.view.Rows)), components.FormatCount(len(p.result.Rows)))
+	} else if len(p.result.Rows) < p.result.RowCount {
+		summary = fmt.Sprintf("fetched %s of %s rows", components.FormatCount(len(p.result.Rows)), components.FormatCount(p.result.RowCount))
+	} else {
+		summary = fmt.Sprintf("%s rows", components.FormatCount(p.result.RowCount))
+	}
+	return summary
+}
+
+// viewDetails describes the sort and the hidden columns, empty when the
+// result is shown as returned
+func (p *ResultsPanel) viewDetails() string {
+	var details []string
+	if p.filter != nil {
+		where := "all columns"
+		if p.filter.column >= 0 {
+			where = p.result.Columns[p.filter.column]
+		}
+		details = append(details, fmt.Sprintf("%s in %s", p.filter.pattern, where))
+	}
+	if p.sortCol >= 0 {
+		details = append(details, fmt.Sprintf("sorted by %s %s", p.result.Columns[p.sortCol], sortDirection(p.sortDesc)))
+	}
+	if hidden := len(p.result.Columns) - len(p.columns); hidden > 0 {
+		details = append(details, fmt.Sprintf("%d hidden", hidden))
+	}
+	if len(details) == 0 {
+		return ""
+	}
+	return " · " + strings.Join(details, " · ")
+}
+
+// registerViewCommands adds the commands sorting, filtering and arranging
+// the columns of the result
+func (p *ResultsPanel) registerViewCommands(registry *components.CommandRegistry) {
+	columns := components.CompleteFrom(p.columnNames)
+
+	registry.Register(components.Command{
+		Name: "sort", Usage: "[column] [asc|desc]",
+		Help: "Sort the rows by a column (the focused one by default); no argument restores the query's order",
+		Complete: func(fields []string, arg string) []string {
+			if len(fields) == 1 {
+				return []string{"asc", "desc"}
+			}
+			return columns(fields, arg)
+		},
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if !p.hasTable() {
+				return nil, errors.New("no result to sort")
+			}
+			fields := args.Fields
+			if len(fields) == 0 {
+				p.SortBy(-1, false)
+				return components.CommandMessage("Sort cleared"), nil
+			}
+			desc := false
+			if last := strings.ToLower(fields[len(fields)-1]); last == "asc" || last == "desc" {
+				desc = last == "desc"
+				fields = fields[:len(fields)-1]
+			}
+			col := p.focusedColumn()
+			if len(fields) > 0 {
+				var err error
+				if col, err = p.columnIndex(strings.Join(fields, " ")); err != nil {
+					return nil, err
+				}
+			}
+			p.SortBy(col, desc)
+			return components.CommandMessage(fmt.Sprintf("Sorted by %s %s", p.result.Columns[col], sortDirection(desc))), nil
+		},
+	})
+
+	registry.Register(components.Command{
+		Name: "filter", Usage: "[text|/regexp/]",
+		Help: "Keep the rows with a value containing text or matching /regexp/; filter! matches the focused column only; no argument clears",
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if !p.hasTable() {
+				return nil, errors.New("no result to filter")
+			}
+			col := -1
+			if args.Bang {
+				col = p.focusedColumn()
+			}
+			if err := p.SetFilter(args.Arg, col); err != nil {
+				return nil, err
+			}
+			if args.Arg == "" {
+				return components.CommandMessage("Filter cleared"), nil
+			}
+			return components.CommandMessage(p.viewSummary()), nil
+		},
+	})
+
+	// eachColumn runs fn on the named columns, or the focused one
+	eachColumn := func(args components.CommandArgs, fn func(col int) error) error {
+		if !p.hasTable() {
+			return errors.New("no result")
+		}
+		if len(args.Fields) == 0 {
+			return fn(p.focusedColumn())
+		}
+		for _, name := range args.Fields {
+			col, err := p.columnIndex(name)
+			if err != nil {
+				return err
+			}
+			if err := fn(col); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	registry.Register(components.Command{
+		Name: "hide", Usage: "[column...]",
+		Help:     "Hide columns (the focused one by default)",
+		Complete: columns,
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			return nil, eachColumn(args, p.HideColumn)
+		},
+	})
+	registry.Register(components.Command{
+		Name: "show", Usage: "[column...]",
+		Help:     "Show hidden columns again (all by default)",
+		Complete: columns,
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if len(args.Fields) == 0 && p.hasTable() {
+				p.ShowAllColumns()
+				return nil, nil
+			}
+			return nil, eachColumn(args, func(col int) error {
+				p.ShowColumn(col)
+				return nil
+			})
+		},
+	})
+	registry.Register(components.Command{
+		Name: "pin", Usage: "[column...]",
+		Help:     "Keep columns on the left while scrolling (the focused one by default)",
+		Complete: columns,
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			return nil, eachColumn(args, func(col int) error {
+				p.ShowColumn(col)
+				p.PinColumn(col, true)
+				return nil
+			})
+		},
+	})
+	registry.Register(components.Command{
+		Name: "unpin", Usage: "[column...]",
+		Help:     "Let pinned columns scroll again (all by default)",
+		Complete: columns,
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if len(args.Fields) == 0 && p.hasTable() {
+				for p.pinned > 0 {
+					p.PinColumn(p.columns[0], false)
+				}
+				return nil, nil
+			}
+			return nil, eachColumn(args, func(col int) error {
+				p.PinColumn(col, false)
+				return nil
+			})
+		},
+	})
+}
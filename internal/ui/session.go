@@ -0,0 +1,21 @@
+package ui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SessionTickMsg asks the application to save the session
+type SessionTickMsg struct{}
+
+// SessionTick schedules the next periodic session save. It returns nil
+// when autosave is disabled (a zero interval), leaving only the save on quit.
+func SessionTick(interval time.Duration) tea.Cmd {
+	if interval <= 0 {
+		return nil
+	}
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return SessionTickMsg{}
+	})
+}
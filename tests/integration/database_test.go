@@ -3,9 +3,11 @@ package integration
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/storage"
 )
 
 // These tests require a running PostgreSQL instance
@@ -92,4 +94,62 @@ func TestQueryExecution(t *testing.T) {
 	if len(result.Columns) != 2 {
 		t.Errorf("Expected 2 columns, got %d", len(result.Columns))
 	}
+
+	// Rows past the limit are counted but not kept
+	result = db.ExecuteQueryLimit(ctx, conn.Conn(), "SELECT generate_series(1, 10)", 3)
+	if result.Error != nil || len(result.Rows) != 3 || result.RowCount != 10 {
+		t.Errorf("Expected 3 of 10 rows, got %d of %d (%v)", len(result.Rows), result.RowCount, result.Error)
+	}
+}
+
+func TestCopyRoundTrip(t *testing.T) {
+	dsn := getTestDSN()
+	if dsn == "" {
+		t.Skip("Skipping integration test: TEST_POSTGRES_DSN not set")
+	}
+
+	config := db.ConnectionConfig{
+		Name:     "test-copy",
+		Host:     "localhost",
+		Port:     5432,
+		Database: "postgres",
+		Username: "postgres",
+		Password: "postgres",
+		SSLMode:  "disable",
+	}
+
+	conn := db.NewPostgresConnection(config)
+	ctx := context.Background()
+
+	err := conn.Connect(ctx)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Disconnect(ctx)
+
+	path := filepath.Join(t.TempDir(), "series.csv")
+	opts := db.CopyOptions{Header: true, Null: `\N`}
+	reports := 0
+	done, err := storage.CopyToFile(ctx, conn.Conn(), "SELECT n, NULLIF(n % 3, 0) AS m FROM generate_series(1, 1000) n", path, opts, func(db.CopyProgress) { reports++ })
+	if err != nil {
+		t.Fatalf("COPY TO failed: %v", err)
+	}
+	if done.Rows != 1000 || done.Bytes == 0 || reports == 0 {
+		t.Errorf("Unexpected progress %+v after %d reports", done, reports)
+	}
+
+	if result := db.ExecuteQuery(ctx, conn.Conn(), "CREATE TEMP TABLE series (n int, m int)"); result.Error != nil {
+		t.Fatalf("Failed to create the table: %v", result.Error)
+	}
+	done, err = storage.CopyFromFile(ctx, conn.Conn(), "series", path, opts, nil)
+	if err != nil {
+		t.Fatalf("COPY FROM failed: %v", err)
+	}
+	if done.Rows != 1000 || done.Total != done.Bytes {
+		t.Errorf("Unexpected progress %+v", done)
+	}
+	result := db.ExecuteQuery(ctx, conn.Conn(), "SELECT count(*) FROM series WHERE m IS NULL")
+	if result.Error != nil || result.Rows[0][0] != "333" {
+		t.Errorf("Expected the NULLs copied back, got %v, %v", result.Rows, result.Error)
+	}
 }
@@ -0,0 +1,196 @@
+package unit
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/panels"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestNumericStats(t *testing.T) {
+	result := db.QueryResult{
+		Columns:     []string{"name", "total"},
+		ColumnTypes: []string{"text", "numeric"},
+		Rows:        [][]string{{"a", "1"}, {"b", "2"}, {"c", "NULL"}, {"d", "3"}, {"e", "4"}},
+		Nulls:       [][]bool{nil, nil, {false, true}, nil, nil},
+	}
+	if db.IsNumericColumn(result, 0) || !db.IsNumericColumn(result, 1) {
+		t.Errorf("Expected only total numeric")
+	}
+	stats, ok := db.NumericStats(result, 1)
+	if !ok {
+		t.Fatalf("Expected statistics")
+	}
+	want := db.ColumnStats{Column: "total", Count: 4, Nulls: 1, Min: 1, Max: 4, Mean: 2.5, StdDev: math.Sqrt(1.25), P25: 1.75, P50: 2.5, P75: 3.25, P95: 3.85}
+	if math.Abs(stats.P95-want.P95) > 1e-9 {
+		t.Errorf("P95 = %v, want %v", stats.P95, want.P95)
+	}
+	stats.P95 = want.P95
+	if stats != want {
+		t.Errorf("NumericStats = %+v, want %+v", stats, want)
+	}
+	if _, ok := db.NumericStats(result, 0); ok {
+		t.Errorf("Expected text refused")
+	}
+
+	// NaN and infinities are counted apart, not the end of the statistics
+	result.Rows[1][1], result.Rows[3][1] = "NaN", "-Infinity"
+	stats, ok = db.NumericStats(result, 1)
+	if !ok || stats.Count != 2 || stats.NonFinite != 2 || stats.Nulls != 1 || stats.Min != 1 || stats.Max != 4 {
+		t.Errorf("Unexpected statistics with NaN %+v, %v", stats, ok)
+	}
+
+	untyped := db.QueryResult{Columns: []string{"n"}, Rows: [][]string{{"1.5"}, {"-2e3"}}}
+	if !db.IsNumericColumn(untyped, 0) {
+		t.Errorf("Expected untyped numbers recognized")
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	for f, want := range map[float64]string{
+		1234567:  "1,234,567",
+		-42:      "-42",
+		3.14159:  "3.142",
+		0.5:      "0.5",
+		1234.567: "1235",
+		0.00001:  "1e-05",
+		2.5e20:   "2.5e+20",
+	} {
+		if got := components.FormatNumber(f); got != want {
+			t.Errorf("FormatNumber(%v) = %q, want %q", f, got, want)
+		}
+	}
+}
+
+func TestCharts(t *testing.T) {
+	bars := components.BarChart([]string{"open", "closed", "long label here"}, []float64{10, 5, -2.5}, 40, 10)
+	if len(bars) != 3 {
+		t.Fatalf("Expected a bar per value, got %q", bars)
+	}
+	plain := make([]string, len(bars))
+	for i, bar := range bars {
+		plain[i] = ansi.Strip(bar)
+		if ansi.StringWidth(plain[i]) > 40 {
+			t.Errorf("Expected bars within the width, got %q", plain[i])
+		}
+	}
+	if strings.Count(plain[0], "█") != 2*strings.Count(plain[1], "█") || !strings.HasSuffix(plain[0], " 10") || !strings.HasSuffix(plain[2], " -2.5") {
+		t.Errorf("Unexpected bars\n%s", strings.Join(plain, "\n"))
+	}
+	if !strings.HasPrefix(plain[2], "long label h… │") {
+		t.Errorf("Expected long labels cut, got %q", plain[2])
+	}
+	if got := components.BarChart(nil, []float64{1, 2, 3}, 40, 2); len(got) != 2 {
+		t.Errorf("Expected at most height bars, got %d", len(got))
+	}
+
+	line := components.LineChart([]float64{0, 5, 10, 5, 0}, "mon", "fri", 30, 6)
+	if len(line) != 7 {
+		t.Fatalf("Expected the plot, axis and labels, got %d lines", len(line))
+	}
+	if !strings.HasPrefix(line[0], "10 ┤") || !strings.HasPrefix(line[4], " 0 ┤") || !strings.Contains(line[5], "└") {
+		t.Errorf("Unexpected axes\n%s", strings.Join(line, "\n"))
+	}
+	if !strings.HasPrefix(strings.TrimSpace(line[6]), "mon") || !strings.HasSuffix(line[6], "fri") {
+		t.Errorf("Unexpected labels %q", line[6])
+	}
+	// The peak is drawn on the top line, the ends on the bottom one
+	if strings.Trim(line[0][strings.Index(line[0], "┤")+len("┤"):], "⠀") == "" || strings.Trim(line[4][strings.Index(line[4], "┤")+len("┤"):], "⠀") == "" {
+		t.Errorf("Expected dots at the top and bottom\n%s", strings.Join(line, "\n"))
+	}
+
+	hist := components.Histogram([]float64{1, 1, 1, 1, 2, 3, 9}, 2, 24, 6)
+	if len(hist) != 6 {
+		t.Fatalf("Expected the bars, axis and labels, got %d lines", len(hist))
+	}
+	// 6 values in the first bin, 1 in the second
+	if !strings.HasPrefix(hist[0], "6 ┤") || !strings.Contains(hist[0], "█") || !strings.Contains(hist[3], "▆") && !strings.Contains(hist[3], "▅") {
+		t.Errorf("Unexpected histogram\n%s", strings.Join(hist, "\n"))
+	}
+	if !strings.HasSuffix(hist[5], "9") || !strings.Contains(hist[5], "1") {
+		t.Errorf("Expected the range under the bins, got %q", hist[5])
+	}
+
+	// More bins than fit are not counted: the highest values still show
+	values := make([]float64, 10000)
+	for i := range values {
+		values[i] = float64(i)
+		if i >= 5000 {
+			values[i] = 9999
+		}
+	}
+	counts, low, high := components.BinValues(values, 40)
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	if total != len(values) || low != 0 || high != 9999 {
+		t.Errorf("Expected %d values from 0 to 9,999, got %d from %v to %v", len(values), total, low, high)
+	}
+	hist = components.Histogram(values, 0, 40, 10)
+	top := hist[0][strings.Index(hist[0], "┤")+len("┤"):]
+	if !strings.HasSuffix(top, "█") || !strings.HasSuffix(hist[9], "9,999") {
+		t.Errorf("Expected the last bin drawn to the top\n%s", strings.Join(hist, "\n"))
+	}
+}
+
+func TestResultsChart(t *testing.T) {
+	p := panels.NewResultsPanel()
+	p.SetSize(80, 24)
+	reg := components.NewCommandRegistry()
+	p.RegisterCommands(reg)
+	p.SetResult(db.QueryResult{
+		Columns:     []string{"day", "orders", "revenue"},
+		ColumnTypes: []string{"date", "int8", "numeric"},
+		Rows: [][]string{
+			{"2024-01-01", "3", "30.5"},
+			{"2024-01-02", "7", "NULL"},
+			{"2024-01-03", "5", "50"},
+		},
+		Nulls:    [][]bool{nil, {false, false, true}, nil},
+		RowCount: 3,
+	})
+
+	if _, err := reg.Run("chart"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	view := ansi.Strip(p.View())
+	if !p.IsCharting() || !strings.Contains(view, "orders by day") || !strings.Contains(view, "2024-01-02 │") {
+		t.Fatalf("Expected a bar chart of orders by day, got\n%s", view)
+	}
+	if !strings.Contains(view, "min 3 · max 7 · avg 5 · p50 5") {
+		t.Errorf("Expected the statistics of orders, got\n%s", view)
+	}
+
+	// Tab switches the chart and y the column charted
+	p.Update(tea.KeyMsg{Type: tea.KeyTab})
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	view = ansi.Strip(p.View())
+	if !strings.Contains(view, "revenue over day") || !strings.Contains(view, "1 NULL") {
+		t.Errorf("Expected a line chart of revenue, got\n%s", view)
+	}
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if p.IsCharting() {
+		t.Errorf("Expected q to close the chart")
+	}
+
+	if _, err := reg.Run("chart hist revenue"); err != nil || !strings.Contains(ansi.Strip(p.View()), "Distribution of revenue") {
+		t.Errorf("Expected a histogram of revenue, got %v", err)
+	}
+	if _, err := reg.Run("chart bar orders day"); err == nil {
+		t.Errorf("Expected a text column refused as y")
+	}
+
+	if _, err := reg.Run("stats"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lines := strings.Split(ansi.Strip(p.View()), "\n")
+	if !strings.HasPrefix(lines[2], "column   count  nulls") || !strings.HasPrefix(lines[3], "orders       3      0") || !strings.HasPrefix(lines[4], "revenue      2      1") {
+		t.Errorf("Unexpected statistics\n%s", strings.Join(lines, "\n"))
+	}
+}
@@ -0,0 +1,218 @@
+package unit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/storage"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/panels"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCommandRegistry(t *testing.T) {
+	reg := components.NewCommandRegistry()
+	var got components.CommandArgs
+	reg.Register(components.Command{
+		Name: "write", Aliases: []string{"w"},
+		Complete: components.CompleteFrom(func() []string { return []string{"a.sql", "b.sql"} }),
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			got = args
+			return nil, nil
+		},
+	})
+	reg.Register(components.Command{Name: "wq", Run: func(components.CommandArgs) (tea.Cmd, error) { return nil, nil }})
+
+	if _, err := reg.Run("w! other.sql  "); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got.Name != "w" || !got.Bang || got.Arg != "other.sql" || !reflect.DeepEqual(got.Fields, []string{"other.sql"}) {
+		t.Errorf("Unexpected parsed command: %+v", got)
+	}
+	if _, err := reg.Run("frobnicate"); err == nil || !strings.Contains(err.Error(), "not an editor command") {
+		t.Errorf("Expected an unknown command error, got %v", err)
+	}
+	if len(reg.Commands()) != 2 {
+		t.Errorf("Expected aliases listed once, got %d commands", len(reg.Commands()))
+	}
+
+	// Names complete first, then arguments
+	if names := reg.Complete("w"); !reflect.DeepEqual(names, []string{"w", "wq", "write"}) {
+		t.Errorf("Unexpected name completions: %q", names)
+	}
+	if lines := reg.Complete("w b"); !reflect.DeepEqual(lines, []string{"w b.sql"}) {
+		t.Errorf("Unexpected argument completions: %q", lines)
+	}
+	if lines := reg.Complete("wq "); lines != nil {
+		t.Errorf("Expected no completions without a completer, got %q", lines)
+	}
+}
+
+func TestCommandLineHistoryAndCompletion(t *testing.T) {
+	reg := components.NewCommandRegistry()
+	reg.Register(components.Command{
+		Name:     "set",
+		Complete: components.CompleteFrom(func() []string { return []string{"lint", "syntax"} }),
+		Run: func(args components.CommandArgs) (tea.Cmd, error) {
+			if args.Arg == "bad" {
+				return nil, errors.New("unknown option: bad")
+			}
+			return nil, nil
+		},
+	})
+	cl := components.NewCommandLine(reg)
+
+	run := func(line string) {
+		cl.Open()
+		cl.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(line)})
+		cl.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	}
+	run("set lint")
+	run("set syntax")
+	run("set lint")
+	run("set bad")
+	if msg, isError := cl.Message(); msg != "unknown option: bad" || !isError {
+		t.Errorf("Expected the error shown, got %q", msg)
+	}
+	if h := cl.History(); !reflect.DeepEqual(h, []string{"set syntax", "set lint", "set bad"}) {
+		t.Errorf("Expected repeated lines moved to the end, got %q", h)
+	}
+
+	// Up browses entries starting with the typed text
+	cl.Open()
+	cl.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("set s")})
+	cl.Update(tea.KeyMsg{Type: tea.KeyUp})
+	if cl.Value() != "set syntax" {
+		t.Errorf("Expected the matching history entry, got %q", cl.Value())
+	}
+	cl.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if cl.Value() != "set s" {
+		t.Errorf("Expected the typed line back, got %q", cl.Value())
+	}
+
+	// Tab cycles through the completions
+	cl.Open()
+	cl.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("set ")})
+	cl.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if cl.Value() != "set lint" {
+		t.Errorf("Expected the first completion, got %q", cl.Value())
+	}
+	cl.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if cl.Value() != "set syntax" {
+		t.Errorf("Expected the second completion, got %q", cl.Value())
+	}
+	cl.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cl.IsActive() {
+		t.Errorf("Expected Esc to close the command line")
+	}
+}
+
+// runEditorCommand types a command line in the editor's normal mode
+func runEditorCommand(p *panels.EditorPanel, line string) tea.Cmd {
+	p.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(":")})
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(line)})
+	return p.Update(tea.KeyMsg{Type: tea.KeyEnter})
+}
+
+func TestEditorCommands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	p := panels.NewEditorPanel()
+	p.SetQuery("SELECT * FROM users")
+
+	runEditorCommand(p, "set nosyntax")
+	if msg, _ := p.CommandLine().Message(); msg != "nosyntax" {
+		t.Errorf("Expected nosyntax, got %q", msg)
+	}
+	runEditorCommand(p, "set lint?")
+	if msg, _ := p.CommandLine().Message(); msg != "lint" {
+		t.Errorf("Expected lint, got %q", msg)
+	}
+	runEditorCommand(p, "set nosuch")
+	if msg, isError := p.CommandLine().Message(); !isError || !strings.Contains(msg, "unknown option") {
+		t.Errorf("Expected an unknown option error, got %q", msg)
+	}
+
+	// :explain runs the statement under the cursor through the app
+	cmd := runEditorCommand(p, "explain analyze buffers")
+	if cmd == nil {
+		t.Fatalf("Expected :explain to return a command")
+	}
+	var query string
+	for _, msg := range collectMsgs(cmd) {
+		if m, ok := msg.(panels.ExecuteQueryMsg); ok {
+			query = m.Query
+		}
+	}
+	if query != "EXPLAIN (ANALYZE, BUFFERS) SELECT * FROM users" {
+		t.Errorf("Unexpected explain query: %q", query)
+	}
+	// Options taking a value are paired with it
+	for command, want := range map[string]string{
+		"explain format json":                        "EXPLAIN (FORMAT JSON) SELECT * FROM users",
+		"explain analyze off serialize text buffers": "EXPLAIN (ANALYZE OFF, SERIALIZE TEXT, BUFFERS) SELECT * FROM users",
+	} {
+		query = ""
+		for _, msg := range collectMsgs(runEditorCommand(p, command)) {
+			if m, ok := msg.(panels.ExecuteQueryMsg); ok {
+				query = m.Query
+			}
+		}
+		if query != want {
+			t.Errorf("%s: got %q, want %q", command, query, want)
+		}
+	}
+	runEditorCommand(p, "explain format analyze")
+	if msg, isError := p.CommandLine().Message(); !isError || !strings.Contains(msg, "format needs a value") {
+		t.Errorf("Expected format without a value refused, got %q", msg)
+	}
+
+	// :q is refused while a buffer has unsaved changes
+	if err := p.OpenBuffer("draft"); err != nil {
+		t.Fatal(err)
+	}
+	p.SetQuery("SELECT 1")
+	if cmd := runEditorCommand(p, "q"); cmd != nil {
+		t.Errorf("Expected :q refused with unsaved changes")
+	}
+	if cmd := runEditorCommand(p, "q!"); cmd == nil {
+		t.Errorf("Expected :q! to quit")
+	}
+}
+
+// collectMsgs runs a command, expanding batches, and returns the messages
+func collectMsgs(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		var msgs []tea.Msg
+		for _, c := range batch {
+			msgs = append(msgs, collectMsgs(c)...)
+		}
+		return msgs
+	}
+	return []tea.Msg{msg}
+}
+
+func TestExportResultCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	result := db.QueryResult{Columns: []string{"id", "name"}, Rows: [][]string{{"1", "Ada, Countess"}, {"2", "Alan"}}}
+	if err := storage.ExportResult(path, "csv", result, storage.ExportOptions{}); err != nil {
+		t.Fatalf("ExportResult failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "id,name\n1,\"Ada, Countess\"\n2,Alan\n" {
+		t.Errorf("Unexpected CSV: %q, %v", data, err)
+	}
+	if err := storage.ExportResult(path, "xml", result, storage.ExportOptions{}); err == nil {
+		t.Errorf("Expected an unknown format error")
+	}
+}
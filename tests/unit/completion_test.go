@@ -0,0 +1,149 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+)
+
+// newTestCompleter returns a completer backed by a small cached schema
+func newTestCompleter() *db.Completer {
+	cache := db.NewMetadataCache(0)
+	cache.PutSchemas("dev", []string{"public", "audit"})
+	cache.PutObjects("dev", "public", db.SchemaObjects{
+		Tables: []db.SchemaObject{
+			{Name: "users", Type: "table", Schema: "public"},
+			{Name: "orders", Type: "table", Schema: "public"},
+		},
+		Views: []db.SchemaObject{{Name: "active_users", Type: "view", Schema: "public"}},
+		Functions: []db.SchemaObject{
+			{Name: "user_score", Type: "function", Schema: "public", Signature: "(user_id integer) → numeric"},
+		},
+		ForeignKeys: []db.ForeignKey{{
+			Name: "orders_user_id_fkey", Schema: "public", Table: "orders", Columns: []string{"user_id"},
+			RefSchema: "public", RefTable: "users", RefColumns: []string{"id"},
+		}},
+	})
+	cache.PutObjects("dev", "audit", db.SchemaObjects{
+		Tables: []db.SchemaObject{{Name: "events", Type: "table", Schema: "audit"}},
+	})
+	cache.PutColumns("dev", "public", "users", []db.TableColumn{
+		{Name: "id", Type: "integer"},
+		{Name: "email", Type: "text"},
+	})
+	cache.PutColumns("dev", "public", "orders", []db.TableColumn{
+		{Name: "id", Type: "integer"},
+		{Name: "user_id", Type: "integer"},
+		{Name: "total", Type: "numeric"},
+	})
+	return db.NewCompleter(cache, "dev")
+}
+
+// complete runs the completer with the cursor at the "|" marker
+func complete(t *testing.T, c *db.Completer, text string) db.CompletionResult {
+	t.Helper()
+	offset := strings.Index(text, "|")
+	if offset < 0 {
+		t.Fatalf("missing cursor marker in %q", text)
+	}
+	return c.Complete(text[:offset]+text[offset+1:], offset)
+}
+
+// labels returns the labels of items of the given kind
+func labels(items []db.CompletionItem, kind db.CompletionKind) []string {
+	var out []string
+	for _, item := range items {
+		if item.Kind == kind {
+			out = append(out, item.Label)
+		}
+	}
+	return out
+}
+
+func TestCompleteTablesAfterFrom(t *testing.T) {
+	c := newTestCompleter()
+
+	result := complete(t, c, "SELECT * FROM us|")
+	if result.Prefix != "us" {
+		t.Errorf("Expected prefix 'us', got %q", result.Prefix)
+	}
+	tables := labels(result.Items, db.CompletionTable)
+	if len(tables) != 1 || tables[0] != "users" {
+		t.Errorf("Expected only 'users', got %v", tables)
+	}
+
+	// Tables outside the default schema are inserted qualified
+	result = complete(t, c, "SELECT * FROM ev|")
+	if len(result.Items) != 1 || result.Items[0].Insert != "audit.events" {
+		t.Errorf("Expected audit.events, got %+v", result.Items)
+	}
+
+	// Schema-qualified lookup
+	result = complete(t, c, "SELECT * FROM audit.|")
+	if tables := labels(result.Items, db.CompletionTable); len(tables) != 1 || tables[0] != "events" {
+		t.Errorf("Expected events in audit schema, got %v", tables)
+	}
+}
+
+func TestCompleteColumnsForAlias(t *testing.T) {
+	c := newTestCompleter()
+
+	result := complete(t, c, "SELECT o.| FROM orders o JOIN users u ON true")
+	columns := labels(result.Items, db.CompletionColumn)
+	if strings.Join(columns, ",") != "id,user_id,total" {
+		t.Errorf("Expected orders columns, got %v", columns)
+	}
+
+	// Unqualified columns come from every table in scope
+	result = complete(t, c, "SELECT em| FROM orders o JOIN users u ON true")
+	if columns := labels(result.Items, db.CompletionColumn); len(columns) != 1 || columns[0] != "email" {
+		t.Errorf("Expected email, got %v", columns)
+	}
+
+	// Only the statement under the cursor is considered
+	result = complete(t, c, "SELECT * FROM users; SELECT to| FROM orders;")
+	if columns := labels(result.Items, db.CompletionColumn); len(columns) != 1 || columns[0] != "total" {
+		t.Errorf("Expected total, got %v", columns)
+	}
+}
+
+func TestCompleteJoinConditionFromForeignKey(t *testing.T) {
+	c := newTestCompleter()
+
+	result := complete(t, c, "SELECT * FROM users u JOIN orders o ON |")
+	joins := labels(result.Items, db.CompletionJoin)
+	if len(joins) != 1 || joins[0] != "o.user_id = u.id" {
+		t.Errorf("Expected FK join condition, got %v", joins)
+	}
+}
+
+func TestCompleteKeywordsAndFunctions(t *testing.T) {
+	c := newTestCompleter()
+
+	result := complete(t, c, "sel|")
+	if len(result.Items) == 0 || result.Items[0].Insert != "select" {
+		t.Errorf("Expected lowercase 'select' keyword, got %+v", result.Items)
+	}
+
+	result = complete(t, c, "SELECT user_s| FROM users")
+	functions := labels(result.Items, db.CompletionFunction)
+	if len(functions) != 1 || result.Items[0].Detail != "(user_id integer) → numeric" {
+		t.Errorf("Expected user_score with signature, got %+v", result.Items)
+	}
+
+	// No completion inside string literals
+	result = complete(t, c, "SELECT * FROM users WHERE email = 'us|'")
+	if len(result.Items) != 0 {
+		t.Errorf("Expected no completions inside a string, got %+v", result.Items)
+	}
+}
+
+func TestCompleteReportsMissingColumns(t *testing.T) {
+	c := newTestCompleter()
+
+	result := complete(t, c, "SELECT | FROM audit.events")
+	if len(result.MissingColumns) != 1 || result.MissingColumns[0].Name != "events" {
+		t.Errorf("Expected audit.events to be reported as missing, got %+v", result.MissingColumns)
+	}
+}
@@ -0,0 +1,68 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/panels"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+func TestCopyStatements(t *testing.T) {
+	opts := db.CopyOptions{Header: true, Null: `\N`}
+	if got := db.CopyOutStatement("SELECT * FROM users;\n", opts); got != "COPY (SELECT * FROM users\n) TO STDOUT WITH (FORMAT csv, HEADER true, DELIMITER ',', NULL '\\N')" {
+		t.Errorf("Unexpected COPY TO: %s", got)
+	}
+	// A line comment at the end doesn't swallow the parenthesis
+	for _, query := range []string{"SELECT 1 -- one", "SELECT 1; -- done\n", "-- users\nSELECT 1\n-- last\n;"} {
+		got := db.CopyOutStatement(query, opts)
+		if _, err := pg_query.Parse(got); err != nil {
+			t.Errorf("COPY of %q doesn't parse: %v\n%s", query, err, got)
+		}
+	}
+	opts = db.CopyOptions{Delimiter: "'"}
+	if got := db.CopyInStatement("users (id, email)", opts); got != `COPY users (id, email) FROM STDIN WITH (FORMAT csv, HEADER false, DELIMITER '''', NULL '')` {
+		t.Errorf("Unexpected COPY FROM: %s", got)
+	}
+}
+
+func TestCopyCommand(t *testing.T) {
+	p := panels.NewEditorPanel()
+	p.SetQuery("SELECT * FROM users;")
+
+	request := func(line string) (panels.CopyRequest, bool) {
+		for _, msg := range collectMsgs(runEditorCommand(p, line)) {
+			if m, ok := msg.(panels.CopyRequestMsg); ok {
+				return m.Request, true
+			}
+		}
+		return panels.CopyRequest{}, false
+	}
+
+	req, ok := request("copy to ~/users.csv delimiter=tab null=\\N")
+	if !ok || req.Import() || req.Query != "SELECT * FROM users" || req.Path != "~/users.csv" ||
+		req.Options != (db.CopyOptions{Delimiter: "\t", Header: true, Null: `\N`}) {
+		t.Errorf("Unexpected export request %+v", req)
+	}
+
+	req, ok = request("copy from users.csv public.users (id, email) noheader")
+	if !ok || !req.Import() || req.Table != "public.users (id, email)" || req.Path != "users.csv" || req.Options.Header {
+		t.Errorf("Unexpected import request %+v", req)
+	}
+
+	if _, ok := request("copy to users.csv delimiter=ab"); ok {
+		t.Errorf("Expected a long delimiter refused")
+	}
+	if msg, isError := p.CommandLine().Message(); !isError || !strings.Contains(msg, "delimiter") {
+		t.Errorf("Expected a delimiter error, got %q", msg)
+	}
+
+	var cancelled bool
+	for _, msg := range collectMsgs(runEditorCommand(p, "copy!")) {
+		_, cancelled = msg.(panels.CopyCancelMsg)
+	}
+	if !cancelled {
+		t.Errorf("Expected :copy! to cancel")
+	}
+}
@@ -0,0 +1,158 @@
+package unit
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/panels"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func usersResult(query string, rows ...[]string) db.QueryResult {
+	return db.QueryResult{
+		Query:       query,
+		Columns:     []string{"id", "email", "active"},
+		ColumnTypes: []string{"int4", "text", "bool"},
+		Rows:        rows,
+		RowCount:    len(rows),
+	}
+}
+
+func TestDiffResults(t *testing.T) {
+	before := usersResult("before",
+		[]string{"1", "a@example.com", "t"},
+		[]string{"2", "b@example.com", "t"},
+		[]string{"3", "c@example.com", "t"},
+	)
+	after := usersResult("after",
+		[]string{"3", "c@example.com", "f"},
+		[]string{"1", "a@example.com", "t"},
+		[]string{"4", "d@example.com", ""},
+	)
+	after.Nulls = [][]bool{nil, nil, {false, false, true}}
+
+	diff, err := db.DiffResults(before, after, "id")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff.Added != 1 || diff.Removed != 1 || diff.Changed != 1 || diff.Same != 1 {
+		t.Fatalf("Unexpected counts %+v", diff)
+	}
+	var got []string
+	for _, row := range diff.Rows {
+		got = append(got, fmt.Sprintf("%d:%s", row.Kind, row.Key))
+	}
+	want := fmt.Sprintf("%d:3 %d:1 %d:4 %d:2", db.DiffChanged, db.DiffSame, db.DiffAdded, db.DiffRemoved)
+	if strings.Join(got, " ") != want {
+		t.Errorf("Rows %v, want %s", got, want)
+	}
+	changed := diff.Rows[0]
+	if changed.Changed[1] || !changed.Changed[2] || changed.Before[2] != "t" || changed.After[2] != "f" {
+		t.Errorf("Unexpected changed row %+v", changed)
+	}
+	if diff.Rows[2].Before != nil || diff.Rows[2].After[2] != db.NullText {
+		t.Errorf("Unexpected added row %+v", diff.Rows[2])
+	}
+
+	// Columns are matched by name; those of one result only don't count
+	renamed := db.QueryResult{Columns: []string{"email", "id", "name"}, Rows: [][]string{{"a@example.com", "1", "A"}}}
+	diff, err = db.DiffResults(before, renamed, "id")
+	if err != nil || strings.Join(diff.Columns, ",") != "email,id,name,active" || diff.Same != 1 || diff.Removed != 2 {
+		t.Errorf("Unexpected diff %+v, %v", diff, err)
+	}
+
+	// A NULL is not the text NULL, as a value or as a key
+	before = usersResult("before", []string{"1", "NULL", "t"}, []string{"NULL", "x@example.com", "t"})
+	after = usersResult("after", []string{"1", "", "t"}, []string{"", "x@example.com", "t"})
+	after.Nulls = [][]bool{{false, true, false}, {true, false, false}}
+	diff, err = db.DiffResults(before, after, "id")
+	if err != nil || diff.Changed != 1 || diff.Added != 1 || diff.Removed != 1 || diff.Same != 0 {
+		t.Fatalf("Unexpected diff %+v, %v", diff, err)
+	}
+	if !diff.Rows[0].Changed[1] || diff.Rows[0].BeforeNulls[1] || !diff.Rows[0].AfterNulls[1] || !diff.Rows[1].KeyNull {
+		t.Errorf("Unexpected rows %+v", diff.Rows)
+	}
+
+	if _, err := db.DiffResults(before, renamed, "name"); err == nil {
+		t.Errorf("Expected a key missing from a result refused")
+	}
+}
+
+func TestResultsHistory(t *testing.T) {
+	p := panels.NewResultsPanel()
+	p.SetSize(100, 24)
+	reg := components.NewCommandRegistry()
+	p.RegisterCommands(reg)
+
+	p.SetConnection("local")
+	p.SetResult(usersResult("SELECT 1", []string{"1", "a@example.com", "t"}))
+	p.SetResult(db.QueryResult{RowCount: 3})
+	p.SetResult(usersResult("SELECT 2", []string{"1", "a@example.com", "f"}))
+	history := p.History()
+	if len(history) != 2 || history[0].ID != 1 || history[1].ID != 2 || history[0].Connection != "local" || history[1].Result.Query != "SELECT 2" {
+		t.Fatalf("Expected the two results kept, got %+v", history)
+	}
+	if !strings.Contains(ansi.Strip(p.View()), " #2 ") {
+		t.Errorf("Expected a tab for the latest result")
+	}
+
+	// [ and ] browse the history
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("[")})
+	if e, _ := p.ShownEntry(); e.ID != 1 {
+		t.Errorf("Expected #1 shown, got #%d", e.ID)
+	}
+	if value, _, _, _ := p.FocusedCell(); value != "1" {
+		t.Errorf("Expected the first result's cells, got %q", value)
+	}
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("]")})
+	if e, _ := p.ShownEntry(); e.ID != 2 {
+		t.Errorf("Expected #2 shown, got #%d", e.ID)
+	}
+
+	// A pinned result stays past the limit and becomes a tab
+	if _, err := reg.Run("history 1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := reg.Run("keep before"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 25; i++ {
+		p.SetResult(usersResult(fmt.Sprintf("SELECT %d", i+3), []string{"1", "a@example.com", "t"}))
+	}
+	history = p.History()
+	if len(history) != 21 || history[0].Name != "before" || history[1].ID != 8 {
+		t.Errorf("Expected 20 results and the pinned one, got %d starting %+v", len(history), history[:2])
+	}
+	pressKeys(p, "g", "t")
+	if e, _ := p.ShownEntry(); e.Name != "before" {
+		t.Errorf("Expected gt to show the pinned tab, got #%d", e.ID)
+	}
+	if _, err := reg.Run("history #2"); err == nil {
+		t.Errorf("Expected a dropped result missing")
+	}
+
+	// The latest result compared with the pinned one
+	p.SetResult(usersResult("SELECT 3", []string{"1", "a@example.com", "f"}, []string{"2", "b@example.com", "t"}))
+	if _, err := reg.Run("diff before key=id"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	view := ansi.Strip(p.View())
+	if !p.IsDiffing() || !strings.Contains(view, "1 added, 0 removed, 1 changed, 0 same") {
+		t.Fatalf("Expected the comparison, got\n%s", view)
+	}
+	if !strings.Contains(view, "~ │ 1") || !strings.Contains(view, "t → f") || !strings.Contains(view, "+ │ 2") {
+		t.Errorf("Expected the changed and added rows, got\n%s", view)
+	}
+	p.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if p.IsDiffing() {
+		t.Errorf("Expected Esc to close the comparison")
+	}
+
+	if _, err := reg.Run("keep!"); err == nil {
+		t.Errorf("Expected unpinning an unpinned result refused")
+	}
+}
@@ -0,0 +1,106 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/panels"
+)
+
+func TestEditorBuffers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	queriesDir := filepath.Join(home, ".lazydb", "queries")
+	if err := os.MkdirAll(queriesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(queriesDir, "existing.sql"), []byte("SELECT 42"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := panels.NewEditorPanel()
+	scratch := p.GetQuery()
+
+	// A new file opens empty and is written by :w
+	if err := p.OpenBuffer("audit"); err != nil {
+		t.Fatalf("OpenBuffer failed: %v", err)
+	}
+	if p.BufferName() != "audit.sql" || p.GetQuery() != "" || p.IsDirty() {
+		t.Fatalf("Expected a clean empty audit.sql buffer, got %q %q", p.BufferName(), p.GetQuery())
+	}
+	p.SetQuery("SELECT * FROM audit_log")
+	if !p.IsDirty() {
+		t.Errorf("Expected the buffer to be dirty after an edit")
+	}
+	if err := p.CloseBuffer(false); err == nil {
+		t.Errorf("Expected closing a dirty buffer to fail")
+	}
+	if err := p.SaveBuffer(""); err != nil {
+		t.Fatalf("SaveBuffer failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(queriesDir, "audit.sql"))
+	if err != nil || string(data) != "SELECT * FROM audit_log" || p.IsDirty() {
+		t.Errorf("Expected the buffer saved, got %q, %v", data, err)
+	}
+
+	// Existing files load their contents; buffers keep their own text
+	if err := p.OpenBuffer("existing.sql"); err != nil || p.GetQuery() != "SELECT 42" {
+		t.Fatalf("Expected existing file loaded, got %q, %v", p.GetQuery(), err)
+	}
+	p.NextBuffer()
+	if p.BufferName() != "" || p.GetQuery() != scratch {
+		t.Errorf("Expected next buffer to wrap to the scratch buffer, got %q", p.BufferName())
+	}
+	p.PrevBuffer()
+	if p.BufferName() != "existing.sql" {
+		t.Errorf("Expected previous buffer to wrap back, got %q", p.BufferName())
+	}
+
+	// Opening an open file switches to it
+	if err := p.OpenBuffer("audit.sql"); err != nil || p.GetQuery() != "SELECT * FROM audit_log" {
+		t.Errorf("Expected to switch to the open buffer, got %q, %v", p.GetQuery(), err)
+	}
+	if err := p.CloseBuffer(false); err != nil || p.BufferName() == "audit.sql" {
+		t.Errorf("Expected the saved buffer to close, got %q, %v", p.BufferName(), err)
+	}
+
+	// Buffer files stay in the queries directory
+	if err := p.OpenBuffer("../secrets"); err == nil {
+		t.Errorf("Expected a path outside the queries directory to be rejected")
+	}
+}
+
+func TestEditorBufferConnection(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cm := db.NewConnectionManager()
+	cm.AddConnection("dev", db.NewPostgresConnection(db.ConnectionConfig{Name: "dev"}))
+	cm.AddConnection("staging", db.NewPostgresConnection(db.ConnectionConfig{Name: "staging"}))
+	if err := cm.SetActive("dev"); err != nil {
+		t.Fatal(err)
+	}
+
+	p := panels.NewEditorPanel()
+	p.SetConnectionManager(cm)
+	if err := p.BindConnection("prod"); err == nil {
+		t.Errorf("Expected binding an unknown connection to fail")
+	}
+	if err := p.BindConnection("staging"); err != nil {
+		t.Fatalf("BindConnection failed: %v", err)
+	}
+
+	// The bound buffer runs against staging, a new buffer against the active connection
+	if conn, err := p.BufferConnection(); err != nil || conn.Config().Name != "staging" {
+		t.Errorf("Expected staging, got %v, %v", conn, err)
+	}
+	p.NewBuffer()
+	if conn, err := p.BufferConnection(); err != nil || conn.Config().Name != "dev" {
+		t.Errorf("Expected the active connection, got %v, %v", conn, err)
+	}
+	p.PrevBuffer()
+	if conn, _ := p.BufferConnection(); conn.Config().Name != "staging" {
+		t.Errorf("Expected the binding to stay with its buffer")
+	}
+}
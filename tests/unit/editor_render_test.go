@@ -0,0 +1,65 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/config"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/panels"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+)
+
+func TestEditorRenderGutterAndScroll(t *testing.T) {
+	p := panels.NewEditorPanel()
+	p.SetSize(40, 12)
+	p.SetQuery("SELECT 1;\nSELECT '" + strings.Repeat("x", 60) + "END';")
+
+	lines := strings.Split(ansi.Strip(p.View()), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], "   1 ") || !strings.HasPrefix(lines[3], "   2 ") {
+		t.Fatalf("Expected numbered lines below the header and tabs, got %q", lines)
+	}
+
+	// The cursor is at the end of the long line: all lines scroll horizontally
+	if !strings.Contains(lines[3], "END';") || strings.Contains(lines[3], "SELECT") || strings.Contains(lines[2], "SELECT") {
+		t.Errorf("Expected the lines scrolled to the cursor, got %q", lines[2:4])
+	}
+	for _, line := range lines[2:] {
+		if ansi.StringWidth(line) > 38 {
+			t.Errorf("Expected text lines cut to the panel width, got %q", line)
+		}
+	}
+
+	// Back to the start of the line scrolls back
+	p.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("0")})
+	lines = strings.Split(ansi.Strip(p.View()), "\n")
+	if !strings.HasPrefix(lines[2], "   1 SELECT 1;") || !strings.HasPrefix(lines[3], "   2 SELECT 'xxx") {
+		t.Errorf("Expected the lines scrolled back, got %q", lines[2:4])
+	}
+}
+
+func TestEditorRenderThemeConfig(t *testing.T) {
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	defer lipgloss.SetColorProfile(termenv.Ascii)
+
+	p := panels.NewEditorPanel()
+	p.SetSize(60, 10)
+	p.SetQuery("SELECT id FROM users")
+
+	// Token colors are true colors from the chroma theme
+	if !strings.Contains(p.View(), "\x1b[38;2;") {
+		t.Errorf("Expected highlighted tokens by default")
+	}
+
+	p.SetThemeConfig(config.ThemeConfig{Name: "monokai", SyntaxHighlighting: false, SQLLinting: true})
+	view := p.View()
+	if strings.Contains(view, "\x1b[38;2;") {
+		t.Errorf("Expected no token colors with syntax highlighting off")
+	}
+	if !strings.Contains(ansi.Strip(view), "1 SELECT id FROM users") {
+		t.Errorf("Expected the plain text with line numbers, got %q", ansi.Strip(view))
+	}
+}
@@ -0,0 +1,155 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/storage"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/panels"
+)
+
+// exportResult has a NULL, quotes, a newline and typed columns
+var exportResult = db.QueryResult{
+	Query:       "SELECT * FROM users",
+	Columns:     []string{"id", "name", "active", "meta"},
+	ColumnTypes: []string{"int4", "text", "bool", "jsonb"},
+	Rows: [][]string{
+		{"1", "Ada \"Countess\"", "true", `{"k":1}`},
+		{"2", "NULL", "false", "NULL"},
+		{"10", "O'Brien|\nline", "true", "[]"},
+	},
+	Nulls:    [][]bool{nil, {false, true, false, true}, nil},
+	RowCount: 3,
+}
+
+func TestWriteResultFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		opts   storage.ExportOptions
+		want   string
+	}{
+		{"csv", storage.ExportOptions{}, "id,name,active,meta\n1,\"Ada \"\"Countess\"\"\",true,\"{\"\"k\"\":1}\"\n2,,false,\n10,\"O'Brien|\nline\",true,[]\n"},
+		{"tsv", storage.ExportOptions{Columns: []string{"name", "ID"}, Null: `\N`, NoHeader: true}, "\"Ada \"\"Countess\"\"\"\t1\n\\N\t2\n\"O'Brien|\nline\"\t10\n"},
+		{"json", storage.ExportOptions{Columns: []string{"id", "meta"}}, "[\n  {\"id\":1,\"meta\":{\"k\":1}},\n  {\"id\":2,\"meta\":null},\n  {\"id\":10,\"meta\":[]}\n]\n"},
+		{"ndjson", storage.ExportOptions{Columns: []string{"name", "active"}}, "{\"name\":\"Ada \\\"Countess\\\"\",\"active\":true}\n{\"name\":null,\"active\":false}\n{\"name\":\"O'Brien|\\nline\",\"active\":true}\n"},
+		{"markdown", storage.ExportOptions{Columns: []string{"id", "name"}, Null: "NULL"}, "| id  | name              |\n| --: | ----------------- |\n|   1 | Ada \"Countess\"    |\n|   2 | NULL              |\n|  10 | O'Brien\\|<br>line |\n"},
+		{"table", storage.ExportOptions{Columns: []string{"id", "name"}}, " id | name\n----+----------------\n  1 | Ada \"Countess\"\n  2 |\n 10 | O'Brien|↵line\n(3 rows)\n"},
+		{"sql", storage.ExportOptions{Table: "public.users", Columns: []string{"id", "name", "active"}}, "INSERT INTO public.users (id, name, active) VALUES (1, 'Ada \"Countess\"', TRUE);\nINSERT INTO public.users (id, name, active) VALUES (2, NULL, FALSE);\nINSERT INTO public.users (id, name, active) VALUES (10, 'O''Brien|\nline', TRUE);\n"},
+	}
+	for _, tt := range tests {
+		got, err := storage.FormatResult(tt.format, exportResult, tt.opts)
+		if err != nil {
+			t.Errorf("%s: %v", tt.format, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.format, got, tt.want)
+		}
+	}
+
+	// Each part of the target table is quoted like the columns
+	for table, want := range map[string]string{
+		"Sales Data.order":    `INSERT INTO "Sales Data"."order" (id) VALUES (1);`,
+		`"my.schema".Users`:   `INSERT INTO "my.schema"."Users" (id) VALUES (1);`,
+		"users; DROP TABLE t": `INSERT INTO "users; DROP TABLE t" (id) VALUES (1);`,
+	} {
+		got, err := storage.FormatResult("sql", exportResult, storage.ExportOptions{Table: table, Columns: []string{"id"}})
+		if err != nil || !strings.HasPrefix(got, want+"\n") {
+			t.Errorf("Table %q: got %q, %v; want %q", table, got, err, want)
+		}
+	}
+
+	if _, err := storage.FormatResult("sql", exportResult, storage.ExportOptions{}); err == nil {
+		t.Errorf("Expected the sql format to need a table")
+	}
+	if _, err := storage.FormatResult("csv", exportResult, storage.ExportOptions{Columns: []string{"nope"}}); err == nil {
+		t.Errorf("Expected an unknown column error")
+	}
+}
+
+func TestResultsExport(t *testing.T) {
+	p := panels.NewResultsPanel()
+	p.SetSize(120, 24)
+	var copied string
+	p.SetClipboard(func(text string) error {
+		copied = text
+		return nil
+	})
+	p.SetResult(exportResult)
+	registry := components.NewCommandRegistry()
+	p.RegisterCommands(registry)
+
+	// The rows and columns shown are exported, filtered and sorted
+	if err := p.SetFilter("true", -1); err != nil {
+		t.Fatal(err)
+	}
+	p.SortBy(0, true)
+	if err := p.HideColumn(3); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := p.Export([]string{"csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied != "id,name,active\n10,\"O'Brien|\nline\",true\n1,\"Ada \"\"Countess\"\"\",true\n" || msg != "Copied 2 rows as csv" {
+		t.Errorf("Unexpected clipboard export %q, %q", copied, msg)
+	}
+
+	path := filepath.Join(t.TempDir(), "users.sql")
+	if _, err := p.Export([]string{"sql", path, "table=users", "columns=id,meta"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "INSERT INTO users (id, meta) VALUES (10, '[]');\n") {
+		t.Errorf("Unexpected file export %q", data)
+	}
+
+	// export! asks for the query to run again with the columns shown
+	cmd, err := registry.Run("export! ndjson all.ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got panels.ExportQueryMsg
+	for _, m := range collectMsgs(cmd) {
+		if m, ok := m.(panels.ExportQueryMsg); ok {
+			got = m
+		}
+	}
+	if got.Query != "SELECT * FROM users" || got.Format != "ndjson" || got.Path != "all.ndjson" || strings.Join(got.Options.Columns, ",") != "id,name,active" {
+		t.Errorf("Unexpected export query %+v", got)
+	}
+
+	// A write isn't run again; the fetched rows are exported instead
+	returning := exportResult
+	returning.Query = "UPDATE users SET active = true RETURNING *"
+	returning.RowCount = 5
+	p.SetResult(returning)
+	path = filepath.Join(t.TempDir(), "updated.csv")
+	cmd, err = registry.Run("export! csv " + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text string
+	for _, m := range collectMsgs(cmd) {
+		switch m := m.(type) {
+		case panels.ExportQueryMsg:
+			t.Errorf("Expected the UPDATE not run again, got %+v", m)
+		case components.CommandMessageMsg:
+			text = m.Text
+		}
+	}
+	if data, _ := os.ReadFile(path); !strings.HasPrefix(string(data), "id,name,active,meta\n1,") {
+		t.Errorf("Expected the fetched rows written, got %q", data)
+	}
+	if !strings.Contains(text, "3 fetched of 5") || strings.Contains(text, "runs the query again") || !strings.Contains(text, "only a SELECT runs again") {
+		t.Errorf("Unexpected message %q", text)
+	}
+	if _, err := storage.ExportQuery(context.Background(), nil, returning.Query, path, "csv", storage.ExportOptions{}); err == nil {
+		t.Errorf("Expected ExportQuery to refuse an UPDATE")
+	}
+}
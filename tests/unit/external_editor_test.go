@@ -0,0 +1,112 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/editor"
+)
+
+func TestEditorArgs(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"nvim", []string{"nvim", "+call cursor(3,7)", "/tmp/q.sql"}},
+		{"/usr/bin/nano -w", []string{"/usr/bin/nano", "-w", "+3,7", "/tmp/q.sql"}},
+		{"code", []string{"code", "--wait", "--goto", "/tmp/q.sql:3:7"}},
+		{"ed", []string{"ed", "/tmp/q.sql"}},
+		{`my-editor --at "{line} {col}" {file}`, []string{"my-editor", "--at", "3 7", "/tmp/q.sql"}},
+		{`'/opt/My Editor/bin/edit' {file}:{line}`, []string{"/opt/My Editor/bin/edit", "/tmp/q.sql:3"}},
+	}
+	for _, tt := range tests {
+		got, err := editor.EditorArgs(tt.command, "/tmp/q.sql", 3, 7)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("EditorArgs(%q) = %q, %v; want %q", tt.command, got, err, tt.want)
+		}
+	}
+
+	if _, err := editor.EditorArgs(`vim "unterminated`, "f", 1, 1); err == nil {
+		t.Errorf("Expected an unterminated quote error")
+	}
+}
+
+func TestByteColumn(t *testing.T) {
+	for _, tt := range []struct {
+		line string
+		col  int
+		want int
+	}{
+		{"SELECT 1", 7, 8},
+		{"SELECT 'été' AS x", 12, 15},
+		{"-- 東京 x", 6, 11},
+		{"ab", 9, 3},
+	} {
+		if got := editor.ByteColumn(tt.line, tt.col); got != tt.want {
+			t.Errorf("ByteColumn(%q, %d) = %d, want %d", tt.line, tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestResolveEditor(t *testing.T) {
+	t.Setenv("VISUAL", "code")
+	t.Setenv("EDITOR", "nano")
+	if got, _ := editor.ResolveEditor("hx"); got != "hx" {
+		t.Errorf("Expected the configured editor first, got %q", got)
+	}
+	if got, _ := editor.ResolveEditor(""); got != "code" {
+		t.Errorf("Expected $VISUAL, got %q", got)
+	}
+	t.Setenv("VISUAL", "")
+	if got, _ := editor.ResolveEditor(""); got != "nano" {
+		t.Errorf("Expected $EDITOR, got %q", got)
+	}
+}
+
+func TestExternalEditSession(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script editor")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "fake-editor")
+	// Appends its arguments to the file given last, fails on line 99
+	content := "#!/bin/sh\nfor f; do :; done\n[ \"$1\" = 99 ] && exit 3\necho \" -- $1 $2\" >> \"$f\"\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	command := script + " {line} {col} {file}"
+
+	cmd, tempFile, err := editor.PrepareEdit(command, "SELECT 1", 4, 2)
+	if err != nil {
+		t.Fatalf("PrepareEdit failed: %v", err)
+	}
+	info, err := os.Stat(tempFile)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a 0600 temp file, got %v, %v", info.Mode(), err)
+	}
+	msg := editor.FinishEdit(tempFile, cmd.Run())
+	if got, ok := msg.(editor.EditorSuccessMsg); !ok || got.Text != "SELECT 1 -- 4 2\n" {
+		t.Errorf("Expected the edited text, got %#v", msg)
+	}
+	if _, err := os.Stat(tempFile); !os.IsNotExist(err) {
+		t.Errorf("Expected the temp file removed after a successful edit")
+	}
+
+	// A failing editor keeps the file for recovery
+	cmd, tempFile, err = editor.PrepareEdit(command, "SELECT secret", 99, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+	msg = editor.FinishEdit(tempFile, cmd.Run())
+	failed, ok := msg.(editor.EditorErrorMsg)
+	if !ok || failed.TempFile != tempFile {
+		t.Fatalf("Expected an error keeping the temp file, got %#v", msg)
+	}
+	if data, err := os.ReadFile(tempFile); err != nil || string(data) != "SELECT secret" {
+		t.Errorf("Expected the query kept, got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,180 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+func TestFormatClausesAndKeywordCase(t *testing.T) {
+	f := db.NewFormatter(db.DefaultFormatOptions())
+
+	got, err := f.Format("select id, email from users where id = 1 order by email;")
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	want := "SELECT id, email\nFROM users\nWHERE id = 1\nORDER BY email;"
+	if got != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatSplitsLongListsAndConditions(t *testing.T) {
+	opts := db.DefaultFormatOptions()
+	opts.LineWidth = 25
+	f := db.NewFormatter(opts)
+
+	got, err := f.Format("SELECT id, email, created_at FROM users WHERE active AND created_at > now() - interval '1 day'")
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	want := strings.Join([]string{
+		"SELECT",
+		"    id,",
+		"    email,",
+		"    created_at",
+		"FROM users",
+		"WHERE active",
+		"    AND created_at > now() - INTERVAL '1 day'",
+	}, "\n")
+	if got != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Leading commas
+	opts.CommaStyle = db.CommaLeading
+	got, _ = db.NewFormatter(opts).Format("SELECT id, email, created_at FROM users")
+	if !strings.Contains(got, "    id\n  , email\n  , created_at") {
+		t.Errorf("Expected leading commas, got:\n%s", got)
+	}
+}
+
+func TestFormatSubqueriesAndCTEs(t *testing.T) {
+	f := db.NewFormatter(db.DefaultFormatOptions())
+
+	got, err := f.Format("with a as (select id from users) select * from a where id in (select user_id from orders)")
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	want := strings.Join([]string{
+		"WITH a AS (",
+		"    SELECT id",
+		"    FROM users",
+		")",
+		"SELECT *",
+		"FROM a",
+		"WHERE id IN (",
+		"    SELECT user_id",
+		"    FROM orders",
+		")",
+	}, "\n")
+	if got != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatPreservesComments(t *testing.T) {
+	f := db.NewFormatter(db.DefaultFormatOptions())
+
+	got, err := f.Format("-- active users\nselect id, -- primary key\n  email /* contact */ from users; -- done")
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	for _, comment := range []string{"-- active users", "-- primary key", "/* contact */", "-- done"} {
+		if !strings.Contains(got, comment) {
+			t.Errorf("Comment %q lost:\n%s", comment, got)
+		}
+	}
+	// Trailing line comments must not swallow the rest of the statement
+	if _, err := db.NewSQLValidator().ValidateAndFormat(got); !err.Valid {
+		t.Errorf("Formatted SQL is invalid:\n%s", got)
+	}
+}
+
+func TestFormatLineCommentBeforeSemicolon(t *testing.T) {
+	f := db.NewFormatter(db.DefaultFormatOptions())
+
+	for _, query := range []string{
+		"DELETE FROM t WHERE id = 1\n-- only the one row\n;\nSELECT 2;",
+		"select 1 -- one\n;",
+		"select 1\n-- first\n-- second\n; select 2 /* two */\n;",
+	} {
+		got, err := f.Format(query)
+		if err != nil {
+			t.Fatalf("Format(%q) failed: %v", query, err)
+		}
+		want, _ := pg_query.Parse(query)
+		parsed, err := pg_query.Parse(got)
+		if err != nil {
+			t.Errorf("Formatted %q doesn't parse: %v\n%s", query, err, got)
+			continue
+		}
+		if len(parsed.Stmts) != len(want.Stmts) {
+			t.Errorf("Formatted %q has %d statements, want %d:\n%s", query, len(parsed.Stmts), len(want.Stmts), got)
+		}
+	}
+}
+
+func TestFormatLineCommentAfterConditionKeyword(t *testing.T) {
+	f := db.NewFormatter(db.DefaultFormatOptions())
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			"SELECT a FROM t WHERE -- why\n x = 1 AND y = 2",
+			"SELECT a\nFROM t\nWHERE -- why\n    x = 1\n    AND y = 2",
+		},
+		{
+			"SELECT a FROM t JOIN u ON -- why\n t.id = u.id AND t.x = u.x",
+			"SELECT a\nFROM t\nJOIN u\n    ON -- why\n        t.id = u.id\n        AND t.x = u.x",
+		},
+	}
+	for _, tt := range tests {
+		got, err := f.Format(tt.query)
+		if err != nil {
+			t.Fatalf("Format(%q) failed: %v", tt.query, err)
+		}
+		if got != tt.want {
+			t.Errorf("Format(%q):\n%s\nwant:\n%s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFormatInvalidAndRange(t *testing.T) {
+	f := db.NewFormatter(db.DefaultFormatOptions())
+
+	if got, err := f.Format("SELEC 1"); err == nil || got != "SELEC 1" {
+		t.Errorf("Expected invalid SQL to be returned unchanged with an error, got %q, %v", got, err)
+	}
+
+	query := "select 1;\nselect a from t;\n"
+	start := strings.Index(query, "\n")
+	end := strings.LastIndex(query, ";")
+	got, err := f.FormatRange(query, start+1, end)
+	if err != nil {
+		t.Fatalf("FormatRange failed: %v", err)
+	}
+	if got != "select 1;\nSELECT a\nFROM t;\n" {
+		t.Errorf("Expected only the second statement to be formatted, got %q", got)
+	}
+}
+
+func TestValidateAndFormat(t *testing.T) {
+	v := db.NewSQLValidator()
+
+	formatted, result := v.ValidateAndFormat("select 1")
+	if !result.Valid || formatted != "SELECT 1" {
+		t.Errorf("Expected formatted valid query, got %q (valid=%v)", formatted, result.Valid)
+	}
+
+	opts := db.DefaultFormatOptions()
+	opts.KeywordCase = db.KeywordCaseLower
+	v.SetFormatOptions(opts)
+	if formatted, _ := v.ValidateAndFormat("SELECT 1"); formatted != "select 1" {
+		t.Errorf("Expected lowercase keywords, got %q", formatted)
+	}
+}
@@ -0,0 +1,307 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/storage"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/panels"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestInferColumnType(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{[]string{"1", "-20"}, "integer"},
+		{[]string{"1", "", "3"}, "text"},
+		{[]string{"1", "3000000000"}, "bigint"},
+		{[]string{"1", "2.5", "1e3"}, "numeric"},
+		{[]string{"true", "F", "yes"}, "boolean"},
+		{[]string{"2024-01-31", "2024-02-29"}, "date"},
+		{[]string{"2024-01-31 10:00:00", "2024-01-31T10:00:00.5"}, "timestamp"},
+		{[]string{"2024-01-31 10:00:00+02", "2024-01-31T10:00:00Z"}, "timestamptz"},
+		{[]string{"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"}, "uuid"},
+		{[]string{`{"a": 1}`, `[1, 2]`}, "jsonb"},
+		{[]string{"1", "two"}, "text"},
+		{[]string{"2024-02-30"}, "text"},
+		{[]string{""}, "text"},
+	}
+	for _, tt := range tests {
+		data := db.QueryResult{Columns: []string{"v"}}
+		for _, v := range tt.values {
+			data.Rows = append(data.Rows, []string{v})
+		}
+		if got := db.InferColumnType(data, 0); got != tt.want {
+			t.Errorf("InferColumnType(%q) = %s, want %s", tt.values, got, tt.want)
+		}
+	}
+
+	nulls := db.QueryResult{Columns: []string{"v"}, Rows: [][]string{{"7"}, {"NULL"}, {""}}, Nulls: [][]bool{nil, {true}, {true}}}
+	if got := db.InferColumnType(nulls, 0); got != "integer" {
+		t.Errorf("Expected NULLs ignored, got %s", got)
+	}
+
+	// With null=\N an empty field is an empty string, not a NULL
+	path := writeImportFile(t, "gaps.csv", "n,m\n1,a\n,b\n3,c\n\\N,d\n")
+	data, err := storage.ReadImportFile(path, db.CopyOptions{Header: true, Null: `\N`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.ColumnTypes[0] != "text" {
+		t.Errorf("Expected an empty string to make the column text, got %s", data.ColumnTypes[0])
+	}
+}
+
+func TestImportIdentifier(t *testing.T) {
+	for name, want := range map[string]string{
+		"Order Date":  "order_date",
+		" e-mail ":    "e_mail",
+		"2024 sales":  "_2024_sales",
+		"!!":          "column",
+		"customer_id": "customer_id",
+	} {
+		if got := db.ImportIdentifier(name); got != want {
+			t.Errorf("ImportIdentifier(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestImportPlanStatements(t *testing.T) {
+	plan := db.ImportPlan{
+		Table:  "scratch.orders",
+		Create: true,
+		Columns: []db.ImportColumn{
+			{Source: 0, Name: "id", Type: "integer", Key: true},
+			{Source: 2, Name: "note", Type: "text"},
+		},
+	}
+	if err := plan.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := plan.CreateStatement(); got != "CREATE TABLE scratch.orders (\n  id integer,\n  note text,\n  PRIMARY KEY (id)\n);" {
+		t.Errorf("Unexpected CREATE TABLE:\n%s", got)
+	}
+
+	row := []string{"1", "ignored", "it's"}
+	if got := plan.InsertStatement(row, nil); got != "INSERT INTO scratch.orders (id, note) VALUES ('1', 'it''s')" {
+		t.Errorf("Unexpected INSERT: %s", got)
+	}
+	plan.OnConflict = db.ConflictSkip
+	if got := plan.InsertStatement(row, []bool{false, false, true}); got != "INSERT INTO scratch.orders (id, note) VALUES ('1', NULL) ON CONFLICT DO NOTHING" {
+		t.Errorf("Unexpected INSERT: %s", got)
+	}
+	plan.OnConflict = db.ConflictUpdate
+	if got := plan.InsertStatement(row, nil); !strings.HasSuffix(got, " ON CONFLICT (id) DO UPDATE SET note = EXCLUDED.note") {
+		t.Errorf("Unexpected INSERT: %s", got)
+	}
+
+	// Each part of the table name is quoted
+	plan.Table, plan.OnConflict = "Sales 2024.order", db.ConflictError
+	if got := plan.CreateStatement(); !strings.HasPrefix(got, `CREATE TABLE "Sales 2024"."order" (`) {
+		t.Errorf("Unexpected CREATE TABLE:\n%s", got)
+	}
+	if got := plan.InsertStatement(row, nil); !strings.HasPrefix(got, `INSERT INTO "Sales 2024"."order" (id, note)`) {
+		t.Errorf("Unexpected INSERT: %s", got)
+	}
+	plan.OnConflict = db.ConflictUpdate
+
+	plan.Columns[0].Key = false
+	if err := plan.Validate(); err == nil {
+		t.Errorf("Expected updating on conflict without a key refused")
+	}
+	if err := plan.ValidateColumns(); err != nil {
+		t.Errorf("Expected the columns valid apart from the conflict handling, got %v", err)
+	}
+	plan.Columns[1].Name = "id"
+	if err := plan.Validate(); err == nil || !strings.Contains(err.Error(), "twice") {
+		t.Errorf("Expected a duplicate column refused, got %v", err)
+	}
+}
+
+// writeImportFile writes a file to import in a temporary directory
+func writeImportFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadImportFile(t *testing.T) {
+	// The delimiter is sniffed and the BOM dropped
+	path := writeImportFile(t, "orders.csv", "\xef\xbb\xbfid;Amount;note\n1;2.50;\"a;b\"\n2;3;\\N\n3\n")
+	data, err := storage.ReadImportFile(path, db.CopyOptions{Header: true, Null: `\N`})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(data.Columns, ",") != "id,Amount,note" || strings.Join(data.ColumnTypes, ",") != "integer,numeric,text" {
+		t.Errorf("Unexpected columns %v %v", data.Columns, data.ColumnTypes)
+	}
+	if len(data.Rows) != 3 || data.Rows[0][2] != "a;b" || !data.IsNull(1, 2) || !data.IsNull(2, 1) || data.IsNull(0, 2) {
+		t.Errorf("Unexpected rows %q %v", data.Rows, data.Nulls)
+	}
+
+	path = writeImportFile(t, "plain.tsv", "1\tx\n2\ty\n")
+	data, err = storage.ReadImportFile(path, db.CopyOptions{})
+	if err != nil || strings.Join(data.Columns, ",") != "column1,column2" || len(data.Rows) != 2 {
+		t.Errorf("Unexpected TSV %v %q %v", data.Columns, data.Rows, err)
+	}
+
+	path = writeImportFile(t, "users.json", `[{"id": 1, "name": "a", "tags": ["x"]}, {"id": 2, "name": null, "active": true}]`)
+	data, err = storage.ReadImportFile(path, db.CopyOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(data.Columns, ",") != "id,name,tags,active" || strings.Join(data.ColumnTypes, ",") != "integer,text,jsonb,boolean" {
+		t.Errorf("Unexpected JSON columns %v %v", data.Columns, data.ColumnTypes)
+	}
+	if data.Rows[0][2] != `["x"]` || !data.IsNull(1, 1) || !data.IsNull(1, 2) || !data.IsNull(0, 3) {
+		t.Errorf("Unexpected JSON rows %q %v", data.Rows, data.Nulls)
+	}
+
+	path = writeImportFile(t, "events.ndjson", "{\"at\": \"2024-01-31\"}\n\n{\"at\": \"2024-02-01\"}\n")
+	data, err = storage.ReadImportFile(path, db.CopyOptions{})
+	if err != nil || len(data.Rows) != 2 || data.ColumnTypes[0] != "date" {
+		t.Errorf("Unexpected NDJSON %q %v %v", data.Rows, data.ColumnTypes, err)
+	}
+
+	path = writeImportFile(t, "broken.json", `{"id": 1}`)
+	if _, err := storage.ReadImportFile(path, db.CopyOptions{}); err == nil {
+		t.Errorf("Expected a JSON object refused")
+	}
+}
+
+// wizardKeys sends keys to the import wizard and returns the messages of
+// the last one
+func wizardKeys(w *components.ImportWizard, keys ...string) []tea.Msg {
+	var cmd tea.Cmd
+	for _, key := range keys {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		switch key {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "shift+tab":
+			msg = tea.KeyMsg{Type: tea.KeyShiftTab}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		case "space":
+			msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}
+		}
+		cmd = w.Update(msg)
+	}
+	return collectMsgs(cmd)
+}
+
+func importData() db.QueryResult {
+	data := db.QueryResult{
+		Columns: []string{"ID", "Email", "Joined"},
+		Rows:    [][]string{{"1", "a@example.com", "2024-01-31"}, {"2", "b@example.com", "2024-02-01"}},
+	}
+	db.InferColumnTypes(&data)
+	return data
+}
+
+func TestImportWizardNewTable(t *testing.T) {
+	w := components.NewImportWizard("/tmp/New Users.csv", importData())
+	if !strings.Contains(w.View(), "a@example.com") {
+		t.Errorf("Expected the preview to show the rows")
+	}
+
+	// A new table named after the file, with the ID as key and the join
+	// date left out
+	wizardKeys(w, "enter")
+	if w.Step() != components.ImportStepTarget || w.Plan().Table != "new_users" {
+		t.Fatalf("Expected the table step for new_users, got %d %q", w.Step(), w.Plan().Table)
+	}
+	wizardKeys(w, "enter", "p", "j", "j", "space", "enter")
+	if w.Step() != components.ImportStepOptions {
+		t.Fatalf("Expected the options step, got %d", w.Step())
+	}
+	wizardKeys(w, "l", "enter")
+	if w.Step() != components.ImportStepReview || !strings.Contains(w.View(), "CREATE TABLE new_users") {
+		t.Fatalf("Expected the review of the CREATE TABLE, got %d", w.Step())
+	}
+
+	var start components.ImportStartMsg
+	for _, msg := range wizardKeys(w, "enter") {
+		start, _ = msg.(components.ImportStartMsg)
+	}
+	plan := start.Plan
+	if !plan.Create || plan.Table != "new_users" || plan.OnConflict != db.ConflictSkip || len(plan.Columns) != 2 ||
+		plan.Columns[0] != (db.ImportColumn{Source: 0, Name: "id", Type: "integer", Key: true}) ||
+		plan.Columns[1] != (db.ImportColumn{Source: 1, Name: "email", Type: "text"}) {
+		t.Errorf("Unexpected plan %+v", plan)
+	}
+	if len(start.Data.Rows) != 2 {
+		t.Errorf("Expected the rows passed along")
+	}
+
+	w.SetReport(db.ImportReport{Inserted: 1, Skipped: 1, Committed: true}, nil)
+	if view := w.View(); !strings.Contains(view, "Committed") || !strings.Contains(view, "1 inserted, 1 skipped, 0 failed") {
+		t.Errorf("Unexpected report:\n%s", view)
+	}
+	closed := false
+	for _, msg := range wizardKeys(w, "enter") {
+		_, closed = msg.(components.ImportWizardClosedMsg)
+	}
+	if !closed {
+		t.Errorf("Expected Enter to close the report")
+	}
+}
+
+func TestImportWizardExistingTable(t *testing.T) {
+	w := components.NewImportWizard("users.csv", importData())
+	w.SetTables([]db.SchemaObject{{Schema: "public", Name: "accounts"}, {Schema: "public", Name: "users"}})
+
+	wizardKeys(w, "enter", "down", "down")
+	var ask components.ImportTableColumnsMsg
+	for _, msg := range wizardKeys(w, "enter") {
+		ask, _ = msg.(components.ImportTableColumnsMsg)
+	}
+	if ask != (components.ImportTableColumnsMsg{Schema: "public", Table: "users"}) {
+		t.Fatalf("Expected the columns of public.users asked for, got %+v", ask)
+	}
+
+	w.SetTargetColumns([]db.TableColumn{{Name: "id", Type: "bigint"}, {Name: "email", Type: "text"}, {Name: "name", Type: "text"}}, nil)
+	plan := w.Plan()
+	if plan.Create || plan.Table != "public.users" || len(plan.Columns) != 2 ||
+		plan.Columns[0].Name != "id" || plan.Columns[1].Name != "email" {
+		t.Errorf("Expected the columns mapped by name, got %+v", plan)
+	}
+
+	// The join date goes into name, the last column
+	wizardKeys(w, "j", "j", "h")
+	if plan := w.Plan(); len(plan.Columns) != 3 || plan.Columns[2] != (db.ImportColumn{Source: 2, Name: "name", Type: "text"}) {
+		t.Errorf("Expected Joined mapped onto name, got %+v", plan.Columns)
+	}
+
+	w.SetTargetColumns(nil, os.ErrNotExist)
+	if w.Step() != components.ImportStepTarget {
+		t.Errorf("Expected an error to go back to the table step")
+	}
+}
+
+func TestImportCommand(t *testing.T) {
+	p := panels.NewEditorPanel()
+	path := writeImportFile(t, "data.csv", "a|b\n1|x\n")
+
+	var open panels.ImportFileMsg
+	for _, msg := range collectMsgs(runEditorCommand(p, "import "+path+" null=x")) {
+		open, _ = msg.(panels.ImportFileMsg)
+	}
+	if open.Path != path || strings.Join(open.Data.Columns, ",") != "a,b" || !open.Data.IsNull(0, 1) {
+		t.Errorf("Unexpected import %+v", open)
+	}
+
+	runEditorCommand(p, "import "+filepath.Join(t.TempDir(), "missing.csv"))
+	if _, isError := p.CommandLine().Message(); !isError {
+		t.Errorf("Expected a missing file reported")
+	}
+}
@@ -0,0 +1,191 @@
+package unit
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/panels"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value    any
+		typeName string
+		want     string
+	}{
+		{map[string]any{"a": "<b>", "n": 1.5}, "jsonb", `{"a":"<b>","n":1.5}`},
+		{[]any{1.0, 2.0}, "json", `[1,2]`},
+		{[]byte("Hi"), "bytea", `\x4869`},
+		{[]any{int32(1), nil, int32(3)}, "_int4", `{1,NULL,3}`},
+		{[]any{"a b", "", "null", `q"`, "x"}, "_text", `{"a b","","null","q\"",x}`},
+		{[]any{[]any{"a"}, []any{"b"}}, "_text", `{{a},{b}}`},
+		{int64(42), "int8", "42"},
+		{nil, "text", "NULL"},
+		{[16]byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 1, 2, 3, 4, 5, 6, 7, 8}, "uuid", "12345678-9abc-def0-0102-030405060708"},
+		{pgtype.Numeric{Int: big.NewInt(12345), Exp: -2, Valid: true}, "numeric", "123.45"},
+	}
+	for _, tt := range tests {
+		if got := db.FormatValue(tt.value, tt.typeName); got != tt.want {
+			t.Errorf("FormatValue(%#v, %q) = %q, want %q", tt.value, tt.typeName, got, tt.want)
+		}
+	}
+}
+
+func TestDetectInspectorKind(t *testing.T) {
+	tests := []struct {
+		value      string
+		columnType string
+		want       components.InspectorKind
+	}{
+		{`{"a":1}`, "jsonb", components.InspectJSON},
+		{`42`, "json", components.InspectJSON},
+		{` [1, 2] `, "text", components.InspectJSON},
+		{`{1,2}`, "_int4", components.InspectArray},
+		{`{1,2}`, "text", components.InspectText},
+		{`<a><b/></a>`, "xml", components.InspectXML},
+		{`<not xml`, "text", components.InspectText},
+		{`\x0102`, "bytea", components.InspectBytes},
+		{`\x0102`, "text", components.InspectText},
+		{"plain words", "text", components.InspectText},
+	}
+	for _, tt := range tests {
+		if got := components.DetectInspectorKind(tt.value, tt.columnType); got != tt.want {
+			t.Errorf("DetectInspectorKind(%q, %q) = %v, want %v", tt.value, tt.columnType, got, tt.want)
+		}
+	}
+}
+
+func inspectorKeys(i *components.CellInspector, keys ...string) {
+	for _, key := range keys {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		if key == "enter" {
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		}
+		i.Update(msg)
+	}
+}
+
+func TestInspectorJSONTree(t *testing.T) {
+	i := components.NewCellInspector("data", "jsonb", `{"users":[{"name":"a b","id":1}],"a key":true}`)
+	i.SetSize(60, 20)
+	var copied string
+	i.SetClipboard(func(text string) error {
+		copied = text
+		return nil
+	})
+
+	view := ansi.Strip(i.View())
+	for _, want := range []string{"data  jsonb · JSON", `  "users": [`, `      "name": "a b",`, `      "id": 1`, `  "a key": true`, "path ."} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the tree, got\n%s", want, view)
+		}
+	}
+
+	// Scalars copy as raw text, like jq -r
+	inspectorKeys(i, "j", "j", "j")
+	if got := i.Path(); got != ".users[0].name" {
+		t.Errorf("Expected the path of the selected node, got %q", got)
+	}
+	inspectorKeys(i, "y")
+	if copied != "a b" {
+		t.Errorf("Expected the raw string copied, got %q", copied)
+	}
+
+	// h goes to the parent, then collapses it
+	inspectorKeys(i, "h", "h")
+	view = ansi.Strip(i.View())
+	if i.Path() != ".users[0]" || !strings.Contains(view, "    {…}  2 keys") || strings.Contains(view, `"name"`) {
+		t.Errorf("Expected .users[0] collapsed, got %q\n%s", i.Path(), view)
+	}
+	inspectorKeys(i, "y")
+	if copied != "{\n  \"name\": \"a b\",\n  \"id\": 1\n}" {
+		t.Errorf("Expected the node copied as indented JSON, got %q", copied)
+	}
+
+	// Enter expands it again; G and p copy the last member's path
+	inspectorKeys(i, "enter", "G", "k", "p")
+	if copied != `.["a key"]` {
+		t.Errorf("Expected a quoted member in the path, got %q", copied)
+	}
+
+	inspectorKeys(i, "H")
+	if view := ansi.Strip(i.View()); !strings.Contains(view, `"users": […],`) {
+		t.Errorf("Expected H to collapse all nodes, got\n%s", view)
+	}
+}
+
+func TestInspectorOtherKinds(t *testing.T) {
+	tests := []struct {
+		value      string
+		columnType string
+		want       []string
+	}{
+		{`<a><b x="1">t</b><ns:c/></a>`, "xml", []string{"<a>", `  <b x="1">t</b>`, "  <ns:c></ns:c>", "</a>"}},
+		{`{1,"a b",NULL,{2,3}}`, "_text", []string{"[1] 1", "[2] a b", "[3] NULL", "[4] {2,3}"}},
+		{`\x48656c6c6f`, "bytea", []string{"bytea · bytes · 5 B", "00000000  48 65 6c 6c 6f", "|Hello|"}},
+		{strings.Repeat("word ", 60), "text", []string{"line 1/4"}},
+	}
+	for _, tt := range tests {
+		i := components.NewCellInspector("col", tt.columnType, tt.value)
+		i.SetSize(80, 20)
+		view := ansi.Strip(i.View())
+		for _, want := range tt.want {
+			if !strings.Contains(view, want) {
+				t.Errorf("Expected %q for %q, got\n%s", want, tt.value, view)
+			}
+		}
+	}
+}
+
+func TestInspectorEscapesControls(t *testing.T) {
+	evil := "\x1b]0;pwned\x07title\x1b[2J\rback"
+	tests := []struct {
+		value      string
+		columnType string
+	}{
+		{evil, "text"},
+		{`{"` + strings.ReplaceAll(evil, "\x1b", `\u001b`) + `"}`, "_text"},
+		{"<a>" + evil + "</a>", "xml"},
+		{`{"k":"\u001b[2J\u009b"}`, "jsonb"},
+	}
+	for _, tt := range tests {
+		i := components.NewCellInspector("col", tt.columnType, tt.value)
+		i.SetSize(80, 20)
+		view := i.View()
+		if strings.Contains(view, "\x1b]") || strings.Contains(view, "\x1b[2J") || strings.ContainsAny(view, "\r\x07\u009b") {
+			t.Errorf("Expected the controls of %q escaped, got %q", tt.value, view)
+		}
+	}
+	i := components.NewCellInspector("col", "text", evil)
+	i.SetSize(80, 20)
+	if view := ansi.Strip(i.View()); !strings.Contains(view, "␛]0;pwned␇title␛[2J␍back") {
+		t.Errorf("Expected control pictures, got\n%s", view)
+	}
+}
+
+func TestResultsInspect(t *testing.T) {
+	p := panels.NewResultsPanel()
+	p.SetSize(80, 24)
+	p.SetResult(db.QueryResult{
+		Columns:     []string{"id", "doc"},
+		ColumnTypes: []string{"int4", "jsonb"},
+		Rows:        [][]string{{"1", `{"k":"v"}`}},
+		RowCount:    1,
+	})
+
+	pressKeys(p, "l")
+	p.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !p.IsInspecting() || !strings.Contains(ansi.Strip(p.View()), `"k": "v"`) {
+		t.Fatalf("Expected Enter to inspect the focused cell, got\n%s", ansi.Strip(p.View()))
+	}
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if p.IsInspecting() || !strings.Contains(ansi.Strip(p.View()), "Row 1/1  Col 2/2") {
+		t.Errorf("Expected q to close the inspector")
+	}
+}
@@ -0,0 +1,141 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+)
+
+// newTestLinter returns a linter backed by a small cached schema
+func newTestLinter() *db.Linter {
+	cache := db.NewMetadataCache(0)
+	cache.PutSchemas("dev", []string{"public"})
+	cache.PutObjects("dev", "public", db.SchemaObjects{
+		Tables: []db.SchemaObject{
+			{Name: "users", Type: "table", Schema: "public"},
+			{Name: "orders", Type: "table", Schema: "public"},
+		},
+	})
+	cache.PutColumns("dev", "public", "users", []db.TableColumn{
+		{Name: "id", Type: "integer"},
+		{Name: "email", Type: "text", Nullable: true},
+	})
+	cache.PutColumns("dev", "public", "orders", []db.TableColumn{
+		{Name: "id", Type: "integer"},
+		{Name: "user_id", Type: "integer", Nullable: true},
+	})
+
+	linter := db.NewLinter()
+	linter.SetSchemaSource(cache, "dev")
+	return linter
+}
+
+// rulesOf returns the rule names of issues
+func rulesOf(issues []db.LintIssue) []string {
+	var rules []string
+	for _, issue := range issues {
+		rules = append(rules, issue.Rule)
+	}
+	return rules
+}
+
+// hasRule reports whether issues contain a finding of rule
+func hasRule(issues []db.LintIssue, rule string) bool {
+	for _, issue := range issues {
+		if issue.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLintUnknownTableAndColumn(t *testing.T) {
+	linter := newTestLinter()
+
+	query := "SELECT u.id, u.emial FROM users u JOIN orderz o ON o.user_id = u.id LIMIT 1"
+	issues := linter.Lint(query)
+	if len(issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %v", rulesOf(issues))
+	}
+
+	column := issues[0]
+	if column.Rule != "unknown-column" || query[column.Offset:column.Offset+column.Length] != "emial" {
+		t.Errorf("Expected unknown column 'emial', got %+v", column)
+	}
+	if column.Suggestion != "Did you mean email?" {
+		t.Errorf("Expected a spelling suggestion, got %q", column.Suggestion)
+	}
+
+	table := issues[1]
+	if table.Rule != "unknown-table" || table.Severity != db.SeverityError || table.Line != 1 {
+		t.Errorf("Expected unknown table error, got %+v", table)
+	}
+
+	// CTEs, output aliases, subqueries and system catalogs are not reported
+	clean := []string{
+		"WITH recent AS (SELECT id FROM users) SELECT id FROM recent LIMIT 5",
+		"SELECT id AS user_key FROM users ORDER BY user_key LIMIT 5",
+		"SELECT id FROM users u WHERE EXISTS (SELECT 1 FROM orders o WHERE o.user_id = u.id) LIMIT 5",
+		"SELECT datname FROM pg_database LIMIT 5",
+	}
+	for _, q := range clean {
+		if issues := linter.Lint(q); len(issues) != 0 {
+			t.Errorf("Expected no issues for %q, got %+v", q, issues)
+		}
+	}
+}
+
+func TestLintStatementRules(t *testing.T) {
+	linter := newTestLinter()
+
+	cases := map[string]string{
+		"DELETE FROM orders":                                                "missing-where",
+		"UPDATE users SET email = NULL":                                     "missing-where",
+		"CREATE VIEW v AS SELECT * FROM users":                              "select-star-in-view",
+		"SELECT u.id FROM users u, orders o LIMIT 1":                        "implicit-cross-join",
+		"SELECT id FROM users WHERE id NOT IN (SELECT user_id FROM orders)": "not-in-nullable",
+		"SELECT id FROM users":                                              "missing-limit",
+	}
+	for query, rule := range cases {
+		if issues := linter.Lint(query); !hasRule(issues, rule) {
+			t.Errorf("Expected %s for %q, got %v", rule, query, rulesOf(issues))
+		}
+	}
+
+	// NOT IN over a NOT NULL column is fine, as are aggregates without LIMIT
+	for _, query := range []string{
+		"SELECT id FROM orders WHERE user_id NOT IN (SELECT id FROM users) LIMIT 1",
+		"SELECT count(*) FROM users",
+	} {
+		if issues := linter.Lint(query); len(issues) != 0 {
+			t.Errorf("Expected no issues for %q, got %v", query, rulesOf(issues))
+		}
+	}
+}
+
+func TestLintConfiguration(t *testing.T) {
+	linter := newTestLinter()
+
+	linter.SetEnabled("missing-limit", false)
+	if issues := linter.Lint("SELECT id FROM users"); len(issues) != 0 {
+		t.Errorf("Expected disabled rule to be skipped, got %v", rulesOf(issues))
+	}
+
+	linter.SetSeverity("missing-where", db.SeverityError)
+	issues := linter.Lint("DELETE FROM users")
+	if len(issues) != 1 || issues[0].Severity != db.SeverityError {
+		t.Errorf("Expected severity override, got %+v", issues)
+	}
+
+	// Without schema metadata only structural rules run
+	issues = db.NewLinter().Lint("SELECT nope FROM missing LIMIT 1")
+	if len(issues) != 0 {
+		t.Errorf("Expected no schema issues without metadata, got %v", rulesOf(issues))
+	}
+
+	// The validator reports lint issues for valid queries
+	result := db.NewSQLValidator().Validate("DELETE FROM users")
+	if !result.Valid || !hasRule(result.Issues, "missing-where") {
+		t.Errorf("Expected validator to include lint issues, got %+v", result)
+	}
+}
@@ -0,0 +1,212 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/storage"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/panels"
+)
+
+func TestMetadataCachePutAndInvalidate(t *testing.T) {
+	cache := db.NewMetadataCache(time.Hour)
+
+	if _, ok := cache.Schemas("dev"); ok {
+		t.Fatal("Expected empty cache to miss")
+	}
+	if !cache.IsStale("dev") {
+		t.Error("Expected empty cache to be stale")
+	}
+
+	cache.PutSchemas("dev", []string{"public", "audit"})
+	cache.PutObjects("dev", "public", db.SchemaObjects{
+		Tables: []db.SchemaObject{{Name: "users", Type: "table", Schema: "public"}},
+	})
+	cache.PutColumns("dev", "public", "users", []db.TableColumn{{Name: "id", Type: "integer"}})
+
+	schemas, ok := cache.Schemas("dev")
+	if !ok || len(schemas) != 2 {
+		t.Fatalf("Expected 2 cached schemas, got %v", schemas)
+	}
+	if cache.IsStale("dev") {
+		t.Error("Expected freshly loaded cache not to be stale")
+	}
+	if columns, ok := cache.Columns("dev", "public", "users"); !ok || columns[0].Name != "id" {
+		t.Errorf("Expected cached columns for public.users, got %v", columns)
+	}
+	if all := cache.AllObjects("dev"); len(all) != 1 {
+		t.Errorf("Expected 1 cached object, got %d", len(all))
+	}
+
+	// Other connections are not affected
+	if _, ok := cache.Schemas("prod"); ok {
+		t.Error("Expected cache entries to be keyed by connection name")
+	}
+
+	// Plain queries keep the cache, DDL invalidates it
+	cache.ObserveQuery("dev", "SELECT * FROM users")
+	if _, ok := cache.Schemas("dev"); !ok {
+		t.Error("Expected SELECT not to invalidate the cache")
+	}
+	cache.ObserveQuery("dev", "ALTER TABLE users ADD COLUMN email text")
+	if _, ok := cache.Schemas("dev"); ok {
+		t.Error("Expected DDL to invalidate the cache")
+	}
+
+	// DDL run from the editor invalidates the connection's metadata
+	cache.PutSchemas("dev", []string{"public"})
+	editor := panels.NewEditorPanel()
+	editor.SetCompletionSource(cache, nil, "dev")
+	editor.SetLastResult(db.QueryResult{Query: "SELECT 1"})
+	if _, ok := cache.Schemas("dev"); !ok {
+		t.Error("Expected a SELECT run from the editor to keep the cache")
+	}
+	editor.SetLastResult(db.QueryResult{Query: "CREATE TABLE orders (id int)"})
+	if _, ok := cache.Schemas("dev"); ok {
+		t.Error("Expected DDL run from the editor to invalidate the cache")
+	}
+}
+
+func TestIsSchemaChange(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"SELECT 1", false},
+		{"INSERT INTO users (id) VALUES (1)", false},
+		{"CREATE TABLE t (id int)", true},
+		{"CREATE VIEW v AS SELECT 1", true},
+		{"DROP TABLE t", true},
+		{"ALTER TABLE t RENAME TO t2", true},
+		{"SELECT 1; CREATE SCHEMA s;", true},
+		{"not valid sql", false},
+	}
+
+	for _, tt := range tests {
+		if got := db.IsSchemaChange(tt.query); got != tt.want {
+			t.Errorf("IsSchemaChange(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMetadataCachePersistence(t *testing.T) {
+	store := storage.NewSchemaCacheStore(t.TempDir())
+
+	cache := db.NewMetadataCache(0)
+	cache.SetStore(store)
+	cache.PutSchemas("team/dev", []string{"public"})
+	cache.PutObjects("team/dev", "public", db.SchemaObjects{
+		Views: []db.SchemaObject{{Name: "active_users", Type: "view", Schema: "public"}},
+	})
+
+	// A new cache backed by the same store picks up the persisted entry
+	reloaded := db.NewMetadataCache(0)
+	reloaded.SetStore(store)
+	objects, ok := reloaded.Objects("team/dev", "public")
+	if !ok || len(objects.Views) != 1 || objects.Views[0].Name != "active_users" {
+		t.Fatalf("Expected persisted view, got %+v", objects)
+	}
+
+	// Names that sanitize alike don't share a file
+	if _, err := store.Load("team_dev"); err == nil {
+		t.Error("Expected team_dev not to read the cache of team/dev")
+	}
+
+	reloaded.Invalidate("team/dev")
+	if _, err := store.Load("team/dev"); err == nil {
+		t.Error("Expected cache file to be removed on invalidate")
+	}
+}
+
+// blockingStore is a metadata store whose saves wait for release
+type blockingStore struct {
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Load(string) (*db.SchemaMetadata, error) { return nil, errors.New("empty") }
+func (s *blockingStore) Delete(string) error                     { return nil }
+func (s *blockingStore) Save(string, *db.SchemaMetadata) error {
+	s.saving <- struct{}{}
+	<-s.release
+	return nil
+}
+
+func TestMetadataCacheSavesOutsideLock(t *testing.T) {
+	store := &blockingStore{saving: make(chan struct{}), release: make(chan struct{})}
+	cache := db.NewMetadataCache(0)
+	cache.SetStore(store)
+
+	go cache.PutSchemas("local", []string{"public"})
+	<-store.saving
+
+	// Lookups go on while the entry is being written
+	done := make(chan []string)
+	go func() {
+		schemas, _ := cache.Schemas("local")
+		done <- schemas
+	}()
+	select {
+	case schemas := <-done:
+		if len(schemas) != 1 {
+			t.Errorf("Expected the new schemas, got %v", schemas)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected lookups not to wait on the store")
+	}
+	close(store.release)
+}
+
+// schemaConn serves schema metadata and records the schemas whose tables
+// were listed
+type schemaConn struct {
+	db.Connection
+	schemas []string
+	listed  []string
+}
+
+func (c *schemaConn) ListSchemas(context.Context) ([]string, error) { return c.schemas, nil }
+func (c *schemaConn) ListTables(_ context.Context, schema string) ([]db.SchemaObject, error) {
+	c.listed = append(c.listed, schema)
+	return []db.SchemaObject{{Name: "t", Type: "table", Schema: schema}}, nil
+}
+func (c *schemaConn) ListViews(context.Context, string) ([]db.SchemaObject, error) { return nil, nil }
+func (c *schemaConn) ListFunctions(context.Context, string) ([]db.SchemaObject, error) {
+	return nil, nil
+}
+func (c *schemaConn) ListForeignKeys(context.Context, string) ([]db.ForeignKey, error) {
+	return nil, nil
+}
+
+func TestMetadataCacheRefresh(t *testing.T) {
+	conn := &schemaConn{schemas: []string{"public", "audit", "big1", "big2"}}
+	cache := db.NewMetadataCache(time.Nanosecond)
+
+	// An empty cache is loaded by the tree, not refreshed
+	tree := components.NewSchemaTree(conn)
+	tree.SetCache(cache, "dev")
+	if tree.RefreshInBackground(context.Background()) != nil {
+		t.Error("Expected no refresh of an empty cache")
+	}
+
+	// Only the schemas cached or asked for are reloaded
+	cache.PutSchemas("dev", []string{"public"})
+	cache.PutObjects("dev", "public", db.SchemaObjects{})
+	if err := cache.Refresh(context.Background(), "dev", conn, []string{"audit", "gone"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(conn.listed, ",") != "public,audit" {
+		t.Errorf("Expected public and audit reloaded, got %v", conn.listed)
+	}
+	if schemas, _ := cache.Schemas("dev"); len(schemas) != 4 {
+		t.Errorf("Expected the schema list reloaded, got %v", schemas)
+	}
+	if _, ok := cache.Objects("dev", "big1"); ok {
+		t.Error("Expected big1 left to load when expanded")
+	}
+}
@@ -0,0 +1,257 @@
+package unit
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/editor"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/panels"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	values := []any{
+		nil, true, false,
+		int64(0), int64(127), int64(128), int64(-1), int64(-33), int64(-200), int64(70000), int64(-1 << 40), int64(1 << 40),
+		3.5, "", "héllo", string(bytes.Repeat([]byte("x"), 300)),
+		[]byte{1, 2, 3},
+		[]any{int64(1), "two", []any{}},
+		map[string]any{"buf": editor.MsgpackExt{Type: 0, Data: []byte{7}}, "n": int64(2)},
+		editor.MsgpackExt{Type: 2, Data: []byte{1, 2, 3}},
+	}
+	for _, v := range values {
+		var buf bytes.Buffer
+		if err := editor.WriteMsgpack(&buf, v); err != nil {
+			t.Fatalf("WriteMsgpack(%v) failed: %v", v, err)
+		}
+		got, err := editor.ReadMsgpack(bufio.NewReader(&buf))
+		if err != nil || !reflect.DeepEqual(got, v) {
+			t.Errorf("Round trip of %#v gave %#v, %v", v, got, err)
+		}
+	}
+
+	// Strings slices encode as arrays
+	var buf bytes.Buffer
+	editor.WriteMsgpack(&buf, []string{"a", "b"})
+	if got, _ := editor.ReadMsgpack(bufio.NewReader(&buf)); !reflect.DeepEqual(got, []any{"a", "b"}) {
+		t.Errorf("Unexpected []string decoding: %#v", got)
+	}
+}
+
+// fakeNvim answers LazyDB's API calls like Neovim and echoes the line
+// changes it is sent as buffer events
+type fakeNvim struct {
+	mu    sync.Mutex
+	conn  net.Conn
+	calls []string
+	lines chan []any // nvim_buf_set_lines arguments
+}
+
+func (f *fakeNvim) send(msg []any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	editor.WriteMsgpack(f.conn, msg)
+}
+
+func (f *fakeNvim) serve() {
+	r := bufio.NewReader(f.conn)
+	for {
+		v, err := editor.ReadMsgpack(r)
+		if err != nil {
+			return
+		}
+		msg := v.([]any)
+		if msg[0] == int64(0) {
+			method := msg[2].(string)
+			f.mu.Lock()
+			f.calls = append(f.calls, method)
+			f.mu.Unlock()
+			if method == "nvim_buf_set_lines" {
+				f.lines <- msg[3].([]any)
+			}
+			var result any
+			switch method {
+			case "nvim_get_api_info":
+				result = []any{int64(3), map[string]any{}}
+			case "nvim_create_buf":
+				result = editor.MsgpackExt{Type: 0, Data: []byte{5}}
+			case "nvim_buf_get_number":
+				result = int64(5)
+			}
+			f.send([]any{int64(1), msg[1], nil, result})
+			continue
+		}
+		if msg[1] == "nvim_buf_set_lines" {
+			args := msg[2].([]any)
+			f.lines <- args
+			f.send([]any{int64(2), "nvim_buf_lines_event", []any{args[0], int64(1), args[1], args[2], args[4], false}})
+		}
+	}
+}
+
+func TestEditorNeovimSync(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "nvim.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer ln.Close()
+
+	fake := &fakeNvim{lines: make(chan []any, 16)}
+	accepted := make(chan struct{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		fake.conn = conn
+		close(accepted)
+		fake.serve()
+	}()
+
+	p := panels.NewEditorPanel()
+	p.SetQuery("SELECT 1;\nSELECT 2;")
+
+	// :nvim connects and mirrors the buffer into a new Neovim buffer
+	var attached tea.Msg
+	for _, msg := range collectMsgs(runEditorCommand(p, "nvim "+sock)) {
+		if m, ok := msg.(editor.NvimAttachedMsg); ok {
+			attached = m
+		}
+	}
+	if attached == nil || attached.(editor.NvimAttachedMsg).Err != nil {
+		t.Fatalf("Expected to attach, got %#v", attached)
+	}
+	<-accepted
+	listen := p.Update(attached)
+	if !p.IsNeovimAttached() || listen == nil {
+		t.Fatalf("Expected the sync to start listening")
+	}
+	if initial := <-fake.lines; !reflect.DeepEqual(initial[4], []any{"SELECT 1;", "SELECT 2;"}) {
+		t.Errorf("Expected the buffer text sent, got %v", initial[4])
+	}
+	fake.mu.Lock()
+	calls := append([]string(nil), fake.calls...)
+	fake.mu.Unlock()
+	for _, want := range []string{"nvim_set_current_buf", "nvim_command", "nvim_buf_attach"} {
+		found := false
+		for _, call := range calls {
+			found = found || call == want
+		}
+		if !found {
+			t.Errorf("Expected %s to be called, got %v", want, calls)
+		}
+	}
+
+	// Edits in LazyDB send only the changed lines
+	p.SetQuery("SELECT 1;\nSELECT 3;")
+	change := <-fake.lines
+	if change[1] != int64(1) || change[2] != int64(2) || !reflect.DeepEqual(change[4], []any{"SELECT 3;"}) {
+		t.Errorf("Expected line 2 replaced, got %v", change)
+	}
+
+	// Edits in Neovim arrive as text; the echo of LazyDB's own edit doesn't
+	fake.send([]any{int64(2), "nvim_buf_lines_event", []any{editor.MsgpackExt{Data: []byte{5}}, int64(2), int64(0), int64(1), []any{"SELECT 10;"}, false}})
+	msg := waitMsg(t, listen)
+	if m, ok := msg.(editor.NvimTextMsg); !ok || m.Text != "SELECT 10;\nSELECT 3;" {
+		t.Fatalf("Expected the Neovim edit, got %#v", msg)
+	}
+	listen = p.Update(msg)
+	if p.GetQuery() != "SELECT 10;\nSELECT 3;" {
+		t.Errorf("Expected the edit applied, got %q", p.GetQuery())
+	}
+
+	// :LazyDBExecute on line 2 runs the statement there
+	fake.send([]any{int64(2), "lazydb_execute", []any{int64(0), int64(2), int64(2), int64(2), int64(3)}})
+	msg = waitMsg(t, listen)
+	if _, ok := msg.(editor.NvimExecuteMsg); !ok {
+		t.Fatalf("Expected an execute message, got %#v", msg)
+	}
+	cmd := p.Update(msg)
+	fake.conn.Close() // Ends the listen command in the batch
+	var query string
+	var detached tea.Msg
+	for _, m := range collectMsgs(cmd) {
+		switch m := m.(type) {
+		case panels.ExecuteQueryMsg:
+			query = m.Query
+		case editor.NvimDetachedMsg:
+			detached = m
+		}
+	}
+	if query != "SELECT 3" {
+		t.Errorf("Expected the statement under the Neovim cursor, got %q", query)
+	}
+
+	// Neovim going away ends the sync
+	if detached == nil {
+		t.Fatalf("Expected the sync to end with the connection")
+	}
+	p.Update(detached)
+	if p.IsNeovimAttached() {
+		t.Errorf("Expected the sync ended")
+	}
+}
+
+// waitMsg runs a command that waits for a message, failing after a while
+func waitMsg(t *testing.T, cmd tea.Cmd) tea.Msg {
+	t.Helper()
+	ch := make(chan tea.Msg, 1)
+	go func() { ch <- cmd() }()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for a message")
+		return nil
+	}
+}
+
+func TestEmbeddedNeovimSync(t *testing.T) {
+	if !editor.IsNvimAvailable() {
+		t.Skip("nvim not installed")
+	}
+	client, err := editor.EmbedNeovim()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	sync, err := editor.AttachNeovim(client, "test.sql", "SELECT 1;\nSELECT 2;")
+	if err != nil {
+		t.Fatalf("AttachNeovim failed: %v", err)
+	}
+	listen := sync.Listen()
+
+	if err := sync.SetText("SELECT 1;\nSELECT 3;"); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := client.Call("nvim_buf_get_lines", 0, 0, -1, false)
+	if err != nil || !reflect.DeepEqual(lines, []any{"SELECT 1;", "SELECT 3;"}) {
+		t.Errorf("Expected Neovim to hold LazyDB's text, got %v, %v", lines, err)
+	}
+
+	// An edit in Neovim comes back as text
+	if _, err := client.Call("nvim_buf_set_lines", 0, 0, 1, false, []string{"SELECT 10;"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := waitMsg(t, listen); !reflect.DeepEqual(msg, editor.NvimTextMsg{Text: "SELECT 10;\nSELECT 3;"}) {
+		t.Errorf("Expected the Neovim edit, got %#v", msg)
+	}
+
+	// :LazyDBExecute reports the cursor
+	client.Call("nvim_win_set_cursor", 0, []any{2, 3})
+	if _, err := client.Call("nvim_command", editor.NvimExecuteCommand); err != nil {
+		t.Fatal(err)
+	}
+	msg := waitMsg(t, listen)
+	if m, ok := msg.(editor.NvimExecuteMsg); !ok || m.Range || m.Row != 1 || m.Col != 3 {
+		t.Errorf("Expected the cursor on line 2, got %#v", msg)
+	}
+}
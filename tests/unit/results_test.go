@@ -0,0 +1,463 @@
+package unit
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/config"
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/panels"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// wideResult returns rows of six 20-character columns
+func wideResult(rows int) db.QueryResult {
+	result := db.QueryResult{
+		Columns:     []string{"id", "name", "email", "city", "country", "notes"},
+		ColumnTypes: []string{"int4", "text", "text", "varchar", "text", "text"},
+	}
+	for i := 0; i < rows; i++ {
+		row := make([]string, len(result.Columns))
+		for j, col := range result.Columns {
+			row[j] = fmt.Sprintf("%s-%d%s", col, i+1, strings.Repeat(".", 20-len(col)-len(fmt.Sprint(i+1))-1))
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	result.RowCount = rows
+	return result
+}
+
+func pressKeys(p *panels.ResultsPanel, keys ...string) {
+	for _, key := range keys {
+		switch key {
+		case "ctrl+d":
+			p.Update(tea.KeyMsg{Type: tea.KeyCtrlD})
+		case "ctrl+u":
+			p.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+		default:
+			p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		}
+	}
+}
+
+func TestResultsCursorMoves(t *testing.T) {
+	p := panels.NewResultsPanel()
+	p.SetSize(80, 20)
+	p.SetResult(wideResult(50))
+
+	tests := []struct {
+		keys    []string
+		row     int
+		col     int
+		message string
+	}{
+		{[]string{"l", "l", "j"}, 1, 2, "hjkl move one cell"},
+		{[]string{"h", "h", "h", "k", "k"}, 0, 0, "moves stop at the edges"},
+		{[]string{"$"}, 0, 5, "$ jumps to the last column"},
+		{[]string{"b", "0"}, 0, 0, "0 jumps to the first column"},
+		{[]string{"w", "G"}, 49, 1, "G jumps to the last row"},
+		{[]string{"j"}, 49, 1, "down stops at the last row"},
+		{[]string{"g", "g"}, 0, 1, "gg jumps to the first row"},
+		{[]string{"g", "j"}, 0, 1, "g waits for a second g"},
+		{[]string{"ctrl+d"}, 4, 1, "ctrl+d moves half a page"},
+		{[]string{"ctrl+u"}, 0, 1, "ctrl+u moves back"},
+	}
+	for _, tt := range tests {
+		pressKeys(p, tt.keys...)
+		if row, col := p.Cursor(); row != tt.row || col != tt.col {
+			t.Errorf("%s: expected cell %d,%d, got %d,%d", tt.message, tt.row, tt.col, row, col)
+		}
+	}
+
+	// A new result starts at the first cell
+	pressKeys(p, "G", "$")
+	p.SetResult(wideResult(3))
+	if row, col := p.Cursor(); row != 0 || col != 0 {
+		t.Errorf("Expected the cursor reset on a new result, got %d,%d", row, col)
+	}
+}
+
+func TestResultsViewFollowsCursor(t *testing.T) {
+	p := panels.NewResultsPanel()
+	p.SetSize(80, 20)
+	p.SetResult(wideResult(50))
+
+	lines := strings.Split(ansi.Strip(p.View()), "\n")
+	if !strings.HasPrefix(lines[2], "   │ id") || !strings.HasPrefix(lines[4], " 1 │ id-1") {
+		t.Fatalf("Expected a row number gutter, got %q", lines[2:5])
+	}
+	for _, line := range lines {
+		if ansi.StringWidth(line) > 76 {
+			t.Errorf("Expected lines cut to the panel width, got %q", line)
+		}
+	}
+
+	// The last column scrolls the table by whole columns
+	pressKeys(p, "$", "G")
+	view := ansi.Strip(p.View())
+	if !strings.Contains(view, "notes-50") || strings.Contains(view, "│ id") {
+		t.Errorf("Expected the table scrolled to the last cell, got\n%s", view)
+	}
+	if !strings.Contains(view, "◄ 50 rows (showing") {
+		t.Errorf("Expected a left scroll indicator, got\n%s", view)
+	}
+}
+
+func TestResultsStatusLine(t *testing.T) {
+	result := wideResult(2)
+	result.Rows[1][5] = "first line\nsecond line"
+	p := panels.NewResultsPanel()
+	p.SetSize(80, 20)
+	p.SetResult(result)
+	pressKeys(p, "j", "$")
+
+	view := ansi.Strip(p.View())
+	if !strings.Contains(view, "Row 2/2  Col 6/6  notes text") {
+		t.Errorf("Expected the cell position, column and type, got\n%s", view)
+	}
+	if !strings.Contains(view, "first line↵second line") {
+		t.Errorf("Expected the full value in the status line, got\n%s", view)
+	}
+
+	var copied string
+	p.SetClipboard(func(text string) error {
+		copied = text
+		return nil
+	})
+	pressKeys(p, "y")
+	if copied != "first line\nsecond line" {
+		t.Errorf("Expected y to copy the raw value, got %q", copied)
+	}
+	if !strings.Contains(p.View(), "Copied notes") {
+		t.Errorf("Expected a copy message")
+	}
+
+	value, column, columnType, ok := p.FocusedCell()
+	if !ok || value != copied || column != "notes" || columnType != "text" {
+		t.Errorf("Unexpected focused cell %q %q %q %v", value, column, columnType, ok)
+	}
+}
+
+func TestResultsRecordView(t *testing.T) {
+	p := panels.NewResultsPanel()
+	p.SetSize(80, 20)
+	p.SetResult(wideResult(3))
+
+	pressKeys(p, "l", "l", "x")
+	if !p.IsExpanded() {
+		t.Fatalf("Expected x to show the record view")
+	}
+	lines := strings.Split(ansi.Strip(p.View()), "\n")
+	if !strings.HasPrefix(lines[2], "─[ RECORD 1 of 3 ]──") || !strings.HasPrefix(lines[3], "id      │ id-1...") || !strings.HasPrefix(lines[5], "email   │ email-1") {
+		t.Errorf("Expected one field per line under a record header, got %q", lines[2:9])
+	}
+
+	// j/k move between fields, h/l between records
+	pressKeys(p, "j", "l", "l", "l")
+	if row, col := p.Cursor(); row != 2 || col != 3 {
+		t.Errorf("Expected record 3 field 4, got %d,%d", row, col)
+	}
+	if view := ansi.Strip(p.View()); !strings.Contains(view, "RECORD 3 of 3") || !strings.Contains(view, "Row 3/3  Col 4/6  city varchar") {
+		t.Errorf("Expected the third record with its focused field, got\n%s", view)
+	}
+	pressKeys(p, "h", "g", "g")
+	if row, _ := p.Cursor(); row != 0 {
+		t.Errorf("Expected h and gg to go back to the first record, got %d", row)
+	}
+
+	pressKeys(p, "x")
+	if p.IsExpanded() || p.Expanded() != panels.ExpandedOff {
+		t.Errorf("Expected x to go back to the table")
+	}
+}
+
+func TestResultsExpandedAuto(t *testing.T) {
+	p := panels.NewResultsPanel()
+	p.SetSize(80, 20)
+	p.SetResultsConfig(config.ResultsConfig{Expanded: "auto"})
+
+	p.SetResult(db.QueryResult{Columns: []string{"id", "name"}, Rows: [][]string{{"1", "a"}}, RowCount: 1})
+	if p.IsExpanded() {
+		t.Errorf("Expected a table that fits to stay a table")
+	}
+	p.SetResult(wideResult(3))
+	if !p.IsExpanded() || !strings.Contains(ansi.Strip(p.View()), "expanded auto") {
+		t.Errorf("Expected a table wider than the panel shown as records")
+	}
+	p.SetSize(200, 20)
+	if p.IsExpanded() {
+		t.Errorf("Expected the table once the panel is wide enough")
+	}
+
+	reg := components.NewCommandRegistry()
+	p.RegisterCommands(reg)
+	if _, err := reg.Run("expanded on"); err != nil || p.Expanded() != panels.ExpandedOn {
+		t.Errorf("Expected :expanded on, got %q, %v", p.Expanded(), err)
+	}
+	if _, err := reg.Run("expanded sideways"); err == nil {
+		t.Errorf("Expected an error for an unknown mode")
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Results.Expanded = "sometimes"
+	if err := config.ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "results.expanded") {
+		t.Errorf("Expected a validation error, got %v", err)
+	}
+}
+
+// columnValues returns the values of a column in the rendered table
+func columnValues(t *testing.T, p *panels.ResultsPanel, col int) []string {
+	t.Helper()
+	var values []string
+	for _, line := range strings.Split(ansi.Strip(p.View()), "\n")[4:] {
+		cells := strings.Split(line, "│")
+		if len(cells) < col+2 {
+			break
+		}
+		values = append(values, strings.TrimSpace(cells[col+1]))
+	}
+	return values
+}
+
+func TestResultsSortAndFilter(t *testing.T) {
+	p := panels.NewResultsPanel()
+	p.SetSize(100, 24)
+	p.SetResult(db.QueryResult{
+		Columns:     []string{"id", "name", "born"},
+		ColumnTypes: []string{"int4", "text", "date"},
+		Rows: [][]string{
+			{"10", "Alice", "1990-05-01"},
+			{"9", "bob", "1985-12-24"},
+			{"NULL", "Carol", "2001-01-01"},
+			{"100", "alan", "1979-07-30"},
+		},
+		Nulls:    [][]bool{nil, nil, {true, false, false}, nil},
+		RowCount: 4,
+	})
+
+	// s cycles ascending, descending, unsorted; numbers sort numerically
+	// and NULLs go last
+	pressKeys(p, "s")
+	if got := columnValues(t, p, 0); strings.Join(got, " ") != "9 10 100 NULL" {
+		t.Errorf("Expected a numeric sort, got %q", got)
+	}
+	pressKeys(p, "s")
+	if got := columnValues(t, p, 0); strings.Join(got, " ") != "100 10 9 NULL" {
+		t.Errorf("Expected a descending sort, got %q", got)
+	}
+	if !strings.Contains(ansi.Strip(p.View()), "id ▼") {
+		t.Errorf("Expected a sort arrow in the header")
+	}
+	pressKeys(p, "s")
+	if got := columnValues(t, p, 0); strings.Join(got, " ") != "10 9 NULL 100" {
+		t.Errorf("Expected the query's order back, got %q", got)
+	}
+
+	reg := components.NewCommandRegistry()
+	p.RegisterCommands(reg)
+	if _, err := reg.Run("sort born desc"); err != nil {
+		t.Fatal(err)
+	}
+	if got := columnValues(t, p, 1); strings.Join(got, " ") != "Carol Alice bob alan" {
+		t.Errorf("Expected a date sort, got %q", got)
+	}
+
+	// Substring filters ignore case unless the pattern has capitals
+	if _, err := reg.Run("filter al"); err != nil {
+		t.Fatal(err)
+	}
+	view := ansi.Strip(p.View())
+	if !strings.Contains(view, "filtered 2 of 4 rows") || !strings.Contains(view, "al in all columns · sorted by born desc") {
+		t.Errorf("Expected the filter in the summary, got\n%s", view)
+	}
+	if _, err := reg.Run("filter Al"); err != nil || len(columnValues(t, p, 0)) != 1 {
+		t.Errorf("Expected a case-sensitive filter, got %q, %v", columnValues(t, p, 0), err)
+	}
+
+	// filter! matches the focused column only
+	pressKeys(p, "0")
+	if _, err := reg.Run("filter! /^1/"); err != nil {
+		t.Fatal(err)
+	}
+	if got := columnValues(t, p, 0); strings.Join(got, " ") != "10 100" {
+		t.Errorf("Expected a regexp filter on id, got %q", got)
+	}
+	if _, err := reg.Run("filter /(/"); err == nil {
+		t.Errorf("Expected an invalid regexp error")
+	}
+
+	// f keeps the rows with the focused value, F clears
+	pressKeys(p, "F", "f")
+	if got := columnValues(t, p, 0); strings.Join(got, " ") != "NULL" {
+		t.Errorf("Expected the rows with the focused value, got %q", got)
+	}
+	pressKeys(p, "F")
+	if !strings.Contains(ansi.Strip(p.View()), "4 rows, 0ms · sorted by born desc") {
+		t.Errorf("Expected all rows back, got\n%s", ansi.Strip(p.View()))
+	}
+}
+
+func TestResultsColumns(t *testing.T) {
+	p := panels.NewResultsPanel()
+	p.SetSize(80, 20)
+	p.SetResult(wideResult(3))
+	reg := components.NewCommandRegistry()
+	p.RegisterCommands(reg)
+
+	header := func() string {
+		return strings.Split(ansi.Strip(p.View()), "\n")[2]
+	}
+
+	// - hides the focused column, + shows them all
+	pressKeys(p, "l", "-")
+	if strings.Contains(header(), "name") || !strings.Contains(ansi.Strip(p.View()), "1 hidden") {
+		t.Errorf("Expected name hidden, got %q", header())
+	}
+	if _, column, _, _ := p.FocusedCell(); column != "email" {
+		t.Errorf("Expected the next column focused, got %q", column)
+	}
+	pressKeys(p, "+")
+	if !strings.Contains(header(), "name") {
+		t.Errorf("Expected name shown again, got %q", header())
+	}
+	if _, err := reg.Run("hide id name email city country"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Run("hide notes"); err == nil {
+		t.Errorf("Expected an error hiding the last column")
+	}
+	reg.Run("show")
+
+	// > and < move the focused column
+	pressKeys(p, "0", ">")
+	if !strings.HasPrefix(strings.TrimSpace(header()), "│ name") {
+		t.Errorf("Expected id moved right, got %q", header())
+	}
+
+	// Pinned columns stay on the left while scrolling
+	if _, err := reg.Run("pin email"); err != nil {
+		t.Fatal(err)
+	}
+	pressKeys(p, "$")
+	if h := header(); !strings.HasPrefix(strings.TrimSpace(h), "│ email") || !strings.Contains(h, "║") || !strings.Contains(h, "notes") {
+		t.Errorf("Expected email pinned before the last columns, got %q", h)
+	}
+	if _, err := reg.Run("unpin"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(header(), "║") {
+		t.Errorf("Expected no pinned columns, got %q", header())
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		a, b, typeName string
+		want           int
+	}{
+		{"9", "10", "int4", -1},
+		{"9", "10", "text", 1},
+		{"1.5e3", "200", "numeric", 1},
+		{"2024-01-02", "2023-12-31", "date", 1},
+		{"2024-01-02 10:00:00 +0100 CET", "2024-01-02 09:30:00 +0000 UTC", "timestamptz", -1},
+		{"abc", "abc", "text", 0},
+		{"x", "10", "int4", 1},
+	}
+	for _, tt := range tests {
+		if got := db.CompareValues(tt.a, tt.b, tt.typeName); got != tt.want {
+			t.Errorf("CompareValues(%q, %q, %q) = %d, want %d", tt.a, tt.b, tt.typeName, got, tt.want)
+		}
+	}
+}
+
+func TestFitCell(t *testing.T) {
+	tests := []struct {
+		value string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc  "},
+		{"abcdef", 4, "abc…"},
+		{"日本語テキスト", 7, "日本語…"},
+		{"日本語", 6, "日本語"},
+		{"👩‍👩‍👧 family", 4, "👩‍👩‍👧 …"},
+		{"café", 4, "café"},
+		{"a\nb\tc\x1b[31m", 10, "a↵b→c␛[31m"},
+		{"a\r\nb\x7f\u0085", 10, `a↵b␡\x85  `},
+	}
+	for _, tt := range tests {
+		got := components.FitCell(tt.value, tt.width)
+		if got != tt.want {
+			t.Errorf("FitCell(%q, %d) = %q, want %q", tt.value, tt.width, got, tt.want)
+		}
+		if ansi.StringWidth(got) != tt.width {
+			t.Errorf("FitCell(%q, %d) is %d columns wide", tt.value, tt.width, ansi.StringWidth(got))
+		}
+	}
+}
+
+func TestResultsUnicodeTable(t *testing.T) {
+	p := panels.NewResultsPanel()
+	p.SetSize(120, 20)
+	p.SetResult(db.QueryResult{
+		Columns:     []string{"名前", "city", "note"},
+		ColumnTypes: []string{"text", "text", "text"},
+		Rows: [][]string{
+			{"山田太郎", "Zürich", "line one\nline two\x1b[2J"},
+			{"José 🎉", "東京都千代田区丸の内一丁目九番一号 東京駅", "ok"},
+		},
+		RowCount: 2,
+	})
+
+	view := p.View()
+	if strings.Contains(view, "\x1b[2J") || strings.Contains(view, "line one\n") {
+		t.Fatalf("Expected control characters escaped, got %q", view)
+	}
+	lines := strings.Split(ansi.Strip(view), "\n")
+	table := lines[2:6]
+	for _, line := range table {
+		if ansi.StringWidth(strings.TrimRight(line, " ")) != ansi.StringWidth(table[1]) {
+			t.Errorf("Expected aligned lines, got\n%s", strings.Join(table, "\n"))
+			break
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("Expected whole characters, got %q", line)
+		}
+	}
+	// Columns line up on the border after the first column
+	border := ansi.StringWidth(strings.SplitAfterN(table[0], "│", 3)[1])
+	for _, line := range table[2:] {
+		if w := ansi.StringWidth(strings.SplitAfterN(line, "│", 3)[1]); w != border {
+			t.Errorf("Expected the border at %d, got %d in %q", border, w, line)
+		}
+	}
+	if !strings.Contains(table[3], "東京都千代田区丸の内一丁目九…") || !strings.Contains(table[2], "line one↵line two␛[2J") {
+		t.Errorf("Unexpected cells\n%s", strings.Join(table, "\n"))
+	}
+
+	// The widths are configurable
+	reg := components.NewCommandRegistry()
+	p.RegisterCommands(reg)
+	if _, err := reg.Run("colwidth 4 12"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if minWidth, maxWidth := p.ColumnWidths(); minWidth != 4 || maxWidth != 12 {
+		t.Errorf("Expected widths 4 to 12, got %d to %d", minWidth, maxWidth)
+	}
+	lines = strings.Split(ansi.Strip(p.View()), "\n")
+	if !strings.Contains(lines[5], "東京都千代… ") || !strings.HasPrefix(lines[2], "  │ 名前     │") {
+		t.Errorf("Expected cells cut at 12 columns, got\n%s", strings.Join(lines[2:6], "\n"))
+	}
+	if _, err := reg.Run("colwidth 10 5"); err == nil {
+		t.Errorf("Expected a maximum below the minimum refused")
+	}
+
+	p.SetResultsConfig(config.ResultsConfig{Expanded: "off", MinColumnWidth: 3, MaxColumnWidth: 8})
+	if minWidth, maxWidth := p.ColumnWidths(); minWidth != 3 || maxWidth != 8 {
+		t.Errorf("Expected the configured widths, got %d to %d", minWidth, maxWidth)
+	}
+}
@@ -0,0 +1,149 @@
+package unit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/editor"
+	"github.com/MachineLearning-Nerd/lazydb/internal/storage"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/panels"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestScriptFile(t *testing.T) {
+	tests := []struct {
+		content string
+		lines   []string
+	}{
+		{"", []string{""}},
+		{"SELECT 1;", []string{"SELECT 1;"}},
+		{"a\nb\n", []string{"a", "b", ""}},
+		{"a\r\n\r\nc", []string{"a", "", "c"}},
+	}
+
+	dir := t.TempDir()
+	for i, tt := range tests {
+		path := filepath.Join(dir, fmt.Sprintf("script%d.sql", i))
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		script, err := storage.OpenScript(path)
+		if err != nil {
+			t.Fatalf("OpenScript(%q) failed: %v", tt.content, err)
+		}
+		if script.LineCount() != len(tt.lines) {
+			t.Errorf("%q: expected %d lines, got %d", tt.content, len(tt.lines), script.LineCount())
+		}
+		lines, err := script.Lines(0, script.LineCount())
+		if err != nil || !reflect.DeepEqual(lines, tt.lines) {
+			t.Errorf("%q: expected lines %q, got %q (%v)", tt.content, tt.lines, lines, err)
+		}
+		if len(tt.lines) > 1 {
+			if tail, _ := script.Lines(1, 100); !reflect.DeepEqual(tail, tt.lines[1:]) {
+				t.Errorf("%q: expected tail %q, got %q", tt.content, tt.lines[1:], tail)
+			}
+		}
+	}
+}
+
+func TestHighlightRange(t *testing.T) {
+	h := components.NewSQLHighlighter()
+	lines := []string{"SELECT 1;", "/* a", "comment */", "SELECT 2;"}
+
+	got := h.HighlightRange(lines, 2, 4)
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 highlighted lines, got %d", len(got))
+	}
+	// Lexing starts after "SELECT 1;", so line 2 is still a comment
+	full := h.HighlightSpans(strings.Join(lines[1:], "\n"))
+	if !reflect.DeepEqual(got, full[1:]) {
+		t.Errorf("Expected the range highlighted in context, got %+v", got)
+	}
+	if comment := full[0][0].Style; !reflect.DeepEqual(got[0], []components.Span{{Text: "comment */", Style: comment}}) {
+		t.Errorf("Expected the comment end styled as a comment, got %+v", got[0])
+	}
+	if out := h.HighlightRange(lines, 3, 1); out != nil {
+		t.Errorf("Expected no lines for an empty range, got %+v", out)
+	}
+}
+
+func TestEditorLargeScript(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	// Too many lines for the textarea: opens read-only
+	var sb strings.Builder
+	for i := 0; i < 12000; i++ {
+		fmt.Fprintf(&sb, "INSERT INTO t VALUES (%d);\n", i)
+	}
+	if err := storage.SaveQuery(sb.String(), "dump.sql"); err != nil {
+		t.Fatal(err)
+	}
+
+	p := panels.NewEditorPanel()
+	p.SetSize(80, 20)
+	if err := p.OpenBuffer("dump"); err != nil {
+		t.Fatalf("OpenBuffer failed: %v", err)
+	}
+	if !p.IsReadOnly() || p.IsDirty() {
+		t.Fatalf("Expected a clean read-only buffer for a large file")
+	}
+	if err := p.SaveBuffer(""); err == nil {
+		t.Errorf("Expected saving a read-only script to fail")
+	}
+	if view := p.View(); !strings.Contains(view, "12001 lines") {
+		t.Errorf("Expected the status to count the script lines, got %q", view)
+	}
+
+	// G then k: the statement on the last non-empty line runs
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k")})
+	if query, _, _ := p.QueryToExecute(false); query != "INSERT INTO t VALUES (11999)" {
+		t.Errorf("Expected the statement at the cursor, got %q", query)
+	}
+	if query, _, _ := p.QueryToExecute(true); query != sb.String() {
+		t.Errorf("Expected the whole script for run all, got %d bytes", len(query))
+	}
+
+	// Buffers hold more than the former 10,000 character limit
+	p.NewBuffer()
+	long := strings.Repeat("SELECT 1;\n", 2000)
+	p.SetQuery(long)
+	if p.GetQuery() != long {
+		t.Errorf("Expected %d characters kept, got %d", len(long), len(p.GetQuery()))
+	}
+	if rows := strings.Count(p.View(), "\n") + 1; rows > 20 {
+		t.Errorf("Expected only the lines in view rendered, got %d rows", rows)
+	}
+
+	// Text past the textarea's lines is refused rather than cut
+	if err := p.SetQuery(sb.String()); err == nil || p.GetQuery() != long {
+		t.Errorf("Expected too many lines refused, got %v", err)
+	}
+	for _, key := range []string{"g", "g", "y", "G", "5", "p"} {
+		p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+	if p.GetQuery() != long || !strings.Contains(p.View(), "more than the editor's 10,000") {
+		t.Errorf("Expected a put past the limit refused, got %d lines", strings.Count(p.GetQuery(), "\n")+1)
+	}
+	p.Update(editor.EditorSuccessMsg{Text: sb.String()})
+	if !p.IsReadOnly() || !strings.Contains(p.View(), "12001 lines") {
+		t.Errorf("Expected long external editor text opened read-only")
+	}
+	p.NewBuffer()
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("i")})
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(sb.String()), Paste: true})
+	if !p.IsReadOnly() || !strings.Contains(p.View(), "12001 lines") {
+		t.Errorf("Expected a long paste opened read-only")
+	}
+
+	p.RestoreSession([]storage.SessionBuffer{{Name: "draft.sql", Text: sb.String()}}, 0)
+	if buffers, _ := p.SessionBuffers(0); !p.IsReadOnly() || len(buffers) != 1 || buffers[0].Script == "" {
+		t.Errorf("Expected long session text restored read-only")
+	}
+}
@@ -0,0 +1,131 @@
+package unit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/MachineLearning-Nerd/lazydb/internal/storage"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/components"
+	"github.com/MachineLearning-Nerd/lazydb/internal/ui/panels"
+)
+
+func TestSessionStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lazydb", "session.json")
+	store := storage.NewSessionStore(path)
+
+	session, err := store.Load()
+	if err != nil || session != nil {
+		t.Fatalf("Expected no session before the first save, got %v, %v", session, err)
+	}
+
+	result := db.QueryResult{
+		Columns:  []string{"id"},
+		Rows:     [][]string{{"1"}, {"2"}, {"3"}},
+		RowCount: 3,
+	}
+	saved := &storage.Session{
+		Buffers: []storage.SessionBuffer{
+			{Text: "SELECT 1", CursorRow: 0, CursorCol: 7},
+			{Name: "report.sql", Text: "SELECT id FROM t", Connection: "prod",
+				LastResult: storage.NewSessionResult(result, 2)},
+		},
+		ActiveBuffer:  1,
+		Layout:        storage.SessionLayout{Connections: 20, Editor: 40, Results: 40},
+		FocusedPanel:  "editor",
+		ExpandedNodes: map[string][]string{"prod": {"public", "public/tables"}},
+	}
+	if err := store.Save(saved); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected session file mode 0600, got %v", info.Mode().Perm())
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Version != 1 || loaded.ActiveBuffer != 1 || loaded.FocusedPanel != "editor" {
+		t.Errorf("Unexpected session header: %+v", loaded)
+	}
+	if !reflect.DeepEqual(loaded.Layout, saved.Layout) || !reflect.DeepEqual(loaded.ExpandedNodes, saved.ExpandedNodes) {
+		t.Errorf("Expected layout and expanded nodes to round-trip, got %+v", loaded)
+	}
+	last := loaded.Buffers[1].LastResult
+	if last == nil || len(last.Rows) != 2 || last.RowCount != 3 {
+		t.Fatalf("Expected the last result capped at 2 of 3 rows, got %+v", last)
+	}
+
+	failed := storage.NewSessionResult(db.QueryResult{Error: errors.New("boom")}, 10).QueryResult()
+	if failed.Error == nil || failed.Error.Error() != "boom" {
+		t.Errorf("Expected the result error to round-trip, got %v", failed.Error)
+	}
+}
+
+func TestEditorSessionRestore(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := storage.SaveQuery("SELECT 1", "report.sql"); err != nil {
+		t.Fatal(err)
+	}
+
+	p := panels.NewEditorPanel()
+	p.RestoreSession([]storage.SessionBuffer{
+		{Text: "scratch\ntext", CursorRow: 1, CursorCol: 2},
+		{Name: "report.sql", Text: "SELECT 2", Connection: "gone",
+			LastResult: &storage.SessionResult{Columns: []string{"n"}, Rows: [][]string{{"2"}}, RowCount: 1}},
+	}, 1)
+
+	if p.BufferName() != "report.sql" || p.GetQuery() != "SELECT 2" {
+		t.Fatalf("Expected report.sql active, got %q %q", p.BufferName(), p.GetQuery())
+	}
+	if !p.IsDirty() {
+		t.Errorf("Expected the file buffer to differ from the file on disk")
+	}
+	if result, ok := p.LastResult(); !ok || result.RowCount != 1 {
+		t.Errorf("Expected the last result restored, got %+v", result)
+	}
+
+	buffers, active := p.SessionBuffers(100)
+	if active != 1 || len(buffers) != 2 {
+		t.Fatalf("Expected 2 buffers with the second active, got %d, %d", len(buffers), active)
+	}
+	if buffers[0].CursorRow != 1 || buffers[0].CursorCol != 2 {
+		t.Errorf("Expected the scratch cursor at 1:2, got %d:%d", buffers[0].CursorRow, buffers[0].CursorCol)
+	}
+	if buffers[1].Connection != "" {
+		t.Errorf("Expected the binding to a missing connection dropped, got %q", buffers[1].Connection)
+	}
+
+	p.PrevBuffer()
+	if p.IsDirty() {
+		t.Errorf("Expected the scratch buffer to restore clean")
+	}
+	if _, ok := p.LastResult(); ok {
+		t.Errorf("Expected no last result for the scratch buffer")
+	}
+}
+
+func TestSchemaTreeRestoreExpanded(t *testing.T) {
+	tree := components.NewSchemaTree(nil)
+	tree.RestoreExpanded([]string{"public", "public/views"})
+	tree.HandleSchemasLoaded([]string{"audit", "public"})
+	tree.HandleSchemaObjectsLoaded("public",
+		[]db.SchemaObject{{Name: "users"}},
+		[]db.SchemaObject{{Name: "active_users"}},
+		nil)
+
+	want := []string{"public", "public/views"}
+	if got := tree.ExpandedPaths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v expanded, got %v", want, got)
+	}
+}
@@ -0,0 +1,75 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := "-- users\nSELECT 1;\n\n  SELECT 'a;b' ;\n/* last */ select 3\n"
+	statements := db.SplitStatements(script)
+	want := []string{"-- users\nSELECT 1", "SELECT 'a;b'", "/* last */ select 3"}
+	if len(statements) != len(want) {
+		t.Fatalf("Expected %d statements, got %+v", len(want), statements)
+	}
+	for i, stmt := range statements {
+		if stmt.Text != want[i] || script[stmt.Start:stmt.End] != want[i] {
+			t.Errorf("Statement %d: got %q at [%d, %d), want %q", i, stmt.Text, stmt.Start, stmt.End, want[i])
+		}
+	}
+
+	// A syntax error in one statement falls back to the scanner's semicolons
+	statements = db.SplitStatements("SELECT 1; SELEC 2; SELECT ';'")
+	if len(statements) != 3 || statements[1].Text != "SELEC 2" || statements[2].Text != "SELECT ';'" {
+		t.Errorf("Expected scanner fallback split, got %+v", statements)
+	}
+
+	// Trailing comments and empty statements are dropped
+	if statements := db.SplitStatements("SELECT 1;; -- done\n"); len(statements) != 1 {
+		t.Errorf("Expected one statement, got %+v", statements)
+	}
+}
+
+func TestIsSelectQuery(t *testing.T) {
+	for query, want := range map[string]bool{
+		"SELECT * FROM users":                                        true,
+		"SELECT 1 UNION SELECT 2":                                    true,
+		"WITH t AS (SELECT 1) SELECT * FROM t":                       true,
+		"VALUES (1), (2)":                                            true,
+		"UPDATE users SET active = false RETURNING id":               false,
+		"DELETE FROM users RETURNING id":                             false,
+		"INSERT INTO users (id) VALUES (1) RETURNING id":             false,
+		"WITH d AS (DELETE FROM users RETURNING id) SELECT * FROM d": false,
+		"SELECT * INTO copy FROM users":                              false,
+		"SELECT * FROM users FOR UPDATE":                             false,
+		"SELECT 1; SELECT 2":                                         false,
+		"SELEC 1":                                                    false,
+	} {
+		if got := db.IsSelectQuery(query); got != want {
+			t.Errorf("IsSelectQuery(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestStatementAt(t *testing.T) {
+	script := "SELECT 1;\nSELECT 2;\n\nSELECT 3"
+	cases := map[int]string{
+		0:                "SELECT 1",
+		len("SELECT 1"):  "SELECT 1", // On the semicolon
+		len("SELECT 1;"): "SELECT 1", // Right after it
+		12:               "SELECT 2",
+		len(script) - 1:  "SELECT 3",
+		len(script):      "SELECT 3",
+	}
+	for offset, want := range cases {
+		stmt, ok := db.StatementAt(script, offset)
+		if !ok || stmt.Text != want {
+			t.Errorf("Offset %d: got %q, want %q", offset, stmt.Text, want)
+		}
+	}
+
+	if _, ok := db.StatementAt("  -- nothing\n", 0); ok {
+		t.Errorf("Expected no statement in a comment-only script")
+	}
+}
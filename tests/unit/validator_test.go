@@ -0,0 +1,55 @@
+package unit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/db"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestValidateReportsParserPosition(t *testing.T) {
+	v := db.NewSQLValidator()
+
+	query := "SELECT id\nFROM users\nWHER id = 1"
+	result := v.Validate(query)
+	if result.Valid || len(result.Errors) != 1 {
+		t.Fatalf("Expected one error, got %+v", result)
+	}
+
+	err := result.Errors[0]
+	if err.Line != 3 || err.Column != 6 {
+		t.Errorf("Expected error at line 3, column 6, got line %d, column %d", err.Line, err.Column)
+	}
+	if got := query[err.Offset : err.Offset+err.Length]; got != "id" {
+		t.Errorf("Expected the offending token 'id', got %q", got)
+	}
+}
+
+func TestServerErrorPosition(t *testing.T) {
+	// Positions are in characters, not bytes
+	query := "SELECT 'é',\n  missing FROM users"
+	pgErr := &pgconn.PgError{Message: `column "missing" does not exist`, Position: 15}
+
+	verr, ok := db.ServerError(query, fmt.Errorf("execute: %w", pgErr))
+	if !ok {
+		t.Fatal("Expected a PostgreSQL error to be recognized")
+	}
+	if verr.Line != 2 || verr.Column != 3 {
+		t.Errorf("Expected line 2, column 3, got line %d, column %d", verr.Line, verr.Column)
+	}
+	if got := query[verr.Offset : verr.Offset+verr.Length]; got != "missing" {
+		t.Errorf("Expected the offending token 'missing', got %q", got)
+	}
+
+	if _, ok := db.ServerError(query, fmt.Errorf("connection refused")); ok {
+		t.Error("Expected non-PostgreSQL errors to be ignored")
+	}
+}
+
+func TestOffsetToLineColumn(t *testing.T) {
+	line, column := db.OffsetToLineColumn("ab\ncd", 4)
+	if line != 2 || column != 2 {
+		t.Errorf("Expected line 2, column 2, got %d, %d", line, column)
+	}
+}
@@ -0,0 +1,335 @@
+package unit
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MachineLearning-Nerd/lazydb/internal/editor"
+	"github.com/MachineLearning-Nerd/lazydb/internal/storage"
+)
+
+// vimRun feeds keys (one per rune) to a new engine over text with the cursor
+// at the "|" marker and returns the buffer and engine
+func vimRun(t *testing.T, text, keys string) (*editor.Buffer, *editor.Vim) {
+	t.Helper()
+	offset := strings.Index(text, "|")
+	if offset < 0 {
+		t.Fatalf("missing cursor marker in %q", text)
+	}
+	buf := editor.NewBuffer(text[:offset] + text[offset+1:])
+	buf.SetCursor(buf.PositionAt(offset))
+
+	vim := editor.NewVim()
+	for _, r := range keys {
+		vim.HandleKey(buf, string(r))
+	}
+	return buf, vim
+}
+
+// withCursor renders the buffer text with a "|" at the cursor
+func withCursor(buf *editor.Buffer) string {
+	text := buf.Text()
+	offset := buf.Offset(buf.Cursor())
+	return text[:offset] + "|" + text[offset:]
+}
+
+func TestVimMotions(t *testing.T) {
+	cases := []struct {
+		text, keys, want string
+	}{
+		{"|SELECT id, name FROM users", "w", "SELECT |id, name FROM users"},
+		{"|SELECT id, name FROM users", "3w", "SELECT id, |name FROM users"},
+		{"|SELECT id, name FROM users", "e", "SELEC|T id, name FROM users"},
+		{"SELECT id, name |FROM users", "b", "SELECT id, |name FROM users"},
+		{"SELECT id, |name FROM users", "$", "SELECT id, name FROM user|s"},
+		{"SELECT id, |name FROM users", "0", "|SELECT id, name FROM users"},
+		{"|SELECT id, name FROM users", "fF", "SELECT id, name |FROM users"},
+		{"|SELECT id, name FROM users", "tF", "SELECT id, name| FROM users"},
+		{"|a b a b a", "fa;", "a b a b |a"},
+		{"one\ntwo\nth|ree", "gg", "|one\ntwo\nthree"},
+		{"|one\ntwo\nthree", "G", "one\ntwo\n|three"},
+		{"|one\ntwo\nthree", "2G", "one\n|two\nthree"},
+		{"|one\n\ntwo", "w", "one\n|\ntwo"},
+	}
+	for _, tc := range cases {
+		buf, _ := vimRun(t, tc.text, tc.keys)
+		if got := withCursor(buf); got != tc.want {
+			t.Errorf("%q + %q: got %q, want %q", tc.text, tc.keys, got, tc.want)
+		}
+	}
+}
+
+func TestVimOperators(t *testing.T) {
+	cases := []struct {
+		text, keys, want string
+	}{
+		{"one\ntw|o\nthree", "dd", "one\n|three"},
+		{"|one\ntwo\nthree", "2dd", "|three"},
+		{"SELECT |id, name FROM users", "dw", "SELECT |, name FROM users"},
+		{"SELECT |id, name FROM users", "d2w", "SELECT |name FROM users"},
+		{"SELECT |id, name FROM users", "dt ", "SELECT | name FROM users"},
+		{"SELECT id, name |FROM users", "d$", "SELECT id, name| "},
+		{"SELECT |id FROM users", "x", "SELECT |d FROM users"},
+		{"one\n|two\nthree", "dj", "|one"},
+		{"one\n|two", "dgg", "|"},
+		{"SELECT |id FROM users", "cwkey", "SELECT key| FROM users"},
+		{"WHERE name = 'al|ice' AND", "di'", "WHERE name = '|' AND"},
+		{"SELECT count(u.|id) FROM", "di(", "SELECT count(|) FROM"},
+		{"SELECT count(u.|id) FROM", "da(", "SELECT count| FROM"},
+		{"SELECT my_c|olumn FROM", "diw", "SELECT | FROM"},
+		{"a\nb|\n\nc", "dip", "|\nc"},
+		{"SELECT round(sum(|x), 2) FROM", "2di(", "SELECT round(|) FROM"},
+		{"SELECT round(sum(|x), 2) FROM", "d2a(", "SELECT round| FROM"},
+		{"SELECT round(sum(|x), 2) FROM", "3di(", "SELECT round(sum(|x), 2) FROM"},
+		{"SELECT |a b c FROM", "d3aw", "SELECT |FROM"},
+		{"SELECT |a b c", "2diw", "SELECT |b c"},
+		{"|a\n\nb\n\nc", "2dap", "|c"},
+		{"x = '|a'", "2di'", "x = '|a'"},
+	}
+	for _, tc := range cases {
+		buf, vim := vimRun(t, tc.text, tc.keys)
+		// Text typed after c lands in insert mode, apply it like the editor would
+		if vim.Mode() == editor.ModeInsert && strings.HasPrefix(tc.keys, "c") {
+			typed := strings.TrimLeft(tc.keys[1:], "0123456789wbe$")
+			buf.SetCursor(buf.InsertText(buf.Cursor(), typed))
+		}
+		if got := withCursor(buf); got != tc.want {
+			t.Errorf("%q + %q: got %q, want %q", tc.text, tc.keys, got, tc.want)
+		}
+	}
+}
+
+func TestVimRegistersAndPut(t *testing.T) {
+	// Linewise yank and put below the cursor line
+	buf, vim := vimRun(t, "o|ne\ntwo", "yyjp")
+	if got := buf.Text(); got != "one\ntwo\none" {
+		t.Errorf("Expected line pasted below, got %q", got)
+	}
+	if reg, _ := vim.Register('0'); !reg.Linewise || reg.Text != "one" {
+		t.Errorf("Expected linewise yank register, got %+v", reg)
+	}
+
+	// Charwise delete and put before the cursor
+	buf, vim = vimRun(t, "|ab cd", "dwP")
+	if got := withCursor(buf); got != "ab| cd" {
+		t.Errorf("Expected charwise put before the cursor, got %q", got)
+	}
+	if reg, _ := vim.Register('-'); reg.Text != "ab " {
+		t.Errorf("Expected small delete register, got %+v", reg)
+	}
+
+	// Pending commands wait for the next key
+	_, vim = vimRun(t, "|one", "2d")
+	if vim.Pending() != "2d" {
+		t.Errorf("Expected pending '2d', got %q", vim.Pending())
+	}
+
+	// gq is handed back to the editor
+	_, vim = vimRun(t, "|select 1", "g")
+	buf = editor.NewBuffer("select 1")
+	if res := vim.HandleKey(buf, "q"); res.Command != editor.CommandFormatStatement {
+		t.Errorf("Expected format command, got %+v", res)
+	}
+}
+
+func TestVimVisualModes(t *testing.T) {
+	cases := []struct {
+		text, keys, want string
+	}{
+		{"SELECT |id, name FROM users", "vlld", "SELECT | name FROM users"},
+		{"SELECT |id, name FROM users", "veU", "SELECT |ID, name FROM users"},
+		{"count(u.|id)", "vi(d", "count(|)"},
+		{"one\n|two\nthree", "Vjd", "|one"},
+		{"one\n|two\nthree", "Vkd", "|three"},
+		{"ab|cd", "vho~", "a|BCd"},
+		{"|a1\nb2\nc3", "ctrl+vjjd", "|1\n2\n3"},
+		{"|one\ntwo", "VJ", "one| two"},
+	}
+	for _, tc := range cases {
+		buf, vim := vimRun(t, tc.text, "")
+		for _, key := range splitKeys(tc.keys) {
+			vim.HandleKey(buf, key)
+		}
+		if got := withCursor(buf); got != tc.want {
+			t.Errorf("%q + %q: got %q, want %q", tc.text, tc.keys, got, tc.want)
+		}
+		if vim.Mode() != editor.ModeNormal {
+			t.Errorf("%q + %q: expected normal mode after the operator, got %v", tc.text, tc.keys, vim.Mode())
+		}
+	}
+}
+
+// splitKeys splits keys into runes, keeping "ctrl+x" names together
+func splitKeys(keys string) []string {
+	var out []string
+	for keys != "" {
+		if strings.HasPrefix(keys, "ctrl+") {
+			out = append(out, keys[:6])
+			keys = keys[6:]
+			continue
+		}
+		r := []rune(keys)[0]
+		out = append(out, string(r))
+		keys = keys[len(string(r)):]
+	}
+	return out
+}
+
+func TestVimVisualSelection(t *testing.T) {
+	buf, vim := vimRun(t, "ab|cd\nefgh", "vj")
+	sel, ok := vim.Selection(buf.Cursor())
+	if !ok || sel.Start != (editor.Position{Row: 0, Col: 2}) || sel.End != (editor.Position{Row: 1, Col: 2}) {
+		t.Fatalf("Unexpected selection %+v", sel)
+	}
+	if from, to, _ := sel.Columns(0, 4); from != 2 || to != 5 {
+		t.Errorf("Expected first row to include the line break, got [%d, %d)", from, to)
+	}
+	if from, to, _ := sel.Columns(1, 4); from != 0 || to != 3 {
+		t.Errorf("Expected last row up to the cursor, got [%d, %d)", from, to)
+	}
+
+	vim.HandleKey(buf, "esc")
+	if _, ok := vim.Selection(buf.Cursor()); ok || vim.Mode() != editor.ModeNormal {
+		t.Errorf("Expected esc to leave visual mode")
+	}
+}
+
+func TestVimVisualBlockInsert(t *testing.T) {
+	buf, vim := vimRun(t, "|id\nname\nemail", "")
+	for _, key := range splitKeys("ctrl+vjjI") {
+		vim.HandleKey(buf, key)
+	}
+	if vim.Mode() != editor.ModeInsert {
+		t.Fatalf("Expected insert mode, got %v", vim.Mode())
+	}
+	buf.SetCursor(buf.InsertText(buf.Cursor(), "u."))
+	vim.EndInsert(buf)
+
+	if got := withCursor(buf); got != "|u.id\nu.name\nu.email" {
+		t.Errorf("Expected block insert on every row, got %q", got)
+	}
+
+	// The whole block insert is one undo step
+	vim.HandleKey(buf, "u")
+	if got := buf.Text(); got != "id\nname\nemail" {
+		t.Errorf("Expected undo to remove the block insert, got %q", got)
+	}
+}
+
+func TestVimUndoRedo(t *testing.T) {
+	buf, vim := vimRun(t, "|SELECT id FROM users", "dwx")
+	if got := buf.Text(); got != "d FROM users" {
+		t.Fatalf("Unexpected text %q", got)
+	}
+
+	vim.HandleKey(buf, "u")
+	if got := buf.Text(); got != "id FROM users" {
+		t.Errorf("Expected one change undone, got %q", got)
+	}
+	vim.HandleKey(buf, "u")
+	if got := buf.Text(); got != "SELECT id FROM users" {
+		t.Errorf("Expected both changes undone, got %q", got)
+	}
+	vim.HandleKey(buf, "ctrl+r")
+	if got := buf.Text(); got != "id FROM users" {
+		t.Errorf("Expected redo, got %q", got)
+	}
+
+	// An insert session, including the command that started it, is one change
+	for _, key := range []string{"c", "w"} {
+		vim.HandleKey(buf, key)
+	}
+	buf.SetCursor(buf.InsertText(buf.Cursor(), "n"))
+	buf.SetCursor(buf.InsertText(buf.Cursor(), "ame"))
+	vim.EndInsert(buf)
+	if got := buf.Text(); got != "name FROM users" {
+		t.Fatalf("Unexpected text after change %q", got)
+	}
+	vim.HandleKey(buf, "u")
+	if got := buf.Text(); got != "id FROM users" {
+		t.Errorf("Expected the insert session undone at once, got %q", got)
+	}
+
+	// A new change clears the redo history
+	vim.HandleKey(buf, "x")
+	if vim.HandleKey(buf, "ctrl+r"); buf.Text() != "d FROM users" {
+		t.Errorf("Expected nothing to redo, got %q", buf.Text())
+	}
+}
+
+func TestVimNamedRegisters(t *testing.T) {
+	buf, vim := vimRun(t, "|one two", "")
+	for _, key := range splitKeys("\"ayw\"Byww\"byw$\"ap") {
+		vim.HandleKey(buf, key)
+	}
+	if reg, _ := vim.Register('a'); reg.Text != "one " {
+		t.Errorf("Expected \"a to hold the yank, got %+v", reg)
+	}
+	if reg, _ := vim.Register('b'); reg.Text != "two" {
+		t.Errorf("Expected \"b to be set after append to empty, got %+v", reg)
+	}
+	if got := buf.Text(); got != "one twoone " {
+		t.Errorf("Expected \"ap to put register a, got %q", got)
+	}
+	// Yanks into a named register don't touch "0
+	if _, ok := vim.Register('0'); ok {
+		t.Errorf("Expected \"0 to stay empty")
+	}
+
+	// Appending with an uppercase name
+	_, vim = vimRun(t, "|ab cd", "\"ayw")
+	buf = editor.NewBuffer("ab cd")
+	buf.SetCursor(editor.Position{Col: 3})
+	for _, key := range splitKeys("\"Ayw") {
+		vim.HandleKey(buf, key)
+	}
+	if reg, _ := vim.Register('a'); reg.Text != "ab cd" {
+		t.Errorf("Expected appended register, got %+v", reg)
+	}
+
+	// "+ goes to the system clipboard, "_ drops the text
+	var copied string
+	buf, vim = vimRun(t, "|select 1", "")
+	vim.SetClipboard(func(text string) error {
+		copied = text
+		return nil
+	})
+	for _, key := range splitKeys("\"+yy\"_dd") {
+		vim.HandleKey(buf, key)
+	}
+	if copied != "select 1" {
+		t.Errorf("Expected clipboard copy, got %q", copied)
+	}
+	if reg, _ := vim.Register('"'); reg.Text != "select 1" {
+		t.Errorf("Expected black hole delete to keep the unnamed register, got %+v", reg)
+	}
+}
+
+func TestRegisterStore(t *testing.T) {
+	store := storage.NewRegisterStore(filepath.Join(t.TempDir(), "registers.json"))
+
+	regs, err := store.Load()
+	if err != nil || len(regs) != 0 {
+		t.Fatalf("Expected no registers before the first save, got %v, %v", regs, err)
+	}
+
+	err = store.Save(map[rune]editor.Register{
+		'a': {Text: "SELECT 1", Linewise: true},
+		'+': {Text: "clipboard"},
+	})
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	regs, err = store.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if reg := regs['a']; reg.Text != "SELECT 1" || !reg.Linewise {
+		t.Errorf("Expected register a to round-trip, got %+v", reg)
+	}
+	if _, ok := regs['+']; ok {
+		t.Errorf("Expected the clipboard register not to be saved")
+	}
+}